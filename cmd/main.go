@@ -17,11 +17,13 @@ package main
 
 import (
 	"context"
+	"github.com/wentaojin/transferdb/common"
 	"github.com/wentaojin/transferdb/signal"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/wentaojin/transferdb/config"
@@ -49,13 +51,21 @@ func main() {
 	}()
 
 	// 信号量监听处理
+	// 收到退出信号取消任务调度，等待进行中任务完成，超过 graceful-timeout 强制退出
+	ctx, cancel := context.WithCancel(context.Background())
 	signal.SetupSignalHandler(func() {
+		cancel()
+		time.Sleep(time.Duration(cfg.AppConfig.GracefulTimeout) * time.Second)
+		zap.L().Warn("graceful shutdown timeout, force exit", zap.Int("graceful timeout", cfg.AppConfig.GracefulTimeout))
 		os.Exit(1)
 	})
 
 	// 程序运行
-	ctx := context.Background()
 	if err := server.Run(ctx, cfg); err != nil {
+		if errors.Is(err, common.ErrGracefulShutdown) {
+			zap.L().Info("server graceful shutdown finished")
+			return
+		}
 		zap.L().Fatal("server run failed", zap.Error(errors.Cause(err)))
 	}
 }
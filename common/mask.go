@@ -0,0 +1,192 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 字段脱敏类型
+const (
+	MaskTypeHash     = "HASH"
+	MaskTypeReplace  = "REPLACE"
+	MaskTypeNullify  = "NULLIFY"
+	MaskTypeFixed    = "FIXED"
+	MaskTypeTruncate = "TRUNCATE"
+	MaskTypeExpr     = "EXPR"
+
+	// DBMS_CRYPTO.HASH_SH1，兼容 ORACLE 11g
+	MaskHashAlgorithm = "3"
+	// REPLACE 默认掩码字符
+	MaskReplaceChar = "*"
+)
+
+var MaskTypes = []string{MaskTypeHash, MaskTypeReplace, MaskTypeNullify, MaskTypeFixed, MaskTypeTruncate, MaskTypeExpr}
+
+// 生成 ORACLE 端字段脱敏表达式，全量、CSV 以及增量统一以 ORACLE 端计算，保证同值脱敏结果一致
+// HASH：mask-value 为盐值，SHA1 十六进制字符串，BLOB 忽略盐值
+// REPLACE：mask-value 格式 "保留前缀长度,保留后缀长度[,掩码字符]"，其余字符以掩码字符替换，保持长度不变
+// NULLIFY：置空
+// FIXED：mask-value 固定值
+// TRUNCATE：mask-value 保留字符长度
+// EXPR：mask-value 为 ORACLE SQL 表达式，以 "字段名" 引用原字段
+func GenOracleMaskColumnExpr(maskType, maskValue, columnName, dataType string) (string, error) {
+	col := StringsBuilder(`"`, columnName, `"`)
+	switch StringUPPER(maskType) {
+	case MaskTypeHash:
+		var hashExpr string
+		switch {
+		case strings.EqualFold(dataType, "BLOB"):
+			hashExpr = StringsBuilder(`RAWTOHEX(DBMS_CRYPTO.HASH(`, col, `,`, MaskHashAlgorithm, `))`)
+		case strings.EqualFold(dataType, "CLOB") || strings.EqualFold(dataType, "NCLOB"):
+			if maskValue == "" {
+				hashExpr = StringsBuilder(`RAWTOHEX(DBMS_CRYPTO.HASH(`, col, `,`, MaskHashAlgorithm, `))`)
+			} else {
+				hashExpr = StringsBuilder(`RAWTOHEX(DBMS_CRYPTO.HASH(TO_CLOB(`, GenOracleStringLiteral(maskValue), `) || `, col, `,`, MaskHashAlgorithm, `))`)
+			}
+		default:
+			hashExpr = StringsBuilder(`RAWTOHEX(DBMS_CRYPTO.HASH(UTL_RAW.CAST_TO_RAW(`, GenOracleStringLiteral(maskValue), ` || TO_CHAR(`, col, `)),`, MaskHashAlgorithm, `))`)
+		}
+		return StringsBuilder(`CASE WHEN `, col, ` IS NULL THEN NULL ELSE `, hashExpr, ` END`), nil
+	case MaskTypeReplace:
+		prefix, suffix, char, err := parseMaskReplaceValue(maskValue)
+		if err != nil {
+			return "", err
+		}
+		charLiteral := GenOracleStringLiteral(char)
+		fullExpr := StringsBuilder(`RPAD(`, charLiteral, `,LENGTH(`, col, `),`, charLiteral, `)`)
+		maskExpr := StringsBuilder(`RPAD(`, charLiteral, `,LENGTH(`, col, `) - `, strconv.Itoa(prefix+suffix), `,`, charLiteral, `)`)
+		if prefix > 0 {
+			maskExpr = StringsBuilder(`SUBSTR(`, col, `,1,`, strconv.Itoa(prefix), `) || `, maskExpr)
+		}
+		if suffix > 0 {
+			maskExpr = StringsBuilder(maskExpr, ` || SUBSTR(`, col, `,-`, strconv.Itoa(suffix), `)`)
+		}
+		return StringsBuilder(`CASE WHEN LENGTH(`, col, `) <= `, strconv.Itoa(prefix+suffix), ` THEN `, fullExpr, ` ELSE `, maskExpr, ` END`), nil
+	case MaskTypeNullify:
+		return "NULL", nil
+	case MaskTypeFixed:
+		return GenOracleStringLiteral(maskValue), nil
+	case MaskTypeTruncate:
+		length, err := strconv.Atoi(strings.TrimSpace(maskValue))
+		if err != nil || length <= 0 {
+			return "", fmt.Errorf("mask type [%s] mask value [%s] must be positive integer", maskType, maskValue)
+		}
+		return StringsBuilder(`SUBSTR(`, col, `,1,`, strconv.Itoa(length), `)`), nil
+	case MaskTypeExpr:
+		if strings.TrimSpace(maskValue) == "" {
+			return "", fmt.Errorf("mask type [%s] mask value can not be null", maskType)
+		}
+		return maskValue, nil
+	default:
+		return "", fmt.Errorf("mask type [%s] isn't support, support mask type %v", maskType, MaskTypes)
+	}
+}
+
+func parseMaskReplaceValue(maskValue string) (int, int, string, error) {
+	var (
+		prefix, suffix int
+		char           = MaskReplaceChar
+		err            error
+	)
+	if strings.TrimSpace(maskValue) == "" {
+		return prefix, suffix, char, nil
+	}
+	values := strings.Split(maskValue, ",")
+	if len(values) < 2 || len(values) > 3 {
+		return prefix, suffix, char, fmt.Errorf("mask type [%s] mask value [%s] format must be [prefix,suffix[,char]]", MaskTypeReplace, maskValue)
+	}
+	if prefix, err = strconv.Atoi(strings.TrimSpace(values[0])); err != nil || prefix < 0 {
+		return prefix, suffix, char, fmt.Errorf("mask type [%s] mask value [%s] prefix must be non-negative integer", MaskTypeReplace, maskValue)
+	}
+	if suffix, err = strconv.Atoi(strings.TrimSpace(values[1])); err != nil || suffix < 0 {
+		return prefix, suffix, char, fmt.Errorf("mask type [%s] mask value [%s] suffix must be non-negative integer", MaskTypeReplace, maskValue)
+	}
+	if len(values) == 3 {
+		if len([]rune(values[2])) != 1 {
+			return prefix, suffix, char, fmt.Errorf("mask type [%s] mask value [%s] char must be single character", MaskTypeReplace, maskValue)
+		}
+		char = values[2]
+	}
+	return prefix, suffix, char, nil
+}
+
+// EXPR 脱敏表达式引用的字段，以双引号引用的标识符为准，忽略字符串字面量
+func GenOracleMaskExprColumns(maskValue string) []string {
+	var columns []string
+	for _, m := range oracleMaskExprColumnRegexp.FindAllStringSubmatch(oracleStringLiteralRegexp.ReplaceAllString(maskValue, "''"), -1) {
+		if !IsContainString(columns, m[1]) {
+			columns = append(columns, m[1])
+		}
+	}
+	return columns
+}
+
+var (
+	oracleStringLiteralRegexp  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	oracleMaskExprColumnRegexp = regexp.MustCompile(`"([^"]+)"`)
+)
+
+// ORACLE 字符串字面量
+func GenOracleStringLiteral(s string) string {
+	return StringsBuilder(`'`, strings.ReplaceAll(s, `'`, `''`), `'`)
+}
+
+// 增量脱敏 MySQL 字段字面量转换为 ORACLE 源端字段类型值，与全量脱敏表达式计算类型一致
+// 时间格式与全量查询格式一致，例如：'2020-01-01 00:00:00' -> TO_DATE('2020-01-01 00:00:00','YYYY-MM-DD HH24:MI:SS')
+// NULL 以及非字符串字面量原样返回，CLOB 统一 TO_CLOB 转换
+func GenOracleMaskLiteral(literal, dataType string) string {
+	dataType = StringUPPER(dataType)
+	// 移除字符集前缀，例如：_UTF8MB4'marvin'
+	if strings.HasPrefix(literal, "_") {
+		if idx := strings.Index(literal, "'"); idx > 0 {
+			literal = literal[idx:]
+		}
+	}
+	if strings.HasPrefix(literal, "'") {
+		literal = strings.ReplaceAll(literal, `\\`, `\`)
+		switch {
+		case dataType == "DATE":
+			return StringsBuilder(`TO_DATE(`, literal, `,'YYYY-MM-DD HH24:MI:SS')`)
+		case strings.Contains(dataType, "TIMESTAMP") && strings.Contains(dataType, "TIME ZONE"):
+			return StringsBuilder(`TO_TIMESTAMP_TZ(`, literal, `,'YYYY-MM-DD HH24:MI:SS.FF')`)
+		case strings.Contains(dataType, "TIMESTAMP"):
+			return StringsBuilder(`TO_TIMESTAMP(`, literal, `,'YYYY-MM-DD HH24:MI:SS.FF')`)
+		case dataType == "BINARY_FLOAT":
+			return StringsBuilder(`TO_BINARY_FLOAT(`, literal, `)`)
+		case dataType == "BINARY_DOUBLE":
+			return StringsBuilder(`TO_BINARY_DOUBLE(`, literal, `)`)
+		case IsContainString([]string{"NUMBER", "DECIMAL", "DEC", "DOUBLE PRECISION", "FLOAT", "INTEGER", "INT", "REAL", "NUMERIC", "SMALLINT"}, dataType):
+			return StringsBuilder(`TO_NUMBER(`, literal, `)`)
+		}
+	}
+	if dataType == "CLOB" || dataType == "NCLOB" {
+		return StringsBuilder(`TO_CLOB(`, literal, `)`)
+	}
+	return literal
+}
+
+// MySQL 字符串字面量，NULLABLE 表示 NULL
+func GenMySQLStringLiteral(s string) string {
+	if s == "NULLABLE" {
+		return "NULL"
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return StringsBuilder(`'`, strings.ReplaceAll(s, `'`, `''`), `'`)
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenOracleMaskColumnExpr(t *testing.T) {
+	tests := []struct {
+		name      string
+		maskType  string
+		maskValue string
+		dataType  string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:     "hash",
+			maskType: MaskTypeHash,
+			dataType: "VARCHAR2",
+			want:     `CASE WHEN "C" IS NULL THEN NULL ELSE RAWTOHEX(DBMS_CRYPTO.HASH(UTL_RAW.CAST_TO_RAW('' || TO_CHAR("C")),3)) END`,
+		},
+		{
+			name:      "replace",
+			maskType:  MaskTypeReplace,
+			maskValue: "3,4",
+			want:      `CASE WHEN LENGTH("C") <= 7 THEN RPAD('*',LENGTH("C"),'*') ELSE SUBSTR("C",1,3) || RPAD('*',LENGTH("C") - 7,'*') || SUBSTR("C",-4) END`,
+		},
+		{
+			name:      "fixed",
+			maskType:  MaskTypeFixed,
+			maskValue: "it's",
+			want:      `'it''s'`,
+		},
+		{
+			name:      "truncate",
+			maskType:  MaskTypeTruncate,
+			maskValue: "2",
+			want:      `SUBSTR("C",1,2)`,
+		},
+		{
+			name:      "replace error",
+			maskType:  MaskTypeReplace,
+			maskValue: "3",
+			wantErr:   true,
+		},
+		{
+			name:     "type error",
+			maskType: "SHUFFLE",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenOracleMaskColumnExpr(tt.maskType, tt.maskValue, "C", tt.dataType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenOracleMaskColumnExpr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GenOracleMaskColumnExpr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenOracleMaskExprColumns(t *testing.T) {
+	tests := []struct {
+		maskValue string
+		want      []string
+	}{
+		{maskValue: `SUBSTR("C",1,2)`, want: []string{"C"}},
+		{maskValue: `"C" || '-"X"-' || "D" || "C"`, want: []string{"C", "D"}},
+		{maskValue: `'it''s "X"'`, want: nil},
+	}
+	for _, tt := range tests {
+		if got := GenOracleMaskExprColumns(tt.maskValue); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GenOracleMaskExprColumns(%s) = %v, want %v", tt.maskValue, got, tt.want)
+		}
+	}
+}
@@ -60,25 +60,39 @@ var (
 	MYSQLCharsetBIG5          = "BIG5"
 	MYSQLCharsetGBK           = "GBK"
 	MYSQLCharsetGB18030       = "GB18030"
+	MYSQLCharsetLATIN1        = "LATIN1"
+	MYSQLCharsetASCII         = "ASCII"
+	MYSQLCharsetCP932         = "CP932"
 	ORACLECharsetUTF8         = "UTF8"
 	ORACLECharsetAL32UTF8     = "AL32UTF8"
 	ORACLECharsetZHT16BIG5    = "ZHT16BIG5"
 	ORACLECharsetZHS16GBK     = "ZHS16GBK"
 	ORACLECharsetZHS32GB18030 = "ZHS32GB18030"
+	ORACLECharsetWE8ISO8859P1 = "WE8ISO8859P1"
+	ORACLECharsetWE8MSWIN1252 = "WE8MSWIN1252"
+	ORACLECharsetUS7ASCII     = "US7ASCII"
+	ORACLECharsetJA16SJIS     = "JA16SJIS"
+	ORACLECharsetKO16MSWIN949 = "KO16MSWIN949"
 )
 
 // 数据迁移、数据校验、表结构默认值、注释
 // 字符类型数据映射规则
 // 1、用于程序连接源端数据库读取数据字符类型数据，以对应字符集写入下游数据库
 // 2、用于数据表默认值或者注释
+// LATIN1 同 MySQL latin1 为 Windows-1252（CP1252），ISO88591 仅用于 ORACLE WE8ISO8859P1 源端读取
 const (
-	CharsetUTF8MB4 = "UTF8MB4"
-	CharsetGB18030 = "GB18030"
-	CharsetBIG5    = "BIG5"
-	CharsetGBK     = "GBK"
+	CharsetUTF8MB4  = "UTF8MB4"
+	CharsetGB18030  = "GB18030"
+	CharsetBIG5     = "BIG5"
+	CharsetGBK      = "GBK"
+	CharsetLATIN1   = "LATIN1"
+	CharsetISO88591 = "ISO88591"
+	CharsetASCII    = "ASCII"
+	CharsetCP932    = "CP932"
+	CharsetCP949    = "CP949"
 )
 
-var MigrateDataSupportCharset = []string{CharsetUTF8MB4, CharsetGBK, CharsetBIG5, CharsetGB18030}
+var MigrateDataSupportCharset = []string{CharsetUTF8MB4, CharsetGBK, CharsetBIG5, CharsetGB18030, CharsetLATIN1, CharsetASCII, CharsetCP932}
 
 var MigrateOracleCharsetStringConvertMapping = map[string]string{
 	ORACLECharsetUTF8:         CharsetUTF8MB4,
@@ -86,6 +100,12 @@ var MigrateOracleCharsetStringConvertMapping = map[string]string{
 	ORACLECharsetZHT16BIG5:    CharsetBIG5,
 	ORACLECharsetZHS16GBK:     CharsetGBK,
 	ORACLECharsetZHS32GB18030: CharsetGB18030,
+	ORACLECharsetWE8ISO8859P1: CharsetISO88591,
+	ORACLECharsetWE8MSWIN1252: CharsetLATIN1,
+	ORACLECharsetUS7ASCII:     CharsetASCII,
+	// JA16SJIS 包含微软扩展字符，按 CP932（Windows-31J）读取
+	ORACLECharsetJA16SJIS:     CharsetCP932,
+	ORACLECharsetKO16MSWIN949: CharsetCP949,
 }
 
 var MigrateMYSQLCompatibleCharsetStringConvertMapping = map[string]string{
@@ -95,6 +115,9 @@ var MigrateMYSQLCompatibleCharsetStringConvertMapping = map[string]string{
 	MYSQLCharsetBIG5:    CharsetBIG5,
 	MYSQLCharsetGBK:     CharsetGBK,
 	MYSQLCharsetGB18030: CharsetGB18030,
+	MYSQLCharsetLATIN1:  CharsetLATIN1,
+	MYSQLCharsetASCII:   CharsetASCII,
+	MYSQLCharsetCP932:   CharsetCP932,
 }
 
 // 表结构迁移以及表结构校验字符集、排序规则
@@ -106,6 +129,12 @@ var MigrateTableStructureDatabaseCharsetMap = map[string]map[string]string{
 		ORACLECharsetZHT16BIG5:    MYSQLCharsetBIG5,
 		ORACLECharsetZHS16GBK:     MYSQLCharsetGBK,
 		ORACLECharsetZHS32GB18030: MYSQLCharsetGB18030,
+		ORACLECharsetWE8ISO8859P1: MYSQLCharsetLATIN1,
+		ORACLECharsetWE8MSWIN1252: MYSQLCharsetLATIN1,
+		ORACLECharsetUS7ASCII:     MYSQLCharsetASCII,
+		ORACLECharsetJA16SJIS:     MYSQLCharsetCP932,
+		// MySQL euckr 不完整支持 CP949 扩展字符，统一使用 UTF8MB4
+		ORACLECharsetKO16MSWIN949: MYSQLCharsetUTF8MB4,
 	},
 	// TiDB 表结构以及字段属性统一使用 UTF8MB4 字符集，适用于 check、compare、reverse 模式下 o2t、t2o
 	TaskTypeOracle2TiDB: {
@@ -114,6 +143,11 @@ var MigrateTableStructureDatabaseCharsetMap = map[string]map[string]string{
 		ORACLECharsetZHT16BIG5:    MYSQLCharsetUTF8MB4,
 		ORACLECharsetZHS16GBK:     MYSQLCharsetUTF8MB4,
 		ORACLECharsetZHS32GB18030: MYSQLCharsetUTF8MB4,
+		ORACLECharsetWE8ISO8859P1: MYSQLCharsetUTF8MB4,
+		ORACLECharsetWE8MSWIN1252: MYSQLCharsetUTF8MB4,
+		ORACLECharsetUS7ASCII:     MYSQLCharsetUTF8MB4,
+		ORACLECharsetJA16SJIS:     MYSQLCharsetUTF8MB4,
+		ORACLECharsetKO16MSWIN949: MYSQLCharsetUTF8MB4,
 	},
 	TaskTypeMySQL2Oracle: {
 		MYSQLCharsetUTF8MB4: ORACLECharsetAL32UTF8,
@@ -145,6 +179,9 @@ var MigrateTableStructureDatabaseCollationMap = map[string]map[string]map[string
 			MYSQLCharsetBIG5:    "BIG5_CHINESE_CI",    // 无此排序规则，用 BIG5_CHINESE_CI 代替
 			MYSQLCharsetGBK:     "GBK_CHINESE_CI",     // 无此排序规则，用 GBK_CHINESE_CI 代替
 			MYSQLCharsetGB18030: "GB18030_CHINESE_CI", // 无此排序规则，用 GB18030_CHINESE_CI 代替，gb18030_unicode_520_ci 区分大小写，但不区分重音
+			MYSQLCharsetLATIN1:  "LATIN1_GENERAL_CI",
+			MYSQLCharsetASCII:   "ASCII_GENERAL_CI",
+			MYSQLCharsetCP932:   "CP932_JAPANESE_CI",
 		},
 		// 不区分大小写和重音
 		"BINARY_AI": {
@@ -153,6 +190,9 @@ var MigrateTableStructureDatabaseCollationMap = map[string]map[string]map[string
 			MYSQLCharsetBIG5:    "BIG5_CHINESE_CI",
 			MYSQLCharsetGBK:     "GBK_CHINESE_CI",
 			MYSQLCharsetGB18030: "GB18030_CHINESE_CI",
+			MYSQLCharsetLATIN1:  "LATIN1_SWEDISH_CI",
+			MYSQLCharsetASCII:   "ASCII_GENERAL_CI",
+			MYSQLCharsetCP932:   "CP932_JAPANESE_CI",
 		},
 		// 区分大小写和重音，如果不使用扩展名下，该规则是 ORACLE 默认值
 		"BINARY_CS": {
@@ -161,6 +201,9 @@ var MigrateTableStructureDatabaseCollationMap = map[string]map[string]map[string
 			MYSQLCharsetBIG5:    "BIG5_BIN",
 			MYSQLCharsetGBK:     "GBK_BIN",
 			MYSQLCharsetGB18030: "GB18030_BIN",
+			MYSQLCharsetLATIN1:  "LATIN1_BIN",
+			MYSQLCharsetASCII:   "ASCII_BIN",
+			MYSQLCharsetCP932:   "CP932_BIN",
 		},
 		// ORACLE 12.2 以下版本
 		// 区分大小写和重音
@@ -170,6 +213,9 @@ var MigrateTableStructureDatabaseCollationMap = map[string]map[string]map[string
 			MYSQLCharsetBIG5:    "BIG5_BIN",
 			MYSQLCharsetGBK:     "GBK_BIN",
 			MYSQLCharsetGB18030: "GB18030_BIN",
+			MYSQLCharsetLATIN1:  "LATIN1_BIN",
+			MYSQLCharsetASCII:   "ASCII_BIN",
+			MYSQLCharsetCP932:   "CP932_BIN",
 		},
 	},
 	// Charset 统一 UTF8MB4
@@ -17,12 +17,17 @@ package common
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/encoding/traditionalchinese"
 	"golang.org/x/text/transform"
 	"io"
+	"math"
 	"os"
 	"reflect"
 	"regexp"
@@ -380,63 +385,113 @@ func DiffStructArray(structA, structB interface{}) ([]interface{}, []interface{}
 	return addDiffs, removeDiffs, false
 }
 
+// 字符集对应编码，UTF8MB4 以及 ASCII 单独处理
+var charsetEncodingMap = map[string]encoding.Encoding{
+	CharsetGBK:      simplifiedchinese.GBK,
+	CharsetGB18030:  simplifiedchinese.GB18030,
+	CharsetBIG5:     traditionalchinese.Big5,
+	CharsetLATIN1:   charmap.Windows1252,
+	CharsetISO88591: charmap.ISO8859_1,
+	CharsetCP932:    japanese.ShiftJIS,
+	CharsetCP949:    korean.EUCKR,
+}
+
+// CharsetConvert 字符集转换，仅支持 UTF8MB4 与其他字符集之间互转
+// 编码时不支持的字符替换为 0x1A，解码时非法字节替换为 U+FFFD
 func CharsetConvert(data []byte, fromCharset, toCharset string) ([]byte, error) {
+	fromCharset = StringUPPER(fromCharset)
+	toCharset = StringUPPER(toCharset)
+
 	switch {
-	case strings.EqualFold(fromCharset, CharsetUTF8MB4) && strings.EqualFold(toCharset, CharsetGBK):
-		reader := transform.NewReader(bytes.NewReader(data), encoding.ReplaceUnsupported(simplifiedchinese.GBK.NewEncoder()))
-		gbkBytes, err := io.ReadAll(reader)
-		if err != nil {
-			return nil, err
-		}
-		return gbkBytes, nil
+	case fromCharset == CharsetUTF8MB4 && toCharset == CharsetUTF8MB4:
+		return data, nil
 
-	case strings.EqualFold(fromCharset, CharsetUTF8MB4) && strings.EqualFold(toCharset, CharsetGB18030):
-		reader := transform.NewReader(bytes.NewReader(data), encoding.ReplaceUnsupported(simplifiedchinese.GB18030.NewEncoder()))
-		gbk18030Bytes, err := io.ReadAll(reader)
-		if err != nil {
-			return nil, err
+	case fromCharset == CharsetUTF8MB4 && toCharset == CharsetASCII:
+		asciiBytes := make([]byte, 0, len(data))
+		for _, r := range string(data) {
+			if r < utf8.RuneSelf {
+				asciiBytes = append(asciiBytes, byte(r))
+			} else {
+				asciiBytes = append(asciiBytes, '\x1a')
+			}
 		}
-		return gbk18030Bytes, nil
+		return asciiBytes, nil
 
-	case strings.EqualFold(fromCharset, CharsetUTF8MB4) && strings.EqualFold(toCharset, CharsetBIG5):
-		reader := transform.NewReader(bytes.NewReader(data), encoding.ReplaceUnsupported(traditionalchinese.Big5.NewEncoder()))
-		bigBytes, err := io.ReadAll(reader)
-		if err != nil {
-			return nil, err
+	case fromCharset == CharsetASCII && toCharset == CharsetUTF8MB4:
+		utf8Data := make([]byte, 0, len(data))
+		for _, b := range data {
+			if b < utf8.RuneSelf {
+				utf8Data = append(utf8Data, b)
+			} else {
+				utf8Data = utf8.AppendRune(utf8Data, utf8.RuneError)
+			}
 		}
-		return bigBytes, nil
-
-	case strings.EqualFold(fromCharset, CharsetUTF8MB4) && strings.EqualFold(toCharset, CharsetUTF8MB4):
-		return data, nil
+		return utf8Data, nil
 
-	case strings.EqualFold(fromCharset, CharsetGBK) && strings.EqualFold(toCharset, CharsetUTF8MB4):
-		decoder := simplifiedchinese.GBK.NewDecoder()
-		utf8Data, err := decoder.Bytes(data)
+	case fromCharset == CharsetUTF8MB4:
+		enc, ok := charsetEncodingMap[toCharset]
+		if !ok {
+			break
+		}
+		reader := transform.NewReader(bytes.NewReader(data), encoding.ReplaceUnsupported(enc.NewEncoder()))
+		convertBytes, err := io.ReadAll(reader)
 		if err != nil {
 			return nil, err
 		}
+		return convertBytes, nil
 
-		return utf8Data, nil
-
-	case strings.EqualFold(fromCharset, CharsetGB18030) && strings.EqualFold(toCharset, CharsetUTF8MB4):
-		decoder := simplifiedchinese.GB18030.NewDecoder()
-		utf8Data, err := decoder.Bytes(data)
+	case toCharset == CharsetUTF8MB4:
+		enc, ok := charsetEncodingMap[fromCharset]
+		if !ok {
+			break
+		}
+		utf8Data, err := enc.NewDecoder().Bytes(data)
 		if err != nil {
 			return nil, err
 		}
 		return utf8Data, nil
+	}
+	return nil, fmt.Errorf("from charset [%v], to charset [%v] convert isn't support", fromCharset, toCharset)
+}
 
-	case strings.EqualFold(fromCharset, CharsetBIG5) && strings.EqualFold(toCharset, CharsetUTF8MB4):
-		decoder := traditionalchinese.Big5.NewDecoder()
-		utf8Data, err := decoder.Bytes(data)
-		if err != nil {
-			return nil, err
+// 源端字符数据存在非法字节
+var ErrInvalidChar = errors.New("invalid character")
+
+// CharsetDecodePolicy 源端字符数据转换为 UTF8MB4，按 invalid-char-policy 处理非法字节
+// 非 UTF8MB4 字符集解码后存在 U+FFFD 且无法原样编码回源字节视为非法，strip 同时删除源端原有 U+FFFD
+func CharsetDecodePolicy(data []byte, fromCharset, policy string) ([]byte, error) {
+	if strings.EqualFold(fromCharset, CharsetUTF8MB4) {
+		if utf8.Valid(data) {
+			return data, nil
+		}
+		switch StringUPPER(policy) {
+		case InvalidCharPolicyStrip:
+			return bytes.ToValidUTF8(data, nil), nil
+		case InvalidCharPolicyReject:
+			return nil, fmt.Errorf("%w: charset [%s] data hex [%x]", ErrInvalidChar, fromCharset, data)
+		default:
+			return bytes.ToValidUTF8(data, []byte(string(utf8.RuneError))), nil
 		}
+	}
 
+	utf8Data, err := CharsetConvert(data, fromCharset, CharsetUTF8MB4)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.ContainsRune(utf8Data, utf8.RuneError) {
 		return utf8Data, nil
-
+	}
+	originData, err := CharsetConvert(utf8Data, CharsetUTF8MB4, fromCharset)
+	if err == nil && bytes.Equal(originData, data) {
+		return utf8Data, nil
+	}
+	switch StringUPPER(policy) {
+	case InvalidCharPolicyStrip:
+		return bytes.ReplaceAll(utf8Data, []byte(string(utf8.RuneError)), nil), nil
+	case InvalidCharPolicyReject:
+		return nil, fmt.Errorf("%w: charset [%s] data hex [%x]", ErrInvalidChar, fromCharset, data)
 	default:
-		return nil, fmt.Errorf("from charset [%v], to charset [%v] convert isn't support", fromCharset, toCharset)
+		return utf8Data, nil
 	}
 }
 
@@ -593,3 +648,132 @@ func escapeBackslashCSV(s []byte, delimiterCsv, separatorCsv string) string {
 	bf.Write(s[last:])
 	return bf.String()
 }
+
+// 解析时间窗口，格式 HH:MM-HH:MM，返回起止时间距零点分钟数，支持跨零点，例如 22:00-06:00
+func ParseTimeWindow(window string) (int, int, error) {
+	times := strings.Split(strings.TrimSpace(window), "-")
+	if len(times) != 2 {
+		return 0, 0, fmt.Errorf("time window [%s] format should be HH:MM-HH:MM", window)
+	}
+	var minutes []int
+	for _, t := range times {
+		hm := strings.Split(strings.TrimSpace(t), ":")
+		if len(hm) != 2 {
+			return 0, 0, fmt.Errorf("time window [%s] format should be HH:MM-HH:MM", window)
+		}
+		h, err := strconv.Atoi(hm[0])
+		if err != nil || h < 0 || h > 23 {
+			return 0, 0, fmt.Errorf("time window [%s] hour [%s] isn't valid", window, hm[0])
+		}
+		m, err := strconv.Atoi(hm[1])
+		if err != nil || m < 0 || m > 59 {
+			return 0, 0, fmt.Errorf("time window [%s] minute [%s] isn't valid", window, hm[1])
+		}
+		minutes = append(minutes, h*60+m)
+	}
+	return minutes[0], minutes[1], nil
+}
+
+// 判断时间点是否处于时间窗口内，窗口左闭右开
+func IsInTimeWindow(start, end, minute int) bool {
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// ORACLE ROWID 保序编码，ROWID 各段定长，按编码后字符串二进制排序与 ORACLE ROWID 排序一致
+func EncodeOracleRowid(rowid string) string {
+	b := []byte(rowid)
+	for i, c := range b {
+		if idx := strings.IndexByte(OracleRowidCharset, c); idx >= 0 {
+			b[i] = OracleRowidOrderCharset[idx]
+		}
+	}
+	return string(b)
+}
+
+// ORACLE ROWID 保序编码还原
+func DecodeOracleRowid(encoded string) string {
+	b := []byte(encoded)
+	for i, c := range b {
+		if idx := strings.IndexByte(OracleRowidOrderCharset, c); idx >= 0 {
+			b[i] = OracleRowidCharset[idx]
+		}
+	}
+	return string(b)
+}
+
+// ORACLE 端 ROWID 保序编码查询字段
+func GenOracleRowidOrderColumn() string {
+	return StringsBuilder(`TRANSLATE(ROWIDTOCHAR(ROWID),'`, OracleRowidCharset, `','`, OracleRowidOrderCharset, `') AS "`, MigrateKeylessRowidColumn, `"`)
+}
+
+// 解析 ROWID chunk 范围，例如：ROWID BETWEEN 'AAAR8+AAEAAAACIAAA' AND 'AAAR8+AAEAAAACPCcP'，返回保序编码后的起止 ROWID
+func ParseOracleRowidChunk(chunk string) (string, string, bool) {
+	matches := oracleRowidChunkRegexp.FindStringSubmatch(chunk)
+	if len(matches) != 3 {
+		return "", "", false
+	}
+	return EncodeOracleRowid(matches[1]), EncodeOracleRowid(matches[2]), true
+}
+
+var oracleRowidChunkRegexp = regexp.MustCompile(`ROWID BETWEEN '([^']+)' AND '([^']+)'`)
+
+// 无主键/唯一键表 ROWID 代理字段以及唯一索引 DDL，唯一索引保证按 ROWID REPLACE 重放幂等
+func GenMySQLKeylessRowidColumnDDL(schemaName, tableName string) string {
+	return fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN `%s` VARCHAR(%d) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '' COMMENT 'transferdb keyless table oracle rowid', ADD UNIQUE INDEX `%s`(`%s`)",
+		schemaName, tableName, MigrateKeylessRowidColumn, MigrateKeylessRowidLength, MigrateKeylessRowidIndex, MigrateKeylessRowidColumn)
+}
+
+// 过滤 ROWID 代理字段唯一索引，重跑时代理字段唯一索引已存在，不视为表唯一键
+func FilterMySQLKeylessRowidIndex(uks []map[string]string) []map[string]string {
+	var keys []map[string]string
+	for _, uk := range uks {
+		if strings.EqualFold(uk["CONSTRAINT_NAME"], MigrateKeylessRowidIndex) {
+			continue
+		}
+		keys = append(keys, uk)
+	}
+	return keys
+}
+
+// 无主键/唯一键表 chunk 清理语句，代理字段存储保序编码 ROWID，按编码后起止 ROWID 二进制范围删除
+// chunk 非 ROWID 范围无法定位 chunk 数据，返回错误，避免清理全表
+func GenMySQLKeylessChunkDeleteSQL(schemaName, tableName, chunk string) (string, error) {
+	startRowid, endRowid, ok := ParseOracleRowidChunk(chunk)
+	if !ok {
+		return "", fmt.Errorf("table [%s.%s] keyless chunk [%s] isn't rowid range, can't be cleaned, please truncate target table and rerun", schemaName, tableName, chunk)
+	}
+	return fmt.Sprintf("DELETE FROM %s.%s WHERE `%s` BETWEEN '%s' AND '%s'", schemaName, tableName, MigrateKeylessRowidColumn, startRowid, endRowid), nil
+}
+
+// 数据校验数值舍入单位转换为 ROUND 保留小数位数，舍入单位只支持 10 的整数次幂，例如：0.001 -> 3，10 -> -1
+// 两端舍入后比较是否相等，不按两端差值大小判断，舍入边界两侧的值（比如 0.0049 与 0.0051 舍入至 0.01）仍不一致
+func CompareNumericRoundUnitScale(unit float64) (int, error) {
+	if unit <= 0 {
+		return 0, fmt.Errorf("numeric round unit [%v] must be greater than 0", unit)
+	}
+	exp := math.Log10(unit)
+	scale := int(math.Round(exp))
+	if math.Abs(exp-float64(scale)) > 1e-9 {
+		return 0, fmt.Errorf("numeric round unit [%v] must be a power of 10, for example: 0.01 or 1", unit)
+	}
+	return -scale, nil
+}
+
+// 抽样校验不一致 chunk 比例置信上限，Wilson score 区间结合有限总体修正，全部抽样时即实际比例
+// z 为置信水平对应正态分位数，例如 95% -> 1.96
+func CompareSampleUpperBound(totalChunks, sampleChunks, unequalChunks int, z float64) float64 {
+	if sampleChunks <= 0 {
+		return 1
+	}
+	n := float64(sampleChunks)
+	p := float64(unequalChunks) / n
+	if sampleChunks >= totalChunks {
+		return p
+	}
+	z2 := z * z * float64(totalChunks-sampleChunks) / float64(totalChunks-1)
+	upper := (p + z2/(2*n) + math.Sqrt(z2*(p*(1-p)/n+z2/(4*n*n)))) / (1 + z2/n)
+	return math.Min(upper, 1)
+}
@@ -1,6 +1,10 @@
 package common
 
-import "testing"
+import (
+	"math"
+	"strings"
+	"testing"
+)
 
 func SpecialLettersEquals(bs []byte) bool {
 	bs1 := SpecialLettersUsingMySQL(bs)
@@ -64,3 +68,225 @@ func BenchmarkSpecialLettersUsingMySQLNew(b *testing.B) {
 		SpecialLettersUsingMySQL(bs1)
 	}
 }
+
+func TestParseTimeWindow(t *testing.T) {
+	tests := []struct {
+		window string
+		minute int
+		want   bool
+		err    bool
+	}{
+		{window: "22:00-06:00", minute: 23 * 60, want: true},
+		{window: "22:00-06:00", minute: 5*60 + 59, want: true},
+		{window: "22:00-06:00", minute: 6 * 60, want: false},
+		{window: "09:30-18:00", minute: 12 * 60, want: true},
+		{window: "09:30-18:00", minute: 9 * 60, want: false},
+		{window: "24:00-06:00", err: true},
+		{window: "22:00", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			start, end, err := ParseTimeWindow(tt.window)
+			if (err != nil) != tt.err {
+				t.Fatalf("ParseTimeWindow() error = %v, want error %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if got := IsInTimeWindow(start, end, tt.minute); got != tt.want {
+				t.Errorf("IsInTimeWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeOracleRowid(t *testing.T) {
+	// 按 ROWID 排序：数据对象号 -> 文件号 -> 块号 -> 行号
+	rowids := []string{
+		"AAAR8+AAEAAAACIAAA",
+		"AAAR8+AAEAAAACIAAz",
+		"AAAR8+AAEAAAACIAA9",
+		"AAAR8+AAEAAAACIAA/",
+		"AAAR8+AAEAAAACJAAA",
+		"AAAR8/AAAAAAAAAAAA",
+		"AAAR9AAAAAAAAAAAAA",
+	}
+	for i := 1; i < len(rowids); i++ {
+		if EncodeOracleRowid(rowids[i-1]) >= EncodeOracleRowid(rowids[i]) {
+			t.Errorf("EncodeOracleRowid() order mismatch: [%s] >= [%s]", rowids[i-1], rowids[i])
+		}
+	}
+
+	for _, rowid := range rowids {
+		if got := DecodeOracleRowid(EncodeOracleRowid(rowid)); got != rowid {
+			t.Errorf("DecodeOracleRowid() = %s, want %s", got, rowid)
+		}
+	}
+
+	start, end, ok := ParseOracleRowidChunk(`ROWID BETWEEN 'AAAR8+AAEAAAACIAAA' AND 'AAAR8+AAEAAAACPCcP' AND ID > 10`)
+	if !ok || start != EncodeOracleRowid("AAAR8+AAEAAAACIAAA") || end != EncodeOracleRowid("AAAR8+AAEAAAACPCcP") {
+		t.Errorf("ParseOracleRowidChunk() = %s, %s, %v", start, end, ok)
+	}
+	if _, _, ok = ParseOracleRowidChunk(`1 = 1`); ok {
+		t.Errorf("ParseOracleRowidChunk() want not ok")
+	}
+}
+
+func TestCharsetConvert(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		fromCharset string
+		toCharset   string
+		want        string
+	}{
+		{name: "gbk", data: []byte{0xd6, 0xd0, 0xce, 0xc4}, fromCharset: CharsetGBK, toCharset: CharsetUTF8MB4, want: "中文"},
+		{name: "iso88591", data: []byte{0x63, 0x61, 0x66, 0xe9, 0x80}, fromCharset: CharsetISO88591, toCharset: CharsetUTF8MB4, want: "café\u0080"},
+		{name: "latin1", data: []byte{0x63, 0x61, 0x66, 0xe9, 0x80}, fromCharset: CharsetLATIN1, toCharset: CharsetUTF8MB4, want: "café€"},
+		{name: "ascii", data: []byte{0x61, 0xe9}, fromCharset: CharsetASCII, toCharset: CharsetUTF8MB4, want: "a�"},
+		{name: "cp932", data: []byte{0x93, 0xfa, 0x96, 0x7b}, fromCharset: CharsetCP932, toCharset: CharsetUTF8MB4, want: "日本"},
+		{name: "cp949", data: []byte{0xc7, 0xd1, 0xb1, 0xb9}, fromCharset: CharsetCP949, toCharset: CharsetUTF8MB4, want: "한국"},
+		{name: "to ascii", data: []byte("a中"), fromCharset: CharsetUTF8MB4, toCharset: CharsetASCII, want: "a\x1a"},
+		{name: "to latin1", data: []byte("café"), fromCharset: CharsetUTF8MB4, toCharset: CharsetLATIN1, want: "caf\xe9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CharsetConvert(tt.data, tt.fromCharset, tt.toCharset)
+			if err != nil {
+				t.Fatalf("CharsetConvert() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CharsetConvert() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := CharsetConvert([]byte("a"), CharsetGBK, CharsetBIG5); err == nil {
+		t.Errorf("CharsetConvert() gbk to big5 want error")
+	}
+}
+
+func TestCharsetDecodePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		fromCharset string
+		policy      string
+		want        string
+		wantErr     bool
+	}{
+		{name: "utf8 valid", data: []byte("中文"), fromCharset: CharsetUTF8MB4, policy: InvalidCharPolicyReject, want: "中文"},
+		{name: "utf8 replace", data: []byte{0x61, 0xff, 0x62}, fromCharset: CharsetUTF8MB4, policy: InvalidCharPolicyReplace, want: "a�b"},
+		{name: "utf8 strip", data: []byte{0x61, 0xff, 0x62}, fromCharset: CharsetUTF8MB4, policy: InvalidCharPolicyStrip, want: "ab"},
+		{name: "utf8 reject", data: []byte{0x61, 0xff, 0x62}, fromCharset: CharsetUTF8MB4, policy: InvalidCharPolicyReject, wantErr: true},
+		{name: "gbk replace", data: []byte{0xd6, 0xd0, 0x81}, fromCharset: CharsetGBK, policy: InvalidCharPolicyReplace, want: "中�"},
+		{name: "gbk strip", data: []byte{0xd6, 0xd0, 0x81}, fromCharset: CharsetGBK, policy: InvalidCharPolicyStrip, want: "中"},
+		{name: "gbk reject", data: []byte{0xd6, 0xd0, 0x81}, fromCharset: CharsetGBK, policy: InvalidCharPolicyReject, wantErr: true},
+		{name: "gb18030 origin replacement char", data: []byte{0x84, 0x31, 0xa4, 0x37}, fromCharset: CharsetGB18030, policy: InvalidCharPolicyReject, want: "�"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CharsetDecodePolicy(tt.data, tt.fromCharset, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CharsetDecodePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("CharsetDecodePolicy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTimeZone(t *testing.T) {
+	tests := []struct {
+		timeZone string
+		want     string
+		wantErr  bool
+	}{
+		{timeZone: "", want: ""},
+		{timeZone: "utc", want: "+00:00"},
+		{timeZone: "+08:00", want: "+08:00"},
+		{timeZone: "-05:30", want: "-05:30"},
+		{timeZone: "+14:30", wantErr: true},
+		{timeZone: "Asia/Shanghai", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeTimeZone(tt.timeZone)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("NormalizeTimeZone(%q) error = %v, wantErr %v", tt.timeZone, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeTimeZone(%q) = %q, want %q", tt.timeZone, got, tt.want)
+		}
+	}
+	if got := GenMySQLTimeZoneConnectParams("parseTime=true", "+08:00"); got != "parseTime=true&time_zone=%27%2B08%3A00%27" {
+		t.Errorf("GenMySQLTimeZoneConnectParams = %q", got)
+	}
+}
+
+func TestCompareNumericRoundUnitScale(t *testing.T) {
+	tests := []struct {
+		unit    float64
+		want    int
+		wantErr bool
+	}{
+		{unit: 0.001, want: 3},
+		{unit: 1, want: 0},
+		{unit: 100, want: -2},
+		{unit: 0.005, wantErr: true},
+		{unit: 0, wantErr: true},
+		{unit: -0.1, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := CompareNumericRoundUnitScale(tt.unit)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("CompareNumericRoundUnitScale(%v) error = %v, wantErr %v", tt.unit, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("CompareNumericRoundUnitScale(%v) = %d, want %d", tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestCompareSampleUpperBound(t *testing.T) {
+	tests := []struct {
+		total, sample, unequal int
+		want                   float64
+	}{
+		{total: 10, sample: 10, unequal: 2, want: 0.2},
+		{total: 1000, sample: 100, unequal: 0, want: 0.0335},
+		{total: 1000, sample: 0, unequal: 0, want: 1},
+	}
+	for _, tt := range tests {
+		got := CompareSampleUpperBound(tt.total, tt.sample, tt.unequal, 1.96)
+		if math.Abs(got-tt.want) > 0.0005 {
+			t.Errorf("CompareSampleUpperBound(%d, %d, %d) = %v, want %v", tt.total, tt.sample, tt.unequal, got, tt.want)
+		}
+	}
+}
+
+func TestGenMySQLKeylessRowidSQL(t *testing.T) {
+	ddl := GenMySQLKeylessRowidColumnDDL("MARVIN", "T1")
+	if !strings.Contains(ddl, "ADD UNIQUE INDEX `"+MigrateKeylessRowidIndex+"`(`"+MigrateKeylessRowidColumn+"`)") {
+		t.Errorf("GenMySQLKeylessRowidColumnDDL() = %s, want unique index", ddl)
+	}
+
+	deleteSQL, err := GenMySQLKeylessChunkDeleteSQL("MARVIN", "T1", `ROWID BETWEEN 'AAAR8+AAEAAAACIAAA' AND 'AAAR8+AAEAAAACPCcP'`)
+	want := "DELETE FROM MARVIN.T1 WHERE `" + MigrateKeylessRowidColumn + "` BETWEEN '" + EncodeOracleRowid("AAAR8+AAEAAAACIAAA") + "' AND '" + EncodeOracleRowid("AAAR8+AAEAAAACPCcP") + "'"
+	if err != nil || deleteSQL != want {
+		t.Errorf("GenMySQLKeylessChunkDeleteSQL() = %s, %v, want %s", deleteSQL, err, want)
+	}
+	// 重跑时代理字段唯一索引已存在，仍视为无主键/唯一键表
+	uks := []map[string]string{{"CONSTRAINT_NAME": MigrateKeylessRowidIndex, "CONSTRAINT_TYPE": "UK", "COLUMN_LIST": MigrateKeylessRowidColumn}}
+	if keys := FilterMySQLKeylessRowidIndex(uks); len(keys) != 0 {
+		t.Errorf("FilterMySQLKeylessRowidIndex() = %v, want empty", keys)
+	}
+	uks = append(uks, map[string]string{"CONSTRAINT_NAME": "UK_T1", "CONSTRAINT_TYPE": "UK", "COLUMN_LIST": "ID"})
+	if keys := FilterMySQLKeylessRowidIndex(uks); len(keys) != 1 || keys[0]["CONSTRAINT_NAME"] != "UK_T1" {
+		t.Errorf("FilterMySQLKeylessRowidIndex() = %v, want [UK_T1]", keys)
+	}
+	// 非 ROWID 范围 chunk 不允许清理全表
+	if deleteSQL, err = GenMySQLKeylessChunkDeleteSQL("MARVIN", "T1", `1 = 1`); err == nil {
+		t.Errorf("GenMySQLKeylessChunkDeleteSQL() = %s, want error", deleteSQL)
+	}
+}
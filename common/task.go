@@ -15,7 +15,10 @@ limitations under the License.
 */
 package common
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // MySQL 连接配置
 const (
@@ -28,6 +31,59 @@ const (
 // 任务并发通道 Channle Size
 const ChannelBufferSize = 1024
 
+// 源端限流非批次读取时，每读取行数统计一次
+const ThrottleBatchRows = 1024
+
+// 数据校验采样切分，每个 chunk 采样行数以及 where 条件最大长度（data_compare_meta where_range 字段长度）
+const (
+	CompareSampleRowsPerChunk  = 100
+	CompareWhereRangeMaxLength = 300
+)
+
+// 增量校验变更行键值每批次最大个数，ORACLE IN 列表最多 1000 个表达式
+const CompareDeltaKeysPerBatch = 1000
+
+// 抽样校验不一致 chunk 比例置信水平 95% 对应正态分位数
+const CompareSampleConfidenceZ = 1.96
+
+// 无主键/唯一键表代理字段，保存 ORACLE ROWID 保序编码，用于断点续传 chunk 清理以及增量 UPDATE/DELETE 定位
+const (
+	MigrateKeylessRowidColumn = "_TRANSFERDB_ROWID"
+	MigrateKeylessRowidIndex  = "IDX_TRANSFERDB_ROWID"
+	MigrateKeylessRowidLength = 18
+)
+
+// ORACLE ROWID base64 编码字符表（按值顺序）以及保序编码字符表（按 ASCII 升序）
+const (
+	OracleRowidCharset      = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	OracleRowidOrderCharset = "-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+)
+
+// 子集迁移每批次 ROWID 数（ORACLE IN 列表上限 1000），同时作为子集表 chunk 行数
+const SubsetChunkRowids = 1000
+
+// 延迟创建索引类型，KEY（主键以外索引）全部创建完成后再创建 CONSTRAINT（外键、检查约束）
+const (
+	DeferIndexTypeKey        = "KEY"
+	DeferIndexTypeConstraint = "CONSTRAINT"
+)
+
+// 坏行隔离阶段，READ 源端读取字符转换失败，WRITE 目标端写入失败
+const (
+	RejectStageRead  = "READ"
+	RejectStageWrite = "WRITE"
+)
+
+// 源端非法字符处理策略
+// replace：非法字节替换为 U+FFFD
+// strip：非法字节删除
+// reject：整行隔离（需开启 [reject] enable，否则 chunk 失败）
+const (
+	InvalidCharPolicyReplace = "REPLACE"
+	InvalidCharPolicyStrip   = "STRIP"
+	InvalidCharPolicyReject  = "REJECT"
+)
+
 // 任务模式
 const (
 	TaskModePrepare = "PREPARE"
@@ -38,6 +94,23 @@ const (
 	TaskModeCSV     = "CSV"
 	TaskModeFull    = "FULL"
 	TaskModeAll     = "ALL"
+	TaskModeExport  = "EXPORT"
+	TaskModeImport  = "IMPORT"
+	// 增量 checkpoint 查看以及重置
+	TaskModeCheckpoint = "CHECKPOINT"
+	// 数据类型剖析，输出字段级数据类型规则文件
+	TaskModeProfile = "PROFILE"
+	// 运行前预检查
+	TaskModePrecheck = "PRECHECK"
+	// ALL 模式增量在线校验，仅用于数据校验元数据 [data_compare_meta] 记录区分
+	TaskModeVerify = "VERIFY"
+)
+
+// 预检查结果
+const (
+	PrecheckResultPass = "PASS"
+	PrecheckResultWarn = "WARN"
+	PrecheckResultFail = "FAIL"
 )
 
 // 任务状态
@@ -48,6 +121,22 @@ const (
 	TaskStatusFailed  = "FAILED"
 )
 
+// 数据校验自动修复模式以及修复状态
+// REPAIR：目标端执行修复语句，DRYRUN：仅记录修复语句不执行
+// SKIPPED：超出表修复行数上限或者表字段对比存在规整，未执行修复
+const (
+	RepairModeExecute = "REPAIR"
+	RepairModeDryRun  = "DRYRUN"
+
+	RepairStatusSkipped = "SKIPPED"
+)
+
+// 任务收到退出信号，进行中任务完成后优雅退出
+var ErrGracefulShutdown = errors.New("task graceful shutdown")
+
+// 数据校验修复实际影响行数超出表修复行数上限，修复事务回滚
+var ErrRepairRowsExceeded = errors.New("repair affected rows exceed repair-max-rows")
+
 // 任务初始值
 const (
 	// 值 0 代表源端表未进行初始化 -> 适用于 full/csv/all 模式
@@ -70,3 +159,53 @@ const (
 	TaskTypeMySQL2Oracle = "MYSQL2ORACLE"
 	TaskTypeTiDB2Oracle  = "TIDB2ORACLE"
 )
+
+// 增量同步方式
+// LOGMINER：基于 logminer 日志挖掘
+// QUERY：基于变更跟踪字段或者 ORA_ROWSCN 轮询查询，无需 logminer 以及附加日志权限
+const (
+	IncrModeLogminer = "LOGMINER"
+	IncrModeQuery    = "QUERY"
+
+	IncrQueryTrackRowSCN = "ORA_ROWSCN"
+)
+
+// 增量起始时间格式以及 checkpoint 操作
+const (
+	IncrStartTimeLayout   = "2006-01-02 15:04:05"
+	CheckpointActionShow  = "SHOW"
+	CheckpointActionReset = "RESET"
+)
+
+// 增量冲突策略以及冲突类型
+// 冲突均记录元数据表 [incr_conflict_detail]
+// overwrite：以源端为准覆盖写入（INSERT 替换已存在行，UPDATE 下游行不存在则写入变更后数据）
+// skip：跳过冲突变更，下游保持不变
+// fail：冲突变更不应用，增量同步报错退出
+// log-only：仅记录，按原有转换 SQL 应用
+const (
+	ConflictPolicyOverwrite = "OVERWRITE"
+	ConflictPolicySkip      = "SKIP"
+	ConflictPolicyFail      = "FAIL"
+	ConflictPolicyLogOnly   = "LOG-ONLY"
+
+	ConflictTypeInsert     = "INSERT_CONFLICT"
+	ConflictTypeMissingRow = "MISSING_ROW"
+)
+
+// 规则文件
+const (
+	// 规则文件版本，文件结构变更需递增
+	RuleFileVersion  = 2
+	RuleFormatTOML   = "TOML"
+	RuleFormatYAML   = "YAML"
+	RuleFileDefault  = "./rule.toml"
+	RuleDiffAdded    = "ADDED"
+	RuleDiffChanged  = "CHANGED"
+	RuleDiffSame     = "UNCHANGED"
+	RuleDiffMetaOnly = "META-ONLY"
+
+	ProfileFileDefault = "./profile_rule.toml"
+
+	PrecheckReportFileDefault = "./precheck_report.txt"
+)
@@ -0,0 +1,77 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var timeZoneOffsetRegex = regexp.MustCompile(`^[+-](\d{2}):(\d{2})$`)
+
+// 时区策略统一以 ±HH:MM 偏移量表示，ORACLE AT TIME ZONE 与 MySQL/TiDB time_zone 均支持，UTC 等价 +00:00
+// 为空表示不处理，沿用数据库会话时区
+func NormalizeTimeZone(timeZone string) (string, error) {
+	tz := strings.TrimSpace(timeZone)
+	if tz == "" {
+		return "", nil
+	}
+	if strings.EqualFold(tz, "UTC") || strings.EqualFold(tz, "Z") {
+		return "+00:00", nil
+	}
+	matches := timeZoneOffsetRegex.FindStringSubmatch(tz)
+	if matches == nil {
+		return "", fmt.Errorf("time zone [%s] isn't valid, only support UTC or offset format [+HH:MM]", timeZone)
+	}
+	hour, _ := strconv.Atoi(matches[1])
+	minute, _ := strconv.Atoi(matches[2])
+	if hour > 14 || minute > 59 || (hour == 14 && minute > 0) {
+		return "", fmt.Errorf("time zone [%s] isn't valid, offset range [-14:00 +14:00]", timeZone)
+	}
+	return tz, nil
+}
+
+// ORACLE TIMESTAMP WITH TIME ZONE / TIMESTAMP WITH LOCAL TIME ZONE
+func IsOracleTimestampWithTimeZone(dataType string) bool {
+	return strings.Contains(StringUPPER(dataType), "TIME ZONE")
+}
+
+func IsOracleTimestampWithLocalTimeZone(dataType string) bool {
+	return strings.Contains(StringUPPER(dataType), "WITH LOCAL TIME ZONE")
+}
+
+// 带时区时间字段按时区策略规整，columnExpr 为字段引用表达式，非时区字段或者未配置时区原样返回
+func GenOracleTimeZoneColumnExpr(columnExpr, dataType, timeZone string) string {
+	if timeZone == "" || !IsOracleTimestampWithTimeZone(dataType) {
+		return columnExpr
+	}
+	return StringsBuilder(`(`, columnExpr, ` AT TIME ZONE '`, timeZone, `')`)
+}
+
+// MySQL/TiDB 连接参数设置会话 time_zone，connect-params 已指定 time_zone 则以 connect-params 为准
+func GenMySQLTimeZoneConnectParams(connectParams, timeZone string) string {
+	if timeZone == "" || strings.Contains(connectParams, "time_zone=") {
+		return connectParams
+	}
+	param := StringsBuilder(`time_zone=`, url.QueryEscape(StringsBuilder(`'`, timeZone, `'`)))
+	if connectParams == "" {
+		return param
+	}
+	return StringsBuilder(connectParams, `&`, param)
+}
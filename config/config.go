@@ -22,43 +22,119 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/wentaojin/transferdb/common"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // 程序配置文件
 type Config struct {
-	*flag.FlagSet `json:"-"`
-	AppConfig     AppConfig     `toml:"app" json:"app"`
-	ReverseConfig ReverseConfig `toml:"reverse" json:"reverse"`
-	CheckConfig   CheckConfig   `toml:"check" json:"check"`
-	FullConfig    FullConfig    `toml:"full" json:"full"`
-	CSVConfig     CSVConfig     `toml:"csv" json:"csv"`
-	AllConfig     AllConfig     `toml:"all" json:"all"`
-	SchemaConfig  SchemaConfig  `toml:"schema-config" json:"schema-config"`
-	OracleConfig  OracleConfig  `toml:"oracle" json:"oracle"`
-	MySQLConfig   MySQLConfig   `toml:"mysql" json:"mysql"`
-	MetaConfig    MetaConfig    `toml:"meta" json:"meta"`
-	LogConfig     LogConfig     `toml:"log" json:"log"`
-	DiffConfig    DiffConfig    `toml:"compare" json:"compare"`
-	ConfigFile    string        `json:"config-file"`
-	PrintVersion  bool
-	TaskMode      string `json:"task-mode"`
-	DBTypeS       string `json:"db-type-s"`
-	DBTypeT       string `json:"db-type-t"`
+	*flag.FlagSet    `json:"-"`
+	AppConfig        AppConfig        `toml:"app" json:"app"`
+	ReverseConfig    ReverseConfig    `toml:"reverse" json:"reverse"`
+	CheckConfig      CheckConfig      `toml:"check" json:"check"`
+	FullConfig       FullConfig       `toml:"full" json:"full"`
+	CSVConfig        CSVConfig        `toml:"csv" json:"csv"`
+	AllConfig        AllConfig        `toml:"all" json:"all"`
+	SchemaConfig     SchemaConfig     `toml:"schema-config" json:"schema-config"`
+	OracleConfig     OracleConfig     `toml:"oracle" json:"oracle"`
+	MySQLConfig      MySQLConfig      `toml:"mysql" json:"mysql"`
+	MetaConfig       MetaConfig       `toml:"meta" json:"meta"`
+	LogConfig        LogConfig        `toml:"log" json:"log"`
+	DiffConfig       DiffConfig       `toml:"compare" json:"compare"`
+	RuleConfig       RuleConfig       `toml:"rule" json:"rule"`
+	ThrottleConfig   ThrottleConfig   `toml:"throttle" json:"throttle"`
+	CheckpointConfig CheckpointConfig `toml:"checkpoint" json:"checkpoint"`
+	ProfileConfig    ProfileConfig    `toml:"profile" json:"profile"`
+	RejectConfig     RejectConfig     `toml:"reject" json:"reject"`
+	PrecheckConfig   PrecheckConfig   `toml:"precheck" json:"precheck"`
+	ConfigFile       string           `json:"config-file"`
+	PrintVersion     bool
+	TaskMode         string `json:"task-mode"`
+	DBTypeS          string `json:"db-type-s"`
+	DBTypeT          string `json:"db-type-t"`
 }
 
 type AppConfig struct {
 	InsertBatchSize  int    `toml:"insert-batch-size" json:"insert-batch-size"`
 	SlowlogThreshold int    `toml:"slowlog-threshold" json:"slowlog-threshold"`
 	PprofPort        string `toml:"pprof-port" json:"pprof-port"`
+	GracefulTimeout  int    `toml:"graceful-timeout" json:"graceful-timeout"`
+	// 自适应并发
+	AdaptiveConcurrency bool `toml:"adaptive-concurrency" json:"adaptive-concurrency"`
+	MinConcurrency      int  `toml:"min-concurrency" json:"min-concurrency"`
+	TargetWriteLatency  int  `toml:"target-write-latency" json:"target-write-latency"`
+	AdjustInterval      int  `toml:"adjust-interval" json:"adjust-interval"`
+	// 时区策略
+	TimeZone string `toml:"time-zone" json:"time-zone"`
 }
 
 type DiffConfig struct {
-	ChunkSize         int    `toml:"chunk-size" json:"chunk-size"`
-	DiffThreads       int    `toml:"diff-threads" json:"diff-threads"`
-	OnlyCheckRows     bool   `toml:"only-check-rows" json:"only-check-rows"`
-	EnableCheckpoint  bool   `toml:"enable-checkpoint" json:"enable-checkpoint"`
-	IgnoreStructCheck bool   `toml:"ignore-struct-check" json:"ignore-struct-check"`
-	FixSqlDir         string `toml:"fix-sql-dir" json:"fix-sql-dir"`
+	ChunkSize         int     `toml:"chunk-size" json:"chunk-size"`
+	DiffThreads       int     `toml:"diff-threads" json:"diff-threads"`
+	OnlyCheckRows     bool    `toml:"only-check-rows" json:"only-check-rows"`
+	EnableCheckpoint  bool    `toml:"enable-checkpoint" json:"enable-checkpoint"`
+	IgnoreStructCheck bool    `toml:"ignore-struct-check" json:"ignore-struct-check"`
+	FixSqlDir         string  `toml:"fix-sql-dir" json:"fix-sql-dir"`
+	Repair            bool    `toml:"repair" json:"repair"`
+	RepairDryRun      bool    `toml:"repair-dry-run" json:"repair-dry-run"`
+	RepairMaxRows     int64   `toml:"repair-max-rows" json:"repair-max-rows"`
+	SinceSCN          uint64  `toml:"since-scn" json:"since-scn"`
+	SinceTime         string  `toml:"since-time" json:"since-time"`
+	LobInlineSize     int64   `toml:"lob-inline-size" json:"lob-inline-size"`
+	SamplePercent     float64 `toml:"sample-percent" json:"sample-percent"`
+	SampleChunks      int     `toml:"sample-chunks" json:"sample-chunks"`
+}
+
+type RuleConfig struct {
+	RuleFile   string `toml:"rule-file" json:"rule-file"`
+	RuleFormat string `toml:"rule-format" json:"rule-format"`
+	DryRun     bool   `toml:"dry-run" json:"dry-run"`
+}
+
+// 增量 checkpoint 查看以及重置
+type CheckpointConfig struct {
+	Action    string   `toml:"action" json:"action"`
+	Tables    []string `toml:"tables" json:"tables"`
+	SCN       uint64   `toml:"scn" json:"scn"`
+	Timestamp string   `toml:"timestamp" json:"timestamp"`
+}
+
+// 数据类型剖析，按实际数据推断无精度 NUMBER、超长 VARCHAR2 以及 CLOB 目标类型，输出字段级数据类型规则文件
+type ProfileConfig struct {
+	ProfileThreads   int     `toml:"profile-threads" json:"profile-threads"`
+	SamplePercent    float64 `toml:"sample-percent" json:"sample-percent"`
+	VarcharMinLength int     `toml:"varchar-min-length" json:"varchar-min-length"`
+	VarcharMaxLength int     `toml:"varchar-max-length" json:"varchar-max-length"`
+	Headroom         int     `toml:"headroom" json:"headroom"`
+	ProfileFile      string  `toml:"profile-file" json:"profile-file"`
+}
+
+// 坏行隔离，作用于 full、csv、all 全量阶段
+// 源端字符转换失败或者目标端批次写入失败（二分定位）的行写入 reject 文件以及元数据表 [reject_row_detail]，chunk 继续
+type RejectConfig struct {
+	Enable            bool   `toml:"enable" json:"enable"`
+	MaxRejectRows     int    `toml:"max-reject-rows" json:"max-reject-rows"`
+	RejectDir         string `toml:"reject-dir" json:"reject-dir"`
+	InvalidCharPolicy string `toml:"invalid-char-policy" json:"invalid-char-policy"`
+}
+
+// 运行前预检查（-mode precheck），按 target-mode 检查权限、日志模式、目标端环境、字符集以及主键/唯一键
+type PrecheckConfig struct {
+	TargetMode string `toml:"target-mode" json:"target-mode"`
+	ReportFile string `toml:"report-file" json:"report-file"`
+	FixSQLFile string `toml:"fix-sql-file" json:"fix-sql-file"`
+}
+
+// 源端限流，作用于 full、csv、compare 源端数据读取
+type ThrottleConfig struct {
+	Enable                 bool     `toml:"enable" json:"enable"`
+	MaxRowsPerSecond       int      `toml:"max-rows-per-second" json:"max-rows-per-second"`
+	MaxMBPerSecond         int      `toml:"max-mb-per-second" json:"max-mb-per-second"`
+	MaxSessions            int      `toml:"max-sessions" json:"max-sessions"`
+	FullSpeedWindows       []string `toml:"full-speed-windows" json:"full-speed-windows"`
+	ActiveSessionThreshold int      `toml:"active-session-threshold" json:"active-session-threshold"`
+	CheckInterval          int      `toml:"check-interval" json:"check-interval"`
 }
 
 type ReverseConfig struct {
@@ -67,6 +143,7 @@ type ReverseConfig struct {
 	DirectWrite        bool   `toml:"direct-write" json:"direct-write"`
 	DDLReverseDir      string `toml:"ddl-reverse-dir" json:"ddl-reverse-dir"`
 	DDLCompatibleDir   string `toml:"ddl-compatible-dir" json:"ddl-compatible-dir"`
+	DeferIndex         bool   `toml:"defer-index" json:"defer-index"`
 }
 
 type CheckConfig struct {
@@ -103,14 +180,27 @@ type FullConfig struct {
 	ConsistentRead   bool   `toml:"consistent-read" json:"consistent-read"`
 	SQLHint          string `toml:"sql-hint" json:"sql-hint"`
 	CallTimeout      int    `toml:"call-timeout" json:"call-timeout"`
+	KeylessRowid     bool   `toml:"keyless-rowid" json:"keyless-rowid"`
+	IndexThreads     int    `toml:"index-threads" json:"index-threads"`
 }
 
 type AllConfig struct {
-	LogminerQueryTimeout int `toml:"logminer-query-timeout" json:"logminer-query-timeout"`
-	FilterThreads        int `toml:"filter-threads" json:"filter-threads"`
-	ApplyThreads         int `toml:"apply-threads" json:"apply-threads"`
-	WorkerQueue          int `toml:"worker-queue" json:"worker-queue"`
-	WorkerThreads        int `toml:"worker-threads" json:"worker-threads"`
+	LogminerQueryTimeout int    `toml:"logminer-query-timeout" json:"logminer-query-timeout"`
+	FilterThreads        int    `toml:"filter-threads" json:"filter-threads"`
+	ApplyThreads         int    `toml:"apply-threads" json:"apply-threads"`
+	WorkerQueue          int    `toml:"worker-queue" json:"worker-queue"`
+	WorkerThreads        int    `toml:"worker-threads" json:"worker-threads"`
+	InsertConflict       string `toml:"insert-conflict" json:"insert-conflict"`
+	MissingRow           string `toml:"missing-row" json:"missing-row"`
+	IncrMode             string `toml:"incr-mode" json:"incr-mode"`
+	TrackColumn          string `toml:"track-column" json:"track-column"`
+	PollInterval         int    `toml:"poll-interval" json:"poll-interval"`
+	DeleteDetect         bool   `toml:"delete-detect" json:"delete-detect"`
+	DeleteDetectInterval int    `toml:"delete-detect-interval" json:"delete-detect-interval"`
+	StartSCN             uint64 `toml:"start-scn" json:"start-scn"`
+	StartTime            string `toml:"start-time" json:"start-time"`
+	VerifyInterval       int    `toml:"verify-interval" json:"verify-interval"`
+	VerifyChunks         int    `toml:"verify-chunks" json:"verify-chunks"`
 }
 
 type SchemaConfig struct {
@@ -121,14 +211,23 @@ type SchemaConfig struct {
 	GlobalTableOption        string                     `toml:"global-table-option" json:"global-table-option"`
 	CompareConfig            []CompareConfig            `toml:"compare-config" json:"compare-config"`
 	MigrateConfig            []MigrateConfig            `toml:"migrate-config" json:"migrate-config"`
+	ConflictConfig           []ConflictConfig           `toml:"conflict-config" json:"conflict-config"`
+	IncrQueryConfig          []IncrQueryConfig          `toml:"incr-query-config" json:"incr-query-config"`
+	SubsetConfig             []SubsetConfig             `toml:"subset-config" json:"subset-config"`
 	StructNonClusteredConfig []StructNonClusteredConfig `toml:"struct-nonclustered-config" json:"struct-nonclustered-config"`
 	StructClusteredConfig    StructClusteredConfig      `toml:"struct-clustered-config" json:"struct-clustered-config"`
 }
 
 type CompareConfig struct {
-	SourceTable string `toml:"source-table" json:"source-table"`
-	IndexFields string `toml:"index-fields" json:"index-fields"`
-	Range       string `toml:"range" json:"range"`
+	SourceTable        string   `toml:"source-table" json:"source-table"`
+	IndexFields        string   `toml:"index-fields" json:"index-fields"`
+	Range              string   `toml:"range" json:"range"`
+	ExcludeColumns     []string `toml:"exclude-columns" json:"exclude-columns"`
+	NumericRoundUnit   float64  `toml:"numeric-round-unit" json:"numeric-round-unit"`
+	TimestampPrecision int      `toml:"timestamp-precision" json:"timestamp-precision"`
+	TrimChar           bool     `toml:"trim-char" json:"trim-char"`
+	CaseFoldColumns    []string `toml:"case-fold-columns" json:"case-fold-columns"`
+	UpdateTimeColumn   string   `toml:"update-time-column" json:"update-time-column"`
 }
 
 type MigrateConfig struct {
@@ -138,6 +237,22 @@ type MigrateConfig struct {
 	SQLHint     string `toml:"sql-hint" json:"sql-hint"`
 }
 
+type ConflictConfig struct {
+	SourceTable    string `toml:"source-table" json:"source-table"`
+	InsertConflict string `toml:"insert-conflict" json:"insert-conflict"`
+	MissingRow     string `toml:"missing-row" json:"missing-row"`
+}
+
+type IncrQueryConfig struct {
+	SourceTable string `toml:"source-table" json:"source-table"`
+	TrackColumn string `toml:"track-column" json:"track-column"`
+}
+
+type SubsetConfig struct {
+	SourceTable string `toml:"source-table" json:"source-table"`
+	Where       string `toml:"where" json:"where"`
+}
+
 type StructNonClusteredConfig struct {
 	SourceTable             []string `toml:"source-table" json:"source-table"`
 	NonClusteredTableOption string   `toml:"nonclustered-table-option" json:"nonclustered-table-option"`
@@ -155,6 +270,7 @@ type OracleConfig struct {
 	ServiceName   string   `toml:"service-name" json:"service-name"`
 	PDBName       string   `toml:"pdb-name" json:"pdb-name"`
 	Charset       string   `toml:"charset" json:"charset"`
+	ActualCharset string   `toml:"actual-charset" json:"actual-charset"`
 	LibDir        string   `toml:"lib-dir" json:"lib-dir"`
 	ConnectParams string   `toml:"connect-params" json:"connect-params"`
 	SessionParams []string `toml:"session-params" json:"session-params"`
@@ -197,7 +313,7 @@ func NewConfig() *Config {
 	}
 	fs.BoolVar(&cfg.PrintVersion, "V", false, "print version information and exit")
 	fs.StringVar(&cfg.ConfigFile, "config", "./config.toml", "path to the configuration file")
-	fs.StringVar(&cfg.TaskMode, "mode", "", "specify the program running mode: [prepare assess reverse full csv all check compare]")
+	fs.StringVar(&cfg.TaskMode, "mode", "", "specify the program running mode: [prepare assess reverse full csv all check compare export import checkpoint profile precheck]")
 	fs.StringVar(&cfg.DBTypeS, "source", "oracle", "specify the source db type")
 	fs.StringVar(&cfg.DBTypeT, "target", "mysql", "specify the target db type")
 	return cfg
@@ -248,18 +364,273 @@ func (c *Config) AdjustConfig() error {
 	c.TaskMode = common.StringUPPER(c.TaskMode)
 	c.OracleConfig.PDBName = common.StringUPPER(c.OracleConfig.PDBName)
 
+	// 数据库声明字符集与实际存储编码不一致（比如 WE8ISO8859P1 库存储 GBK 字节），按 actual-charset 解码字符数据
+	// 未配置则与 charset 一致
+	c.OracleConfig.ActualCharset = common.StringUPPER(c.OracleConfig.ActualCharset)
+	if c.OracleConfig.ActualCharset == "" {
+		c.OracleConfig.ActualCharset = common.StringUPPER(c.OracleConfig.Charset)
+	} else if _, ok := common.MigrateOracleCharsetStringConvertMapping[c.OracleConfig.ActualCharset]; !ok {
+		return fmt.Errorf("config [oracle] actual-charset [%s] isn't support", c.OracleConfig.ActualCharset)
+	}
+
 	c.SchemaConfig.SourceSchema = common.StringUPPER(c.SchemaConfig.SourceSchema)
 	c.SchemaConfig.TargetSchema = common.StringUPPER(c.SchemaConfig.TargetSchema)
 
+	if c.AppConfig.GracefulTimeout == 0 {
+		c.AppConfig.GracefulTimeout = 60
+	}
+	if c.AppConfig.MinConcurrency == 0 {
+		c.AppConfig.MinConcurrency = 1
+	}
+	if c.AppConfig.TargetWriteLatency == 0 {
+		c.AppConfig.TargetWriteLatency = 2000
+	}
+	if c.AppConfig.AdjustInterval == 0 {
+		c.AppConfig.AdjustInterval = 5
+	}
+	// 带时区时间字段统一规整至 time-zone，目标端会话 time_zone 保持一致
+	timeZone, err := common.NormalizeTimeZone(c.AppConfig.TimeZone)
+	if err != nil {
+		return fmt.Errorf("config [app] time-zone failed: %v", err)
+	}
+	c.AppConfig.TimeZone = timeZone
+	c.MySQLConfig.ConnectParams = common.GenMySQLTimeZoneConnectParams(c.MySQLConfig.ConnectParams, c.AppConfig.TimeZone)
+
 	if c.FullConfig.CallTimeout == 0 {
 		c.FullConfig.CallTimeout = 36000
 	}
+	if c.FullConfig.IndexThreads == 0 {
+		c.FullConfig.IndexThreads = 4
+	}
 	if c.CSVConfig.CallTimeout == 0 {
 		c.CSVConfig.CallTimeout = 36000
 	}
+
+	if c.ThrottleConfig.CheckInterval == 0 {
+		c.ThrottleConfig.CheckInterval = 10
+	}
+	for _, w := range c.ThrottleConfig.FullSpeedWindows {
+		if _, _, err := common.ParseTimeWindow(w); err != nil {
+			return fmt.Errorf("config [throttle] full-speed-windows [%s] isn't valid: %v", w, err)
+		}
+	}
+
+	if c.AllConfig.IncrMode == "" {
+		c.AllConfig.IncrMode = common.IncrModeLogminer
+	}
+	c.AllConfig.IncrMode = common.StringUPPER(c.AllConfig.IncrMode)
+	if c.AllConfig.IncrMode != common.IncrModeLogminer && c.AllConfig.IncrMode != common.IncrModeQuery {
+		return fmt.Errorf("config [all] incr-mode [%s] isn't support, only support [logminer query]", c.AllConfig.IncrMode)
+	}
+	if c.AllConfig.StartSCN > 0 && c.AllConfig.StartTime != "" {
+		return fmt.Errorf("config [all] start-scn and start-time can't be configured at the same time")
+	}
+	if c.AllConfig.StartTime != "" {
+		if _, err := time.Parse(common.IncrStartTimeLayout, c.AllConfig.StartTime); err != nil {
+			return fmt.Errorf("config [all] start-time [%s] isn't valid, format [%s]: %v", c.AllConfig.StartTime, common.IncrStartTimeLayout, err)
+		}
+	}
+
+	if c.CheckpointConfig.Action == "" {
+		c.CheckpointConfig.Action = common.CheckpointActionShow
+	}
+	c.CheckpointConfig.Action = common.StringUPPER(c.CheckpointConfig.Action)
+	if c.CheckpointConfig.Action != common.CheckpointActionShow && c.CheckpointConfig.Action != common.CheckpointActionReset {
+		return fmt.Errorf("config [checkpoint] action [%s] isn't support, only support [show reset]", c.CheckpointConfig.Action)
+	}
+	if c.TaskMode == common.TaskModeCheckpoint && c.CheckpointConfig.Action == common.CheckpointActionReset {
+		if (c.CheckpointConfig.SCN > 0) == (c.CheckpointConfig.Timestamp != "") {
+			return fmt.Errorf("config [checkpoint] action reset need configure only one of scn and timestamp")
+		}
+		if c.CheckpointConfig.Timestamp != "" {
+			if _, err := time.Parse(common.IncrStartTimeLayout, c.CheckpointConfig.Timestamp); err != nil {
+				return fmt.Errorf("config [checkpoint] timestamp [%s] isn't valid, format [%s]: %v", c.CheckpointConfig.Timestamp, common.IncrStartTimeLayout, err)
+			}
+		}
+	}
+	for i, t := range c.CheckpointConfig.Tables {
+		c.CheckpointConfig.Tables[i] = common.StringUPPER(t)
+	}
+
+	if c.AllConfig.PollInterval == 0 {
+		c.AllConfig.PollInterval = 10
+	}
+	if c.AllConfig.DeleteDetectInterval == 0 {
+		c.AllConfig.DeleteDetectInterval = 3600
+	}
+	if c.AllConfig.VerifyInterval < 0 {
+		return fmt.Errorf("config [all] verify-interval [%d] can't less than 0", c.AllConfig.VerifyInterval)
+	}
+	if c.AllConfig.VerifyChunks <= 0 {
+		c.AllConfig.VerifyChunks = 10
+	}
+
+	if c.DiffConfig.SinceSCN > 0 && c.DiffConfig.SinceTime != "" {
+		return fmt.Errorf("config [compare] since-scn and since-time can't be configured at the same time")
+	}
+	if c.DiffConfig.SinceTime != "" {
+		if _, err := time.Parse(common.IncrStartTimeLayout, c.DiffConfig.SinceTime); err != nil {
+			return fmt.Errorf("config [compare] since-time [%s] isn't valid, format [%s]: %v", c.DiffConfig.SinceTime, common.IncrStartTimeLayout, err)
+		}
+	}
+	// 修复语句 LOB 字段值超过该大小写入数据文件，负数表示全部内联
+	if c.DiffConfig.LobInlineSize == 0 {
+		c.DiffConfig.LobInlineSize = 1048576
+	}
+	if c.DiffConfig.SamplePercent < 0 || c.DiffConfig.SamplePercent > 100 {
+		return fmt.Errorf("config [compare] sample-percent [%v] must be between 0 and 100", c.DiffConfig.SamplePercent)
+	}
+	if c.DiffConfig.SampleChunks < 0 {
+		return fmt.Errorf("config [compare] sample-chunks [%d] can't less than 0", c.DiffConfig.SampleChunks)
+	}
+	if c.DiffConfig.SamplePercent > 0 || c.DiffConfig.SampleChunks > 0 {
+		if c.DiffConfig.SamplePercent > 0 && c.DiffConfig.SampleChunks > 0 {
+			return fmt.Errorf("config [compare] sample-percent and sample-chunks can't be configured at the same time")
+		}
+		if c.DiffConfig.SinceSCN > 0 || c.DiffConfig.SinceTime != "" {
+			return fmt.Errorf("config [compare] sample-percent/sample-chunks and since-scn/since-time can't be configured at the same time")
+		}
+	}
+	if c.DiffConfig.Repair {
+		// 只对比数据行数不产生修复语句
+		if c.DiffConfig.OnlyCheckRows {
+			return fmt.Errorf("config [compare] repair and only-check-rows can't be enabled at the same time")
+		}
+		if c.DiffConfig.RepairMaxRows <= 0 {
+			c.DiffConfig.RepairMaxRows = 10000
+		}
+	}
+
+	if c.AllConfig.InsertConflict == "" {
+		c.AllConfig.InsertConflict = common.ConflictPolicyLogOnly
+	}
+	if c.AllConfig.MissingRow == "" {
+		c.AllConfig.MissingRow = common.ConflictPolicyLogOnly
+	}
+	c.AllConfig.InsertConflict = common.StringUPPER(c.AllConfig.InsertConflict)
+	c.AllConfig.MissingRow = common.StringUPPER(c.AllConfig.MissingRow)
+	for _, p := range []string{c.AllConfig.InsertConflict, c.AllConfig.MissingRow} {
+		if !isConflictPolicy(p) {
+			return fmt.Errorf("config [all] conflict policy [%s] isn't support, only support [overwrite skip fail log-only]", p)
+		}
+	}
+	for i, cc := range c.SchemaConfig.ConflictConfig {
+		c.SchemaConfig.ConflictConfig[i].InsertConflict = common.StringUPPER(cc.InsertConflict)
+		c.SchemaConfig.ConflictConfig[i].MissingRow = common.StringUPPER(cc.MissingRow)
+		for _, p := range []string{c.SchemaConfig.ConflictConfig[i].InsertConflict, c.SchemaConfig.ConflictConfig[i].MissingRow} {
+			if p != "" && !isConflictPolicy(p) {
+				return fmt.Errorf("config [schema-config] table [%s] conflict policy [%s] isn't support, only support [overwrite skip fail log-only]", cc.SourceTable, p)
+			}
+		}
+	}
+	// 子集迁移仅支持 FULL 模式，CSV 模式不支持 ROWID 子集分片，ALL 模式增量无法限定子集范围
+	if len(c.SchemaConfig.SubsetConfig) > 0 && (c.TaskMode == common.TaskModeCSV || c.TaskMode == common.TaskModeAll) {
+		return fmt.Errorf("config [schema-config] subset-config only support task mode [%s], current task mode [%s]", common.TaskModeFull, c.TaskMode)
+	}
+	for i, sc := range c.SchemaConfig.SubsetConfig {
+		if sc.SourceTable == "" || strings.TrimSpace(sc.Where) == "" {
+			return fmt.Errorf("config [schema-config] subset-config item [%d] source-table and where can not be null", i)
+		}
+		c.SchemaConfig.SubsetConfig[i].SourceTable = common.StringUPPER(sc.SourceTable)
+	}
+	for i, cc := range c.SchemaConfig.CompareConfig {
+		if cc.NumericRoundUnit < 0 {
+			return fmt.Errorf("config [schema-config] table [%s] numeric-round-unit [%v] can not be less than 0", cc.SourceTable, cc.NumericRoundUnit)
+		}
+		if cc.NumericRoundUnit > 0 {
+			if _, err := common.CompareNumericRoundUnitScale(cc.NumericRoundUnit); err != nil {
+				return fmt.Errorf("config [schema-config] table [%s] %v", cc.SourceTable, err)
+			}
+		}
+		// 目标端 MySQL/TiDB 时间类型最大精度 6
+		if cc.TimestampPrecision < 0 || cc.TimestampPrecision > 6 {
+			return fmt.Errorf("config [schema-config] table [%s] timestamp-precision [%d] isn't support, only support [0-6]", cc.SourceTable, cc.TimestampPrecision)
+		}
+		for j, col := range cc.ExcludeColumns {
+			c.SchemaConfig.CompareConfig[i].ExcludeColumns[j] = common.StringUPPER(col)
+		}
+		for j, col := range cc.CaseFoldColumns {
+			c.SchemaConfig.CompareConfig[i].CaseFoldColumns[j] = common.StringUPPER(col)
+		}
+		c.SchemaConfig.CompareConfig[i].UpdateTimeColumn = common.StringUPPER(cc.UpdateTimeColumn)
+	}
+
+	if c.RuleConfig.RuleFile == "" {
+		c.RuleConfig.RuleFile = common.RuleFileDefault
+	}
+	// 未指定规则文件格式，以文件后缀为准
+	if c.RuleConfig.RuleFormat == "" {
+		switch strings.ToLower(filepath.Ext(c.RuleConfig.RuleFile)) {
+		case ".yaml", ".yml":
+			c.RuleConfig.RuleFormat = common.RuleFormatYAML
+		default:
+			c.RuleConfig.RuleFormat = common.RuleFormatTOML
+		}
+	}
+	c.RuleConfig.RuleFormat = common.StringUPPER(c.RuleConfig.RuleFormat)
+	if c.RuleConfig.RuleFormat != common.RuleFormatTOML && c.RuleConfig.RuleFormat != common.RuleFormatYAML {
+		return fmt.Errorf("config [rule] rule-format [%s] isn't support, only support [toml yaml]", c.RuleConfig.RuleFormat)
+	}
+
+	if c.ProfileConfig.ProfileThreads == 0 {
+		c.ProfileConfig.ProfileThreads = 8
+	}
+	if c.ProfileConfig.VarcharMinLength == 0 {
+		c.ProfileConfig.VarcharMinLength = 256
+	}
+	if c.ProfileConfig.VarcharMaxLength == 0 {
+		c.ProfileConfig.VarcharMaxLength = 4000
+	}
+	if c.ProfileConfig.Headroom == 0 {
+		c.ProfileConfig.Headroom = 20
+	}
+	if c.ProfileConfig.ProfileFile == "" {
+		c.ProfileConfig.ProfileFile = common.ProfileFileDefault
+	}
+	if c.ProfileConfig.SamplePercent < 0 || c.ProfileConfig.SamplePercent > 100 {
+		return fmt.Errorf("config [profile] sample-percent [%v] isn't valid, range [0 100]", c.ProfileConfig.SamplePercent)
+	}
+
+	if c.PrecheckConfig.TargetMode == "" {
+		c.PrecheckConfig.TargetMode = common.TaskModeFull
+	}
+	c.PrecheckConfig.TargetMode = common.StringUPPER(c.PrecheckConfig.TargetMode)
+	switch c.PrecheckConfig.TargetMode {
+	case common.TaskModeReverse, common.TaskModeFull, common.TaskModeCSV, common.TaskModeAll, common.TaskModeCompare:
+	default:
+		return fmt.Errorf("config [precheck] target-mode [%s] isn't support, only support [reverse full csv all compare]", c.PrecheckConfig.TargetMode)
+	}
+	if c.PrecheckConfig.ReportFile == "" {
+		c.PrecheckConfig.ReportFile = common.PrecheckReportFileDefault
+	}
+
+	if c.RejectConfig.MaxRejectRows == 0 {
+		c.RejectConfig.MaxRejectRows = 100
+	}
+	if c.RejectConfig.RejectDir == "" {
+		c.RejectConfig.RejectDir = "./reject"
+	}
+	if c.RejectConfig.InvalidCharPolicy == "" {
+		c.RejectConfig.InvalidCharPolicy = common.InvalidCharPolicyReplace
+	}
+	c.RejectConfig.InvalidCharPolicy = common.StringUPPER(c.RejectConfig.InvalidCharPolicy)
+	switch c.RejectConfig.InvalidCharPolicy {
+	case common.InvalidCharPolicyReplace, common.InvalidCharPolicyStrip, common.InvalidCharPolicyReject:
+	default:
+		return fmt.Errorf("config [reject] invalid-char-policy [%s] isn't support, only support [replace strip reject]", c.RejectConfig.InvalidCharPolicy)
+	}
 	return nil
 }
 
+func isConflictPolicy(policy string) bool {
+	switch policy {
+	case common.ConflictPolicyOverwrite, common.ConflictPolicySkip, common.ConflictPolicyFail, common.ConflictPolicyLogOnly:
+		return true
+	default:
+		return false
+	}
+}
+
 func (c *Config) String() string {
 	cfg, err := json.Marshal(c)
 	if err != nil {
@@ -77,3 +77,18 @@ func (rw *BuildinColumnDefaultval) DetailColumnDefaultVal(ctx context.Context, d
 
 	return defaultRuleMap, nil
 }
+
+func (rw *BuildinColumnDefaultval) DetailColumnDefaultValByDBType(ctx context.Context, detailS *BuildinColumnDefaultval) ([]BuildinColumnDefaultval, error) {
+	var ruleMap []BuildinColumnDefaultval
+
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return ruleMap, err
+	}
+	if err = rw.DB(ctx).Where("UPPER(db_type_s) = ? AND UPPER(db_type_t) = ?",
+		common.StringUPPER(detailS.DBTypeS),
+		common.StringUPPER(detailS.DBTypeT)).Order("id ASC").Find(&ruleMap).Error; err != nil {
+		return ruleMap, fmt.Errorf("detail table [%s] record by db type failed: %v", table, err)
+	}
+	return ruleMap, nil
+}
@@ -166,3 +166,18 @@ func (rw *BuildinGlobalDefaultval) InitMT2OBuildinGlobalDefaultValue(ctx context
 
 	return rw.DB(ctx).Clauses(clause.Insert{Modifier: "IGNORE"}).Create(buildinColumDefaultvals).Error
 }
+
+func (rw *BuildinGlobalDefaultval) DetailGlobalDefaultValByDBType(ctx context.Context, detailS *BuildinGlobalDefaultval) ([]BuildinGlobalDefaultval, error) {
+	var ruleMap []BuildinGlobalDefaultval
+
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return ruleMap, err
+	}
+	if err = rw.DB(ctx).Where("UPPER(db_type_s) = ? AND UPPER(db_type_t) = ?",
+		common.StringUPPER(detailS.DBTypeS),
+		common.StringUPPER(detailS.DBTypeT)).Order("id ASC").Find(&ruleMap).Error; err != nil {
+		return ruleMap, fmt.Errorf("detail table [%s] record by db type failed: %v", table, err)
+	}
+	return ruleMap, nil
+}
@@ -35,6 +35,7 @@ type DataCompareMeta struct {
 	ColumnDetailT string `gorm:"type:text;comment:'目标端查询字段信息'" json:"column_detail_t"`
 	WhereColumn   string `gorm:"comment:'查询类型字段列'" json:"where_column"`
 	WhereRange    string `gorm:"type:varchar(300);not null;index:idx_dbtype_st_obj,unique;comment:'查询 where 条件'" json:"where_range"`
+	WhereRangeT   string `gorm:"type:text;comment:'目标端查询 where 条件，为空与源端一致'" json:"where_range_t"`
 	TaskMode      string `gorm:"type:varchar(30);not null;index:idx_dbtype_st_obj,unique;comment:'任务模式'" json:"task_mode"`
 	TaskStatus    string `gorm:"type:varchar(30);not null;comment:'数据对比状态,only waiting,success,failed'" json:"task_status"`
 	IsPartition   string `gorm:"comment:'是否是分区表'" json:"is_partition"` // 同步转换统一转换成非分区表，此处只做标志
@@ -183,3 +184,24 @@ func (rw *DataCompareMeta) DistinctDataCompareMetaTableNameSByTaskStatus(ctx con
 	}
 	return tableNames, nil
 }
+
+// 按最近校验时间升序获取 chunk，失败 chunk 优先，用于增量在线校验轮转
+func (rw *DataCompareMeta) DetailDataCompareMetaByLeastRecent(ctx context.Context, detailS *DataCompareMeta, limit int) ([]DataCompareMeta, error) {
+	var dsMetas []DataCompareMeta
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return dsMetas, err
+	}
+	if err = rw.DB(ctx).Model(&DataCompareMeta{}).
+		Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ? AND task_mode = ?",
+			common.StringUPPER(detailS.DBTypeS),
+			common.StringUPPER(detailS.DBTypeT),
+			common.StringUPPER(detailS.SchemaNameS),
+			common.StringUPPER(detailS.TaskMode)).
+		Order(fmt.Sprintf("CASE WHEN task_status = '%s' THEN 0 ELSE 1 END, updated_at ASC", common.TaskStatusFailed)).
+		Limit(limit).
+		Find(&dsMetas).Error; err != nil {
+		return dsMetas, fmt.Errorf("detail table [%s] record by least recent failed: %v", table, err)
+	}
+	return dsMetas, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package meta
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// 数据校验自动修复记录表，记录 chunk 修复前后状态以及执行语句
+type DataRepairMeta struct {
+	ID           uint   `gorm:"primary_key;autoIncrement;comment:'自增编号'" json:"id"`
+	DBTypeS      string `gorm:"type:varchar(30);index:idx_dbtype_st_map;comment:'源数据库类型'" json:"db_type_s"`
+	DBTypeT      string `gorm:"type:varchar(30);index:idx_dbtype_st_map;comment:'目标数据库类型'" json:"db_type_t"`
+	SchemaNameS  string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map;comment:'源端 schema'" json:"schema_name_s"`
+	TableNameS   string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map;comment:'源端表名'" json:"table_name_s"`
+	SchemaNameT  string `gorm:"type:varchar(100);not null;comment:'目标端 schema'" json:"schema_name_t"`
+	TableNameT   string `gorm:"type:varchar(100);not null;comment:'目标端表名'" json:"table_name_t"`
+	WhereRange   string `gorm:"type:text;comment:'chunk where 条件'" json:"where_range"`
+	RepairMode   string `gorm:"type:varchar(30);not null;comment:'修复模式 REPAIR/DRYRUN'" json:"repair_mode"`
+	StatusBefore string `gorm:"type:varchar(30);not null;comment:'修复前 chunk 对比状态'" json:"status_before"`
+	StatusAfter  string `gorm:"type:varchar(30);not null;comment:'修复后 chunk 对比状态 SUCCESS/FAILED/SKIPPED'" json:"status_after"`
+	RepairRows   int64  `gorm:"comment:'修复语句数'" json:"repair_rows"`
+	AffectedRows int64  `gorm:"comment:'目标端实际影响行数'" json:"affected_rows"`
+	RepairSQL    string `gorm:"type:longtext;comment:'修复语句'" json:"repair_sql"`
+	ErrorDetail  string `gorm:"type:longtext;comment:'错误详情'" json:"error_detail"`
+	*BaseModel
+}
+
+func NewDataRepairMetaModel(m *Meta) *DataRepairMeta {
+	return &DataRepairMeta{BaseModel: &BaseModel{
+		Meta: m,
+	}}
+}
+
+func (rw *DataRepairMeta) ParseSchemaTable() (string, error) {
+	stmt := &gorm.Statement{DB: rw.GormDB}
+	err := stmt.Parse(rw)
+	if err != nil {
+		return "", fmt.Errorf("parse struct [DataRepairMeta] get table_name failed: %v", err)
+	}
+	return stmt.Schema.Table, nil
+}
+
+func (rw *DataRepairMeta) CreateDataRepairMeta(ctx context.Context, createS *DataRepairMeta) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	if err = rw.DB(ctx).Create(createS).Error; err != nil {
+		return fmt.Errorf("create table [%s] record failed: %v", table, err)
+	}
+	return nil
+}
@@ -84,3 +84,18 @@ func (rw *ColumnDatatypeRule) DetailColumnRule(ctx context.Context, detailS *Col
 
 	return columnRuleMap, nil
 }
+
+func (rw *ColumnDatatypeRule) DetailColumnRuleByDBType(ctx context.Context, detailS *ColumnDatatypeRule) ([]ColumnDatatypeRule, error) {
+	var ruleMap []ColumnDatatypeRule
+
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return ruleMap, err
+	}
+	if err = rw.DB(ctx).Where("UPPER(db_type_s) = ? AND UPPER(db_type_t) = ?",
+		common.StringUPPER(detailS.DBTypeS),
+		common.StringUPPER(detailS.DBTypeT)).Order("id ASC").Find(&ruleMap).Error; err != nil {
+		return ruleMap, fmt.Errorf("detail table [%s] record by db type failed: %v", table, err)
+	}
+	return ruleMap, nil
+}
@@ -63,3 +63,18 @@ func (rw *SchemaDatatypeRule) DetailSchemaRule(ctx context.Context, detailS *Sch
 	}
 	return schemaRuleMap, nil
 }
+
+func (rw *SchemaDatatypeRule) DetailSchemaRuleByDBType(ctx context.Context, detailS *SchemaDatatypeRule) ([]SchemaDatatypeRule, error) {
+	var ruleMap []SchemaDatatypeRule
+
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return ruleMap, err
+	}
+	if err = rw.DB(ctx).Where("UPPER(db_type_s) = ? AND UPPER(db_type_t) = ?",
+		common.StringUPPER(detailS.DBTypeS),
+		common.StringUPPER(detailS.DBTypeT)).Order("id ASC").Find(&ruleMap).Error; err != nil {
+		return ruleMap, fmt.Errorf("detail table [%s] record by db type failed: %v", table, err)
+	}
+	return ruleMap, nil
+}
@@ -66,3 +66,18 @@ func (rw *TableDatatypeRule) DetailTableRule(ctx context.Context, detailS *Table
 	}
 	return tableRuleMap, nil
 }
+
+func (rw *TableDatatypeRule) DetailTableRuleByDBType(ctx context.Context, detailS *TableDatatypeRule) ([]TableDatatypeRule, error) {
+	var ruleMap []TableDatatypeRule
+
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return ruleMap, err
+	}
+	if err = rw.DB(ctx).Where("UPPER(db_type_s) = ? AND UPPER(db_type_t) = ?",
+		common.StringUPPER(detailS.DBTypeS),
+		common.StringUPPER(detailS.DBTypeT)).Order("id ASC").Find(&ruleMap).Error; err != nil {
+		return ruleMap, fmt.Errorf("detail table [%s] record by db type failed: %v", table, err)
+	}
+	return ruleMap, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package meta
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// 增量同步冲突记录表
+type IncrConflictDetail struct {
+	ID           uint   `gorm:"primary_key;autoIncrement;comment:'自增编号'" json:"id"`
+	DBTypeS      string `gorm:"type:varchar(30);index:idx_dbtype_st_map;comment:'源数据库类型'" json:"db_type_s"`
+	DBTypeT      string `gorm:"type:varchar(30);index:idx_dbtype_st_map;comment:'目标数据库类型'" json:"db_type_t"`
+	SchemaNameS  string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map;comment:'源端 schema'" json:"schema_name_s"`
+	TableNameS   string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map;comment:'源端表名'" json:"table_name_s"`
+	SchemaNameT  string `gorm:"type:varchar(100);not null;comment:'目标端 schema'" json:"schema_name_t"`
+	TableNameT   string `gorm:"type:varchar(100);not null;comment:'目标端表名'" json:"table_name_t"`
+	TaskMode     string `gorm:"type:varchar(30);not null;index:idx_dbtype_st_map;comment:'任务模式'" json:"task_mode"`
+	ScnS         uint64 `gorm:"comment:'源端变更 SCN'" json:"scn_s"`
+	Operation    string `gorm:"type:varchar(30);not null;comment:'变更操作类型'" json:"operation"`
+	ConflictType string `gorm:"type:varchar(30);not null;comment:'冲突类型 INSERT_CONFLICT/MISSING_ROW'" json:"conflict_type"`
+	Policy       string `gorm:"type:varchar(30);not null;comment:'冲突处理策略'" json:"policy"`
+	KeyValues    string `gorm:"type:text;comment:'主键/唯一键值'" json:"key_values"`
+	OracleRedo   string `gorm:"type:longtext;comment:'源端 redo SQL'" json:"oracle_redo"`
+	MySQLRedo    string `gorm:"type:longtext;comment:'目标端执行 SQL'" json:"mysql_redo"`
+	ErrorDetail  string `gorm:"type:longtext;comment:'冲突错误详情'" json:"error_detail"`
+	*BaseModel
+}
+
+func NewIncrConflictDetailModel(m *Meta) *IncrConflictDetail {
+	return &IncrConflictDetail{BaseModel: &BaseModel{
+		Meta: m,
+	}}
+}
+
+func (rw *IncrConflictDetail) ParseSchemaTable() (string, error) {
+	stmt := &gorm.Statement{DB: rw.GormDB}
+	err := stmt.Parse(rw)
+	if err != nil {
+		return "", fmt.Errorf("parse struct [IncrConflictDetail] get table_name failed: %v", err)
+	}
+	return stmt.Schema.Table, nil
+}
+
+func (rw *IncrConflictDetail) CreateIncrConflictDetail(ctx context.Context, createS *IncrConflictDetail) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	if err = rw.DB(ctx).Create(createS).Error; err != nil {
+		return fmt.Errorf("create table [%s] record failed: %v", table, err)
+	}
+	return nil
+}
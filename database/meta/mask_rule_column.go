@@ -0,0 +1,97 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package meta
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"gorm.io/gorm"
+)
+
+// 自定义字段脱敏规则 - 字段列级别
+// 全量、CSV 以及增量同步字段值脱敏写入，数据校验忽略脱敏字段
+type ColumnMaskRule struct {
+	ID          uint   `gorm:"primary_key;autoIncrement;comment:'自增编号'" json:"id"`
+	DBTypeS     string `gorm:"type:varchar(30);index:idx_dbtype_st_map,unique;comment:'源数据库类型'" json:"db_type_s"`
+	DBTypeT     string `gorm:"type:varchar(30);index:idx_dbtype_st_map,unique;comment:'目标数据库类型'" json:"db_type_t"`
+	SchemaNameS string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map,unique;comment:'源端库 schema'" json:"schema_name_s"`
+	TableNameS  string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map,unique;comment:'源端表名'" json:"table_name_s"`
+	ColumnNameS string `gorm:"type:varchar(200);not null;index:idx_dbtype_st_map,unique;comment:'源端表字段列名'" json:"column_name_s"`
+	MaskType    string `gorm:"type:varchar(30);not null;comment:'脱敏类型'" json:"mask_type"`
+	MaskValue   string `gorm:"type:varchar(1000);comment:'脱敏参数'" json:"mask_value"`
+	*BaseModel
+}
+
+func NewColumnMaskRuleModel(m *Meta) *ColumnMaskRule {
+	return &ColumnMaskRule{BaseModel: &BaseModel{
+		Meta: m,
+	}}
+}
+
+func (rw *ColumnMaskRule) ParseSchemaTable() (string, error) {
+	stmt := &gorm.Statement{DB: rw.GormDB}
+	err := stmt.Parse(rw)
+	if err != nil {
+		return "", fmt.Errorf("parse struct [ColumnMaskRule] get table_name failed: %v", err)
+	}
+	return stmt.Schema.Table, nil
+}
+
+func (rw *ColumnMaskRule) DetailColumnMaskRuleByTable(ctx context.Context, detailS *ColumnMaskRule) ([]ColumnMaskRule, error) {
+	var ruleMap []ColumnMaskRule
+
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return ruleMap, err
+	}
+	if err = rw.DB(ctx).Where("UPPER(db_type_s) = ? AND UPPER(db_type_t) = ? AND UPPER(schema_name_s) = ? AND UPPER(table_name_s) = ?",
+		common.StringUPPER(detailS.DBTypeS),
+		common.StringUPPER(detailS.DBTypeT),
+		common.StringUPPER(detailS.SchemaNameS),
+		common.StringUPPER(detailS.TableNameS)).Find(&ruleMap).Error; err != nil {
+		return ruleMap, fmt.Errorf("detail table [%s] record by table failed: %v", table, err)
+	}
+	return ruleMap, nil
+}
+
+func (rw *ColumnMaskRule) DetailColumnMaskRuleByDBType(ctx context.Context, detailS *ColumnMaskRule) ([]ColumnMaskRule, error) {
+	var ruleMap []ColumnMaskRule
+
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return ruleMap, err
+	}
+	if err = rw.DB(ctx).Where("UPPER(db_type_s) = ? AND UPPER(db_type_t) = ?",
+		common.StringUPPER(detailS.DBTypeS),
+		common.StringUPPER(detailS.DBTypeT)).Order("id ASC").Find(&ruleMap).Error; err != nil {
+		return ruleMap, fmt.Errorf("detail table [%s] record by db type failed: %v", table, err)
+	}
+	return ruleMap, nil
+}
+
+// 获取表字段脱敏规则，字段名 -> 规则
+func (rw *ColumnMaskRule) GetColumnMaskRuleMap(ctx context.Context, detailS *ColumnMaskRule) (map[string]ColumnMaskRule, error) {
+	rules, err := rw.DetailColumnMaskRuleByTable(ctx, detailS)
+	if err != nil {
+		return nil, err
+	}
+	ruleMap := make(map[string]ColumnMaskRule)
+	for _, r := range rules {
+		ruleMap[common.StringUPPER(r.ColumnNameS)] = r
+	}
+	return ruleMap, nil
+}
@@ -113,6 +113,12 @@ func (m *Meta) MigrateTables() (err error) {
 		new(BuildinDatatypeRule),
 		new(TableNameRule),
 		new(ChunkErrorDetail),
+		new(IncrConflictDetail),
+		new(IncrThreadMeta),
+		new(ColumnMaskRule),
+		new(IndexSyncMeta),
+		new(RejectRowDetail),
+		new(DataRepairMeta),
 	)
 }
 
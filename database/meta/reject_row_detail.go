@@ -0,0 +1,122 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package meta
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"gorm.io/gorm"
+)
+
+// 坏行隔离记录表，行数据以字段名 -> 十六进制原始字节 JSON 存储
+type RejectRowDetail struct {
+	ID           uint   `gorm:"primary_key;autoIncrement;comment:'自增编号'" json:"id"`
+	DBTypeS      string `gorm:"type:varchar(30);index:idx_dbtype_st_map;comment:'源数据库类型'" json:"db_type_s"`
+	DBTypeT      string `gorm:"type:varchar(30);index:idx_dbtype_st_map;comment:'目标数据库类型'" json:"db_type_t"`
+	SchemaNameS  string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map;comment:'源端 schema'" json:"schema_name_s"`
+	TableNameS   string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map;comment:'源端表名'" json:"table_name_s"`
+	SchemaNameT  string `gorm:"type:varchar(100);not null;comment:'目标端 schema'" json:"schema_name_t"`
+	TableNameT   string `gorm:"type:varchar(100);not null;comment:'目标端表名'" json:"table_name_t"`
+	TaskMode     string `gorm:"type:varchar(30);not null;index:idx_dbtype_st_map;comment:'任务模式'" json:"task_mode"`
+	ChunkDetailS string `gorm:"type:varchar(300);not null;comment:'表 chunk 切分信息'" json:"chunk_detail_s"`
+	RejectStage  string `gorm:"type:varchar(30);not null;comment:'隔离阶段 READ/WRITE'" json:"reject_stage"`
+	RowData      string `gorm:"type:longtext;comment:'行数据（十六进制）'" json:"row_data"`
+	ErrorDetail  string `gorm:"type:longtext;comment:'错误详情'" json:"error_detail"`
+	*BaseModel
+}
+
+func NewRejectRowDetailModel(m *Meta) *RejectRowDetail {
+	return &RejectRowDetail{BaseModel: &BaseModel{
+		Meta: m,
+	}}
+}
+
+func (rw *RejectRowDetail) ParseSchemaTable() (string, error) {
+	stmt := &gorm.Statement{DB: rw.GormDB}
+	err := stmt.Parse(rw)
+	if err != nil {
+		return "", fmt.Errorf("parse struct [RejectRowDetail] get table_name failed: %v", err)
+	}
+	return stmt.Schema.Table, nil
+}
+
+func (rw *RejectRowDetail) CreateRejectRowDetail(ctx context.Context, createS *RejectRowDetail) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	if err = rw.DB(ctx).Create(createS).Error; err != nil {
+		return fmt.Errorf("create table [%s] record failed: %v", table, err)
+	}
+	return nil
+}
+
+func (rw *RejectRowDetail) DeleteRejectRowDetailBySchemaTaskMode(ctx context.Context, deleteS *RejectRowDetail) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	err = rw.DB(ctx).Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ? AND task_mode = ?",
+		common.StringUPPER(deleteS.DBTypeS),
+		common.StringUPPER(deleteS.DBTypeT),
+		common.StringUPPER(deleteS.SchemaNameS),
+		deleteS.TaskMode).Delete(&RejectRowDetail{}).Error
+	if err != nil {
+		return fmt.Errorf("delete table [%s] reocrd failed: %v", table, err)
+	}
+	return nil
+}
+
+// 断点续传重新导入的 chunk，清理该 chunk 已有隔离记录
+func (rw *RejectRowDetail) DeleteRejectRowDetailByChunks(ctx context.Context, deleteS *RejectRowDetail, chunks []string) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	err = rw.DB(ctx).Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ? AND table_name_s = ? AND task_mode = ? AND chunk_detail_s IN ?",
+		common.StringUPPER(deleteS.DBTypeS),
+		common.StringUPPER(deleteS.DBTypeT),
+		common.StringUPPER(deleteS.SchemaNameS),
+		common.StringUPPER(deleteS.TableNameS),
+		deleteS.TaskMode, chunks).Delete(&RejectRowDetail{}).Error
+	if err != nil {
+		return fmt.Errorf("delete table [%s] reocrd failed: %v", table, err)
+	}
+	return nil
+}
+
+func (rw *RejectRowDetail) CountsRejectRowDetailByTable(ctx context.Context, detailS *RejectRowDetail) (int64, error) {
+	var totals int64
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return totals, err
+	}
+	if err = rw.DB(ctx).Model(&RejectRowDetail{}).
+		Where(`db_type_s = ? AND db_type_t = ? AND schema_name_s = ? AND table_name_s = ? AND task_mode = ?`,
+			common.StringUPPER(detailS.DBTypeS),
+			common.StringUPPER(detailS.DBTypeT),
+			common.StringUPPER(detailS.SchemaNameS),
+			common.StringUPPER(detailS.TableNameS),
+			detailS.TaskMode).
+		Count(&totals).Error; err != nil {
+		return totals, fmt.Errorf("get table [%s] counts failed: %v", table, err)
+	}
+	return totals, nil
+}
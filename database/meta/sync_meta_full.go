@@ -37,6 +37,7 @@ type FullSyncMeta struct {
 	SQLHint        string `gorm:"type:varchar(300);comment:'sql hint'" json:"sql_hint"`
 	ColumnDetailS  string `gorm:"type:text;comment:'源端查询字段信息'" json:"column_detail_s"`
 	ChunkDetailS   string `gorm:"type:varchar(300);not null;index:idx_dbtype_st_map,unique;comment:'表 chunk 切分信息'" json:"chunk_detail_s"`
+	ChunkRowidS    string `gorm:"type:text;comment:'子集迁移 chunk ROWID 列表'" json:"chunk_rowid_s"`
 	TaskMode       string `gorm:"type:varchar(30);not null;index:idx_dbtype_st_map,unique;index:idx_schema_mode;comment:'任务模式'" json:"task_mode"`
 	TaskStatus     string `gorm:"type:varchar(30);not null;comment:'任务 chunk 状态'" json:"task_status"`
 	CSVFile        string `gorm:"type:varchar(300);comment:'csv 文件名'" json:"csv_file"`
@@ -135,6 +136,25 @@ func (rw *FullSyncMeta) UpdateFullSyncMetaChunk(ctx context.Context, detailS *Fu
 	return nil
 }
 
+func (rw *FullSyncMeta) UpdateFullSyncMetaChunkByTaskStatus(ctx context.Context, detailS *FullSyncMeta, updates map[string]interface{}) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	if err = rw.DB(ctx).Model(FullSyncMeta{}).
+		Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ? AND table_name_s = ? AND task_mode = ? AND task_status = ?",
+			common.StringUPPER(detailS.DBTypeS),
+			common.StringUPPER(detailS.DBTypeT),
+			common.StringUPPER(detailS.SchemaNameS),
+			common.StringUPPER(detailS.TableNameS),
+			common.StringUPPER(detailS.TaskMode),
+			detailS.TaskStatus).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("update table [%s] record by task status failed: %v", table, err)
+	}
+	return nil
+}
+
 func (rw *FullSyncMeta) CountsErrorFullSyncMeta(ctx context.Context, dataErr *FullSyncMeta) (int64, error) {
 	var countsErr int64
 	table, err := rw.ParseSchemaTable()
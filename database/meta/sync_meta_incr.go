@@ -153,3 +153,23 @@ func (rw *IncrSyncMeta) UpdateIncrSyncMeta(ctx context.Context, detailS *IncrSyn
 	}
 	return nil
 }
+
+// 重置表 checkpoint，SCN 值为 0 同样更新
+func (rw *IncrSyncMeta) ResetIncrSyncMetaSCN(ctx context.Context, detailS *IncrSyncMeta) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	if err = rw.DB(ctx).Model(&IncrSyncMeta{}).Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ? and table_name_s = ?",
+		common.StringUPPER(detailS.DBTypeS),
+		common.StringUPPER(detailS.DBTypeT),
+		common.StringUPPER(detailS.SchemaNameS),
+		common.StringUPPER(detailS.TableNameS)).
+		Updates(map[string]interface{}{
+			"global_scn_s": detailS.GlobalScnS,
+			"table_scn_s":  detailS.TableScnS,
+		}).Error; err != nil {
+		return fmt.Errorf("reset table [%s] record scn failed: %v", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package meta
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 增量同步 redo 线程进度表，RAC 每个 redo 线程一条记录，记录最近挖掘的日志文件
+type IncrThreadMeta struct {
+	ID          uint   `gorm:"primary_key;autoIncrement;comment:'自增编号'" json:"id"`
+	DBTypeS     string `gorm:"type:varchar(30);index:idx_dbtype_st_map,unique;comment:'源数据库类型'" json:"db_type_s"`
+	DBTypeT     string `gorm:"type:varchar(30);index:idx_dbtype_st_map,unique;comment:'目标数据库类型'" json:"db_type_t"`
+	SchemaNameS string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map,unique;comment:'源端 schema'" json:"schema_name_s"`
+	ThreadS     string `gorm:"type:varchar(30);not null;index:idx_dbtype_st_map,unique;comment:'源端 redo 线程号'" json:"thread_s"`
+	SequenceS   uint64 `gorm:"comment:'源端日志序列号'" json:"sequence_s"`
+	LogFileS    string `gorm:"type:varchar(500);comment:'源端日志文件'" json:"log_file_s"`
+	FirstScnS   uint64 `gorm:"comment:'源端日志起始 SCN'" json:"first_scn_s"`
+	NextScnS    uint64 `gorm:"comment:'源端日志结束 SCN'" json:"next_scn_s"`
+	*BaseModel
+}
+
+func NewIncrThreadMetaModel(m *Meta) *IncrThreadMeta {
+	return &IncrThreadMeta{BaseModel: &BaseModel{
+		Meta: m}}
+}
+
+func (rw *IncrThreadMeta) ParseSchemaTable() (string, error) {
+	stmt := &gorm.Statement{DB: rw.GormDB}
+	err := stmt.Parse(rw)
+	if err != nil {
+		return "", fmt.Errorf("parse struct [IncrThreadMeta] get table_name failed: %v", err)
+	}
+	return stmt.Schema.Table, nil
+}
+
+func (rw *IncrThreadMeta) DetailIncrThreadMetaBySchema(ctx context.Context, detailS *IncrThreadMeta) ([]IncrThreadMeta, error) {
+	var threadMetas []IncrThreadMeta
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return threadMetas, err
+	}
+	if err = rw.DB(ctx).
+		Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ?",
+			common.StringUPPER(detailS.DBTypeS),
+			common.StringUPPER(detailS.DBTypeT),
+			common.StringUPPER(detailS.SchemaNameS),
+		).
+		Find(&threadMetas).Error; err != nil {
+		return threadMetas, fmt.Errorf("detail table [%s] record by column [schema_name_s] failed: %v", table, err)
+	}
+	return threadMetas, nil
+}
+
+func (rw *IncrThreadMeta) UpsertIncrThreadMeta(ctx context.Context, upsertS []IncrThreadMeta) error {
+	if len(upsertS) == 0 {
+		return nil
+	}
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	if err = rw.DB(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "db_type_s"},
+			{Name: "db_type_t"},
+			{Name: "schema_name_s"},
+			{Name: "thread_s"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"sequence_s", "log_file_s", "first_scn_s", "next_scn_s", "updated_at"}),
+	}).Create(upsertS).Error; err != nil {
+		return fmt.Errorf("upsert table [%s] record failed: %v", table, err)
+	}
+	return nil
+}
+
+func (rw *IncrThreadMeta) DeleteIncrThreadMetaBySchema(ctx context.Context, deleteS *IncrThreadMeta) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	if err = rw.DB(ctx).
+		Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ?",
+			common.StringUPPER(deleteS.DBTypeS),
+			common.StringUPPER(deleteS.DBTypeT),
+			common.StringUPPER(deleteS.SchemaNameS),
+		).
+		Delete(&IncrThreadMeta{}).Error; err != nil {
+		return fmt.Errorf("delete table [%s] record by column [schema_name_s] failed: %v", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package meta
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"gorm.io/gorm"
+)
+
+// 延迟创建索引/约束元数据表，reverse 开启 defer-index 时记录，全量数据导入完成后创建
+type IndexSyncMeta struct {
+	ID          uint   `gorm:"primary_key;autoIncrement;comment:'自增编号'" json:"id"`
+	DBTypeS     string `gorm:"type:varchar(30);index:idx_dbtype_st_map,unique;comment:'源数据库类型'" json:"db_type_s"`
+	DBTypeT     string `gorm:"type:varchar(30);index:idx_dbtype_st_map,unique;comment:'目标数据库类型'" json:"db_type_t"`
+	SchemaNameS string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map,unique;comment:'源端 schema'" json:"schema_name_s"`
+	TableNameS  string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map,unique;comment:'源端表名'" json:"table_name_s"`
+	IndexType   string `gorm:"type:varchar(30);not null;index:idx_dbtype_st_map,unique;comment:'索引类型 KEY/CONSTRAINT'" json:"index_type"`
+	IndexNameS  string `gorm:"type:varchar(100);not null;index:idx_dbtype_st_map,unique;comment:'索引/约束名'" json:"index_name_s"`
+	SchemaNameT string `gorm:"type:varchar(100);not null;comment:'目标 schema'" json:"schema_name_t"`
+	TableNameT  string `gorm:"type:varchar(100);not null;comment:'目标表名'" json:"table_name_t"`
+	IndexDDL    string `gorm:"type:text;comment:'索引/约束创建语句'" json:"index_ddl"`
+	TaskStatus  string `gorm:"type:varchar(30);not null;comment:'任务状态'" json:"task_status"`
+	ErrorDetail string `gorm:"type:text;comment:'错误详情'" json:"error_detail"`
+	*BaseModel
+}
+
+func NewIndexSyncMetaModel(m *Meta) *IndexSyncMeta {
+	return &IndexSyncMeta{BaseModel: &BaseModel{
+		Meta: m}}
+}
+
+func (rw *IndexSyncMeta) ParseSchemaTable() (string, error) {
+	stmt := &gorm.Statement{DB: rw.GormDB}
+	err := stmt.Parse(rw)
+	if err != nil {
+		return "", fmt.Errorf("parse struct [IndexSyncMeta] get table_name failed: %v", err)
+	}
+	return stmt.Schema.Table, nil
+}
+
+// 重新 reverse 表时覆盖表级记录
+func (rw *IndexSyncMeta) ReplaceIndexSyncMetaByTable(ctx context.Context, deleteS *IndexSyncMeta, createS []IndexSyncMeta) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	err = rw.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ? AND table_name_s = ?",
+			common.StringUPPER(deleteS.DBTypeS),
+			common.StringUPPER(deleteS.DBTypeT),
+			common.StringUPPER(deleteS.SchemaNameS),
+			common.StringUPPER(deleteS.TableNameS)).
+			Delete(&IndexSyncMeta{}).Error; err != nil {
+			return err
+		}
+		if len(createS) == 0 {
+			return nil
+		}
+		return tx.Create(createS).Error
+	})
+	if err != nil {
+		return fmt.Errorf("replace table [%s] record by transaction failed: %v", table, err)
+	}
+	return nil
+}
+
+// 未创建成功的索引/约束（WAITING/RUNNING/FAILED），用于全量完成后创建以及失败重试
+func (rw *IndexSyncMeta) DetailUnfinishedIndexSyncMeta(ctx context.Context, detailS *IndexSyncMeta) ([]IndexSyncMeta, error) {
+	var indexMetas []IndexSyncMeta
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return indexMetas, err
+	}
+	if err = rw.DB(ctx).
+		Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ? AND task_status <> ?",
+			common.StringUPPER(detailS.DBTypeS),
+			common.StringUPPER(detailS.DBTypeT),
+			common.StringUPPER(detailS.SchemaNameS),
+			common.TaskStatusSuccess).
+		Order("id").
+		Find(&indexMetas).Error; err != nil {
+		return indexMetas, fmt.Errorf("detail table [%s] record by column [schema_name_s] failed: %v", table, err)
+	}
+	return indexMetas, nil
+}
+
+func (rw *IndexSyncMeta) UpdateIndexSyncMeta(ctx context.Context, detailS *IndexSyncMeta, updates map[string]interface{}) error {
+	table, err := rw.ParseSchemaTable()
+	if err != nil {
+		return err
+	}
+	err = rw.DB(ctx).Model(&IndexSyncMeta{}).
+		Where("db_type_s = ? AND db_type_t = ? AND schema_name_s = ? AND table_name_s = ? AND index_type = ? AND index_name_s = ?",
+			common.StringUPPER(detailS.DBTypeS),
+			common.StringUPPER(detailS.DBTypeT),
+			common.StringUPPER(detailS.SchemaNameS),
+			common.StringUPPER(detailS.TableNameS),
+			detailS.IndexType,
+			detailS.IndexNameS).
+		Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("update table [%s] record failed: %v", table, err)
+	}
+	return nil
+}
@@ -277,6 +277,26 @@ func (rw *Transaction) DeleteIncrSyncMetaAndWaitSyncMeta(ctx context.Context, in
 	return nil
 }
 
+// 指定起始 SCN 增量同步，各表 [wait_sync_meta] 以及 [incr_sync_meta] 记录单事务写入，任一失败回滚，避免部分写入阻塞重跑
+func (rw *Transaction) BatchCreateWaitSyncMetaAndIncrSyncMeta(ctx context.Context, waitSyncMetas []WaitSyncMeta, incrSyncMetas []IncrSyncMeta, batchSize int) error {
+	if err := rw.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(waitSyncMetas) > 0 {
+			if err := tx.CreateInBatches(waitSyncMetas, batchSize).Error; err != nil {
+				return fmt.Errorf("batch create table [wait_sync_meta] record by transaction failed: %v", err)
+			}
+		}
+		if len(incrSyncMetas) > 0 {
+			if err := tx.CreateInBatches(incrSyncMetas, batchSize).Error; err != nil {
+				return fmt.Errorf("batch create table [incr_sync_meta] record by transaction failed: %v", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (rw *Transaction) UpdateIncrSyncMetaSCNByCurrentRedo(ctx context.Context,
 	dbTypeS, dbTypeT, sourceSchemaName string, lastRedoLogMaxSCN, logFileStartSCN, logFileEndSCN uint64) error {
 	var logFileSCN uint64
@@ -357,3 +377,90 @@ func (rw *Transaction) UpdateIncrSyncMetaSCNByArchivedLog(ctx context.Context,
 	}
 	return nil
 }
+
+func (rw *Transaction) UpsertRuleTables(ctx context.Context, schemaRules []SchemaDatatypeRule, tableRules []TableDatatypeRule,
+	columnRules []ColumnDatatypeRule, columnDefaultvals []BuildinColumnDefaultval, globalDefaultvals []BuildinGlobalDefaultval,
+	tableNameRules []TableNameRule, columnMaskRules []ColumnMaskRule, batchSize int) error {
+	txn := rw.DB(ctx).Begin()
+	for _, data := range ArrayStructGroupsOf(schemaRules, int64(batchSize)) {
+		if len(data) == 0 {
+			continue
+		}
+		if err := txn.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"column_type_t", "comment", "updated_at"}),
+		}).Create(data).Error; err != nil {
+			txn.Rollback()
+			return fmt.Errorf("upsert table [schema_datatype_rule] record by transaction failed: %v", err)
+		}
+	}
+	for _, data := range ArrayStructGroupsOf(tableRules, int64(batchSize)) {
+		if len(data) == 0 {
+			continue
+		}
+		if err := txn.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"column_type_t", "comment", "updated_at"}),
+		}).Create(data).Error; err != nil {
+			txn.Rollback()
+			return fmt.Errorf("upsert table [table_datatype_rule] record by transaction failed: %v", err)
+		}
+	}
+	for _, data := range ArrayStructGroupsOf(columnRules, int64(batchSize)) {
+		if len(data) == 0 {
+			continue
+		}
+		if err := txn.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"column_type_s", "column_type_t", "comment", "updated_at"}),
+		}).Create(data).Error; err != nil {
+			txn.Rollback()
+			return fmt.Errorf("upsert table [column_datatype_rule] record by transaction failed: %v", err)
+		}
+	}
+	for _, data := range ArrayStructGroupsOf(columnDefaultvals, int64(batchSize)) {
+		if len(data) == 0 {
+			continue
+		}
+		if err := txn.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"default_value_s", "default_value_t", "comment", "updated_at"}),
+		}).Create(data).Error; err != nil {
+			txn.Rollback()
+			return fmt.Errorf("upsert table [buildin_column_defaultval] record by transaction failed: %v", err)
+		}
+	}
+	for _, data := range ArrayStructGroupsOf(globalDefaultvals, int64(batchSize)) {
+		if len(data) == 0 {
+			continue
+		}
+		if err := txn.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"default_value_t", "comment", "updated_at"}),
+		}).Create(data).Error; err != nil {
+			txn.Rollback()
+			return fmt.Errorf("upsert table [buildin_global_defaultval] record by transaction failed: %v", err)
+		}
+	}
+	for _, data := range ArrayStructGroupsOf(tableNameRules, int64(batchSize)) {
+		if len(data) == 0 {
+			continue
+		}
+		if err := txn.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"schema_name_t", "table_name_t", "comment", "updated_at"}),
+		}).Create(data).Error; err != nil {
+			txn.Rollback()
+			return fmt.Errorf("upsert table [table_name_rule] record by transaction failed: %v", err)
+		}
+	}
+	for _, data := range ArrayStructGroupsOf(columnMaskRules, int64(batchSize)) {
+		if len(data) == 0 {
+			continue
+		}
+		if err := txn.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"mask_type", "mask_value", "comment", "updated_at"}),
+		}).Create(data).Error; err != nil {
+			txn.Rollback()
+			return fmt.Errorf("upsert table [column_mask_rule] record by transaction failed: %v", err)
+		}
+	}
+	if err := txn.Commit().Error; err != nil {
+		return fmt.Errorf("upsert rule tables commit transaction failed: %v", err)
+	}
+	return nil
+}
@@ -209,3 +209,31 @@ func (m *MySQL) GetMySQLDataRowStrings(querySQL string) ([]string, *strset.Set,
 
 	return cols, stringSet, crc32SUM, err
 }
+
+// 数据校验修复语句单事务执行，任一语句失败回滚，返回影响行数
+// 提交前以实际影响行数调用 reserve 预占表修复行数，预占失败回滚并返回 common.ErrRepairRowsExceeded
+func (m *MySQL) ExecRepairSQL(repairSQL []string, reserve func(affected int64) bool) (int64, error) {
+	var affected int64
+	txn, err := m.MySQLDB.BeginTx(m.Ctx, &sql.TxOptions{})
+	if err != nil {
+		return affected, fmt.Errorf("repair sql transaction start failed: %v", err)
+	}
+	for _, s := range repairSQL {
+		res, err := txn.ExecContext(m.Ctx, s)
+		if err != nil {
+			_ = txn.Rollback()
+			return 0, fmt.Errorf("repair sql [%v] exec failed: %v", s, err)
+		}
+		if rows, err := res.RowsAffected(); err == nil {
+			affected += rows
+		}
+	}
+	if reserve != nil && !reserve(affected) {
+		_ = txn.Rollback()
+		return affected, common.ErrRepairRowsExceeded
+	}
+	if err = txn.Commit(); err != nil {
+		return 0, fmt.Errorf("repair sql transaction commit failed: %v", err)
+	}
+	return affected, nil
+}
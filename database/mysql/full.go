@@ -16,7 +16,11 @@ limitations under the License.
 package mysql
 
 import (
+	"errors"
 	"fmt"
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/wentaojin/transferdb/common"
+	"strings"
 )
 
 func (m *MySQL) TruncateMySQLTable(targetSchema string, targetTable string) error {
@@ -34,3 +38,125 @@ func (m *MySQL) WriteMySQLTable(sql string, args ...any) error {
 	}
 	return nil
 }
+
+// 是否主键/唯一键冲突错误
+func IsMySQLDuplicateKeyError(err error) bool {
+	var me *driver.MySQLError
+	if errors.As(err, &me) {
+		return me.Number == 1062
+	}
+	return false
+}
+
+// 是否行数据错误，用于批次写入失败二分定位坏行，连接、锁等非数据错误不定位
+func IsMySQLDataError(err error) bool {
+	var me *driver.MySQLError
+	if errors.As(err, &me) {
+		switch me.Number {
+		// 1048 字段不允许 NULL，1062 主键/唯一键冲突，1264 数值越界，1265 数据截断，1292 时间值非法
+		// 1366 字符串值非法，1406 数据超长，1452 外键约束，3819 检查约束
+		case 1048, 1062, 1264, 1265, 1292, 1366, 1406, 1452, 3819:
+			return true
+		}
+	}
+	return false
+}
+
+// 是否索引/约束已存在错误（重复创建）
+func IsMySQLDuplicateObjectError(err error) bool {
+	var me *driver.MySQLError
+	if errors.As(err, &me) {
+		// 1061 索引名重复，1826 外键名重复，3822 检查约束名重复
+		return me.Number == 1061 || me.Number == 1826 || me.Number == 3822
+	}
+	return false
+}
+
+// TiDB 开启快速加索引（ingest 方式），返回开启前原值用于恢复，低版本不支持报错
+func (m *MySQL) EnableTiDBFastReorg() (string, error) {
+	_, res, err := Query(m.Ctx, m.MySQLDB, `SHOW GLOBAL VARIABLES LIKE 'tidb_ddl_enable_fast_reorg'`)
+	if err != nil {
+		return "", fmt.Errorf("get tidb global variable [tidb_ddl_enable_fast_reorg] failed: %v", err)
+	}
+	if len(res) == 0 {
+		return "", fmt.Errorf("tidb global variable [tidb_ddl_enable_fast_reorg] isn't exist, tidb version isn't support")
+	}
+	origin := res[0]["VALUE"]
+	if strings.EqualFold(origin, "ON") || origin == "1" {
+		return origin, nil
+	}
+	if _, err = m.MySQLDB.ExecContext(m.Ctx, "SET GLOBAL tidb_ddl_enable_fast_reorg = ON"); err != nil {
+		return "", fmt.Errorf("set tidb global variable [tidb_ddl_enable_fast_reorg] failed: %v", err)
+	}
+	return origin, nil
+}
+
+// TiDB 恢复快速加索引原值，原值已开启无需恢复
+func (m *MySQL) RestoreTiDBFastReorg(origin string) error {
+	if strings.EqualFold(origin, "ON") || origin == "1" {
+		return nil
+	}
+	if _, err := m.MySQLDB.ExecContext(m.Ctx, common.StringsBuilder("SET GLOBAL tidb_ddl_enable_fast_reorg = ", origin)); err != nil {
+		return fmt.Errorf("restore tidb global variable [tidb_ddl_enable_fast_reorg] value [%s] failed: %v", origin, err)
+	}
+	return nil
+}
+
+// 表是否不存在主键以及唯一键，忽略 ROWID 代理字段唯一索引
+func (m *MySQL) IsMySQLTableKeyless(schemaName, tableName string) (bool, error) {
+	pks, err := m.GetMySQLTablePrimaryKey(schemaName, tableName)
+	if err != nil {
+		return false, err
+	}
+	if len(pks) > 0 {
+		return false, nil
+	}
+	uks, err := m.GetMySQLTableUniqueKey(schemaName, tableName)
+	if err != nil {
+		return false, err
+	}
+	return len(common.FilterMySQLKeylessRowidIndex(uks)) == 0, nil
+}
+
+func (m *MySQL) IsExistMySQLTableColumn(schemaName, tableName, columnName string) (bool, error) {
+	_, res, err := Query(m.Ctx, m.MySQLDB, fmt.Sprintf(`SELECT COUNT(1) AS CT
+ FROM information_schema.COLUMNS
+ WHERE UPPER(TABLE_SCHEMA) = UPPER('%s')
+   AND UPPER(TABLE_NAME) = UPPER('%s')
+   AND UPPER(COLUMN_NAME) = UPPER('%s')`, schemaName, tableName, columnName))
+	if err != nil {
+		return false, err
+	}
+	if res[0]["CT"] == "0" {
+		return false, nil
+	}
+	return true, nil
+}
+
+// 无主键/唯一键表增加 ROWID 代理字段以及唯一索引，字段已存在忽略
+func (m *MySQL) AddMySQLTableKeylessRowidColumn(schemaName, tableName string) error {
+	isExist, err := m.IsExistMySQLTableColumn(schemaName, tableName, common.MigrateKeylessRowidColumn)
+	if err != nil {
+		return err
+	}
+	if isExist {
+		return nil
+	}
+	_, err = m.MySQLDB.ExecContext(m.Ctx, common.GenMySQLKeylessRowidColumnDDL(schemaName, tableName))
+	if err != nil {
+		return fmt.Errorf("add table [%s.%s] keyless rowid column failed: %v", schemaName, tableName, err)
+	}
+	return nil
+}
+
+// 清理无主键/唯一键表 chunk 数据，chunk 非 ROWID 范围返回错误
+func (m *MySQL) DeleteMySQLTableKeylessChunk(schemaName, tableName, chunk string) error {
+	deleteSQL, err := common.GenMySQLKeylessChunkDeleteSQL(schemaName, tableName, chunk)
+	if err != nil {
+		return err
+	}
+	if _, err := m.MySQLDB.ExecContext(m.Ctx, deleteSQL); err != nil {
+		return fmt.Errorf("delete table [%s.%s] keyless chunk [%s] failed: %v", schemaName, tableName, chunk, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package mysql
+
+// 当前用户，格式 user@host
+func (m *MySQL) GetMySQLCurrentUser() (string, error) {
+	_, res, err := Query(m.Ctx, m.MySQLDB, `SELECT CURRENT_USER() AS CURRENT_USER_NAME`)
+	if err != nil {
+		return "", err
+	}
+	return res[0]["CURRENT_USER_NAME"], nil
+}
+
+// 当前用户授权语句
+func (m *MySQL) GetMySQLCurrentUserGrants() ([]string, error) {
+	cols, res, err := Query(m.Ctx, m.MySQLDB, `SHOW GRANTS FOR CURRENT_USER()`)
+	if err != nil {
+		return nil, err
+	}
+	var grants []string
+	for _, r := range res {
+		grants = append(grants, r[cols[0]])
+	}
+	return grants, nil
+}
+
+func (m *MySQL) GetMySQLSessionSQLModeAndLowerCaseTableNames() (string, string, error) {
+	_, res, err := Query(m.Ctx, m.MySQLDB, `SELECT @@SESSION.sql_mode AS SQL_MODE, @@lower_case_table_names AS LOWER_CASE_TABLE_NAMES`)
+	if err != nil {
+		return "", "", err
+	}
+	return res[0]["SQL_MODE"], res[0]["LOWER_CASE_TABLE_NAMES"], nil
+}
@@ -21,143 +21,113 @@ import (
 	"github.com/wentaojin/transferdb/common"
 )
 
-func (o *Oracle) GetOracleRedoLogSCN(scn string) (uint64, error) {
-	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`select  FIRST_CHANGE# AS SCN
-  from (SELECT  GROUP#,
-               first_change#,
-               MEMBERS,
-               FIRST_TIME
-          FROM v$LOG
-         WHERE FIRST_CHANGE# <= `, scn, ` order by FIRST_CHANGE# desc)
- where rownum = 1`))
-	var globalSCN uint64
+// 时间点转换 SCN，时间格式 YYYY-MM-DD HH24:MI:SS，超出 UNDO/闪回保留范围报错
+func (o *Oracle) GetOracleTimestampToSCN(timestamp string) (uint64, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`SELECT TIMESTAMP_TO_SCN(TO_TIMESTAMP('`, timestamp, `','YYYY-MM-DD HH24:MI:SS')) AS SCN FROM DUAL`))
 	if err != nil {
-		return globalSCN, err
+		return 0, err
 	}
-	if len(res) > 0 {
-		globalSCN, err = common.StrconvUintBitSize(res[0]["SCN"], 64)
-		if err != nil {
-			return globalSCN, fmt.Errorf("get oracle redo log scn %s utils.StrconvUintBitSize failed: %v", res[0]["SCN"], err)
-		}
+	scn, err := common.StrconvUintBitSize(res[0]["SCN"], 64)
+	if err != nil {
+		return scn, fmt.Errorf("get oracle timestamp [%s] scn %s utils.StrconvUintBitSize failed: %v", timestamp, res[0]["SCN"], err)
 	}
-	return globalSCN, nil
+	return scn, nil
 }
 
-func (o *Oracle) GetOracleArchivedLogSCN(scn string) (uint64, error) {
-	var globalSCN uint64
-	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`select FIRST_CHANGE# AS SCN
-  from (select FIRST_CHANGE#,FIRST_TIME
-          from v$archived_log
-         where STATUS = 'A'
-           and DELETED='NO'
-           and FIRST_CHANGE# <= `, scn, ` order by FIRST_CHANGE# desc)
- where rownum = 1`))
+// 获取 redo 线程，RAC 每个实例一个 redo 线程，DISABLED 线程不产生日志
+func (o *Oracle) GetOracleRedoThread() ([]string, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, `SELECT THREAD# AS THREAD FROM v$THREAD WHERE ENABLED <> 'DISABLED' ORDER BY THREAD# ASC`)
 	if err != nil {
-		return globalSCN, err
+		return []string{}, err
 	}
-	if len(res) > 0 {
-		globalSCN, err = common.StrconvUintBitSize(res[0]["SCN"], 64)
-		if err != nil {
-			return globalSCN, fmt.Errorf("get oracle archive log scn %s utils.StrconvUintBitSize failed: %v", res[0]["SCN"], err)
-		}
+	if len(res) == 0 {
+		return []string{}, fmt.Errorf("oracle redo thread can't null")
 	}
-	return globalSCN, nil
+	var threads []string
+	for _, r := range res {
+		threads = append(threads, r["THREAD"])
+	}
+	return threads, nil
 }
 
+// 获取所有 redo 线程包含 SCN 之后变更的在线重做日志，多成员日志组只取一个成员
+// 当前重做日志 NEXT_CHANGE# 可能为空或者最大值，统一以 STATUS 判断
 func (o *Oracle) GetOracleRedoLogFile(scn string) ([]map[string]string, error) {
-	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`SELECT 
-       --l.GROUP# GROUP_NUMBER,
+	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`SELECT l.THREAD# AS THREAD,
+       l.SEQUENCE# AS SEQUENCE,
        l.FIRST_CHANGE# AS FIRST_CHANGE,
-       --l.BYTES / 1024 / 1024 AS LOG_SIZE,
-       L.NEXT_CHANGE# AS NEXT_CHANGE,
-       lf.MEMBER LOG_FILE
+       l.NEXT_CHANGE# AS NEXT_CHANGE,
+       l.STATUS AS STATUS,
+       MIN(lf.MEMBER) AS LOG_FILE
   FROM v$LOGFILE lf, v$LOG l
  WHERE l.GROUP# = lf.GROUP#
-   AND l.FIRST_CHANGE# >= `, scn, ` ORDER BY l.FIRST_CHANGE# ASC`))
+   AND l.STATUS IN ('CURRENT', 'ACTIVE', 'INACTIVE')
+   AND (l.NEXT_CHANGE# > `, scn, ` OR l.STATUS = 'CURRENT')
+ GROUP BY l.THREAD#, l.SEQUENCE#, l.FIRST_CHANGE#, l.NEXT_CHANGE#, l.STATUS
+ ORDER BY l.FIRST_CHANGE# ASC, l.THREAD# ASC`))
 	if err != nil {
 		return []map[string]string{}, err
 	}
 	return res, nil
 }
 
+// 获取所有 redo 线程包含 SCN 之后变更的归档日志，多归档路径同一序列号只取一个，只取当前 incarnation
 func (o *Oracle) GetOracleArchivedLogFile(scn string) ([]map[string]string, error) {
-	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`SELECT NAME AS LOG_FILE,
-       NEXT_CHANGE# AS NEXT_CHANGE,
-       --BLOCKS * BLOCK_SIZE / 1024 / 1024 AS LOG_SIZE,
-       FIRST_CHANGE# AS FIRST_CHANGE
-  FROM v$ARCHIVED_LOG
- WHERE STATUS = 'A'
-   AND DELETED = 'NO'
-   AND FIRST_CHANGE# >= `, scn, ` ORDER BY FIRST_CHANGE# ASC`))
+	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`SELECT THREAD,
+       SEQUENCE,
+       FIRST_CHANGE,
+       NEXT_CHANGE,
+       'ARCHIVED' AS STATUS,
+       LOG_FILE
+  FROM (SELECT THREAD# AS THREAD,
+               SEQUENCE# AS SEQUENCE,
+               FIRST_CHANGE# AS FIRST_CHANGE,
+               NEXT_CHANGE# AS NEXT_CHANGE,
+               NAME AS LOG_FILE,
+               ROW_NUMBER() OVER (PARTITION BY THREAD#, SEQUENCE# ORDER BY DEST_ID) AS RN
+          FROM v$ARCHIVED_LOG
+         WHERE STATUS = 'A'
+           AND DELETED = 'NO'
+           AND NAME IS NOT NULL
+           AND STANDBY_DEST = 'NO'
+           AND RESETLOGS_CHANGE# = (SELECT RESETLOGS_CHANGE# FROM v$DATABASE)
+           AND NEXT_CHANGE# > `, scn, `)
+ WHERE RN = 1
+ ORDER BY FIRST_CHANGE ASC, THREAD ASC`))
 	if err != nil {
 		return []map[string]string{}, err
 	}
 	return res, nil
 }
 
-func (o *Oracle) GetOracleCurrentRedoMaxSCN() (uint64, uint64, string, error) {
-	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`SELECT
-       l.FIRST_CHANGE# AS FIRST_CHANGE,
-       l.NEXT_CHANGE# AS NEXT_CHANGE,
-       lf.MEMBER LOG_FILE
-  FROM v$LOGFILE lf, v$LOG l
- WHERE l.GROUP# = lf.GROUP#
- AND l.STATUS='CURRENT'`))
-	if err != nil {
-		return 0, 0, "", err
-	}
-	if len(res) == 0 {
-		return 0, 0, "", fmt.Errorf("oracle current redo log can't null")
-	}
-	firstSCN, err := common.StrconvUintBitSize(res[0]["FIRST_CHANGE"], 64)
-	if err != nil {
-		return firstSCN, 0, res[0]["LOG_FILE"], fmt.Errorf("get oracle current redo first_change scn %s utils.StrconvUintBitSize falied: %v", res[0]["FIRST_CHANGE"], err)
-	}
-	maxSCN, err := common.StrconvUintBitSize(res[0]["NEXT_CHANGE"], 64)
-	if err != nil {
-		return firstSCN, maxSCN, res[0]["LOG_FILE"], fmt.Errorf("get oracle current redo next_change scn %s utils.StrconvUintBitSize falied: %v", res[0]["NEXT_CHANGE"], err)
-	}
-	if maxSCN == 0 || firstSCN == 0 {
-		return firstSCN, maxSCN, res[0]["LOG_FILE"], fmt.Errorf("GetOracleCurrentRedoMaxSCN value is euqal to 0, does't meet expectations")
-	}
-	return firstSCN, maxSCN, res[0]["LOG_FILE"], nil
-}
-
-func (o *Oracle) GetOracleALLRedoLogFile() ([]string, error) {
-	_, res, err := Query(o.Ctx, o.OracleDB, common.StringsBuilder(`SELECT
-       lf.MEMBER LOG_FILE
-  FROM v$LOGFILE lf`))
-	if err != nil {
-		return []string{}, err
-	}
-	if len(res) == 0 {
-		return []string{}, fmt.Errorf("oracle all redo log can't null")
-	}
-
-	var logs []string
-	for _, r := range res {
-		logs = append(logs, r["LOG_FILE"])
-	}
-	return logs, nil
-}
-
-func (o *Oracle) AddOracleLogminerlogFile(logFile string) error {
-	ctx, _ := context.WithCancel(context.Background())
-	sql := common.StringsBuilder(`BEGIN
+// 同一 logminer 会话添加多个日志文件，首个日志文件新建会话，其余追加
+func (o *Oracle) AddOracleLogminerlogFiles(logFiles []string) error {
+	for i, logFile := range logFiles {
+		option := `dbms_logmnr.ADDFILE`
+		if i == 0 {
+			option = `dbms_logmnr.NEW`
+		}
+		sql := common.StringsBuilder(`BEGIN
   dbms_logmnr.add_logfile(logfilename => '`, logFile, `',
-                          options     => dbms_logmnr.NEW);
+                          options     => `, option, `);
 END;`)
-	_, err := o.OracleDB.ExecContext(ctx, sql)
-	if err != nil {
-		return fmt.Errorf("oracle logminer sql [%v] add log file [%s] failed: %v", sql, logFile, err)
+		if _, err := o.OracleDB.ExecContext(o.Ctx, sql); err != nil {
+			return fmt.Errorf("oracle logminer sql [%v] add log file [%s] failed: %v", sql, logFile, err)
+		}
 	}
 	return nil
 }
 
-func (o *Oracle) StartOracleLogminerStoredProcedure(scn string) error {
+// endSCN 为空表示挖掘至已添加日志文件末尾
+func (o *Oracle) StartOracleLogminerStoredProcedure(scn, endSCN string) error {
 	ctx, _ := context.WithCancel(context.Background())
+	var endOption string
+	if endSCN != "" {
+		endOption = common.StringsBuilder(`
+                           endSCN   => `, endSCN, `,`)
+	}
 	sql := common.StringsBuilder(`BEGIN
-  dbms_logmnr.start_logmnr(startSCN => `, scn, `,
+  dbms_logmnr.start_logmnr(startSCN => `, scn, `,`, endOption, `
                            options  => SYS.DBMS_LOGMNR.SKIP_CORRUPTION +       -- 日志遇到坏块，不报错退出，直接跳过
                                        SYS.DBMS_LOGMNR.NO_SQL_DELIMITER +
                                        SYS.DBMS_LOGMNR.NO_ROWID_IN_STMT +
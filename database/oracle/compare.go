@@ -131,7 +131,70 @@ FROM user_parallel_execute_chunks WHERE  task_name = '`, taskName, `' ORDER BY c
 	return res, nil
 }
 
+// 采样 NTILE 分桶获取 chunk 边界值，返回第 2~N 个分桶按字段顺序最小值
+// sampleBlock 按数据块采样，读取数据块更少，用于抽样校验
+func (o *Oracle) GetOracleTableChunksBySample(schemaName, tableName string, selectColumns, orderColumns []string, samplePercent float64, chunkNums int, sampleBlock bool) ([]map[string]string, error) {
+	var notNullConds []string
+	for _, c := range orderColumns {
+		notNullConds = append(notNullConds, common.StringsBuilder(c, " IS NOT NULL"))
+	}
+	orderCols := strings.Join(orderColumns, ",")
+
+	sampleSQL := ""
+	if samplePercent > 0 && samplePercent < 100 {
+		sampleSQL = fmt.Sprintf(" SAMPLE (%s)", strconv.FormatFloat(samplePercent, 'f', 6, 64))
+		if sampleBlock {
+			sampleSQL = fmt.Sprintf(" SAMPLE BLOCK (%s)", strconv.FormatFloat(samplePercent, 'f', 6, 64))
+		}
+	}
+
+	querySQL := common.StringsBuilder(`SELECT `, strings.Join(selectColumns, ","), ` FROM (
+	SELECT `, orderCols, `, NT, ROW_NUMBER() OVER (PARTITION BY NT ORDER BY `, orderCols, `) RN FROM (
+		SELECT `, orderCols, `, NTILE(`, strconv.Itoa(chunkNums), `) OVER (ORDER BY `, orderCols, `) NT
+		FROM `, schemaName, `.`, tableName, sampleSQL, ` WHERE `, strings.Join(notNullConds, " AND "), `))
+WHERE RN = 1 AND NT > 1 ORDER BY NT`)
+
+	_, res, err := Query(o.Ctx, o.OracleDB, querySQL)
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// 增量校验获取变更行键值，源端 AS OF SCN 保证与对比查询一致
+// ORA_ROWSCN 未开启 ROWDEPENDENCIES 时为数据块级别 SCN，结果可能包含同数据块未变更行
+func (o *Oracle) GetOracleTableChangedKeys(schemaName, tableName string, selectColumns, keyColumns []string, changedCond string, asOfSCN uint64) ([]map[string]string, error) {
+	var notNullConds []string
+	for _, c := range keyColumns {
+		notNullConds = append(notNullConds, common.StringsBuilder(c, " IS NOT NULL"))
+	}
+	querySQL := common.StringsBuilder(`SELECT `, strings.Join(selectColumns, ","), ` FROM `, schemaName, `.`, tableName,
+		` AS OF SCN `, strconv.FormatUint(asOfSCN, 10), ` WHERE `, changedCond, ` AND `, strings.Join(notNullConds, " AND "),
+		` ORDER BY `, strings.Join(keyColumns, ","))
+
+	_, res, err := Query(o.Ctx, o.OracleDB, querySQL)
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// 修复语句生成按非 LOB 字段值定位源端行，获取 LOB 字段原始值
+func (o *Oracle) GetOracleTableRowLobs(querySQL string) ([]map[string]string, error) {
+	release := o.Throttle.Acquire()
+	defer release()
+
+	_, res, err := Query(o.Ctx, o.OracleDB, querySQL)
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
 func (o *Oracle) GetOracleTableActualRows(oraQuery string) (int64, error) {
+	release := o.Throttle.Acquire()
+	defer release()
+
 	_, res, err := Query(o.Ctx, o.OracleDB, oraQuery)
 	if err != nil {
 		return 0, err
@@ -156,6 +219,10 @@ func (o *Oracle) GetOracleDataRowStrings(querySQL string) ([]string, *strset.Set
 
 	stringSet := set.NewStringSet()
 
+	// 源端限流
+	release := o.Throttle.Acquire()
+	defer release()
+
 	rows, err = o.OracleDB.Query(querySQL)
 	if err != nil {
 		return cols, stringSet, crc32Value, fmt.Errorf("general sql [%v] query failed: [%v]", querySQL, err.Error())
@@ -187,13 +254,16 @@ func (o *Oracle) GetOracleDataRowStrings(querySQL string) ([]string, *strset.Set
 		scans[i] = &rawResult[i]
 	}
 
+	var batchRows, batchBytes int
 	for rows.Next() {
 		err = rows.Scan(scans...)
 		if err != nil {
 			return cols, stringSet, crc32Value, fmt.Errorf("general sql [%v] query rows.Scan failed: [%v]", querySQL, err.Error())
 		}
 
+		batchRows++
 		for i, raw := range rawResult {
+			batchBytes += len(raw)
 			// ORACLE/MySQL 空字符串以及 NULL 统一NULL处理，忽略 MySQL 空字符串与 NULL 区别
 			if raw == nil {
 				rowsTMP = append(rowsTMP, fmt.Sprintf("%v", `NULL`))
@@ -264,11 +334,17 @@ func (o *Oracle) GetOracleDataRowStrings(querySQL string) ([]string, *strset.Set
 
 		// 数组清空
 		rowsTMP = rowsTMP[0:0]
+
+		if batchRows == common.ThrottleBatchRows {
+			o.Throttle.Wait(batchRows, batchBytes)
+			batchRows, batchBytes = 0, 0
+		}
 	}
 
 	if err = rows.Err(); err != nil {
 		return cols, stringSet, crc32Value, fmt.Errorf("general sql [%v] query rows.Next failed: [%v]", querySQL, err.Error())
 	}
+	o.Throttle.Wait(batchRows, batchBytes)
 
 	return cols, stringSet, crc32SUM, err
 }
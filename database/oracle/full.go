@@ -138,7 +138,8 @@ func (o *Oracle) GetOracleTableRowsColumnCSV(querySQL string, sourceDBCharset, t
 	return columns, nil
 }
 
-func (o *Oracle) GetOracleTableRowsDataCSV(querySQL, sourceDBCharset, targetDBCharset string, cfg *config.Config, dataChan chan [][]string, tableColumnNames []string) error {
+// rejectFunc 非空时行数据转换失败交由 rejectFunc 隔离，否则报错
+func (o *Oracle) GetOracleTableRowsDataCSV(querySQL, sourceDBCharset, targetDBCharset string, cfg *config.Config, rejectFunc func(columns []string, row []interface{}, err error) error, dataChan chan [][]string, tableColumnNames []string) error {
 
 	var (
 		err         error
@@ -148,11 +149,16 @@ func (o *Oracle) GetOracleTableRowsDataCSV(querySQL, sourceDBCharset, targetDBCh
 	// 临时数据存放
 	rowsTMP := make([][]string, 0, cfg.AppConfig.InsertBatchSize)
 	rowData := make([]string, len(tableColumnNames))
+	batchBytes := 0
 	tableColumnNameIndex := make(map[string]int)
 	for i, v := range tableColumnNames {
 		tableColumnNameIndex[v] = i
 	}
 
+	// 源端限流
+	release := o.Throttle.Acquire()
+	defer release()
+
 	rows, err := o.OracleDB.QueryContext(o.Ctx, querySQL)
 	if err != nil {
 		return err
@@ -194,92 +200,18 @@ func (o *Oracle) GetOracleTableRowsDataCSV(querySQL, sourceDBCharset, targetDBCh
 			return err
 		}
 
-		for i, raw := range rawResult {
-			// 注意 Oracle/Mysql NULL VS 空字符串区别
-			// Oracle 空字符串与 NULL 归于一类，统一 NULL 处理 （is null 可以查询 NULL 以及空字符串值，空字符串查询无法查询到空字符串值）
-			// Mysql 空字符串与 NULL 非一类，NULL 是 NULL，空字符串是空字符串（is null 只查询 NULL 值，空字符串查询只查询到空字符串值）
-			// 按照 Oracle 特性来，转换同步统一转换成 NULL 即可，但需要注意业务逻辑中空字符串得写入，需要变更
-			if raw == nil {
-				if cfg.CSVConfig.NullValue != "" {
-					rowData[tableColumnNameIndex[columnNames[i]]] = cfg.CSVConfig.NullValue
-				} else {
-					rowData[tableColumnNameIndex[columnNames[i]]] = `NULL`
-				}
-			} else if common.BytesToString(raw) == "" {
-				if cfg.CSVConfig.NullValue != "" {
-					rowData[tableColumnNameIndex[columnNames[i]]] = cfg.CSVConfig.NullValue
-				} else {
-					rowData[tableColumnNameIndex[columnNames[i]]] = `NULL`
-				}
-			} else {
-				switch columnTypes[i] {
-				case "int64":
-					r, err := common.StrconvIntBitSize(common.BytesToString(raw), 64)
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowData[tableColumnNameIndex[columnNames[i]]] = strconv.FormatInt(r, 10)
-				case "uint64":
-					r, err := common.StrconvUintBitSize(common.BytesToString(raw), 64)
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowData[tableColumnNameIndex[columnNames[i]]] = strconv.FormatUint(r, 10)
-				case "float32":
-					r, err := common.StrconvFloatBitSize(common.BytesToString(raw), 32)
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowData[tableColumnNameIndex[columnNames[i]]] = strconv.FormatFloat(r, 'f', -1, 32)
-				case "float64":
-					r, err := common.StrconvFloatBitSize(common.BytesToString(raw), 64)
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowData[tableColumnNameIndex[columnNames[i]]] = strconv.FormatFloat(r, 'f', -1, 64)
-				case "rune":
-					r, err := common.StrconvRune(common.BytesToString(raw))
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowData[tableColumnNameIndex[columnNames[i]]] = string(r)
-				case "godror.Number":
-					r, err := decimal.NewFromString(common.BytesToString(raw))
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowData[tableColumnNameIndex[columnNames[i]]] = r.String()
-				case "[]uint8":
-					// binary data -> raw、long raw、blob
-					rowData[tableColumnNameIndex[columnNames[i]]] = common.EscapeBinaryCSV(raw, cfg.CSVConfig.EscapeBackslash, cfg.CSVConfig.Delimiter, cfg.CSVConfig.Separator)
-				default:
-					var convertTargetRaw []byte
-
-					convertUtf8Raw, err := common.CharsetConvert(raw, sourceDBCharset, common.CharsetUTF8MB4)
-					if err != nil {
-						return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
-					}
-
-					// 处理字符集、特殊字符转义、字符串引用定界符
-					if cfg.CSVConfig.EscapeBackslash {
-						convertTargetRaw, err = common.CharsetConvert([]byte(common.SpecialLettersUsingMySQL(convertUtf8Raw)), common.CharsetUTF8MB4, targetDBCharset)
-						if err != nil {
-							return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
-						}
-					} else {
-						convertTargetRaw, err = common.CharsetConvert(convertUtf8Raw, common.CharsetUTF8MB4, targetDBCharset)
-						if err != nil {
-							return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
-						}
-					}
-
-					if cfg.CSVConfig.Delimiter == "" {
-						rowData[tableColumnNameIndex[columnNames[i]]] = common.BytesToString(convertTargetRaw)
-					} else {
-						rowData[tableColumnNameIndex[columnNames[i]]] = common.StringsBuilder(cfg.CSVConfig.Delimiter, common.BytesToString(convertTargetRaw), cfg.CSVConfig.Delimiter)
-					}
-				}
+		for _, raw := range rawResult {
+			batchBytes += len(raw)
+		}
+		if err = genOracleRowsDataCSV(rawResult, columnNames, columnTypes, tableColumnNameIndex, sourceDBCharset, targetDBCharset, cfg, rowData); err != nil {
+			if rejectFunc == nil {
+				return err
 			}
+			if err = rejectFunc(columnNames, genOracleRejectRow(rawResult), err); err != nil {
+				return err
+			}
+			rowData = make([]string, len(tableColumnNames))
+			continue
 		}
 
 		// 临时数组
@@ -290,6 +222,8 @@ func (o *Oracle) GetOracleTableRowsDataCSV(querySQL, sourceDBCharset, targetDBCh
 
 		// batch 批次
 		if len(rowsTMP) == cfg.AppConfig.InsertBatchSize {
+			o.Throttle.Wait(len(rowsTMP), batchBytes)
+			batchBytes = 0
 
 			dataChan <- rowsTMP
 
@@ -304,12 +238,105 @@ func (o *Oracle) GetOracleTableRowsDataCSV(querySQL, sourceDBCharset, targetDBCh
 
 	// 非 batch 批次
 	if len(rowsTMP) > 0 {
+		o.Throttle.Wait(len(rowsTMP), batchBytes)
 		dataChan <- rowsTMP
 	}
 
 	return nil
 }
 
+// 源端行数据按字段类型转换为 CSV 字段值，字符数据按 invalid-char-policy 处理非法字符后转换为目标字符集
+func genOracleRowsDataCSV(rawResult [][]byte, columnNames, columnTypes []string, tableColumnNameIndex map[string]int, sourceDBCharset, targetDBCharset string, cfg *config.Config, rowData []string) error {
+	for i, raw := range rawResult {
+		// 注意 Oracle/Mysql NULL VS 空字符串区别
+		// Oracle 空字符串与 NULL 归于一类，统一 NULL 处理 （is null 可以查询 NULL 以及空字符串值，空字符串查询无法查询到空字符串值）
+		// Mysql 空字符串与 NULL 非一类，NULL 是 NULL，空字符串是空字符串（is null 只查询 NULL 值，空字符串查询只查询到空字符串值）
+		// 按照 Oracle 特性来，转换同步统一转换成 NULL 即可，但需要注意业务逻辑中空字符串得写入，需要变更
+		if raw == nil {
+			if cfg.CSVConfig.NullValue != "" {
+				rowData[tableColumnNameIndex[columnNames[i]]] = cfg.CSVConfig.NullValue
+			} else {
+				rowData[tableColumnNameIndex[columnNames[i]]] = `NULL`
+			}
+		} else if common.BytesToString(raw) == "" {
+			if cfg.CSVConfig.NullValue != "" {
+				rowData[tableColumnNameIndex[columnNames[i]]] = cfg.CSVConfig.NullValue
+			} else {
+				rowData[tableColumnNameIndex[columnNames[i]]] = `NULL`
+			}
+		} else {
+			switch columnTypes[i] {
+			case "int64":
+				r, err := common.StrconvIntBitSize(common.BytesToString(raw), 64)
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowData[tableColumnNameIndex[columnNames[i]]] = strconv.FormatInt(r, 10)
+			case "uint64":
+				r, err := common.StrconvUintBitSize(common.BytesToString(raw), 64)
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowData[tableColumnNameIndex[columnNames[i]]] = strconv.FormatUint(r, 10)
+			case "float32":
+				r, err := common.StrconvFloatBitSize(common.BytesToString(raw), 32)
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowData[tableColumnNameIndex[columnNames[i]]] = strconv.FormatFloat(r, 'f', -1, 32)
+			case "float64":
+				r, err := common.StrconvFloatBitSize(common.BytesToString(raw), 64)
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowData[tableColumnNameIndex[columnNames[i]]] = strconv.FormatFloat(r, 'f', -1, 64)
+			case "rune":
+				r, err := common.StrconvRune(common.BytesToString(raw))
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowData[tableColumnNameIndex[columnNames[i]]] = string(r)
+			case "godror.Number":
+				r, err := decimal.NewFromString(common.BytesToString(raw))
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowData[tableColumnNameIndex[columnNames[i]]] = r.String()
+			case "[]uint8":
+				// binary data -> raw、long raw、blob
+				rowData[tableColumnNameIndex[columnNames[i]]] = common.EscapeBinaryCSV(raw, cfg.CSVConfig.EscapeBackslash, cfg.CSVConfig.Delimiter, cfg.CSVConfig.Separator)
+			default:
+				var convertTargetRaw []byte
+
+				convertUtf8Raw, err := common.CharsetDecodePolicy(raw, sourceDBCharset, cfg.RejectConfig.InvalidCharPolicy)
+				if err != nil {
+					return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
+				}
+
+				// 处理字符集、特殊字符转义、字符串引用定界符
+				if cfg.CSVConfig.EscapeBackslash {
+					convertTargetRaw, err = common.CharsetConvert([]byte(common.SpecialLettersUsingMySQL(convertUtf8Raw)), common.CharsetUTF8MB4, targetDBCharset)
+					if err != nil {
+						return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
+					}
+				} else {
+					convertTargetRaw, err = common.CharsetConvert(convertUtf8Raw, common.CharsetUTF8MB4, targetDBCharset)
+					if err != nil {
+						return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
+					}
+				}
+
+				if cfg.CSVConfig.Delimiter == "" {
+					rowData[tableColumnNameIndex[columnNames[i]]] = common.BytesToString(convertTargetRaw)
+				} else {
+					rowData[tableColumnNameIndex[columnNames[i]]] = common.StringsBuilder(cfg.CSVConfig.Delimiter, common.BytesToString(convertTargetRaw), cfg.CSVConfig.Delimiter)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // 获取表字段名以及行数据 -> 用于 FULL/ALL
 func (o *Oracle) GetOracleTableRowsColumn(querySQL string, sourceDBCharset, targetDBCharset string) ([]string, error) {
 	var (
@@ -344,21 +371,36 @@ func (o *Oracle) GetOracleTableRowsColumn(querySQL string, sourceDBCharset, targ
 	return columns, nil
 }
 
-func (o *Oracle) GetOracleTableRowsData(querySQL string, insertBatchSize, callTimeout int, sourceDBCharset, targetDBCharset string, dataChan chan []map[string]interface{}) error {
+// 源端行数据批次，RawRows 与 Rows 一一对应，记录源端原始字节（字符集转换前），只在开启坏行隔离时记录，用于写入阶段隔离行
+type RowsBatch struct {
+	Rows    []map[string]interface{}
+	RawRows [][]interface{}
+}
+
+// invalidCharPolicy 源端非法字符处理策略，rejectFunc 非空时行数据转换失败交由 rejectFunc 隔离，否则报错
+func (o *Oracle) GetOracleTableRowsData(querySQL string, insertBatchSize, callTimeout int, sourceDBCharset, targetDBCharset, invalidCharPolicy string, rejectFunc func(columns []string, row []interface{}, err error) error, dataChan chan RowsBatch) error {
 	var (
 		err  error
 		cols []string
 	)
 
 	// 临时数据存放
-	var rowsTMP []map[string]interface{}
+	var (
+		rowsTMP    []map[string]interface{}
+		rawRowsTMP [][]interface{}
+	)
 	rowsMap := make(map[string]interface{})
+	batchBytes := 0
 
 	deadline := time.Now().Add(time.Duration(callTimeout) * time.Second)
 
 	ctx, cancel := context.WithDeadline(o.Ctx, deadline)
 	defer cancel()
 
+	// 源端限流
+	release := o.Throttle.Acquire()
+	defer release()
+
 	rows, err := o.OracleDB.QueryContext(ctx, querySQL)
 	if err != nil {
 		return err
@@ -415,81 +457,38 @@ func (o *Oracle) GetOracleTableRowsData(querySQL string, insertBatchSize, callTi
 			return err
 		}
 
-		for i, raw := range rawResult {
-			if raw == nil {
-				//rowsMap[cols[i]] = `NULL` -> sql
-				rowsMap[cols[i]] = nil
-			} else if string(raw) == "" {
-				//rowsMap[cols[i]] = `NULL` -> sql
-				rowsMap[cols[i]] = nil
-			} else {
-				switch columnTypes[i] {
-				case "int64":
-					r, err := common.StrconvIntBitSize(string(raw), 64)
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowsMap[cols[i]] = fmt.Sprintf("%v", r)
-				case "uint64":
-					r, err := common.StrconvUintBitSize(string(raw), 64)
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowsMap[cols[i]] = fmt.Sprintf("%v", r)
-				case "float32":
-					r, err := common.StrconvFloatBitSize(string(raw), 32)
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowsMap[cols[i]] = fmt.Sprintf("%v", r)
-				case "float64":
-					r, err := common.StrconvFloatBitSize(string(raw), 64)
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowsMap[cols[i]] = fmt.Sprintf("%v", r)
-				case "rune":
-					r, err := common.StrconvRune(string(raw))
-					if err != nil {
-						return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
-					}
-					rowsMap[cols[i]] = fmt.Sprintf("%v", r)
-				case "godror.Number":
-					r, err := decimal.NewFromString(string(raw))
-					if err != nil {
-						return fmt.Errorf("column [%s] NewFromString strconv failed, %v", columnNames[i], err)
-					}
-					rowsMap[cols[i]] = fmt.Sprintf("%v", r)
-				case "[]uint8":
-					// binary data -> raw、long raw、blob
-					rowsMap[cols[i]] = raw
-				default:
-					// 特殊字符
-					convertUtf8Raw, err := common.CharsetConvert(raw, sourceDBCharset, common.CharsetUTF8MB4)
-					if err != nil {
-						return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
-					}
-
-					convertTargetRaw, err := common.CharsetConvert(convertUtf8Raw, common.CharsetUTF8MB4, targetDBCharset)
-					if err != nil {
-						return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
-					}
-					rowsMap[cols[i]] = fmt.Sprintf("%v", string(convertTargetRaw))
-				}
+		for _, raw := range rawResult {
+			batchBytes += len(raw)
+		}
+		if err = genOracleRowsMap(rawResult, cols, columnNames, columnTypes, sourceDBCharset, targetDBCharset, invalidCharPolicy, rowsMap); err != nil {
+			if rejectFunc == nil {
+				return err
+			}
+			if err = rejectFunc(columnNames, genOracleRejectRow(rawResult), err); err != nil {
+				return err
 			}
+			rowsMap = make(map[string]interface{})
+			continue
 		}
 
 		// 临时数组
 		rowsTMP = append(rowsTMP, rowsMap)
+		if rejectFunc != nil {
+			rawRowsTMP = append(rawRowsTMP, genOracleRejectRow(rawResult))
+		}
 		// MAP 清空
 		rowsMap = make(map[string]interface{})
 
 		// batch 批次
 		if len(rowsTMP) == insertBatchSize {
-			dataChan <- rowsTMP
+			o.Throttle.Wait(len(rowsTMP), batchBytes)
+			batchBytes = 0
+
+			dataChan <- RowsBatch{Rows: rowsTMP, RawRows: rawRowsTMP}
 
 			// 数组清空
 			rowsTMP = make([]map[string]interface{}, 0)
+			rawRowsTMP = nil
 		}
 	}
 
@@ -499,8 +498,88 @@ func (o *Oracle) GetOracleTableRowsData(querySQL string, insertBatchSize, callTi
 
 	// 非 batch 批次
 	if len(rowsTMP) > 0 {
-		dataChan <- rowsTMP
+		o.Throttle.Wait(len(rowsTMP), batchBytes)
+		dataChan <- RowsBatch{Rows: rowsTMP, RawRows: rawRowsTMP}
 	}
 
 	return nil
 }
+
+// 源端行数据按字段类型转换，字符数据按 invalidCharPolicy 处理非法字符后转换为目标端字符集
+func genOracleRowsMap(rawResult [][]byte, cols, columnNames, columnTypes []string, sourceDBCharset, targetDBCharset, invalidCharPolicy string, rowsMap map[string]interface{}) error {
+	for i, raw := range rawResult {
+		if raw == nil {
+			//rowsMap[cols[i]] = `NULL` -> sql
+			rowsMap[cols[i]] = nil
+		} else if string(raw) == "" {
+			//rowsMap[cols[i]] = `NULL` -> sql
+			rowsMap[cols[i]] = nil
+		} else {
+			switch columnTypes[i] {
+			case "int64":
+				r, err := common.StrconvIntBitSize(string(raw), 64)
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowsMap[cols[i]] = fmt.Sprintf("%v", r)
+			case "uint64":
+				r, err := common.StrconvUintBitSize(string(raw), 64)
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowsMap[cols[i]] = fmt.Sprintf("%v", r)
+			case "float32":
+				r, err := common.StrconvFloatBitSize(string(raw), 32)
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowsMap[cols[i]] = fmt.Sprintf("%v", r)
+			case "float64":
+				r, err := common.StrconvFloatBitSize(string(raw), 64)
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowsMap[cols[i]] = fmt.Sprintf("%v", r)
+			case "rune":
+				r, err := common.StrconvRune(string(raw))
+				if err != nil {
+					return fmt.Errorf("column [%s] strconv failed, %v", columnNames[i], err)
+				}
+				rowsMap[cols[i]] = fmt.Sprintf("%v", r)
+			case "godror.Number":
+				r, err := decimal.NewFromString(string(raw))
+				if err != nil {
+					return fmt.Errorf("column [%s] NewFromString strconv failed, %v", columnNames[i], err)
+				}
+				rowsMap[cols[i]] = fmt.Sprintf("%v", r)
+			case "[]uint8":
+				// binary data -> raw、long raw、blob
+				rowsMap[cols[i]] = raw
+			default:
+				// 特殊字符
+				convertUtf8Raw, err := common.CharsetDecodePolicy(raw, sourceDBCharset, invalidCharPolicy)
+				if err != nil {
+					return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
+				}
+
+				convertTargetRaw, err := common.CharsetConvert(convertUtf8Raw, common.CharsetUTF8MB4, targetDBCharset)
+				if err != nil {
+					return fmt.Errorf("column [%s] charset convert failed, %v", columnNames[i], err)
+				}
+				rowsMap[cols[i]] = fmt.Sprintf("%v", string(convertTargetRaw))
+			}
+		}
+	}
+	return nil
+}
+
+// 隔离行原始数据，NULL 保持 nil
+func genOracleRejectRow(rawResult [][]byte) []interface{} {
+	row := make([]interface{}, len(rawResult))
+	for i, raw := range rawResult {
+		if raw != nil {
+			row[i] = raw
+		}
+	}
+	return row
+}
@@ -31,6 +31,7 @@ import (
 type Oracle struct {
 	Ctx      context.Context
 	OracleDB *sql.DB
+	Throttle *Throttle
 }
 
 // 创建 oracle 数据库引擎
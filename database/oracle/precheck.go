@@ -0,0 +1,124 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oracle
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (o *Oracle) GetOracleCurrentUser() (string, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, `SELECT USER AS USERNAME FROM DUAL`)
+	if err != nil {
+		return "", err
+	}
+	return res[0]["USERNAME"], nil
+}
+
+// 当前会话生效角色（含角色嵌套授予）
+func (o *Oracle) GetOracleSessionRoles() ([]string, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, `SELECT ROLE FROM SESSION_ROLES`)
+	if err != nil {
+		return nil, err
+	}
+	var roles []string
+	for _, r := range res {
+		roles = append(roles, strings.ToUpper(r["ROLE"]))
+	}
+	return roles, nil
+}
+
+// 当前会话生效系统权限
+func (o *Oracle) GetOracleSessionPrivs() ([]string, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, `SELECT PRIVILEGE FROM SESSION_PRIVS`)
+	if err != nil {
+		return nil, err
+	}
+	var privs []string
+	for _, r := range res {
+		privs = append(privs, strings.ToUpper(r["PRIVILEGE"]))
+	}
+	return privs, nil
+}
+
+// 视图/表可访问性探测，无权限返回错误
+func (o *Oracle) IsOracleObjectAccessible(objectName string) error {
+	_, _, err := Query(o.Ctx, o.OracleDB, fmt.Sprintf(`SELECT COUNT(1) AS COUNTS FROM %s WHERE ROWNUM = 1`, objectName))
+	return err
+}
+
+// 对象权限探测（包执行、视图查询），ALL_OBJECTS 只展示当前用户有权限访问的对象
+func (o *Oracle) IsOracleObjectVisible(owner, objectName string) (bool, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, fmt.Sprintf(`SELECT COUNT(1) AS COUNTS FROM ALL_OBJECTS WHERE OWNER = '%s' AND OBJECT_NAME = '%s'`,
+		strings.ToUpper(owner), strings.ToUpper(objectName)))
+	if err != nil {
+		return false, err
+	}
+	return res[0]["COUNTS"] != "0", nil
+}
+
+// 归档模式以及库级别附加日志
+func (o *Oracle) GetOracleDatabaseLogMode() (map[string]string, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, `SELECT LOG_MODE,
+       SUPPLEMENTAL_LOG_DATA_MIN,
+       SUPPLEMENTAL_LOG_DATA_PK,
+       SUPPLEMENTAL_LOG_DATA_UI,
+       SUPPLEMENTAL_LOG_DATA_ALL
+  FROM V$DATABASE`)
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// 开启表级别 (ALL) COLUMNS 附加日志的表
+func (o *Oracle) GetOracleSchemaAllColumnLogGroupTable(schemaName string) ([]string, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, fmt.Sprintf(`SELECT DISTINCT TABLE_NAME FROM DBA_LOG_GROUPS WHERE OWNER = '%s' AND LOG_GROUP_TYPE = 'ALL COLUMN LOGGING'`,
+		strings.ToUpper(schemaName)))
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for _, r := range res {
+		tables = append(tables, r["TABLE_NAME"])
+	}
+	return tables, nil
+}
+
+// 不存在主键、唯一约束以及唯一索引的表
+func (o *Oracle) GetOracleSchemaTableWithoutPUKey(schemaName string) ([]string, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, fmt.Sprintf(`SELECT T.TABLE_NAME
+  FROM DBA_TABLES T
+ WHERE T.OWNER = '%s'
+   AND NOT EXISTS (SELECT 1
+          FROM DBA_CONSTRAINTS C
+         WHERE C.OWNER = T.OWNER
+           AND C.TABLE_NAME = T.TABLE_NAME
+           AND C.CONSTRAINT_TYPE IN ('P', 'U'))
+   AND NOT EXISTS (SELECT 1
+          FROM DBA_INDEXES I
+         WHERE I.TABLE_OWNER = T.OWNER
+           AND I.TABLE_NAME = T.TABLE_NAME
+           AND I.UNIQUENESS = 'UNIQUE')`, strings.ToUpper(schemaName)))
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for _, r := range res {
+		tables = append(tables, r["TABLE_NAME"])
+	}
+	return tables, nil
+}
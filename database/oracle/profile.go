@@ -0,0 +1,55 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oracle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 数据类型剖析候选字段：无精度 NUMBER、声明长度不小于 varcharMinLength 的 VARCHAR2/NVARCHAR2 以及 CLOB/NCLOB
+func (o *Oracle) GetOracleSchemaProfileColumn(schemaName string, varcharMinLength int) ([]map[string]string, error) {
+	querySQL := fmt.Sprintf(`SELECT TABLE_NAME,
+       COLUMN_NAME,
+       DATA_TYPE,
+       NVL(DATA_SCALE, -1) DATA_SCALE,
+       NVL(DATA_LENGTH, 0) DATA_LENGTH,
+       NVL(CHAR_LENGTH, 0) CHAR_LENGTH,
+       NVL(CHAR_USED, 'B') CHAR_USED
+  FROM DBA_TAB_COLUMNS
+ WHERE OWNER = '%s'
+   AND ((DATA_TYPE = 'NUMBER' AND DATA_PRECISION IS NULL)
+    OR (DATA_TYPE IN ('VARCHAR2', 'NVARCHAR2') AND DECODE(CHAR_USED, 'C', CHAR_LENGTH, DATA_LENGTH) >= %d)
+    OR DATA_TYPE IN ('CLOB', 'NCLOB'))
+ ORDER BY TABLE_NAME, COLUMN_ID`, strings.ToUpper(schemaName), varcharMinLength)
+	_, res, err := Query(o.Ctx, o.OracleDB, querySQL)
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// 单表一次扫描获取多个字段剖析统计
+func (o *Oracle) GetOracleTableProfileStats(querySQL string) (map[string]string, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, querySQL)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("oracle profile sql [%s] result isn't exist", querySQL)
+	}
+	return res[0], nil
+}
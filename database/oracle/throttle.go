@@ -0,0 +1,216 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Throttle 源端读取限流，nil 表示不限流
+// 1、rows/s、MB/s 令牌桶限速，处于全速时间窗口内不限速
+// 2、源端并发会话数限制
+// 3、Oracle 活跃会话数超过阈值自动暂停读取
+type Throttle struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	windows  [][2]int
+	sessions chan struct{}
+	rows     *bucket
+	bytes    *bucket
+	paused   atomic.Bool
+}
+
+// ctx 为任务 context，任务退出或者 Close 后停止活跃会话监控并解除暂停
+func NewThrottle(ctx context.Context, oracle *Oracle, cfg config.ThrottleConfig) (*Throttle, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+	t := &Throttle{}
+
+	for _, w := range cfg.FullSpeedWindows {
+		start, end, err := common.ParseTimeWindow(w)
+		if err != nil {
+			return nil, err
+		}
+		t.windows = append(t.windows, [2]int{start, end})
+	}
+	t.ctx, t.cancel = context.WithCancel(ctx)
+	if cfg.MaxSessions > 0 {
+		t.sessions = make(chan struct{}, cfg.MaxSessions)
+	}
+	if cfg.MaxRowsPerSecond > 0 {
+		t.rows = newBucket(float64(cfg.MaxRowsPerSecond))
+	}
+	if cfg.MaxMBPerSecond > 0 {
+		t.bytes = newBucket(float64(cfg.MaxMBPerSecond) * 1024 * 1024)
+	}
+	if cfg.ActiveSessionThreshold > 0 {
+		go t.monitor(oracle, cfg.ActiveSessionThreshold, time.Duration(cfg.CheckInterval)*time.Second)
+	}
+
+	zap.L().Info("source oracle throttle enable",
+		zap.Int("max rows per second", cfg.MaxRowsPerSecond),
+		zap.Int("max mb per second", cfg.MaxMBPerSecond),
+		zap.Int("max sessions", cfg.MaxSessions),
+		zap.Strings("full speed windows", cfg.FullSpeedWindows),
+		zap.Int("active session threshold", cfg.ActiveSessionThreshold))
+	return t, nil
+}
+
+// Close 停止活跃会话监控，任务返回时调用
+func (t *Throttle) Close() {
+	if t == nil {
+		return
+	}
+	t.cancel()
+}
+
+// Acquire 获取源端会话，暂停期间等待，返回会话释放函数
+func (t *Throttle) Acquire() func() {
+	if t == nil {
+		return func() {}
+	}
+	t.waitResume()
+	if t.sessions == nil {
+		return func() {}
+	}
+	t.sessions <- struct{}{}
+	return func() { <-t.sessions }
+}
+
+// Wait 按已读取行数以及字节数限速，暂停期间等待
+func (t *Throttle) Wait(rows, bytes int) {
+	if t == nil {
+		return
+	}
+	t.waitResume()
+	if t.isFullSpeed(time.Now()) {
+		return
+	}
+	var delay time.Duration
+	if t.rows != nil {
+		delay = t.rows.reserve(float64(rows))
+	}
+	if t.bytes != nil {
+		if d := t.bytes.reserve(float64(bytes)); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+func (t *Throttle) isFullSpeed(now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	for _, w := range t.windows {
+		if common.IsInTimeWindow(w[0], w[1], minute) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Throttle) waitResume() {
+	for t.paused.Load() {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (t *Throttle) monitor(oracle *Oracle, threshold int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		counts, err := oracle.GetOracleActiveSessionCount()
+		if err != nil {
+			// 负载查询失败不影响读取，保持当前状态
+			zap.L().Warn("source oracle active session count query failed", zap.Error(err))
+		} else {
+			switch {
+			case counts > threshold && !t.paused.Load():
+				t.paused.Store(true)
+				zap.L().Warn("source oracle active session over threshold, source read paused",
+					zap.Int("active sessions", counts),
+					zap.Int("threshold", threshold))
+			case counts <= threshold && t.paused.Load():
+				t.paused.Store(false)
+				zap.L().Info("source oracle active session below threshold, source read resumed",
+					zap.Int("active sessions", counts),
+					zap.Int("threshold", threshold))
+			}
+		}
+		select {
+		case <-t.ctx.Done():
+			t.paused.Store(false)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetOracleActiveSessionCount 获取除当前用户外的活跃用户会话数（RAC 全实例）
+func (o *Oracle) GetOracleActiveSessionCount() (int, error) {
+	_, res, err := Query(o.Ctx, o.OracleDB, `SELECT COUNT(1) AS COUNTS FROM GV$SESSION WHERE STATUS = 'ACTIVE' AND TYPE = 'USER' AND USERNAME <> SYS_CONTEXT('USERENV','SESSION_USER')`)
+	if err != nil {
+		return 0, err
+	}
+	counts, err := strconv.Atoi(res[0]["COUNTS"])
+	if err != nil {
+		return 0, fmt.Errorf("get oracle active session count [%s] strconv failed: %v", res[0]["COUNTS"], err)
+	}
+	return counts, nil
+}
+
+// bucket 令牌桶，容量为每秒速率，预占令牌后返回需等待时长
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64) *bucket {
+	return &bucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+func (b *bucket) reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
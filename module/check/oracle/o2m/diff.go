@@ -37,10 +37,11 @@ type Diff struct {
 	OracleTableINFO *public.Table `json:"oracle_table_info"`
 	MySQLTableINFO  *public.Table `json:"mysql_table_info"`
 	MySQLDBVersion  string        `json:"mysqldb_version"`
+	TimeZone        string        `json:"time_zone"`
 	MetaDB          *meta.Meta    `json:"-"`
 }
 
-func NewChecker(ctx context.Context, oracleTableInfo, mysqlTableInfo *public.Table, dbTypeS, dbTypeT, mysqlDBVersion string, metaDB *meta.Meta) *Diff {
+func NewChecker(ctx context.Context, oracleTableInfo, mysqlTableInfo *public.Table, dbTypeS, dbTypeT, mysqlDBVersion, timeZone string, metaDB *meta.Meta) *Diff {
 	return &Diff{
 		Ctx:             ctx,
 		DBTypeS:         dbTypeS,
@@ -48,6 +49,7 @@ func NewChecker(ctx context.Context, oracleTableInfo, mysqlTableInfo *public.Tab
 		OracleTableINFO: oracleTableInfo,
 		MySQLTableINFO:  mysqlTableInfo,
 		MySQLDBVersion:  mysqlDBVersion,
+		TimeZone:        timeZone,
 		MetaDB:          metaDB,
 	}
 }
@@ -261,7 +263,7 @@ func (c *Diff) CheckColumnCounts() (string, error) {
 				columnMeta string
 				err        error
 			)
-			columnMeta, err = public.GenOracleTableColumnMeta(c.Ctx, c.MetaDB, c.DBTypeS, c.DBTypeT, c.OracleTableINFO.SchemaName, c.OracleTableINFO.TableName, oracleColName, oracleColInfo)
+			columnMeta, err = public.GenOracleTableColumnMeta(c.Ctx, c.MetaDB, c.DBTypeS, c.DBTypeT, c.OracleTableINFO.SchemaName, c.OracleTableINFO.TableName, oracleColName, oracleColInfo, c.TimeZone)
 			if err != nil {
 				return columnMeta, err
 			}
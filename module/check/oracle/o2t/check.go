@@ -203,6 +203,10 @@ func (r *Check) Check() error {
 	if err != nil {
 		return err
 	}
+	// 数据库声明字符集与实际存储编码不一致，以 actual-charset 校验表结构字符集
+	if !strings.EqualFold(r.cfg.OracleConfig.ActualCharset, r.cfg.OracleConfig.Charset) {
+		oracleDBCharacterSet = common.StringsBuilder(strings.Split(oracleDBCharacterSet, ".")[0], ".", r.cfg.OracleConfig.ActualCharset)
+	}
 	if _, ok := common.MigrateTableStructureDatabaseCharsetMap[common.TaskTypeOracle2TiDB][strings.Split(oracleDBCharacterSet, ".")[1]]; !ok {
 		return fmt.Errorf("oracle db character set [%v] isn't support", oracleDBCharacterSet)
 	}
@@ -316,7 +320,7 @@ func (r *Check) Check() error {
 				return err
 			}
 			err = NewChecker(r.ctx, oracleTableInfo, mysqlTableInfo,
-				r.cfg.DBTypeS, r.cfg.DBTypeT, mysqlDBVersion, r.metaDB).Writer(f)
+				r.cfg.DBTypeS, r.cfg.DBTypeT, mysqlDBVersion, r.cfg.AppConfig.TimeZone, r.metaDB).Writer(f)
 			if err != nil {
 				// skip error and continue
 				errMeta := meta.NewCommonModel(r.metaDB).CreateErrorDetailAndUpdateWaitSyncMetaTaskStatus(r.ctx, &meta.ErrorLogDetail{
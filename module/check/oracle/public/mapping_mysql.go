@@ -27,7 +27,7 @@ import (
 /*
 Oracle 表字段映射转换 -> Check 阶段
 */
-func GenOracleTableColumnMeta(ctx context.Context, metaDB *meta.Meta, dbTypeS, dbTypeT, sourceSchema, sourceTableName, columnName string, columnINFO Column) (string, error) {
+func GenOracleTableColumnMeta(ctx context.Context, metaDB *meta.Meta, dbTypeS, dbTypeT, sourceSchema, sourceTableName, columnName string, columnINFO Column, timeZone string) (string, error) {
 	var (
 		nullable        string
 		dataDefault     string
@@ -82,7 +82,7 @@ func GenOracleTableColumnMeta(ctx context.Context, metaDB *meta.Meta, dbTypeS, d
 		return columnMeta, err
 	}
 
-	columnType, err := ChangeTableColumnType(ctx, metaDB, dbTypeS, dbTypeT, sourceSchema, sourceTableName, columnName, columnINFO)
+	columnType, err := ChangeTableColumnType(ctx, metaDB, dbTypeS, dbTypeT, sourceSchema, sourceTableName, columnName, columnINFO, timeZone)
 	if err != nil {
 		return "", err
 	}
@@ -167,7 +167,7 @@ func GenOracleTableColumnMeta(ctx context.Context, metaDB *meta.Meta, dbTypeS, d
 // 数据库查询获取自定义表结构转换规则
 // 加载数据类型转换规则【处理字段级别、表级别、库级别数据类型映射规则】
 // 数据类型转换规则判断，未设置自定义规则，默认采用内置默认字段类型转换
-func ChangeTableColumnType(ctx context.Context, metaDB *meta.Meta, dbTypeS, dbTypeT, sourceSchema, sourceTableName, columnName string, columnINFO Column) (string, error) {
+func ChangeTableColumnType(ctx context.Context, metaDB *meta.Meta, dbTypeS, dbTypeT, sourceSchema, sourceTableName, columnName string, columnINFO Column, timeZone string) (string, error) {
 	var columnType string
 	// 获取内置映射规则
 	buildinDatatypeNames, err := meta.NewBuildinDatatypeRuleModel(metaDB).BatchQueryBuildinDatatype(ctx, &meta.BuildinDatatypeRule{
@@ -194,7 +194,7 @@ func ChangeTableColumnType(ctx context.Context, metaDB *meta.Meta, dbTypeS, dbTy
 			DataDefault:       columnINFO.DataDefault,
 			Comment:           columnINFO.Comment,
 		},
-	}, buildinDatatypeNames)
+	}, buildinDatatypeNames, timeZone)
 	if err != nil {
 		return columnType, err
 	}
@@ -24,6 +24,7 @@ import (
 	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/database/mysql"
 	"github.com/wentaojin/transferdb/database/oracle"
+	"github.com/wentaojin/transferdb/module/compare/oracle/public"
 	"go.uber.org/zap"
 	"strconv"
 	"strings"
@@ -42,6 +43,7 @@ type Chunk struct {
 	TargetColumnInfo string          `json:"target_column_info"`
 	WhereColumn      string          `json:"where_column"`
 	WhereRange       string          `json:"where_range"` // chunk split need
+	OracleCollation  bool            `json:"oracle_collation"`
 	Cfg              *config.Config  `json:"-"`
 	Oracle           *oracle.Oracle  `json:"-"`
 	MySQL            *mysql.MySQL    `json:"-"`
@@ -50,7 +52,7 @@ type Chunk struct {
 
 func NewChunk(ctx context.Context, cfg *config.Config, oracle *oracle.Oracle, mysql *mysql.MySQL, metaDB *meta.Meta,
 	chunkID int, sourceGlobalSCN uint64, sourceTable, targetTable string, isPartition string, sourceColumnInfo, targetColumnInfo string,
-	whereColumn string) *Chunk {
+	whereColumn string, oracleCollation bool) *Chunk {
 	return &Chunk{
 		Ctx:              ctx,
 		ChunkID:          chunkID,
@@ -61,6 +63,7 @@ func NewChunk(ctx context.Context, cfg *config.Config, oracle *oracle.Oracle, my
 		SourceColumnInfo: sourceColumnInfo,
 		TargetColumnInfo: targetColumnInfo,
 		WhereColumn:      whereColumn,
+		OracleCollation:  oracleCollation,
 		Oracle:           oracle,
 		MySQL:            mysql,
 		MetaDB:           metaDB,
@@ -72,18 +75,10 @@ func (c *Chunk) CustomTableConfig() (customColumn string, customRange string, er
 	// 获取配置文件自定义配置
 	for _, tableCfg := range c.Cfg.SchemaConfig.CompareConfig {
 		if strings.EqualFold(c.SourceTable, tableCfg.SourceTable) {
-			// 同张表 indexFields vs Range 优先级，indexFields 需要是 number 数据类型字段
+			// 同张表 indexFields vs Range 优先级，indexFields 单个 number 数据类型字段按 NUMBER 切分，其他（字符、联合字段）采样切分
 			// 同张表如果同时存在 indexFields 以及 Range，那么 Range 优先级 > indexFields
 			if tableCfg.IndexFields != "" && tableCfg.Range == "" {
-				isNUMBER, err := c.Oracle.IsNumberColumnTYPE(c.Cfg.SchemaConfig.SourceSchema, tableCfg.SourceTable, tableCfg.IndexFields)
-				if err != nil || !isNUMBER {
-					zap.L().Warn("compare table config index filed isn't number data type",
-						zap.String("table", tableCfg.SourceTable),
-						zap.String("index filed", tableCfg.IndexFields),
-						zap.String("range", tableCfg.Range))
-					return customColumn, customRange, fmt.Errorf("config file index-filed isn't number type, error: %v", err)
-				}
-				customColumn = tableCfg.IndexFields
+				customColumn = common.StringUPPER(tableCfg.IndexFields)
 				return customColumn, customRange, nil
 			}
 
@@ -252,6 +247,18 @@ func (c *Chunk) Split() error {
 		c.WhereColumn = customColumn
 	}
 
+	// 非单个 NUMBER 字段（字符、联合字段）采样切分
+	isNUMBER := false
+	if !strings.Contains(c.WhereColumn, ",") {
+		isNUMBER, err = c.Oracle.IsNumberColumnTYPE(common.StringUPPER(c.Cfg.SchemaConfig.SourceSchema), c.SourceTable, c.WhereColumn)
+		if err != nil {
+			return err
+		}
+	}
+	if !isNUMBER {
+		return c.SplitBySample(startTime, tableRowsByStatistics)
+	}
+
 	taskName := common.StringsBuilder(common.StringUPPER(c.Cfg.SchemaConfig.SourceSchema), `_`, c.SourceTable, `_`, `TASK`, strconv.Itoa(c.ChunkID))
 
 	if err = c.Oracle.StartOracleChunkCreateTask(taskName); err != nil {
@@ -402,3 +409,229 @@ func (c *Chunk) String() string {
 	jsonByte, _ := json.Marshal(c)
 	return string(jsonByte)
 }
+
+// SplitBySample 字符、联合字段采样切分
+// SAMPLE + NTILE 获取 chunk 边界，字符字段两端统一二进制排序比较，无法切分则全表对比
+func (c *Chunk) SplitBySample(startTime time.Time, tableRowsByStatistics int) error {
+	schemaNameS := common.StringUPPER(c.Cfg.SchemaConfig.SourceSchema)
+	tableNameS := common.StringUPPER(c.SourceTable)
+
+	chunkNums := (tableRowsByStatistics + c.Cfg.DiffConfig.ChunkSize - 1) / c.Cfg.DiffConfig.ChunkSize
+	if chunkNums <= 1 {
+		return c.splitSingleChunk("table rows less than chunk size")
+	}
+
+	splitColumns, err := c.genSplitColumns()
+	if err != nil {
+		return err
+	}
+	if len(splitColumns) == 0 {
+		return c.splitSingleChunk("split column datatype or collation isn't support")
+	}
+
+	var selectColumns, orderColumns []string
+	for _, col := range splitColumns {
+		selectColumns = append(selectColumns, public.GenSplitSelectColumn(col))
+		orderColumns = append(orderColumns, col.ColumnName)
+	}
+
+	samplePercent := float64(chunkNums*common.CompareSampleRowsPerChunk) * 100 / float64(tableRowsByStatistics)
+	res, err := c.Oracle.GetOracleTableChunksBySample(schemaNameS, tableNameS, selectColumns, orderColumns, samplePercent, chunkNums, false)
+	if err != nil {
+		return err
+	}
+
+	sourceCharset := common.MigrateOracleCharsetStringConvertMapping[c.Cfg.OracleConfig.ActualCharset]
+	var boundaries [][]string
+	for _, r := range res {
+		values, err := public.ValidSplitBoundary(splitColumns, r)
+		if err != nil {
+			return fmt.Errorf("oracle table [%s.%s] sample split failed: %v", schemaNameS, tableNameS, err)
+		}
+		for i, v := range values {
+			convertRaw, err := common.CharsetConvert([]byte(v), sourceCharset, common.CharsetUTF8MB4)
+			if err != nil {
+				return fmt.Errorf("column [%s] boundary charset convert failed, %v", splitColumns[i].ColumnName, err)
+			}
+			values[i] = string(convertRaw)
+		}
+		boundaries = append(boundaries, values)
+	}
+
+	sourceRanges, targetRanges := public.GenSplitRanges(splitColumns, boundaries)
+
+	// where_range 超过元数据字段长度，联合字段退化为引导字段切分
+	if isSplitRangeOverflow(sourceRanges) && len(splitColumns) > 1 {
+		splitColumns = splitColumns[:1]
+		var leadBoundaries [][]string
+		for _, b := range boundaries {
+			if len(leadBoundaries) == 0 || leadBoundaries[len(leadBoundaries)-1][0] != b[0] {
+				leadBoundaries = append(leadBoundaries, b[:1])
+			}
+		}
+		sourceRanges, targetRanges = public.GenSplitRanges(splitColumns, leadBoundaries)
+	}
+	if isSplitRangeOverflow(sourceRanges) {
+		return c.splitSingleChunk("split where range length over meta limit")
+	}
+	if len(sourceRanges) <= 1 {
+		return c.splitSingleChunk("sample boundaries are empty")
+	}
+
+	c.WhereColumn = strings.Join(orderColumns[:len(splitColumns)], ",")
+
+	var fullMetas []meta.DataCompareMeta
+	for i := range sourceRanges {
+		fullMetas = append(fullMetas, meta.DataCompareMeta{
+			DBTypeS:       c.Cfg.DBTypeS,
+			DBTypeT:       c.Cfg.DBTypeT,
+			SchemaNameS:   schemaNameS,
+			TableNameS:    tableNameS,
+			SchemaNameT:   common.StringUPPER(c.Cfg.SchemaConfig.TargetSchema),
+			TableNameT:    common.StringUPPER(c.TargetTable),
+			ColumnDetailS: c.SourceColumnInfo,
+			ColumnDetailT: c.TargetColumnInfo,
+			WhereRange:    sourceRanges[i],
+			WhereRangeT:   targetRanges[i],
+			WhereColumn:   c.WhereColumn,
+			IsPartition:   c.IsPartition,
+			TaskMode:      c.Cfg.TaskMode,
+			TaskStatus:    common.TaskStatusWaiting})
+	}
+
+	// 元数据库信息 batch 写入
+	err = meta.NewCommonModel(c.MetaDB).BatchCreateDataCompareMetaAndUpdateWaitSyncMeta(c.Ctx,
+		fullMetas, c.Cfg.AppConfig.InsertBatchSize, &meta.WaitSyncMeta{
+			DBTypeS:          c.Cfg.DBTypeS,
+			DBTypeT:          c.Cfg.DBTypeT,
+			SchemaNameS:      schemaNameS,
+			TableNameS:       tableNameS,
+			TaskMode:         c.Cfg.TaskMode,
+			GlobalScnS:       c.SourceGlobalSCN,
+			ChunkTotalNums:   int64(len(fullMetas)),
+			ChunkSuccessNums: 0,
+			ChunkFailedNums:  0,
+			IsPartition:      c.IsPartition,
+		})
+	if err != nil {
+		return fmt.Errorf("create table [%s.%s] data_diff_meta [batch size] failed: %v", schemaNameS, tableNameS, err)
+	}
+
+	endTime := time.Now()
+	zap.L().Info("pre split oracle and mysql table chunk by sample finished",
+		zap.String("schema", schemaNameS),
+		zap.String("table", tableNameS),
+		zap.String("split column", c.WhereColumn),
+		zap.Int("chunks", len(fullMetas)),
+		zap.Float64("sample percent", samplePercent),
+		zap.String("cost", endTime.Sub(startTime).String()))
+	return nil
+}
+
+// 获取采样切分字段信息，存在不支持字段类型或者非二进制排序字符字段返回空
+func (c *Chunk) genSplitColumns() ([]public.SplitColumn, error) {
+	sourceColumns, err := c.Oracle.GetOracleSchemaTableColumn(common.StringUPPER(c.Cfg.SchemaConfig.SourceSchema), c.SourceTable, c.OracleCollation)
+	if err != nil {
+		return nil, err
+	}
+	targetColumns, err := c.MySQL.GetMySQLTableColumn(common.StringUPPER(c.Cfg.SchemaConfig.TargetSchema), c.TargetTable)
+	if err != nil {
+		return nil, err
+	}
+	nlsComp, err := c.Oracle.GetOracleDBCharacterNLSCompCollation()
+	if err != nil {
+		return nil, err
+	}
+
+	sourceColumnMap := make(map[string]map[string]string)
+	for _, col := range sourceColumns {
+		sourceColumnMap[common.StringUPPER(col["COLUMN_NAME"])] = col
+	}
+	targetColumnMap := make(map[string]map[string]string)
+	for _, col := range targetColumns {
+		targetColumnMap[common.StringUPPER(col["COLUMN_NAME"])] = col
+	}
+	sourceCharset := common.MigrateOracleCharsetStringConvertMapping[c.Cfg.OracleConfig.ActualCharset]
+
+	var splitColumns []public.SplitColumn
+	for _, column := range strings.Split(c.WhereColumn, ",") {
+		column = common.StringUPPER(strings.TrimSpace(column))
+		sourceCol, ok := sourceColumnMap[column]
+		if !ok {
+			return nil, fmt.Errorf("oracle table [%s.%s] split column [%s] isn't exist", c.Cfg.SchemaConfig.SourceSchema, c.SourceTable, column)
+		}
+		targetCol, ok := targetColumnMap[column]
+		if !ok {
+			return nil, fmt.Errorf("mysql table [%s.%s] split column [%s] isn't exist", c.Cfg.SchemaConfig.TargetSchema, c.TargetTable, column)
+		}
+
+		collation := nlsComp
+		if c.OracleCollation {
+			collation = sourceCol["COLLATION"]
+		}
+		dataType := sourceCol["DATA_TYPE"]
+		if !public.IsSplitSupportDataType(dataType) || (strings.Contains(common.StringUPPER(dataType), "CHAR") && !public.IsSplitBinaryCollation(collation)) {
+			zap.L().Warn("compare table split column isn't support sample split",
+				zap.String("schema", c.Cfg.SchemaConfig.SourceSchema),
+				zap.String("table", c.SourceTable),
+				zap.String("column", column),
+				zap.String("datatype", dataType),
+				zap.String("collation", collation))
+			return nil, nil
+		}
+		splitColumns = append(splitColumns, public.SplitColumn{
+			ColumnName: column,
+			DataType:   dataType,
+			Nullable:   strings.EqualFold(sourceCol["NULLABLE"], "Y"),
+			TargetExpr: public.GenSplitTargetExpr(column, dataType, sourceCharset, targetCol["CHARACTER_SET_NAME"], targetCol["COLLATION_NAME"]),
+		})
+	}
+	return splitColumns, nil
+}
+
+// 单 chunk 全表对比
+func (c *Chunk) splitSingleChunk(reason string) error {
+	zap.L().Warn("compare table split single chunk",
+		zap.String("schema", common.StringUPPER(c.Cfg.SchemaConfig.SourceSchema)),
+		zap.String("table", c.SourceTable),
+		zap.String("where", "1 = 1"),
+		zap.String("reason", reason))
+
+	c.WhereRange = "1 = 1"
+	c.WhereColumn = ""
+	return meta.NewCommonModel(c.MetaDB).CreateDataCompareMetaAndUpdateWaitSyncMeta(c.Ctx, &meta.DataCompareMeta{
+		DBTypeS:       c.Cfg.DBTypeS,
+		DBTypeT:       c.Cfg.DBTypeT,
+		SchemaNameS:   common.StringUPPER(c.Cfg.SchemaConfig.SourceSchema),
+		TableNameS:    common.StringUPPER(c.SourceTable),
+		ColumnDetailS: c.SourceColumnInfo,
+		SchemaNameT:   common.StringUPPER(c.Cfg.SchemaConfig.TargetSchema),
+		TableNameT:    common.StringUPPER(c.TargetTable),
+		ColumnDetailT: c.TargetColumnInfo,
+		WhereColumn:   c.WhereColumn,
+		WhereRange:    c.WhereRange,
+		TaskMode:      c.Cfg.TaskMode,
+		TaskStatus:    common.TaskStatusWaiting,
+		IsPartition:   c.IsPartition,
+	}, &meta.WaitSyncMeta{
+		DBTypeS:          c.Cfg.DBTypeS,
+		DBTypeT:          c.Cfg.DBTypeT,
+		SchemaNameS:      common.StringUPPER(c.Cfg.SchemaConfig.SourceSchema),
+		TableNameS:       common.StringUPPER(c.SourceTable),
+		TaskMode:         c.Cfg.TaskMode,
+		GlobalScnS:       c.SourceGlobalSCN,
+		ChunkTotalNums:   1,
+		ChunkSuccessNums: 0,
+		ChunkFailedNums:  0,
+		IsPartition:      c.IsPartition,
+	})
+}
+
+func isSplitRangeOverflow(ranges []string) bool {
+	for _, r := range ranges {
+		if len(r) > common.CompareWhereRangeMaxLength {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package o2m
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenDeleteConds(t *testing.T) {
+	columns := []string{"ID", "NAME", "AMOUNT", "RAW_COL", "DOC"}
+	fixColumns := []fixColumn{
+		{columnName: "ID", dataType: "NUMBER"},
+		{columnName: "NAME", dataType: "VARCHAR2"},
+		{columnName: "AMOUNT", dataType: "NUMBER"},
+		{columnName: "RAW_COL", dataType: "RAW", isHex: true},
+		{columnName: "DOC", dataType: "CLOB", isLobHash: true, isCharacter: true},
+	}
+	tests := []struct {
+		name    string
+		row     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "values", row: "1,'a',2.5,'0A',123",
+			want: []string{"ID=1", "NAME='a'", "AMOUNT=2.5", "HEX(RAW_COL)='0A'"}},
+		{name: "null values", row: "1,NULL,NULL,NULL,NULL",
+			want: []string{"ID=1", "(NAME IS NULL OR NAME = '')", "AMOUNT IS NULL", "RAW_COL IS NULL"}},
+		{name: "column counts mismatch", row: "1,'a'", wantErr: true},
+	}
+	r := &Report{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.genDeleteConds(columns, fixColumns, tt.row)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("genDeleteConds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("genDeleteConds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenLobLiteral(t *testing.T) {
+	c := fixColumn{columnName: "DOC", dataType: "CLOB", isLobHash: true, isCharacter: true}
+	tests := []struct {
+		name         string
+		value        string
+		wantLoadFile bool
+	}{
+		{name: "inline", value: "ab", wantLoadFile: false},
+		{name: "data file", value: "abcd", wantLoadFile: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Report{LobInlineSize: 3, LobDataDir: t.TempDir()}
+			got, err := r.genLobLiteral(c, []byte(tt.value))
+			if err != nil {
+				t.Fatalf("genLobLiteral() error = %v", err)
+			}
+			if strings.Contains(got, "LOAD_FILE") != tt.wantLoadFile || r.lobFile != tt.wantLoadFile {
+				t.Errorf("genLobLiteral() = %v, lobFile %v, want load file %v", got, r.lobFile, tt.wantLoadFile)
+			}
+		})
+	}
+}
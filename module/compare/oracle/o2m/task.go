@@ -26,7 +26,9 @@ import (
 	"github.com/wentaojin/transferdb/module/check"
 	"github.com/wentaojin/transferdb/module/check/oracle/o2m"
 	"github.com/wentaojin/transferdb/module/check/oracle/public"
+	comparePublic "github.com/wentaojin/transferdb/module/compare/oracle/public"
 	"go.uber.org/zap"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -37,6 +39,7 @@ type Task struct {
 	sourceTableName string
 	targetTableName string
 	oracleCollation bool
+	maskColumns     map[string]meta.ColumnMaskRule // 脱敏字段，上下游值不一致不参与校验
 	mysql           *mysql.MySQL
 	oracle          *oracle.Oracle
 }
@@ -116,7 +119,7 @@ func PreTableStructCheck(ctx context.Context, cfg *config.Config, metaDB *meta.M
 		})
 
 		if errTotals != 0 || err != nil {
-			return fmt.Errorf("compare schema [%s] mode [%s] table structure task failed: %v, please check log, error: %v", strings.ToUpper(cfg.SchemaConfig.SourceSchema), cfg.TaskMode, err)
+			return fmt.Errorf("compare schema [%s] mode [%s] table structure task failed: %v, please check log, error: %v", strings.ToUpper(cfg.SchemaConfig.SourceSchema), cfg.TaskMode, errTotals, err)
 		}
 		endTime := time.Now()
 		zap.L().Info("pre check schema oracle to mysql finished",
@@ -132,33 +135,77 @@ func PreTableStructCheck(ctx context.Context, cfg *config.Config, metaDB *meta.M
 // Date/Timestamp 字段类型格式化
 // Interval Year/Day 数据字符 TO_CHAR 格式化
 func (t *Task) AdjustDBSelectColumn() (sourceColumnInfo string, targetColumnInfo string, err error) {
-	var (
-		sourceColumnInfos, targetColumnInfos []string
-	)
 	columnInfo, err := t.oracle.GetOracleSchemaTableColumn(t.cfg.SchemaConfig.SourceSchema, t.sourceTableName, t.oracleCollation)
 	if err != nil {
 		return sourceColumnInfo, targetColumnInfo, err
 	}
+	return t.genDBSelectColumn(columnInfo)
+}
+
+// 按字段类型以及表级对比选项生成上下游对比字段
+func (t *Task) genDBSelectColumn(columnInfo []map[string]string) (sourceColumnInfo string, targetColumnInfo string, err error) {
+	var (
+		sourceColumnInfos, targetColumnInfos []string
+	)
+
+	// 表级字段对比选项，数值舍入单位配置加载已校验
+	compareCfg := t.compareConfig()
+	var numericScale int
+	if compareCfg.NumericRoundUnit > 0 {
+		numericScale, _ = common.CompareNumericRoundUnitScale(compareCfg.NumericRoundUnit)
+	}
 
 	for _, colsInfo := range columnInfo {
 		colName := colsInfo["COLUMN_NAME"]
+		if t.isSkipColumn(colName) {
+			continue
+		}
+		caseFold := common.IsContainString(compareCfg.CaseFoldColumns, common.StringUPPER(colName))
 		switch strings.ToUpper(colsInfo["DATA_TYPE"]) {
 		// 数字
-		case "NUMBER":
-			sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("DECODE(SUBSTR(", colName, ",1,1),'.','0' || ", colName, ",", colName, ") AS ", colName))
-			targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("CAST(0 + CAST(", colName, " AS CHAR) AS CHAR) AS ", colName))
-		case "DECIMAL", "DEC", "DOUBLE PRECISION", "FLOAT", "INTEGER", "INT", "REAL", "NUMERIC", "BINARY_FLOAT", "BINARY_DOUBLE", "SMALLINT":
-			sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("DECODE(SUBSTR(", colName, ",1,1),'.','0' || ", colName, ",", colName, ") AS ", colName))
-			targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("CAST(0 + CAST(", colName, " AS CHAR) AS CHAR) AS ", colName))
+		case "NUMBER", "DECIMAL", "DEC", "DOUBLE PRECISION", "FLOAT", "INTEGER", "INT", "REAL", "NUMERIC", "BINARY_FLOAT", "BINARY_DOUBLE", "SMALLINT":
+			// 数值舍入，两端按舍入单位四舍五入后对比
+			sourceCol, targetCol := colName, colName
+			if compareCfg.NumericRoundUnit > 0 {
+				sourceCol = common.StringsBuilder("ROUND(CAST(", colName, " AS NUMBER),", strconv.Itoa(numericScale), ")")
+				targetCol = common.StringsBuilder("ROUND(", colName, ",", strconv.Itoa(numericScale), ")")
+			}
+			sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("DECODE(SUBSTR(", sourceCol, ",1,1),'.','0' || ", sourceCol, ",", sourceCol, ") AS ", colName))
+			targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("CAST(0 + CAST(", targetCol, " AS CHAR) AS CHAR) AS ", colName))
 		// 字符
-		case "BFILE", "CHARACTER", "LONG", "NCHAR VARYING", "ROWID", "UROWID", "VARCHAR", "CHAR", "NCHAR", "NVARCHAR2", "NCLOB", "CLOB":
-			sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("NVL(", colName, ",'') AS ", colName))
-			targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("IFNULL(", colName, ",'') AS ", colName))
+		case "NCLOB", "CLOB":
+			// CLOB 源端以数据库字符集编码哈希，仅 AL32UTF8 与目标端 utf8mb4 一致时哈希对比，否则按字段值对比
+			if strings.EqualFold(colsInfo["DATA_TYPE"], "CLOB") && comparePublic.IsLobHashCharset(t.cfg.OracleConfig.Charset, t.cfg.OracleConfig.ActualCharset) {
+				sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder(comparePublic.GenLobHashColumnS(colName), " AS ", colName))
+				targetColumnInfos = append(targetColumnInfos, common.StringsBuilder(comparePublic.GenLobHashColumnT(colName, true), " AS ", colName))
+			} else {
+				sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("NVL(", colName, ",'') AS ", colName))
+				targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("IFNULL(", colName, ",'') AS ", colName))
+			}
+		case "BFILE", "CHARACTER", "LONG", "NCHAR VARYING", "ROWID", "UROWID", "VARCHAR", "CHAR", "NCHAR", "NVARCHAR2":
+			sourceCol, targetCol := colName, colName
+			// ORACLE CHAR 定长补齐空格，MySQL CHAR 读取去除尾部空格
+			if compareCfg.TrimChar && common.IsContainString([]string{"CHAR", "NCHAR", "CHARACTER"}, strings.ToUpper(colsInfo["DATA_TYPE"])) {
+				sourceCol = common.StringsBuilder("RTRIM(", sourceCol, ")")
+				targetCol = common.StringsBuilder("RTRIM(", targetCol, ")")
+			}
+			if caseFold {
+				sourceCol = common.StringsBuilder("UPPER(", sourceCol, ")")
+				targetCol = common.StringsBuilder("UPPER(", targetCol, ")")
+			}
+			sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("NVL(", sourceCol, ",'') AS ", colName))
+			targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("IFNULL(", targetCol, ",'') AS ", colName))
 		case "XMLTYPE":
 			sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("NVL(XMLSERIALIZE(CONTENT ", colName, " AS CLOB),'') AS ", colName))
 			targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("IFNULL(", colName, ",'') AS ", colName))
 		// 二进制
-		case "BLOB", "LONG RAW", "RAW":
+		case "BLOB":
+			sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder(comparePublic.GenLobHashColumnS(colName), " AS ", colName))
+			targetColumnInfos = append(targetColumnInfos, common.StringsBuilder(comparePublic.GenLobHashColumnT(colName, false), " AS ", colName))
+		case "RAW":
+			sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("RAWTOHEX(", colName, ") AS ", colName))
+			targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("HEX(", colName, ") AS ", colName))
+		case "LONG RAW":
 			sourceColumnInfos = append(sourceColumnInfos, colName)
 			targetColumnInfos = append(targetColumnInfos, colName)
 		// 时间
@@ -171,8 +218,19 @@ func (t *Task) AdjustDBSelectColumn() (sourceColumnInfo string, targetColumnInfo
 				sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("TO_CHAR(", colName, ") AS ", colName))
 				targetColumnInfos = append(targetColumnInfos, colName)
 			} else if strings.Contains(colsInfo["DATA_TYPE"], "TIMESTAMP") {
-				sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("TO_CHAR(", colName, ",'yyyy-MM-dd HH24:mi:ss') AS ", colName))
-				targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("FROM_UNIXTIME(UNIX_TIMESTAMP(", colName, "),'%Y-%m-%d %H:%i:%s') AS ", colName))
+				// 带时区时间字段按 [app] time-zone 规整，目标端会话 time_zone 一致
+				if compareCfg.TimestampPrecision > 0 {
+					// 小数秒截断至 timestamp-precision 位对比
+					precision := strconv.Itoa(20 + compareCfg.TimestampPrecision)
+					sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("SUBSTR(TO_CHAR(", common.GenOracleTimeZoneColumnExpr(colName, colsInfo["DATA_TYPE"], t.cfg.AppConfig.TimeZone), ",'yyyy-MM-dd HH24:mi:ss.FF9'),1,", precision, ") AS ", colName))
+					targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("SUBSTRING(DATE_FORMAT(", colName, ",'%Y-%m-%d %H:%i:%s.%f'),1,", precision, ") AS ", colName))
+				} else {
+					sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("TO_CHAR(", common.GenOracleTimeZoneColumnExpr(colName, colsInfo["DATA_TYPE"], t.cfg.AppConfig.TimeZone), ",'yyyy-MM-dd HH24:mi:ss') AS ", colName))
+					targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("FROM_UNIXTIME(UNIX_TIMESTAMP(", colName, "),'%Y-%m-%d %H:%i:%s') AS ", colName))
+				}
+			} else if caseFold && strings.Contains(colsInfo["DATA_TYPE"], "CHAR") {
+				sourceColumnInfos = append(sourceColumnInfos, common.StringsBuilder("UPPER(", colName, ") AS ", colName))
+				targetColumnInfos = append(targetColumnInfos, common.StringsBuilder("UPPER(", colName, ") AS ", colName))
 			} else {
 				sourceColumnInfos = append(sourceColumnInfos, colName)
 				targetColumnInfos = append(targetColumnInfos, colName)
@@ -180,6 +238,9 @@ func (t *Task) AdjustDBSelectColumn() (sourceColumnInfo string, targetColumnInfo
 		}
 	}
 
+	if len(sourceColumnInfos) == 0 {
+		return sourceColumnInfo, targetColumnInfo, fmt.Errorf("oracle schema [%s] table [%s] all columns are masked or excluded, it's not support, please skip", t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
+	}
 	sourceColumnInfo = strings.Join(sourceColumnInfos, ",")
 	targetColumnInfo = strings.Join(targetColumnInfos, ",")
 
@@ -190,7 +251,8 @@ func (t *Task) AdjustDBSelectColumn() (sourceColumnInfo string, targetColumnInfo
 // 第一优先级配置文件指定字段【忽略是否存在索引】
 // 第二优先级任意取某个主键/唯一索引 NUMBER 字段
 // 第三优先级取某个唯一性 DISTINCT 高的索引 NUMBER 字段
-// 如果表没有索引 NUMBER 字段或者没有 NUMBER 字段则报错
+// 第四优先级取主键/唯一键/唯一索引全部字段（字符、联合字段），chunk 采样切分
+// 如果表没有主键/唯一键/唯一索引则报错
 func (t *Task) FilterDBWhereColumn() (string, error) {
 	// 以参数配置文件 indexFiledName 忽略是否存在索引，需要人工确认
 	// 字段筛选优先级：配置文件优先级 > PK > UK > Index > Distinct Value
@@ -205,15 +267,14 @@ func (t *Task) FilterDBWhereColumn() (string, error) {
 	var integerColumns []string
 	for _, colsInfo := range columnInfo {
 		// 数字
+		if t.isSkipColumn(colsInfo["COLUMN_NAME"]) {
+			continue
+		}
 		if strings.EqualFold(strings.ToUpper(colsInfo["DATA_TYPE"]), "NUMBER") {
 			integerColumns = append(integerColumns, colsInfo["COLUMN_NAME"])
 		}
 	}
 
-	if len(integerColumns) == 0 {
-		return "", fmt.Errorf("oracle schema [%s] table [%s] number column isn't exist, not support, pelase exclude skip or add number column index", t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
-	}
-
 	// PK、UK
 	var puConstraints []public.ConstraintPUKey
 	pkInfo, err := t.oracle.GetOracleSchemaTablePrimaryKey(t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
@@ -325,12 +386,11 @@ func (t *Task) FilterDBWhereColumn() (string, error) {
 	// 普通索引、联合主键/联合唯一键/联合唯一索引，选择 number distinct 高的字段
 	indexArr = append(indexArr, nonUkIndex...)
 
-	orderCols, err := t.oracle.GetOracleTableColumnDistinctValue(t.cfg.SchemaConfig.SourceSchema, t.sourceTableName, integerColumns)
-	if err != nil {
-		return "", fmt.Errorf("get oracle schema [%s] table [%s] column distinct values failed: %v", t.cfg.SchemaConfig.SourceSchema, t.sourceTableName, err)
-	}
-
-	if len(indexArr) > 0 {
+	if len(integerColumns) > 0 && len(indexArr) > 0 {
+		orderCols, err := t.oracle.GetOracleTableColumnDistinctValue(t.cfg.SchemaConfig.SourceSchema, t.sourceTableName, integerColumns)
+		if err != nil {
+			return "", fmt.Errorf("get oracle schema [%s] table [%s] column distinct values failed: %v", t.cfg.SchemaConfig.SourceSchema, t.sourceTableName, err)
+		}
 		for _, column := range orderCols {
 			for _, index := range indexArr {
 				if strings.EqualFold(column, strings.Split(index, ",")[0]) {
@@ -339,7 +399,90 @@ func (t *Task) FilterDBWhereColumn() (string, error) {
 			}
 		}
 	}
-	return "", fmt.Errorf("oracle schema [%s] table [%s] pk/uk/index number datatype column isn't exist, please skip or fixed", t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
+
+	// 不存在 NUMBER 索引字段，取主键 > 唯一键 > 唯一索引全部字段，采样切分
+	// 脱敏字段上下游值不一致以及对比排除字段，忽略包含该类字段的约束以及索引
+	var keyColumns []string
+	for _, pu := range puConstraints {
+		keyColumns = append(keyColumns, pu.ConstraintColumn)
+	}
+	keyColumns = append(keyColumns, ukIndex...)
+	for _, key := range keyColumns {
+		if !t.isSkipColumns(key) {
+			return strings.ToUpper(key), nil
+		}
+	}
+	return "", fmt.Errorf("oracle schema [%s] table [%s] pk/uk/unique index columns are masked or excluded, please config compare index-fields", t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
+}
+
+// 增量校验键值字段，取主键 > 唯一键 > 唯一索引，忽略包含脱敏以及排除字段的键
+func (t *Task) FilterDBKeyColumn() (string, error) {
+	var keyColumns []string
+	pkInfo, err := t.oracle.GetOracleSchemaTablePrimaryKey(t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
+	if err != nil {
+		return "", err
+	}
+	for _, pk := range pkInfo {
+		keyColumns = append(keyColumns, strings.ToUpper(pk["COLUMN_LIST"]))
+	}
+	ukInfo, err := t.oracle.GetOracleSchemaTableUniqueKey(t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
+	if err != nil {
+		return "", err
+	}
+	for _, uk := range ukInfo {
+		keyColumns = append(keyColumns, strings.ToUpper(uk["COLUMN_LIST"]))
+	}
+	indexInfo, err := t.oracle.GetOracleSchemaTableUniqueIndex(t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
+	if err != nil {
+		return "", err
+	}
+	for _, idx := range indexInfo {
+		if strings.EqualFold(idx["INDEX_TYPE"], "NORMAL") && strings.EqualFold(idx["UNIQUENESS"], "UNIQUE") {
+			keyColumns = append(keyColumns, strings.ToUpper(idx["COLUMN_LIST"]))
+		}
+	}
+	for _, key := range keyColumns {
+		if !t.isSkipColumns(key) {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("oracle schema [%s] table [%s] pk/uk/unique index isn't exist or columns are masked or excluded, it's not support, please skip", t.cfg.SchemaConfig.SourceSchema, t.sourceTableName)
+}
+
+func (t *Task) isSkipColumns(columnList string) bool {
+	for _, col := range strings.Split(columnList, ",") {
+		if t.isSkipColumn(strings.TrimSpace(col)) {
+			return true
+		}
+	}
+	return false
+}
+
+// 脱敏字段以及 compare-config exclude-columns 排除字段不参与对比
+func (t *Task) isSkipColumn(colName string) bool {
+	if _, ok := t.maskColumns[common.StringUPPER(colName)]; ok {
+		return true
+	}
+	return common.IsContainString(t.compareConfig().ExcludeColumns, common.StringUPPER(colName))
+}
+
+// 表级 compare-config 配置，未配置返回空配置
+func (t *Task) compareConfig() config.CompareConfig {
+	for _, cc := range t.cfg.SchemaConfig.CompareConfig {
+		if strings.EqualFold(cc.SourceTable, t.sourceTableName) {
+			return cc
+		}
+	}
+	return config.CompareConfig{}
+}
+
+// 对比字段存在规整（脱敏、排除字段、数值舍入、忽略大小写、去除尾部空格、时间精度截断），对比值非源端原始值
+func (t *Task) isNormalized() bool {
+	return len(t.maskColumns) > 0 || isCompareNormalized(t.compareConfig())
+}
+
+func isCompareNormalized(cc config.CompareConfig) bool {
+	return len(cc.ExcludeColumns) > 0 || cc.NumericRoundUnit > 0 || len(cc.CaseFoldColumns) > 0 || cc.TrimChar || cc.TimestampPrecision > 0
 }
 
 func (t *Task) IsPartitionTable() (string, error) {
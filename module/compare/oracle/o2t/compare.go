@@ -25,6 +25,7 @@ import (
 	"github.com/wentaojin/transferdb/database/oracle"
 	"github.com/wentaojin/transferdb/module/compare"
 	"github.com/wentaojin/transferdb/module/compare/oracle/public"
+	"github.com/wentaojin/transferdb/module/pool"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"path/filepath"
@@ -38,6 +39,7 @@ type Compare struct {
 	oracle *oracle.Oracle
 	mysql  *mysql.MySQL
 	metaDB *meta.Meta
+	repair *Repair // [compare] repair 开启自动修复
 }
 
 func NewCompare(ctx context.Context, cfg *config.Config) (*Compare, error) {
@@ -45,6 +47,10 @@ func NewCompare(ctx context.Context, cfg *config.Config) (*Compare, error) {
 	if err != nil {
 		return nil, err
 	}
+	oracleDB.Throttle, err = oracle.NewThrottle(ctx, oracleDB, cfg.ThrottleConfig)
+	if err != nil {
+		return nil, err
+	}
 	mysqlDB, err := mysql.NewMySQLDBEngine(ctx, cfg.MySQLConfig)
 	if err != nil {
 		return nil, err
@@ -53,16 +59,21 @@ func NewCompare(ctx context.Context, cfg *config.Config) (*Compare, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Compare{
+	r := &Compare{
 		ctx:    ctx,
 		cfg:    cfg,
 		oracle: oracleDB,
 		mysql:  mysqlDB,
 		metaDB: metaDB,
-	}, nil
+	}
+	if cfg.DiffConfig.Repair {
+		r.repair = NewRepair(ctx, cfg, oracleDB, mysqlDB, metaDB)
+	}
+	return r, nil
 }
 
 func (r *Compare) NewCompare() error {
+	defer r.oracle.Throttle.Close()
 	startTime := time.Now()
 	zap.L().Info("diff table oracle to tidb start",
 		zap.String("schema", r.cfg.SchemaConfig.SourceSchema))
@@ -102,6 +113,16 @@ func (r *Compare) NewCompare() error {
 		return nil
 	}
 
+	// 增量校验，只对比 since-scn / since-time 以来源端变更行
+	if r.cfg.DiffConfig.SinceSCN > 0 || r.cfg.DiffConfig.SinceTime != "" {
+		return r.compareDeltaTables(exporters, oraDBVersion)
+	}
+
+	// 抽样校验，每表随机抽取 chunk 对比
+	if r.cfg.DiffConfig.SamplePercent > 0 || r.cfg.DiffConfig.SampleChunks > 0 {
+		return r.compareSampleTables(exporters, oraDBVersion)
+	}
+
 	// 关于全量断点恢复
 	if !r.cfg.DiffConfig.EnableCheckpoint {
 		err = meta.NewDataCompareMetaModel(r.metaDB).TruncateDataCompareMeta(r.ctx)
@@ -502,17 +523,47 @@ func (r *Compare) comparePartTableTasks(f *compare.File, partTableTasks []*Task)
 
 		waitCompareMetas = append(waitCompareMetas, failedCompareMetas...)
 
+		// 修复语句是否输出以对比字段是否规整判断，断点续传表未加载脱敏字段
+		task.maskColumns, err = meta.NewColumnMaskRuleModel(r.metaDB).GetColumnMaskRuleMap(r.ctx, &meta.ColumnMaskRule{
+			DBTypeS:     r.cfg.DBTypeS,
+			DBTypeT:     r.cfg.DBTypeT,
+			SchemaNameS: r.cfg.SchemaConfig.SourceSchema,
+			TableNameS:  task.sourceTableName,
+		})
+		if err != nil {
+			return err
+		}
+		normalized := task.isNormalized()
+
+		var repairable bool
+		if r.repair != nil {
+			repairable, err = r.repair.Repairable(task)
+			if err != nil {
+				return err
+			}
+			if !repairable {
+				zap.L().Warn("repair table skip",
+					zap.String("schema", r.cfg.SchemaConfig.SourceSchema),
+					zap.String("table", task.sourceTableName),
+					zap.String("reason", "table exist mask columns or compare-config exclude-columns/numeric-round-unit/case-fold-columns/trim-char/timestamp-precision"))
+			}
+		}
+
 		// 设置工作池
 		// 设置 goroutine 数
-		g1 := &errgroup.Group{}
-		g1.SetLimit(r.cfg.DiffConfig.DiffThreads)
+		sched := pool.NewScheduler(common.StringsBuilder(r.cfg.TaskMode, "/", r.cfg.SchemaConfig.SourceSchema, ".", task.sourceTableName),
+			r.cfg.DiffConfig.DiffThreads, r.cfg.AppConfig)
+		g1 := sched.NewGroup()
 
 		for _, compareMeta := range waitCompareMetas {
 			newReport := NewReport(compareMeta, r.mysql, r.oracle, r.cfg.DiffConfig.OnlyCheckRows)
+			newReport.SetLobDataFile(r.cfg.DiffConfig.LobInlineSize, r.cfg.DiffConfig.FixSqlDir)
+			newReport.Normalized = normalized
 			g1.Go(func() error {
 				// 数据对比报告
 				report, err := public.IReport(newReport)
 				if err != nil {
+					sched.Feedback(err)
 					// error skip, continue
 					if err = meta.NewDataCompareMetaModel(r.metaDB).UpdateDataCompareMeta(r.ctx, &meta.DataCompareMeta{
 						DBTypeS:     newReport.DataCompareMeta.DBTypeS,
@@ -540,6 +591,33 @@ func (r *Compare) comparePartTableTasks(f *compare.File, partTableTasks []*Task)
 					if _, err := f.CWriteString(report); err != nil {
 						errMsg = fmt.Errorf("fix sql file write failed: %v", err.Error())
 					}
+
+					// 自动修复，修复后重新对比一致则 chunk 记录 SUCCESS
+					if repairable {
+						converged, err := r.repair.Repair(newReport)
+						if err != nil {
+							return err
+						}
+						if converged {
+							errMsg = nil
+						} else if !r.cfg.DiffConfig.RepairDryRun {
+							errMsg = fmt.Errorf("schema table data chunk isn't euqal, repair not converged, please see table [data_repair_meta]")
+						}
+					}
+					if errMsg == nil {
+						return meta.NewDataCompareMetaModel(r.metaDB).UpdateDataCompareMeta(r.ctx, &meta.DataCompareMeta{
+							DBTypeS:     newReport.DataCompareMeta.DBTypeS,
+							DBTypeT:     newReport.DataCompareMeta.DBTypeT,
+							SchemaNameS: newReport.DataCompareMeta.SchemaNameS,
+							TableNameS:  newReport.DataCompareMeta.TableNameS,
+							TaskMode:    newReport.DataCompareMeta.TaskMode,
+							WhereRange:  newReport.DataCompareMeta.WhereRange,
+						}, map[string]interface{}{
+							"TaskStatus":  common.TaskStatusSuccess,
+							"InfoDetail":  newReport.String(),
+							"ErrorDetail": "",
+						})
+					}
 					// error skip, continue
 					if err = meta.NewDataCompareMetaModel(r.metaDB).UpdateDataCompareMeta(r.ctx, &meta.DataCompareMeta{
 						DBTypeS:     newReport.DataCompareMeta.DBTypeS,
@@ -576,7 +654,9 @@ func (r *Compare) comparePartTableTasks(f *compare.File, partTableTasks []*Task)
 			})
 		}
 
-		if err = g1.Wait(); err != nil {
+		err = g1.Wait()
+		sched.Release()
+		if err != nil {
 			return fmt.Errorf("compare table task failed, update table [data_compare_meta] failed: %v", err)
 		}
 
@@ -669,6 +749,15 @@ func (r *Compare) compareWaitTableTasks(f *compare.File, waitTableTasks []*Task)
 
 	var chunks []*Chunk
 	for cid, task := range waitTableTasks {
+		task.maskColumns, err = meta.NewColumnMaskRuleModel(r.metaDB).GetColumnMaskRuleMap(r.ctx, &meta.ColumnMaskRule{
+			DBTypeS:     r.cfg.DBTypeS,
+			DBTypeT:     r.cfg.DBTypeT,
+			SchemaNameS: r.cfg.SchemaConfig.SourceSchema,
+			TableNameS:  task.sourceTableName,
+		})
+		if err != nil {
+			return err
+		}
 		sourceColumnInfo, targetColumnInfo, err := task.AdjustDBSelectColumn()
 		if err != nil {
 			return err
@@ -683,7 +772,7 @@ func (r *Compare) compareWaitTableTasks(f *compare.File, waitTableTasks []*Task)
 		}
 		chunks = append(chunks, NewChunk(r.ctx, r.cfg, r.oracle, r.mysql, r.metaDB,
 			cid, globalSCN, task.sourceTableName, task.targetTableName, isPartition, sourceColumnInfo, targetColumnInfo,
-			whereColumn))
+			whereColumn, task.oracleCollation))
 	}
 
 	// chunk split
@@ -718,8 +807,8 @@ func (r *Compare) AdjustCompareConfig(sourceDBCharset string) error {
 			zap.String("oracle config charset", r.cfg.OracleConfig.Charset))
 		return fmt.Errorf("oracle charset [%v] and oracle config charset [%v] aren't equal, please adjust oracle config charset", sourceDBCharset, r.cfg.OracleConfig.Charset)
 	}
-	if _, ok := common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(r.cfg.OracleConfig.Charset)]; !ok {
-		return fmt.Errorf("oracle current charset [%v] isn't support, support charset [%v]", r.cfg.OracleConfig.Charset, common.MigrateOracleCharsetStringConvertMapping)
+	if _, ok := common.MigrateOracleCharsetStringConvertMapping[r.cfg.OracleConfig.ActualCharset]; !ok {
+		return fmt.Errorf("oracle current charset [%v] isn't support, support charset [%v]", r.cfg.OracleConfig.ActualCharset, common.MigrateOracleCharsetStringConvertMapping)
 	}
 	if !common.IsContainString(common.MigrateDataSupportCharset, common.StringUPPER(r.cfg.MySQLConfig.Charset)) {
 		return fmt.Errorf("mysql current config charset [%v] isn't support, support charset [%v]", r.cfg.MySQLConfig.Charset, common.MigrateDataSupportCharset)
@@ -0,0 +1,168 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package o2t
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/database/mysql"
+	"github.com/wentaojin/transferdb/database/oracle"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+)
+
+// Repair 数据校验自动修复
+// 不一致 chunk 修复语句于目标端单 chunk 事务执行，执行后重新对比确认收敛，修复记录于 [data_repair_meta]
+type Repair struct {
+	ctx     context.Context
+	cfg     *config.Config
+	oracle  *oracle.Oracle
+	mysql   *mysql.MySQL
+	metaDB  *meta.Meta
+	mu      sync.Mutex
+	changed map[string]int64 // 表已修复（预占）目标端影响行数，不超过 repair-max-rows
+}
+
+func NewRepair(ctx context.Context, cfg *config.Config, oracle *oracle.Oracle, mysql *mysql.MySQL, metaDB *meta.Meta) *Repair {
+	return &Repair{
+		ctx:     ctx,
+		cfg:     cfg,
+		oracle:  oracle,
+		mysql:   mysql,
+		metaDB:  metaDB,
+		changed: make(map[string]int64),
+	}
+}
+
+// Repairable 修复语句以对比字段值生成，存在脱敏、排除字段或者数值舍入、忽略大小写、去除尾部空格、时间精度截断对比时修复值与源端不一致，不支持自动修复
+func (r *Repair) Repairable(task *Task) (bool, error) {
+	if isCompareNormalized(task.compareConfig()) {
+		return false, nil
+	}
+	maskColumns, err := meta.NewColumnMaskRuleModel(r.metaDB).GetColumnMaskRuleMap(r.ctx, &meta.ColumnMaskRule{
+		DBTypeS:     r.cfg.DBTypeS,
+		DBTypeT:     r.cfg.DBTypeT,
+		SchemaNameS: r.cfg.SchemaConfig.SourceSchema,
+		TableNameS:  task.sourceTableName,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(maskColumns) == 0, nil
+}
+
+// Repair 返回修复后 chunk 是否一致，修复失败或者未修复仅记录不中断数据校验
+func (r *Repair) Repair(report *Report) (bool, error) {
+	if len(report.FixStatements) == 0 {
+		return false, nil
+	}
+	repairMeta := &meta.DataRepairMeta{
+		DBTypeS:      report.DataCompareMeta.DBTypeS,
+		DBTypeT:      report.DataCompareMeta.DBTypeT,
+		SchemaNameS:  report.DataCompareMeta.SchemaNameS,
+		TableNameS:   report.DataCompareMeta.TableNameS,
+		SchemaNameT:  report.DataCompareMeta.SchemaNameT,
+		TableNameT:   report.DataCompareMeta.TableNameT,
+		WhereRange:   report.DataCompareMeta.WhereRange,
+		RepairMode:   common.RepairModeExecute,
+		StatusBefore: common.TaskStatusFailed,
+		StatusAfter:  common.TaskStatusFailed,
+		RepairRows:   int64(len(report.FixStatements)),
+		RepairSQL:    strings.Join(report.FixStatements, ";\n") + ";",
+	}
+
+	if r.cfg.DiffConfig.RepairDryRun {
+		repairMeta.RepairMode = common.RepairModeDryRun
+		return false, meta.NewDataRepairMetaModel(r.metaDB).CreateDataRepairMeta(r.ctx, repairMeta)
+	}
+
+	// 以目标端实际影响行数预占表修复行数（DELETE 可能影响多行），超出 repair-max-rows 回滚
+	var reserved int64
+	affected, err := r.mysql.ExecRepairSQL(report.FixStatements, func(rows int64) bool {
+		if !r.reserve(report.DataCompareMeta.TableNameS, rows) {
+			return false
+		}
+		reserved = rows
+		return true
+	})
+	if errors.Is(err, common.ErrRepairRowsExceeded) {
+		repairMeta.StatusAfter = common.RepairStatusSkipped
+		repairMeta.AffectedRows = affected
+		repairMeta.ErrorDetail = fmt.Sprintf("table repair affected rows [%d] exceed repair-max-rows [%d], rollback and skip", affected, r.cfg.DiffConfig.RepairMaxRows)
+		zap.L().Warn("repair table chunk skip",
+			zap.String("schema", report.DataCompareMeta.SchemaNameS),
+			zap.String("table", report.DataCompareMeta.TableNameS),
+			zap.String("chunk", report.DataCompareMeta.WhereRange),
+			zap.Int64("affected rows", affected),
+			zap.Int64("repair max rows", r.cfg.DiffConfig.RepairMaxRows))
+		return false, meta.NewDataRepairMetaModel(r.metaDB).CreateDataRepairMeta(r.ctx, repairMeta)
+	}
+	if err != nil {
+		r.release(report.DataCompareMeta.TableNameS, reserved)
+		repairMeta.ErrorDetail = err.Error()
+		zap.L().Warn("repair table chunk failed",
+			zap.String("schema", report.DataCompareMeta.SchemaNameS),
+			zap.String("table", report.DataCompareMeta.TableNameS),
+			zap.String("chunk", report.DataCompareMeta.WhereRange),
+			zap.Error(err))
+		return false, meta.NewDataRepairMetaModel(r.metaDB).CreateDataRepairMeta(r.ctx, repairMeta)
+	}
+	repairMeta.AffectedRows = affected
+
+	// 修复后重新对比 chunk 确认收敛
+	recheck := NewReport(report.DataCompareMeta, r.mysql, r.oracle, r.cfg.DiffConfig.OnlyCheckRows)
+	diff, err := recheck.Report()
+	switch {
+	case err != nil:
+		repairMeta.ErrorDetail = fmt.Sprintf("repair table chunk recheck failed: %v", err)
+	case diff != "":
+		repairMeta.ErrorDetail = "repair table chunk recheck isn't equal"
+	default:
+		repairMeta.StatusAfter = common.TaskStatusSuccess
+	}
+	zap.L().Info("repair table chunk finished",
+		zap.String("schema", report.DataCompareMeta.SchemaNameS),
+		zap.String("table", report.DataCompareMeta.TableNameS),
+		zap.String("chunk", report.DataCompareMeta.WhereRange),
+		zap.Int64("repair rows", repairMeta.RepairRows),
+		zap.Int64("affected rows", affected),
+		zap.String("status", repairMeta.StatusAfter))
+	if err = meta.NewDataRepairMetaModel(r.metaDB).CreateDataRepairMeta(r.ctx, repairMeta); err != nil {
+		return false, err
+	}
+	return repairMeta.StatusAfter == common.TaskStatusSuccess, nil
+}
+
+func (r *Repair) reserve(tableName string, rows int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.changed[tableName]+rows > r.cfg.DiffConfig.RepairMaxRows {
+		return false
+	}
+	r.changed[tableName] += rows
+	return true
+}
+
+func (r *Repair) release(tableName string, rows int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changed[tableName] -= rows
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package o2t
 
 import (
+	"crypto/md5"
 	"encoding/json"
 	"fmt"
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -25,8 +26,12 @@ import (
 	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/database/mysql"
 	"github.com/wentaojin/transferdb/database/oracle"
+	"github.com/wentaojin/transferdb/module/compare/oracle/public"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -42,6 +47,12 @@ type Report struct {
 	Mysql           *mysql.MySQL         `json:"-"`
 	Oracle          *oracle.Oracle       `json:"-"`
 	OnlyCheckRows   bool                 `json:"only_check_rows"`
+	SourceSCN       uint64               `json:"source_scn"` // 非 0 表示源端 AS OF SCN 闪回查询，用于增量在线校验
+	FixStatements   []string             `json:"-"`          // 对比不一致 chunk 修复语句，用于 [compare] repair 自动修复
+	LobInlineSize   int64                `json:"-"`          // 修复语句 LOB 字段值超过该大小写入 LobDataDir 数据文件，<= 0 表示全部内联
+	LobDataDir      string               `json:"-"`
+	lobFile         bool                 // 修复语句存在 LOAD_FILE 读取 LOB 数据文件
+	Normalized      bool                 `json:"-"` // 对比字段存在规整，对比值无法还原源端原始值，修复语句仅输出不一致行注释
 }
 
 func NewReport(dataCompareMeta meta.DataCompareMeta, mysql *mysql.MySQL, oracle *oracle.Oracle, onlyCheckRows bool) *Report {
@@ -53,24 +64,49 @@ func NewReport(dataCompareMeta meta.DataCompareMeta, mysql *mysql.MySQL, oracle
 	}
 }
 
+// 修复语句 LOB 字段值数据文件输出 fix-sql-dir/lob 目录
+func (r *Report) SetLobDataFile(lobInlineSize int64, fixSqlDir string) {
+	r.LobInlineSize = lobInlineSize
+	r.LobDataDir = filepath.Join(fixSqlDir, "lob")
+}
+
 func (r *Report) GenDBQuery() (oracleQuery string, mysqlQuery string) {
+	targetRange := r.TargetWhereRange()
+	sourceTable := r.SourceTable()
 	if r.DataCompareMeta.WhereColumn == "" {
 		oracleQuery = common.StringsBuilder(
-			"SELECT ", r.DataCompareMeta.ColumnDetailS, " FROM ", r.DataCompareMeta.SchemaNameS, ".", r.DataCompareMeta.TableNameS, " WHERE ", r.DataCompareMeta.WhereRange)
+			"SELECT ", r.DataCompareMeta.ColumnDetailS, " FROM ", sourceTable, " WHERE ", r.DataCompareMeta.WhereRange)
 
 		mysqlQuery = common.StringsBuilder(
-			"SELECT ", r.DataCompareMeta.ColumnDetailT, " FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameT, " WHERE ", r.DataCompareMeta.WhereRange)
+			"SELECT ", r.DataCompareMeta.ColumnDetailT, " FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameT, " WHERE ", targetRange)
 	} else {
 		oracleQuery = common.StringsBuilder(
-			"SELECT ", r.DataCompareMeta.ColumnDetailS, " FROM ", r.DataCompareMeta.SchemaNameS, ".", r.DataCompareMeta.TableNameS, " WHERE ", r.DataCompareMeta.WhereRange,
+			"SELECT ", r.DataCompareMeta.ColumnDetailS, " FROM ", sourceTable, " WHERE ", r.DataCompareMeta.WhereRange,
 			" ORDER BY ", r.DataCompareMeta.WhereColumn, " DESC")
 
 		mysqlQuery = common.StringsBuilder(
-			"SELECT ", r.DataCompareMeta.ColumnDetailT, " FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameT, " WHERE ", r.DataCompareMeta.WhereRange, " ORDER BY ", r.DataCompareMeta.WhereColumn, " DESC")
+			"SELECT ", r.DataCompareMeta.ColumnDetailT, " FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameT, " WHERE ", targetRange, " ORDER BY ", r.DataCompareMeta.WhereColumn, " DESC")
 	}
 	return
 }
 
+// 源端查询表，SourceSCN 非 0 闪回查询
+func (r *Report) SourceTable() string {
+	sourceTable := common.StringsBuilder(r.DataCompareMeta.SchemaNameS, ".", r.DataCompareMeta.TableNameS)
+	if r.SourceSCN > 0 {
+		sourceTable = common.StringsBuilder(sourceTable, " AS OF SCN ", strconv.FormatUint(r.SourceSCN, 10))
+	}
+	return sourceTable
+}
+
+// 目标端 where 条件，采样切分字符字段两端表达式不同，未单独记录则与源端一致
+func (r *Report) TargetWhereRange() string {
+	if r.DataCompareMeta.WhereRangeT != "" {
+		return r.DataCompareMeta.WhereRangeT
+	}
+	return r.DataCompareMeta.WhereRange
+}
+
 func (r *Report) CheckOracleRows(oracleQuery string) (int64, error) {
 	rows, err := r.Oracle.GetOracleTableActualRows(oracleQuery)
 	if err != nil {
@@ -172,6 +208,8 @@ func (r *Report) ReportCheckCRC32() (string, error) {
 	mysqlChan := make(chan DBSummary, 1)
 
 	oracleQuery, mysqlQuery := r.GenDBQuery()
+	r.FixStatements = nil
+	r.lobFile = false
 
 	errORA.Go(func() error {
 		oraColumns, oraStringSet, oraCrc32Val, err := r.Oracle.GetOracleDataRowStrings(oracleQuery)
@@ -240,8 +278,24 @@ func (r *Report) ReportCheckCRC32() (string, error) {
 
 	var fixSQL strings.Builder
 
-	// 判断下游数据是否多
 	targetMore := strset.Difference(mysqlReport.StringSet, oraReport.StringSet).List()
+	sourceMore := strset.Difference(oraReport.StringSet, mysqlReport.StringSet).List()
+	if r.Normalized {
+		return r.genNormalizedComment(oraReport.Columns, targetMore, sourceMore), nil
+	}
+	var (
+		fixColumns []fixColumn
+		lobSkipped bool
+	)
+	if len(targetMore) > 0 || len(sourceMore) > 0 {
+		var err error
+		fixColumns, err = r.genFixColumns(oraReport.Columns)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// 判断下游数据是否多
 	if len(targetMore) > 0 {
 		fixSQL.WriteString("/*\n")
 		fixSQL.WriteString(fmt.Sprintf(" tidb table [%s.%s] chunk [%s] data rows are more \n", r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameT, r.DataCompareMeta.WhereRange))
@@ -254,30 +308,29 @@ func (r *Report) ReportCheckCRC32() (string, error) {
 				common.StringsBuilder("SELECT COUNT(1)", " FROM ", r.DataCompareMeta.SchemaNameS, ".", r.DataCompareMeta.TableNameS, " WHERE ", r.DataCompareMeta.WhereRange),
 				oraReport.Crc32Val},
 			{"MySQL", common.StringsBuilder(
-				"SELECT COUNT(1)", " FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameS, " WHERE ", r.DataCompareMeta.WhereRange),
+				"SELECT COUNT(1)", " FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameS, " WHERE ", r.TargetWhereRange()),
 				mysqlReport.Crc32Val},
 		})
 		fixSQL.WriteString(fmt.Sprintf("%v\n", sw.Render()))
 		fixSQL.WriteString("*/\n")
-		deletePrefix := common.StringsBuilder("DELETE FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameS, " WHERE ")
+		deletePrefix := common.StringsBuilder("DELETE FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameT, " WHERE ")
 		for _, t := range targetMore {
-			var whereCond []string
-
-			// 计算字段列个数
-			colValues := strings.Split(t, ",")
-			if len(mysqlReport.Columns) != len(colValues) {
-				return "", fmt.Errorf("tidb schema [%s] table [%s] column counts [%d] isn't match values counts [%d]", r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameS, len(mysqlReport.Columns), len(colValues))
+			whereCond, err := r.genDeleteConds(mysqlReport.Columns, fixColumns, t)
+			if err != nil {
+				return "", err
 			}
-			for i := 0; i < len(mysqlReport.Columns); i++ {
-				whereCond = append(whereCond, common.StringsBuilder(mysqlReport.Columns[i], "=", colValues[i]))
+			if len(whereCond) == 0 {
+				lobSkipped = true
+				fixSQL.WriteString(fmt.Sprintf("/* tidb table [%s.%s] lob row [%s] hasn't delete condition, skip */\n", r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameT, strings.ReplaceAll(t, "*/", "* /")))
+				continue
 			}
-
-			fixSQL.WriteString(fmt.Sprintf("%v;\n", common.StringsBuilder(deletePrefix, exstrings.Join(whereCond, " AND "))))
+			deleteSQL := common.StringsBuilder(deletePrefix, exstrings.Join(whereCond, " AND "))
+			r.FixStatements = append(r.FixStatements, deleteSQL)
+			fixSQL.WriteString(fmt.Sprintf("%v;\n", deleteSQL))
 		}
 	}
 
 	// 判断上游数据是否多
-	sourceMore := strset.Difference(oraReport.StringSet, mysqlReport.StringSet).List()
 	if len(sourceMore) > 0 {
 		fixSQL.WriteString("/*\n")
 		fixSQL.WriteString(fmt.Sprintf(" tidb table [%s.%s] chunk [%s] data rows are less \n", r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameS, r.DataCompareMeta.WhereRange))
@@ -290,16 +343,40 @@ func (r *Report) ReportCheckCRC32() (string, error) {
 				common.StringsBuilder("SELECT COUNT(1)", " FROM ", r.DataCompareMeta.SchemaNameS, ".", r.DataCompareMeta.TableNameS, " WHERE ", r.DataCompareMeta.WhereRange),
 				oraReport.Crc32Val},
 			{"MySQL", common.StringsBuilder(
-				"SELECT COUNT(1)", " FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameS, " WHERE ", r.DataCompareMeta.WhereRange),
+				"SELECT COUNT(1)", " FROM ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameS, " WHERE ", r.TargetWhereRange()),
 				mysqlReport.Crc32Val},
 		})
 		fixSQL.WriteString(fmt.Sprintf("%v\n", sw.Render()))
 		fixSQL.WriteString("*/\n")
-		insertPrefix := common.StringsBuilder("INSERT INTO ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameS, " (", strings.Join(oraReport.Columns, ","), ") VALUES (")
+		insertPrefix := common.StringsBuilder("INSERT INTO ", r.DataCompareMeta.SchemaNameT, ".", r.DataCompareMeta.TableNameT, " (", strings.Join(oraReport.Columns, ","), ") VALUES (")
 		for _, s := range sourceMore {
-			fixSQL.WriteString(fmt.Sprintf("%v;\n", common.StringsBuilder(insertPrefix, s, ")")))
+			values, err := r.genInsertValues(fixColumns, s)
+			if err != nil {
+				return "", err
+			}
+			if values == "" {
+				lobSkipped = true
+				fixSQL.WriteString(fmt.Sprintf("/* oracle table [%s.%s] lob row [%s] can't be located uniquely, skip */\n", r.DataCompareMeta.SchemaNameS, r.DataCompareMeta.TableNameS, strings.ReplaceAll(s, "*/", "* /")))
+				continue
+			}
+			insertSQL := common.StringsBuilder(insertPrefix, values, ")")
+			r.FixStatements = append(r.FixStatements, insertSQL)
+			fixSQL.WriteString(fmt.Sprintf("%v;\n", insertSQL))
 		}
 	}
+
+	// 存在跳过的 LOB 行，修复语句不完整（比如目标端行已删除而源端行无法写回），chunk 不自动修复
+	if lobSkipped && len(r.FixStatements) > 0 {
+		r.FixStatements = nil
+		fixSQL.WriteString(fmt.Sprintf("/* tidb table [%s.%s] chunk [%s] exist skipped lob rows, fix sql isn't complete, chunk isn't repaired automatically */\n",
+			r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameT, r.DataCompareMeta.WhereRange))
+	}
+	// LOAD_FILE 缺少 FILE 权限、secure_file_priv 不允许或者数据文件不在目标端服务器时静默返回 NULL，chunk 不自动修复
+	if r.lobFile && len(r.FixStatements) > 0 {
+		r.FixStatements = nil
+		fixSQL.WriteString(fmt.Sprintf("/* tidb table [%s.%s] chunk [%s] fix sql exist LOAD_FILE lob data file, chunk isn't repaired automatically, please confirm FILE privilege, secure_file_priv and data file on the tidb server host, then fix manually */\n",
+			r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameT, r.DataCompareMeta.WhereRange))
+	}
 	return fixSQL.String(), nil
 }
 
@@ -314,3 +391,173 @@ func (r *Report) String() string {
 	jsonStr, _ := json.Marshal(r)
 	return string(jsonStr)
 }
+
+// 对比字段存在规整（比如 ROUND、UPPER、RTRIM、时间截断）时对比值非源端原始值，生成的修复语句写入规整值且条件无法匹配目标端行
+// 仅以注释输出不一致行对比值，需人工修复
+func (r *Report) genNormalizedComment(columns, targetMore, sourceMore []string) string {
+	sw := table.NewWriter()
+	sw.SetStyle(table.StyleLight)
+	sw.AppendHeader(table.Row{"DIFF", common.StringsBuilder("COMPARE VALUES (", strings.Join(columns, ","), ")")})
+	for _, t := range targetMore {
+		sw.AppendRow(table.Row{"TIDB MORE", t})
+	}
+	for _, s := range sourceMore {
+		sw.AppendRow(table.Row{"TIDB LESS", s})
+	}
+	return common.StringsBuilder("/*\n",
+		fmt.Sprintf(" tidb table [%s.%s] chunk [%s] data rows aren't equal, compare columns are normalized (mask/exclude-columns/numeric-round-unit/case-fold-columns/trim-char/timestamp-precision), fix sql isn't generated, please fix manually\n",
+			r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameT, r.DataCompareMeta.WhereRange),
+		strings.ReplaceAll(sw.Render(), "*/", "* /"), "\n*/\n")
+}
+
+// 修复语句字段，RAW 字段两端十六进制对比，LOB 字段两端哈希对比
+type fixColumn struct {
+	columnName  string
+	dataType    string
+	isHex       bool
+	isLobHash   bool
+	isCharacter bool
+}
+
+func (r *Report) genFixColumns(columns []string) ([]fixColumn, error) {
+	columnInfo, err := r.Oracle.GetOracleSchemaTableColumn(r.DataCompareMeta.SchemaNameS, r.DataCompareMeta.TableNameS, false)
+	if err != nil {
+		return nil, err
+	}
+	dataTypes := make(map[string]string)
+	for _, c := range columnInfo {
+		dataTypes[common.StringUPPER(c["COLUMN_NAME"])] = common.StringUPPER(c["DATA_TYPE"])
+	}
+	var fixColumns []fixColumn
+	for _, c := range columns {
+		dataType := dataTypes[common.StringUPPER(c)]
+		fixColumns = append(fixColumns, fixColumn{
+			columnName:  c,
+			dataType:    dataType,
+			isHex:       dataType == "RAW",
+			isLobHash:   public.IsLobHashColumn(r.DataCompareMeta.ColumnDetailS, c),
+			isCharacter: dataType == "CLOB" || dataType == "NCLOB",
+		})
+	}
+	return fixColumns, nil
+}
+
+// 修复语句 DELETE 条件，NULL 值以 IS NULL 匹配（目标端空字符串对比值同为 NULL，字符字段同时匹配空字符串），RAW 十六进制值 HEX 对比，LOB 哈希值不作为删除条件
+func (r *Report) genDeleteConds(columns []string, fixColumns []fixColumn, row string) ([]string, error) {
+	colValues := strings.Split(row, ",")
+	if len(columns) != len(colValues) {
+		return nil, fmt.Errorf("tidb schema [%s] table [%s] column counts [%d] isn't match values counts [%d]", r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameS, len(columns), len(colValues))
+	}
+	var whereCond []string
+	for i := 0; i < len(columns); i++ {
+		switch {
+		case fixColumns[i].isLobHash:
+			continue
+		case colValues[i] == "NULL" && strings.Contains(fixColumns[i].dataType, "CHAR"):
+			whereCond = append(whereCond, common.StringsBuilder("(", columns[i], " IS NULL OR ", columns[i], " = '')"))
+		case colValues[i] == "NULL":
+			whereCond = append(whereCond, common.StringsBuilder(columns[i], " IS NULL"))
+		case fixColumns[i].isHex:
+			whereCond = append(whereCond, common.StringsBuilder("HEX(", columns[i], ")=", colValues[i]))
+		default:
+			whereCond = append(whereCond, common.StringsBuilder(columns[i], "=", colValues[i]))
+		}
+	}
+	return whereCond, nil
+}
+
+// 修复语句 INSERT 字段值，RAW 十六进制值 UNHEX 还原
+// LOB 对比值为哈希值，按数字、字符字段值于 chunk 范围内定位源端行重新获取 LOB 原始值，无法唯一定位返回空
+func (r *Report) genInsertValues(fixColumns []fixColumn, row string) (string, error) {
+	values := strings.Split(row, ",")
+	if len(fixColumns) != len(values) {
+		return "", fmt.Errorf("oracle schema [%s] table [%s] column counts [%d] isn't match values counts [%d]", r.DataCompareMeta.SchemaNameS, r.DataCompareMeta.TableNameS, len(fixColumns), len(values))
+	}
+
+	var lobColumns, locateConds []string
+	for i, c := range fixColumns {
+		switch {
+		case c.isLobHash:
+			lobColumns = append(lobColumns, c.columnName)
+			continue
+		case c.isHex && values[i] != "NULL":
+			values[i] = common.StringsBuilder("UNHEX(", values[i], ")")
+		}
+		if !isLobLocateDataType(c.dataType) {
+			continue
+		}
+		switch {
+		case values[i] == "NULL":
+			locateConds = append(locateConds, common.StringsBuilder(c.columnName, " IS NULL"))
+		// 字符值按 MySQL 转义，含反斜杠转义字符不适用于源端定位
+		case !strings.Contains(values[i], "\\"):
+			locateConds = append(locateConds, common.StringsBuilder(c.columnName, " = ", values[i]))
+		}
+	}
+	if len(lobColumns) == 0 {
+		return strings.Join(values, ","), nil
+	}
+	if len(locateConds) == 0 {
+		return "", nil
+	}
+
+	querySQL := common.StringsBuilder("SELECT ", strings.Join(lobColumns, ","), " FROM ", r.SourceTable(),
+		" WHERE ", strings.Join(locateConds, " AND "), " AND (", r.DataCompareMeta.WhereRange, ")")
+	res, err := r.Oracle.GetOracleTableRowLobs(querySQL)
+	if err != nil {
+		zap.L().Warn("oracle table lob row locate failed",
+			zap.String("oracle schema", r.DataCompareMeta.SchemaNameS),
+			zap.String("oracle table", r.DataCompareMeta.TableNameS),
+			zap.Error(err))
+		return "", nil
+	}
+	if len(res) != 1 {
+		return "", nil
+	}
+	for i, c := range fixColumns {
+		if !c.isLobHash {
+			continue
+		}
+		lobValue := res[0][c.columnName]
+		if lobValue == "NULLABLE" || lobValue == "" {
+			values[i] = "NULL"
+			continue
+		}
+		values[i], err = r.genLobLiteral(c, []byte(lobValue))
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(values, ","), nil
+}
+
+// LOB 字段值超过 lob-inline-size 写入数据文件，文件名以字段值 MD5 去重
+func (r *Report) genLobLiteral(c fixColumn, value []byte) (string, error) {
+	if r.LobInlineSize <= 0 || int64(len(value)) <= r.LobInlineSize {
+		return public.GenLobHexLiteral(value, c.isCharacter), nil
+	}
+	if err := os.MkdirAll(r.LobDataDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	dataFile, err := filepath.Abs(filepath.Join(r.LobDataDir, fmt.Sprintf("%s.%s.%s.%x.dat",
+		r.DataCompareMeta.SchemaNameT, r.DataCompareMeta.TableNameT, c.columnName, md5.Sum(value))))
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(dataFile, value, 0644); err != nil {
+		return "", fmt.Errorf("lob data file [%s] write failed: %v", dataFile, err)
+	}
+	r.lobFile = true
+	return public.GenLobFileLiteral(dataFile, c.isCharacter), nil
+}
+
+// 源端 LOB 行定位字段类型
+func isLobLocateDataType(dataType string) bool {
+	switch {
+	case dataType == "NUMBER" || dataType == "INTEGER" || dataType == "FLOAT",
+		dataType == "CHAR" || dataType == "NCHAR" || dataType == "VARCHAR2" || dataType == "NVARCHAR2":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,345 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package o2t
+
+import (
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/module/compare"
+	"github.com/wentaojin/transferdb/module/compare/oracle/public"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 抽样校验单表结果
+type sampleSummary struct {
+	tableName     string
+	totalChunks   int
+	sampleChunks  int
+	unequalChunks int
+	errorChunks   int
+}
+
+// 抽样校验，每表按 sample-chunks 或者 sample-percent 随机抽取 chunk 对比，用于测试装载后快速冒烟验证，不记录断点
+// 源端 SAMPLE BLOCK 数据块采样 NTILE 分桶生成 chunk 范围，随机抽取 chunk 按常规 CRC32 对比，输出不一致 chunk 比例以及 95% 置信上限
+func (r *Compare) compareSampleTables(exporters []string, oraDBVersion string) error {
+	startTime := time.Now()
+	oracleCollation := common.VersionOrdinal(oraDBVersion) >= common.VersionOrdinal(common.OracleTableColumnCollationDBVersion)
+
+	globalSCN, err := r.oracle.GetOracleCurrentSnapshotSCN()
+	if err != nil {
+		return err
+	}
+
+	tableNameRules, err := meta.NewTableNameRuleModel(r.metaDB).DetailTableNameRule(r.ctx, &meta.TableNameRule{
+		DBTypeS:     r.cfg.DBTypeS,
+		DBTypeT:     r.cfg.DBTypeT,
+		SchemaNameS: r.cfg.SchemaConfig.SourceSchema,
+		SchemaNameT: r.cfg.SchemaConfig.TargetSchema,
+	})
+	if err != nil {
+		return err
+	}
+	tableNameRuleMap := make(map[string]string)
+	for _, tr := range tableNameRules {
+		tableNameRuleMap[common.StringUPPER(tr.TableNameS)] = common.StringUPPER(tr.TableNameT)
+	}
+
+	if err = common.PathExist(r.cfg.DiffConfig.FixSqlDir); err != nil {
+		return err
+	}
+	sampleFile := filepath.Join(r.cfg.DiffConfig.FixSqlDir, fmt.Sprintf("sample_%s.sql", r.cfg.SchemaConfig.SourceSchema))
+	f, err := compare.NewWriter(sampleFile)
+	if err != nil {
+		return err
+	}
+
+	var (
+		summaries    []sampleSummary
+		failedTables []string
+	)
+	for _, task := range NewWaitCompareTableTask(r.ctx, r.cfg, exporters, oracleCollation, r.mysql, r.oracle, tableNameRuleMap) {
+		summary, err := r.compareSampleTable(f, task, globalSCN)
+		if err != nil {
+			failedTables = append(failedTables, task.sourceTableName)
+			zap.L().Warn("sample compare table failed, skip",
+				zap.String("schema", r.cfg.SchemaConfig.SourceSchema),
+				zap.String("table", task.sourceTableName),
+				zap.Error(err))
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+
+	var unequalTables int
+	for _, s := range summaries {
+		if s.unequalChunks > 0 || s.errorChunks > 0 {
+			unequalTables++
+		}
+	}
+	if _, err = f.CWriteString(genSampleSummary(summaries)); err != nil {
+		return fmt.Errorf("fix sql file write failed: %v", err)
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	zap.L().Info("compare", zap.String("fix sql file output", sampleFile))
+	if len(failedTables) > 0 || unequalTables > 0 {
+		zap.L().Warn("sample compare table oracle to tidb finished",
+			zap.Int("table totals", len(exporters)),
+			zap.Int("table unequal", unequalTables),
+			zap.Strings("table failed", failedTables),
+			zap.Uint64("global scn", globalSCN),
+			zap.String("cost", time.Now().Sub(startTime).String()))
+		return nil
+	}
+	zap.L().Info("sample compare table oracle to tidb finished",
+		zap.Int("table totals", len(exporters)),
+		zap.Uint64("global scn", globalSCN),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return nil
+}
+
+func (r *Compare) compareSampleTable(f *compare.File, task *Task, globalSCN uint64) (sampleSummary, error) {
+	startTime := time.Now()
+	summary := sampleSummary{tableName: task.sourceTableName}
+
+	var err error
+	task.maskColumns, err = meta.NewColumnMaskRuleModel(r.metaDB).GetColumnMaskRuleMap(r.ctx, &meta.ColumnMaskRule{
+		DBTypeS:     r.cfg.DBTypeS,
+		DBTypeT:     r.cfg.DBTypeT,
+		SchemaNameS: r.cfg.SchemaConfig.SourceSchema,
+		TableNameS:  task.sourceTableName,
+	})
+	if err != nil {
+		return summary, err
+	}
+	sourceColumnInfo, targetColumnInfo, err := task.AdjustDBSelectColumn()
+	if err != nil {
+		return summary, err
+	}
+	sourceRanges, targetRanges, err := r.genSampleRanges(task, sourceColumnInfo, targetColumnInfo)
+	if err != nil {
+		return summary, err
+	}
+
+	// 随机抽取 chunk，按切分顺序对比
+	summary.totalChunks = len(sourceRanges)
+	sampleIdx := rand.Perm(summary.totalChunks)[:r.sampleChunkNums(summary.totalChunks)]
+	sort.Ints(sampleIdx)
+	summary.sampleChunks = len(sampleIdx)
+
+	var repairable bool
+	if r.repair != nil {
+		if repairable, err = r.repair.Repairable(task); err != nil {
+			return summary, err
+		}
+	}
+
+	var mu sync.Mutex
+	g := &errgroup.Group{}
+	g.SetLimit(r.cfg.DiffConfig.DiffThreads)
+	for _, idx := range sampleIdx {
+		newReport := NewReport(meta.DataCompareMeta{
+			DBTypeS:       r.cfg.DBTypeS,
+			DBTypeT:       r.cfg.DBTypeT,
+			SchemaNameS:   common.StringUPPER(r.cfg.SchemaConfig.SourceSchema),
+			TableNameS:    task.sourceTableName,
+			SchemaNameT:   common.StringUPPER(r.cfg.SchemaConfig.TargetSchema),
+			TableNameT:    task.targetTableName,
+			ColumnDetailS: sourceColumnInfo,
+			ColumnDetailT: targetColumnInfo,
+			WhereRange:    sourceRanges[idx],
+			WhereRangeT:   targetRanges[idx],
+			TaskMode:      r.cfg.TaskMode,
+		}, r.mysql, r.oracle, r.cfg.DiffConfig.OnlyCheckRows)
+		newReport.SourceSCN = globalSCN
+		newReport.SetLobDataFile(r.cfg.DiffConfig.LobInlineSize, r.cfg.DiffConfig.FixSqlDir)
+		newReport.Normalized = task.isNormalized()
+		g.Go(func() error {
+			report, err := public.IReport(newReport)
+			if err != nil {
+				mu.Lock()
+				summary.errorChunks++
+				mu.Unlock()
+				zap.L().Warn("sample compare table chunk failed",
+					zap.String("schema", newReport.DataCompareMeta.SchemaNameS),
+					zap.String("table", newReport.DataCompareMeta.TableNameS),
+					zap.String("chunk", newReport.DataCompareMeta.WhereRange),
+					zap.Error(err))
+				return nil
+			}
+			if report == "" {
+				return nil
+			}
+			if _, err = f.CWriteString(report); err != nil {
+				return fmt.Errorf("fix sql file write failed: %v", err)
+			}
+			// 抽样结果以修复前为准，修复不影响置信统计
+			mu.Lock()
+			summary.unequalChunks++
+			mu.Unlock()
+			if repairable {
+				if _, err = r.repair.Repair(newReport); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return summary, err
+	}
+
+	zap.L().Info("sample compare single table oracle to tidb finished",
+		zap.String("schema", r.cfg.SchemaConfig.SourceSchema),
+		zap.String("table", task.sourceTableName),
+		zap.Int("chunk totals", summary.totalChunks),
+		zap.Int("chunk sample", summary.sampleChunks),
+		zap.Int("chunk unequal", summary.unequalChunks),
+		zap.Int("chunk error", summary.errorChunks),
+		zap.Float64("unequal upper bound", common.CompareSampleUpperBound(summary.totalChunks, summary.sampleChunks-summary.errorChunks, summary.unequalChunks, common.CompareSampleConfidenceZ)),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return summary, nil
+}
+
+// 抽样 chunk 范围，统计信息行数按 chunk-size 计算 chunk 数，抽样 chunk 数不小于总 chunk 数的表全表对比
+// 切分字段优先 compare-config index-fields，其次主键 > 唯一键 > 唯一索引，compare-config range 与各 chunk 范围同时生效
+func (r *Compare) genSampleRanges(task *Task, sourceColumnInfo, targetColumnInfo string) ([]string, []string, error) {
+	schemaNameS := common.StringUPPER(r.cfg.SchemaConfig.SourceSchema)
+	chunk := NewChunk(r.ctx, r.cfg, r.oracle, r.mysql, r.metaDB, 0, 0, task.sourceTableName, task.targetTableName, "",
+		sourceColumnInfo, targetColumnInfo, "", task.oracleCollation)
+	customColumn, customRange, err := chunk.CustomTableConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	withCustomRange := func(ranges []string) []string {
+		if customRange == "" {
+			return ranges
+		}
+		var newRanges []string
+		for _, rg := range ranges {
+			newRanges = append(newRanges, common.StringsBuilder("(", customRange, ") AND ", rg))
+		}
+		return newRanges
+	}
+	fullRanges := withCustomRange([]string{"1 = 1"})
+
+	tableRows, err := r.oracle.GetOracleTableRowsByStatistics(schemaNameS, task.sourceTableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunkNums := (tableRows + r.cfg.DiffConfig.ChunkSize - 1) / r.cfg.DiffConfig.ChunkSize
+	if chunkNums <= r.sampleChunkNums(chunkNums) {
+		return fullRanges, fullRanges, nil
+	}
+
+	chunk.WhereColumn = customColumn
+	if chunk.WhereColumn == "" {
+		if chunk.WhereColumn, err = task.FilterDBKeyColumn(); err != nil {
+			return nil, nil, err
+		}
+	}
+	splitColumns, err := chunk.genSplitColumns()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(splitColumns) == 0 {
+		return nil, nil, fmt.Errorf("split column [%s] datatype or collation isn't support", chunk.WhereColumn)
+	}
+
+	var selectColumns, orderColumns []string
+	for _, col := range splitColumns {
+		selectColumns = append(selectColumns, public.GenSplitSelectColumn(col))
+		orderColumns = append(orderColumns, col.ColumnName)
+	}
+	samplePercent := float64(chunkNums*common.CompareSampleRowsPerChunk) * 100 / float64(tableRows)
+	res, err := r.oracle.GetOracleTableChunksBySample(schemaNameS, task.sourceTableName, selectColumns, orderColumns, samplePercent, chunkNums, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sourceCharset := common.MigrateOracleCharsetStringConvertMapping[r.cfg.OracleConfig.ActualCharset]
+	var boundaries [][]string
+	for _, row := range res {
+		values, err := public.ValidSplitBoundary(splitColumns, row)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, v := range values {
+			convertRaw, err := common.CharsetConvert([]byte(v), sourceCharset, common.CharsetUTF8MB4)
+			if err != nil {
+				return nil, nil, fmt.Errorf("column [%s] boundary charset convert failed, %v", splitColumns[i].ColumnName, err)
+			}
+			values[i] = string(convertRaw)
+		}
+		boundaries = append(boundaries, values)
+	}
+	if len(boundaries) == 0 {
+		return fullRanges, fullRanges, nil
+	}
+	sourceRanges, targetRanges := public.GenSplitRanges(splitColumns, boundaries)
+	return withCustomRange(sourceRanges), withCustomRange(targetRanges), nil
+}
+
+// 抽样 chunk 数，sample-percent 向上取整，至少 1 个且不超过总 chunk 数
+func (r *Compare) sampleChunkNums(totalChunks int) int {
+	nums := r.cfg.DiffConfig.SampleChunks
+	if nums <= 0 {
+		nums = int(math.Ceil(float64(totalChunks) * r.cfg.DiffConfig.SamplePercent / 100))
+	}
+	if nums < 1 {
+		nums = 1
+	}
+	if nums > totalChunks {
+		nums = totalChunks
+	}
+	return nums
+}
+
+// 抽样校验结果汇总，不一致比例以及置信上限只统计对比成功 chunk
+func genSampleSummary(summaries []sampleSummary) string {
+	sw := table.NewWriter()
+	sw.SetStyle(table.StyleLight)
+	sw.AppendHeader(table.Row{"TABLE", "TOTAL CHUNKS", "SAMPLE CHUNKS", "UNEQUAL CHUNKS", "ERROR CHUNKS", "UNEQUAL RATIO", "UPPER BOUND (95% CONFIDENCE)"})
+	for _, s := range summaries {
+		checked := s.sampleChunks - s.errorChunks
+		ratio := 0.0
+		if checked > 0 {
+			ratio = float64(s.unequalChunks) / float64(checked)
+		}
+		sw.AppendRow(table.Row{
+			s.tableName,
+			s.totalChunks,
+			s.sampleChunks,
+			s.unequalChunks,
+			s.errorChunks,
+			common.StringsBuilder(strconv.FormatFloat(ratio*100, 'f', 2, 64), "%"),
+			common.StringsBuilder(strconv.FormatFloat(common.CompareSampleUpperBound(s.totalChunks, checked, s.unequalChunks, common.CompareSampleConfidenceZ)*100, 'f', 2, 64), "%"),
+		})
+	}
+	return fmt.Sprintf("/*\n sample compare summary\n%v\n*/\n", sw.Render())
+}
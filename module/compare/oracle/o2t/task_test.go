@@ -0,0 +1,75 @@
+package o2t
+
+import (
+	"testing"
+
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/meta"
+)
+
+func TestAdjustDBSelectColumn(t *testing.T) {
+	columnInfo := []map[string]string{
+		{"COLUMN_NAME": "ID", "DATA_TYPE": "NUMBER"},
+		{"COLUMN_NAME": "NAME", "DATA_TYPE": "VARCHAR2"},
+		{"COLUMN_NAME": "CODE", "DATA_TYPE": "CHAR"},
+		{"COLUMN_NAME": "TS", "DATA_TYPE": "TIMESTAMP(6)"},
+	}
+	tests := []struct {
+		name           string
+		compareCfg     config.CompareConfig
+		maskColumns    map[string]meta.ColumnMaskRule
+		wantSource     string
+		wantTarget     string
+		wantNormalized bool
+		wantErr        bool
+	}{
+		{
+			name:       "exact",
+			wantSource: `DECODE(SUBSTR(ID,1,1),'.','0' || ID,ID) AS ID,NAME,NVL(CODE,'') AS CODE,TO_CHAR(TS,'yyyy-MM-dd HH24:mi:ss') AS TS`,
+			wantTarget: `CAST(0 + CAST(ID AS CHAR) AS CHAR) AS ID,NAME,IFNULL(CODE,'') AS CODE,FROM_UNIXTIME(UNIX_TIMESTAMP(TS),'%Y-%m-%d %H:%i:%s') AS TS`,
+		},
+		{
+			name:       "normalized",
+			compareCfg: config.CompareConfig{NumericRoundUnit: 0.01, CaseFoldColumns: []string{"NAME"}, TrimChar: true, TimestampPrecision: 3},
+			wantSource: `DECODE(SUBSTR(ROUND(CAST(ID AS NUMBER),2),1,1),'.','0' || ROUND(CAST(ID AS NUMBER),2),ROUND(CAST(ID AS NUMBER),2)) AS ID,` +
+				`UPPER(NAME) AS NAME,NVL(RTRIM(CODE),'') AS CODE,SUBSTR(TO_CHAR(TS,'yyyy-MM-dd HH24:mi:ss.FF9'),1,23) AS TS`,
+			wantTarget: `CAST(0 + CAST(ROUND(ID,2) AS CHAR) AS CHAR) AS ID,` +
+				`UPPER(NAME) AS NAME,IFNULL(RTRIM(CODE),'') AS CODE,SUBSTRING(DATE_FORMAT(TS,'%Y-%m-%d %H:%i:%s.%f'),1,23) AS TS`,
+			wantNormalized: true,
+		},
+		{
+			name:           "exclude and mask",
+			compareCfg:     config.CompareConfig{ExcludeColumns: []string{"CODE"}},
+			maskColumns:    map[string]meta.ColumnMaskRule{"TS": {}},
+			wantSource:     `DECODE(SUBSTR(ID,1,1),'.','0' || ID,ID) AS ID,NAME`,
+			wantTarget:     `CAST(0 + CAST(ID AS CHAR) AS CHAR) AS ID,NAME`,
+			wantNormalized: true,
+		},
+		{
+			name:           "all excluded",
+			compareCfg:     config.CompareConfig{ExcludeColumns: []string{"ID", "NAME", "CODE", "TS"}},
+			wantNormalized: true,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.compareCfg.SourceTable = "T1"
+			cfg := &config.Config{}
+			cfg.SchemaConfig.SourceSchema = "MARVIN"
+			cfg.SchemaConfig.CompareConfig = []config.CompareConfig{tt.compareCfg}
+			task := &Task{cfg: cfg, sourceTableName: "T1", maskColumns: tt.maskColumns}
+
+			source, target, err := task.genDBSelectColumn(columnInfo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("genDBSelectColumn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if source != tt.wantSource || target != tt.wantTarget {
+				t.Errorf("genDBSelectColumn() = %s | %s, want %s | %s", source, target, tt.wantSource, tt.wantTarget)
+			}
+			if got := task.isNormalized(); got != tt.wantNormalized {
+				t.Errorf("isNormalized() = %v, want %v", got, tt.wantNormalized)
+			}
+		})
+	}
+}
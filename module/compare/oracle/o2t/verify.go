@@ -0,0 +1,281 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package o2t
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/database/mysql"
+	"github.com/wentaojin/transferdb/database/oracle"
+	"github.com/wentaojin/transferdb/module/compare/oracle/public"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verify ALL 模式增量在线校验
+// 增量应用至 SCN 并暂停应用期间，源端 AS OF SCN 与目标端按 chunk 轮转对比，结果记录于 [data_compare_meta] task_mode VERIFY
+type Verify struct {
+	ctx             context.Context
+	cfg             *config.Config
+	oracle          *oracle.Oracle
+	mysql           *mysql.MySQL
+	metaDB          *meta.Meta
+	oracleCollation bool
+	lastVerify      time.Time
+}
+
+func NewVerify(ctx context.Context, cfg *config.Config, oracle *oracle.Oracle, mysql *mysql.MySQL, metaDB *meta.Meta) (*Verify, error) {
+	oraDBVersion, err := oracle.GetOracleDBVersion()
+	if err != nil {
+		return nil, err
+	}
+	// chunk 切分以及对比沿用 [compare] 配置，任务模式区分 ALL 以及 COMPARE，不更新 [wait_sync_meta] 全量记录
+	verifyCfg := *cfg
+	verifyCfg.TaskMode = common.TaskModeVerify
+	return &Verify{
+		ctx:             ctx,
+		cfg:             &verifyCfg,
+		oracle:          oracle,
+		mysql:           mysql,
+		metaDB:          metaDB,
+		oracleCollation: common.VersionOrdinal(oraDBVersion) >= common.VersionOrdinal(common.OracleTableColumnCollationDBVersion),
+	}, nil
+}
+
+// Verify 增量已应用至 appliedSCN 且应用暂停时调用，距上次校验不足 verify-interval 跳过
+// chunk 对比失败或者不一致记录 FAILED 并优先于下次校验重新对比，不中断增量同步
+func (v *Verify) Verify(appliedSCN uint64) error {
+	if time.Since(v.lastVerify) < time.Duration(v.cfg.AllConfig.VerifyInterval)*time.Second {
+		return nil
+	}
+	startTime := time.Now()
+	defer func() {
+		v.lastVerify = time.Now()
+	}()
+
+	if err := v.splitTableChunks(appliedSCN); err != nil {
+		return err
+	}
+
+	compareMetas, err := meta.NewDataCompareMetaModel(v.metaDB).DetailDataCompareMetaByLeastRecent(v.ctx, &meta.DataCompareMeta{
+		DBTypeS:     v.cfg.DBTypeS,
+		DBTypeT:     v.cfg.DBTypeT,
+		SchemaNameS: v.cfg.SchemaConfig.SourceSchema,
+		TaskMode:    v.cfg.TaskMode,
+	}, v.cfg.AllConfig.VerifyChunks)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu                    sync.Mutex
+		fixSQL                strings.Builder
+		equals, drifts, fails int
+	)
+	normalizedTables := make(map[string]bool)
+	g := &errgroup.Group{}
+	g.SetLimit(v.cfg.DiffConfig.DiffThreads)
+	for _, compareMeta := range compareMetas {
+		normalized, ok := normalizedTables[compareMeta.TableNameS]
+		if !ok {
+			if normalized, err = v.isNormalized(compareMeta.TableNameS); err != nil {
+				return err
+			}
+			normalizedTables[compareMeta.TableNameS] = normalized
+		}
+		newReport := NewReport(compareMeta, v.mysql, v.oracle, v.cfg.DiffConfig.OnlyCheckRows)
+		newReport.SourceSCN = appliedSCN
+		newReport.SetLobDataFile(v.cfg.DiffConfig.LobInlineSize, v.cfg.DiffConfig.FixSqlDir)
+		newReport.Normalized = normalized
+		g.Go(func() error {
+			updates := map[string]interface{}{
+				"TaskStatus":  common.TaskStatusSuccess,
+				"InfoDetail":  newReport.String(),
+				"ErrorDetail": "",
+			}
+			report, err := public.IReport(newReport)
+			mu.Lock()
+			switch {
+			case err != nil:
+				fails++
+				updates["TaskStatus"] = common.TaskStatusFailed
+				updates["ErrorDetail"] = err.Error()
+			case report != "":
+				drifts++
+				updates["TaskStatus"] = common.TaskStatusFailed
+				updates["ErrorDetail"] = fmt.Sprintf("schema table data chunk isn't equal at source scn [%d]", appliedSCN)
+				fixSQL.WriteString(report)
+				zap.L().Warn("increment verify table chunk drift",
+					zap.String("schema", newReport.DataCompareMeta.SchemaNameS),
+					zap.String("table", newReport.DataCompareMeta.TableNameS),
+					zap.String("chunk", newReport.DataCompareMeta.WhereRange),
+					zap.Uint64("source scn", appliedSCN))
+			default:
+				equals++
+			}
+			mu.Unlock()
+
+			return meta.NewDataCompareMetaModel(v.metaDB).UpdateDataCompareMeta(v.ctx, &meta.DataCompareMeta{
+				DBTypeS:     newReport.DataCompareMeta.DBTypeS,
+				DBTypeT:     newReport.DataCompareMeta.DBTypeT,
+				SchemaNameS: newReport.DataCompareMeta.SchemaNameS,
+				TableNameS:  newReport.DataCompareMeta.TableNameS,
+				TaskMode:    newReport.DataCompareMeta.TaskMode,
+				WhereRange:  newReport.DataCompareMeta.WhereRange,
+			}, updates)
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return fmt.Errorf("increment verify task failed, update table [data_compare_meta] failed: %v", err)
+	}
+
+	if fixSQL.Len() > 0 {
+		if err = v.writeFixSQL(appliedSCN, fixSQL.String()); err != nil {
+			return err
+		}
+	}
+
+	if drifts > 0 || fails > 0 {
+		zap.L().Warn("increment verify table chunk finished",
+			zap.String("schema", v.cfg.SchemaConfig.SourceSchema),
+			zap.Uint64("source scn", appliedSCN),
+			zap.Int("chunk equal", equals),
+			zap.Int("chunk drift", drifts),
+			zap.Int("chunk error", fails),
+			zap.String("failed tips", "failed detail, please see table [data_compare_meta] task_mode [VERIFY]"),
+			zap.String("cost", time.Now().Sub(startTime).String()))
+		return nil
+	}
+	zap.L().Info("increment verify table chunk finished",
+		zap.String("schema", v.cfg.SchemaConfig.SourceSchema),
+		zap.Uint64("source scn", appliedSCN),
+		zap.Int("chunk equal", equals),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return nil
+}
+
+// 增量同步表首次校验前切分 chunk，切分失败跳过该表不影响增量同步，下次校验重试
+func (v *Verify) splitTableChunks(appliedSCN uint64) error {
+	incrSyncMetas, err := meta.NewIncrSyncMetaModel(v.metaDB).DetailIncrSyncMetaBySchema(v.ctx, &meta.IncrSyncMeta{
+		DBTypeS:     v.cfg.DBTypeS,
+		DBTypeT:     v.cfg.DBTypeT,
+		SchemaNameS: v.cfg.SchemaConfig.SourceSchema,
+	})
+	if err != nil {
+		return err
+	}
+	for cid, incrMeta := range incrSyncMetas {
+		chunkCounts, err := meta.NewDataCompareMetaModel(v.metaDB).CountsDataCompareMetaByTaskTable(v.ctx, &meta.DataCompareMeta{
+			DBTypeS:     v.cfg.DBTypeS,
+			DBTypeT:     v.cfg.DBTypeT,
+			SchemaNameS: v.cfg.SchemaConfig.SourceSchema,
+			TableNameS:  incrMeta.TableNameS,
+			TaskMode:    v.cfg.TaskMode,
+		})
+		if err != nil {
+			return err
+		}
+		if chunkCounts > 0 {
+			continue
+		}
+		if err = v.splitTableChunk(cid, appliedSCN, incrMeta); err != nil {
+			zap.L().Warn("increment verify table chunk split failed, skip",
+				zap.String("schema", incrMeta.SchemaNameS),
+				zap.String("table", incrMeta.TableNameS),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (v *Verify) splitTableChunk(cid int, appliedSCN uint64, incrMeta meta.IncrSyncMeta) error {
+	task := &Task{
+		ctx:             v.ctx,
+		cfg:             v.cfg,
+		sourceTableName: common.StringUPPER(incrMeta.TableNameS),
+		targetTableName: common.StringUPPER(incrMeta.TableNameT),
+		oracleCollation: v.oracleCollation,
+		mysql:           v.mysql,
+		oracle:          v.oracle,
+	}
+	var err error
+	task.maskColumns, err = meta.NewColumnMaskRuleModel(v.metaDB).GetColumnMaskRuleMap(v.ctx, &meta.ColumnMaskRule{
+		DBTypeS:     v.cfg.DBTypeS,
+		DBTypeT:     v.cfg.DBTypeT,
+		SchemaNameS: v.cfg.SchemaConfig.SourceSchema,
+		TableNameS:  task.sourceTableName,
+	})
+	if err != nil {
+		return err
+	}
+	sourceColumnInfo, targetColumnInfo, err := task.AdjustDBSelectColumn()
+	if err != nil {
+		return err
+	}
+	whereColumn, err := task.FilterDBWhereColumn()
+	if err != nil {
+		return err
+	}
+	isPartition, err := task.IsPartitionTable()
+	if err != nil {
+		return err
+	}
+	return public.IChunker(NewChunk(v.ctx, v.cfg, v.oracle, v.mysql, v.metaDB,
+		cid, appliedSCN, task.sourceTableName, task.targetTableName, isPartition, sourceColumnInfo, targetColumnInfo,
+		whereColumn, task.oracleCollation))
+}
+
+// 表对比字段是否存在规整，规整表不一致 chunk 仅输出注释
+func (v *Verify) isNormalized(tableName string) (bool, error) {
+	task := &Task{cfg: v.cfg, sourceTableName: common.StringUPPER(tableName)}
+	var err error
+	task.maskColumns, err = meta.NewColumnMaskRuleModel(v.metaDB).GetColumnMaskRuleMap(v.ctx, &meta.ColumnMaskRule{
+		DBTypeS:     v.cfg.DBTypeS,
+		DBTypeT:     v.cfg.DBTypeT,
+		SchemaNameS: v.cfg.SchemaConfig.SourceSchema,
+		TableNameS:  task.sourceTableName,
+	})
+	if err != nil {
+		return false, err
+	}
+	return task.isNormalized(), nil
+}
+
+// 不一致 chunk 修复 SQL 追加写入 verify_${schema}.sql，仅反映对应 SCN 时刻差异
+func (v *Verify) writeFixSQL(appliedSCN uint64, fixSQL string) error {
+	if err := common.PathExist(v.cfg.DiffConfig.FixSqlDir); err != nil {
+		return err
+	}
+	verifyFile := filepath.Join(v.cfg.DiffConfig.FixSqlDir, fmt.Sprintf("verify_%s.sql", v.cfg.SchemaConfig.SourceSchema))
+	f, err := os.OpenFile(verifyFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(fmt.Sprintf("/* increment verify source scn [%d] time [%s] */\n%s",
+		appliedSCN, time.Now().Format("2006-01-02 15:04:05"), fixSQL)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
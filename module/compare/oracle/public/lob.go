@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package public
+
+import (
+	"encoding/hex"
+	"github.com/wentaojin/transferdb/common"
+	"strings"
+)
+
+// LOB 字段两端 MD5 哈希对比，避免拉取完整字段值，NULL 以及空 LOB 统一 NULL
+// ORACLE DBMS_CRYPTO.HASH 类型 2 即 HASH_MD5，CLOB 以数据库字符集编码计算，需 IsLobHashCharset 判断是否与目标端一致
+func GenLobHashColumnS(columnName string) string {
+	return common.StringsBuilder("CASE WHEN ", columnName, " IS NULL OR DBMS_LOB.GETLENGTH(", columnName, ") = 0 THEN NULL ELSE LOWER(RAWTOHEX(DBMS_CRYPTO.HASH(", columnName, ",2))) END")
+}
+
+// 目标端字符 LOB 转换 utf8mb4 后计算，与源端 AL32UTF8 编码一致
+func GenLobHashColumnT(columnName string, isCharacter bool) string {
+	hashValue := columnName
+	if isCharacter {
+		hashValue = common.StringsBuilder("CONVERT(", columnName, " USING utf8mb4)")
+	}
+	return common.StringsBuilder("CASE WHEN ", columnName, " IS NULL OR LENGTH(", columnName, ") = 0 THEN NULL ELSE MD5(", hashValue, ") END")
+}
+
+// 字符 LOB 哈希以源端数据库字符集编码计算，仅数据库字符集以及实际存储编码均为 AL32UTF8 时与目标端 utf8mb4 编码一致
+// NCLOB 以国家字符集编码，不适用哈希对比
+func IsLobHashCharset(charset, actualCharset string) bool {
+	return strings.EqualFold(charset, common.ORACLECharsetAL32UTF8) && strings.EqualFold(actualCharset, common.ORACLECharsetAL32UTF8)
+}
+
+// 对比查询字段是否 LOB 哈希字段
+func IsLobHashColumn(columnDetailS, columnName string) bool {
+	return strings.Contains(columnDetailS, GenLobHashColumnS(columnName))
+}
+
+// 修复语句 LOB 字段值十六进制字面量，字符 LOB 按 utf8mb4 转换写入
+func GenLobHexLiteral(value []byte, isCharacter bool) string {
+	literal := common.StringsBuilder("X'", strings.ToUpper(hex.EncodeToString(value)), "'")
+	if isCharacter {
+		return common.StringsBuilder("CONVERT(", literal, " USING utf8mb4)")
+	}
+	return literal
+}
+
+// 修复语句 LOB 字段值超过 lob-inline-size 写入数据文件，目标端 LOAD_FILE 读取
+func GenLobFileLiteral(dataFile string, isCharacter bool) string {
+	literal := common.StringsBuilder("LOAD_FILE('", strings.ReplaceAll(dataFile, "'", "''"), "')")
+	if isCharacter {
+		return common.StringsBuilder("CONVERT(", literal, " USING utf8mb4)")
+	}
+	return literal
+}
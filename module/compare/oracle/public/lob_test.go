@@ -0,0 +1,47 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package public
+
+import "testing"
+
+func TestGenLobHashColumn(t *testing.T) {
+	columnDetailS := "ID," + GenLobHashColumnS("DOC") + " AS DOC"
+	if !IsLobHashColumn(columnDetailS, "DOC") || IsLobHashColumn(columnDetailS, "ID") {
+		t.Errorf("IsLobHashColumn() with column detail %v isn't expected", columnDetailS)
+	}
+	if want := "CASE WHEN DOC IS NULL OR LENGTH(DOC) = 0 THEN NULL ELSE MD5(CONVERT(DOC USING utf8mb4)) END"; GenLobHashColumnT("DOC", true) != want {
+		t.Errorf("GenLobHashColumnT() = %v, want %v", GenLobHashColumnT("DOC", true), want)
+	}
+	if got := GenLobHexLiteral([]byte{0x0a, 0xff}, false); got != "X'0AFF'" {
+		t.Errorf("GenLobHexLiteral() = %v, want X'0AFF'", got)
+	}
+}
+
+func TestIsLobHashCharset(t *testing.T) {
+	tests := []struct {
+		charset, actualCharset string
+		want                   bool
+	}{
+		{charset: "AL32UTF8", actualCharset: "AL32UTF8", want: true},
+		{charset: "ZHS16GBK", actualCharset: "ZHS16GBK", want: false},
+		{charset: "AL32UTF8", actualCharset: "ZHS16GBK", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsLobHashCharset(tt.charset, tt.actualCharset); got != tt.want {
+			t.Errorf("IsLobHashCharset(%s, %s) = %v, want %v", tt.charset, tt.actualCharset, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package public
+
+import (
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"strings"
+)
+
+// SplitColumn 采样切分字段
+// 源端字段保持原样以便走索引，字符类型字段要求 ORACLE 二进制排序
+// 目标端字符类型字段若非源端字符集对应的 _bin 排序规则，则转换字符集并以 _bin 排序规则比较，保证两端边界一致
+type SplitColumn struct {
+	ColumnName string
+	DataType   string
+	Nullable   bool
+	TargetExpr string
+}
+
+// 采样切分支持的 ORACLE 字段类型，字符类型需结合排序规则判断
+func IsSplitSupportDataType(dataType string) bool {
+	switch {
+	case isSplitNumberType(dataType), isSplitStringType(dataType), isSplitDateType(dataType), isSplitTimestampType(dataType):
+		return true
+	default:
+		return false
+	}
+}
+
+// ORACLE 字符排序规则是否二进制排序
+func IsSplitBinaryCollation(collation string) bool {
+	switch common.StringUPPER(collation) {
+	case "", "BINARY", "BINARY_CS":
+		return true
+	default:
+		return false
+	}
+}
+
+// 目标端字段表达式，目标端字段字符集排序规则与源端二进制排序一致直接使用字段，否则转换字符集并以 _bin 排序规则比较
+func GenSplitTargetExpr(columnName, dataType, sourceCharset, targetColumnCharset, targetColumnCollation string) string {
+	if !isSplitStringType(dataType) {
+		return columnName
+	}
+	charset := strings.ToLower(sourceCharset)
+	if strings.EqualFold(targetColumnCharset, charset) && strings.EqualFold(targetColumnCollation, common.StringsBuilder(charset, "_bin")) {
+		return columnName
+	}
+	return common.StringsBuilder("CONVERT(", columnName, " USING ", charset, ") COLLATE ", charset, "_bin")
+}
+
+// 采样查询字段，时间类型统一 TO_CHAR 格式化，便于两端生成边界值
+func GenSplitSelectColumn(col SplitColumn) string {
+	switch {
+	case isSplitDateType(col.DataType):
+		return common.StringsBuilder("TO_CHAR(", col.ColumnName, ",'YYYY-MM-DD HH24:MI:SS') AS ", col.ColumnName)
+	case isSplitTimestampType(col.DataType):
+		return common.StringsBuilder("TO_CHAR(", col.ColumnName, ",'YYYY-MM-DD HH24:MI:SS.FF6') AS ", col.ColumnName)
+	default:
+		return col.ColumnName
+	}
+}
+
+// GenSplitRanges 根据采样边界生成源端以及目标端 chunk 范围，边界需按字段顺序升序排列
+// 左闭右开：(-∞, b1)、[b1, b2)、...、[bn, +∞)，可空字段额外生成 IS NULL chunk
+func GenSplitRanges(cols []SplitColumn, boundaries [][]string) ([]string, []string) {
+	var (
+		sourceRanges, targetRanges []string
+		notNullS, nullS            []string
+	)
+	for _, c := range cols {
+		if c.Nullable {
+			notNullS = append(notNullS, common.StringsBuilder(c.ColumnName, " IS NOT NULL"))
+			nullS = append(nullS, common.StringsBuilder(c.ColumnName, " IS NULL"))
+		}
+	}
+
+	for i := 0; i <= len(boundaries); i++ {
+		var condS, condT []string
+		if i > 0 {
+			condS = append(condS, genTupleRange(cols, boundaries[i-1], ">=", false))
+			condT = append(condT, genTupleRange(cols, boundaries[i-1], ">=", true))
+		}
+		if i < len(boundaries) {
+			condS = append(condS, genTupleRange(cols, boundaries[i], "<", false))
+			condT = append(condT, genTupleRange(cols, boundaries[i], "<", true))
+		}
+		// 联合字段存在可空字段，排除 NULL 值避免跨 chunk 重复或遗漏
+		if len(cols) > 1 {
+			condS = append(condS, notNullS...)
+			condT = append(condT, notNullS...)
+		}
+		sourceRanges = append(sourceRanges, strings.Join(condS, " AND "))
+		targetRanges = append(targetRanges, strings.Join(condT, " AND "))
+	}
+
+	if len(nullS) > 0 {
+		sourceRanges = append(sourceRanges, common.StringsBuilder("(", strings.Join(nullS, " OR "), ")"))
+		targetRanges = append(targetRanges, common.StringsBuilder("(", strings.Join(nullS, " OR "), ")"))
+	}
+	return sourceRanges, targetRanges
+}
+
+// GenKeyInRange 根据键值生成源端以及目标端 IN 列表条件，联合字段 (A,B) IN ((x,y),...)
+func GenKeyInRange(cols []SplitColumn, keys [][]string) (string, string) {
+	var colsS, colsT, valuesS, valuesT []string
+	for _, c := range cols {
+		colsS = append(colsS, c.ColumnName)
+		colsT = append(colsT, c.TargetExpr)
+	}
+	for _, key := range keys {
+		var vs, vt []string
+		for i, c := range cols {
+			vs = append(vs, genSplitLiteral(c.DataType, key[i], false))
+			vt = append(vt, genSplitLiteral(c.DataType, key[i], true))
+		}
+		valuesS = append(valuesS, genTuple(vs))
+		valuesT = append(valuesT, genTuple(vt))
+	}
+	return common.StringsBuilder(genTuple(colsS), " IN (", strings.Join(valuesS, ","), ")"),
+		common.StringsBuilder(genTuple(colsT), " IN (", strings.Join(valuesT, ","), ")")
+}
+
+func genTuple(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return common.StringsBuilder("(", strings.Join(items, ","), ")")
+}
+
+// 联合字段按字典序展开，ORACLE 不支持行值比较
+// (A,B) >= (x,y) -> (A > x OR (A = x AND B >= y))
+func genTupleRange(cols []SplitColumn, values []string, op string, isTarget bool) string {
+	colExpr := cols[0].ColumnName
+	if isTarget {
+		colExpr = cols[0].TargetExpr
+	}
+	val := genSplitLiteral(cols[0].DataType, values[0], isTarget)
+	if len(cols) == 1 {
+		return common.StringsBuilder(colExpr, " ", op, " ", val)
+	}
+	strictOp := strings.TrimSuffix(op, "=")
+	return common.StringsBuilder("(", colExpr, " ", strictOp, " ", val, " OR (", colExpr, " = ", val, " AND ",
+		genTupleRange(cols[1:], values[1:], op, isTarget), "))")
+}
+
+func genSplitLiteral(dataType, value string, isTarget bool) string {
+	switch {
+	case isSplitNumberType(dataType):
+		return value
+	case isSplitDateType(dataType):
+		if isTarget {
+			return common.StringsBuilder("'", value, "'")
+		}
+		return common.StringsBuilder("TO_DATE('", value, "','YYYY-MM-DD HH24:MI:SS')")
+	case isSplitTimestampType(dataType):
+		if isTarget {
+			return common.StringsBuilder("'", value, "'")
+		}
+		return common.StringsBuilder("TO_TIMESTAMP('", value, "','YYYY-MM-DD HH24:MI:SS.FF6')")
+	default:
+		// CHAR 定长补齐空格，ORACLE 空格填充比较语义，MySQL 读取去除尾部空格，统一去除尾部空格
+		if strings.Contains(common.StringUPPER(dataType), "CHAR") && !strings.Contains(common.StringUPPER(dataType), "VARCHAR") {
+			value = strings.TrimRight(value, " ")
+		}
+		value = strings.ReplaceAll(value, "'", "''")
+		if isTarget {
+			value = strings.ReplaceAll(value, `\`, `\\`)
+		}
+		return common.StringsBuilder("'", value, "'")
+	}
+}
+
+func isSplitNumberType(dataType string) bool {
+	switch common.StringUPPER(dataType) {
+	case "NUMBER", "DECIMAL", "DEC", "INTEGER", "INT", "SMALLINT", "NUMERIC", "FLOAT", "REAL", "DOUBLE PRECISION":
+		return true
+	default:
+		return false
+	}
+}
+
+func isSplitStringType(dataType string) bool {
+	switch common.StringUPPER(dataType) {
+	case "CHAR", "NCHAR", "VARCHAR", "VARCHAR2", "NVARCHAR2", "CHARACTER":
+		return true
+	default:
+		return false
+	}
+}
+
+func isSplitDateType(dataType string) bool {
+	return strings.EqualFold(dataType, "DATE")
+}
+
+func isSplitTimestampType(dataType string) bool {
+	dataType = common.StringUPPER(dataType)
+	return strings.HasPrefix(dataType, "TIMESTAMP") && !strings.Contains(dataType, "TIME ZONE")
+}
+
+// 采样边界值校验，边界值不允许 NULL
+func ValidSplitBoundary(cols []SplitColumn, row map[string]string) ([]string, error) {
+	var values []string
+	for _, c := range cols {
+		v, ok := row[c.ColumnName]
+		if !ok || v == "NULLABLE" {
+			return nil, fmt.Errorf("split column [%s] boundary value isn't exist or null", c.ColumnName)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package public
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenSplitRanges(t *testing.T) {
+	cols := []SplitColumn{
+		{ColumnName: "NAME", DataType: "VARCHAR2", TargetExpr: GenSplitTargetExpr("NAME", "VARCHAR2", "UTF8MB4", "utf8mb4", "utf8mb4_general_ci")},
+		{ColumnName: "ID", DataType: "NUMBER", Nullable: true, TargetExpr: "ID"},
+	}
+	sourceRanges, targetRanges := GenSplitRanges(cols, [][]string{{`a'b\`, "10"}})
+
+	wantSource := []string{
+		`(NAME < 'a''b\' OR (NAME = 'a''b\' AND ID < 10)) AND ID IS NOT NULL`,
+		`(NAME > 'a''b\' OR (NAME = 'a''b\' AND ID >= 10)) AND ID IS NOT NULL`,
+		`(ID IS NULL)`,
+	}
+	wantTarget := []string{
+		`(CONVERT(NAME USING utf8mb4) COLLATE utf8mb4_bin < 'a''b\\' OR (CONVERT(NAME USING utf8mb4) COLLATE utf8mb4_bin = 'a''b\\' AND ID < 10)) AND ID IS NOT NULL`,
+		`(CONVERT(NAME USING utf8mb4) COLLATE utf8mb4_bin > 'a''b\\' OR (CONVERT(NAME USING utf8mb4) COLLATE utf8mb4_bin = 'a''b\\' AND ID >= 10)) AND ID IS NOT NULL`,
+		`(ID IS NULL)`,
+	}
+	if !reflect.DeepEqual(sourceRanges, wantSource) {
+		t.Errorf("GenSplitRanges() source = %v, want %v", sourceRanges, wantSource)
+	}
+	if !reflect.DeepEqual(targetRanges, wantTarget) {
+		t.Errorf("GenSplitRanges() target = %v, want %v", targetRanges, wantTarget)
+	}
+	if expr := GenSplitTargetExpr("NAME", "VARCHAR2", "UTF8MB4", "utf8mb4", "utf8mb4_bin"); expr != "NAME" {
+		t.Errorf("GenSplitTargetExpr() = %v, want NAME", expr)
+	}
+}
+
+func TestGenKeyInRange(t *testing.T) {
+	cols := []SplitColumn{
+		{ColumnName: "ID", DataType: "NUMBER", TargetExpr: "ID"},
+		{ColumnName: "CODE", DataType: "CHAR", TargetExpr: "CODE"},
+	}
+	source, target := GenKeyInRange(cols, [][]string{{"1", "a  "}, {"2", `b'\`}})
+	if want := `(ID,CODE) IN ((1,'a'),(2,'b''\'))`; source != want {
+		t.Errorf("GenKeyInRange() source = %v, want %v", source, want)
+	}
+	if want := `(ID,CODE) IN ((1,'a'),(2,'b''\\'))`; target != want {
+		t.Errorf("GenKeyInRange() target = %v, want %v", target, want)
+	}
+	if source, _ = GenKeyInRange(cols[:1], [][]string{{"1"}, {"2"}}); source != "ID IN (1,2)" {
+		t.Errorf("GenKeyInRange() single column source = %v, want ID IN (1,2)", source)
+	}
+}
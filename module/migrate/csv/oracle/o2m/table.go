@@ -30,6 +30,7 @@ import (
 	"github.com/wentaojin/transferdb/config"
 	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/database/oracle"
+	"github.com/wentaojin/transferdb/module/migrate"
 	"go.uber.org/zap"
 )
 
@@ -41,12 +42,13 @@ type Rows struct {
 	DBCharsetS   string
 	DBCharsetT   string
 	ColumnNameS  []string
+	Rejecter     *migrate.Rejecter
 	ReadChannel  chan [][]string
 	WriteChannel chan string
 }
 
 func NewRows(ctx context.Context, syncMeta meta.FullSyncMeta,
-	oracle *oracle.Oracle, cfg *config.Config, columnNameS []string, sourceDBCharset string) *Rows {
+	oracle *oracle.Oracle, cfg *config.Config, columnNameS []string, sourceDBCharset string, rejecter *migrate.Rejecter) *Rows {
 
 	writeChannel := make(chan string, common.ChannelBufferSize)
 	readChannel := make(chan [][]string, common.ChannelBufferSize)
@@ -59,6 +61,7 @@ func NewRows(ctx context.Context, syncMeta meta.FullSyncMeta,
 		DBCharsetS:   sourceDBCharset,
 		DBCharsetT:   common.StringUPPER(cfg.CSVConfig.Charset),
 		ColumnNameS:  columnNameS,
+		Rejecter:     rejecter,
 		ReadChannel:  readChannel,
 		WriteChannel: writeChannel,
 	}
@@ -72,7 +75,7 @@ func (t *Rows) ReadData() error {
 		columnDetailS  string
 	)
 
-	convertRaw, err := common.CharsetConvert([]byte(t.SyncMeta.ColumnDetailS), common.CharsetUTF8MB4, common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(t.Cfg.OracleConfig.Charset)])
+	convertRaw, err := common.CharsetConvert([]byte(t.SyncMeta.ColumnDetailS), common.CharsetUTF8MB4, common.MigrateOracleCharsetStringConvertMapping[t.Cfg.OracleConfig.ActualCharset])
 	if err != nil {
 		return fmt.Errorf("schema [%s] table [%s] column [%s] charset convert failed, %v", t.SyncMeta.SchemaNameS, t.SyncMeta.TableNameS, t.SyncMeta.ColumnDetailS, err)
 	}
@@ -93,7 +96,7 @@ func (t *Rows) ReadData() error {
 		execQuerySQL = common.StringsBuilder(`SELECT `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, t.SyncMeta.ChunkDetailS)
 	}
 
-	err = t.Oracle.GetOracleTableRowsDataCSV(execQuerySQL, t.DBCharsetS, t.DBCharsetT, t.Cfg, t.ReadChannel, t.ColumnNameS)
+	err = t.Oracle.GetOracleTableRowsDataCSV(execQuerySQL, t.DBCharsetS, t.DBCharsetT, t.Cfg, t.Rejecter.ReadRejectFunc(t.SyncMeta.ChunkDetailS), t.ReadChannel, t.ColumnNameS)
 	if err != nil {
 		// 通道关闭
 		close(t.ReadChannel)
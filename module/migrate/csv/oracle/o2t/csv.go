@@ -29,7 +29,9 @@ import (
 	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/database/mysql"
 	"github.com/wentaojin/transferdb/database/oracle"
+	"github.com/wentaojin/transferdb/module/migrate"
 	"github.com/wentaojin/transferdb/module/migrate/csv/oracle/public"
+	"github.com/wentaojin/transferdb/module/pool"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -47,6 +49,10 @@ func NewCSV(ctx context.Context, cfg *config.Config) (*CSV, error) {
 	if err != nil {
 		return nil, err
 	}
+	oracleDB.Throttle, err = oracle.NewThrottle(ctx, oracleDB, cfg.ThrottleConfig)
+	if err != nil {
+		return nil, err
+	}
 	mysqlDB, err := mysql.NewMySQLDBEngine(ctx, cfg.MySQLConfig)
 	if err != nil {
 		return nil, err
@@ -65,6 +71,7 @@ func NewCSV(ctx context.Context, cfg *config.Config) (*CSV, error) {
 }
 
 func (r *CSV) CSV() error {
+	defer r.Oracle.Throttle.Close()
 	startTime := time.Now()
 	zap.L().Info("source schema full table data csv start",
 		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema))
@@ -125,6 +132,16 @@ func (r *CSV) CSV() error {
 			return err
 		}
 
+		err = meta.NewRejectRowDetailModel(r.MetaDB).DeleteRejectRowDetailBySchemaTaskMode(r.Ctx, &meta.RejectRowDetail{
+			DBTypeS:     r.Cfg.DBTypeS,
+			DBTypeT:     r.Cfg.DBTypeT,
+			SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+			TaskMode:    r.Cfg.TaskMode,
+		})
+		if err != nil {
+			return err
+		}
+
 		for _, tableName := range exporters {
 			err = meta.NewWaitSyncMetaModel(r.MetaDB).DeleteWaitSyncMeta(r.Ctx, &meta.WaitSyncMeta{
 				DBTypeS:     r.Cfg.DBTypeS,
@@ -318,13 +335,13 @@ func (r *CSV) CSV() error {
 	// 优先存在断点的表
 	// partTableTask -> waitTableTasks
 	if len(partSyncTables) > 0 {
-		err = r.csvPartSyncTable(partSyncTables, sourceDBCharset)
+		err = r.csvPartSyncTable(partSyncTables, r.Cfg.OracleConfig.ActualCharset)
 		if err != nil {
 			return err
 		}
 	}
 	if len(waitSyncTables) > 0 {
-		err = r.csvWaitSyncTable(waitSyncTables, sourceDBCharset, oracleCollation)
+		err = r.csvWaitSyncTable(waitSyncTables, r.Cfg.OracleConfig.ActualCharset, oracleCollation)
 		if err != nil {
 			return err
 		}
@@ -421,14 +438,43 @@ func (r *CSV) csvPartSyncTable(csvPartTables []string, sourceDBCharset string) e
 				return nil
 			}
 
-			g1 := &errgroup.Group{}
-			g1.SetLimit(r.Cfg.CSVConfig.SQLThreads)
+			// 坏行隔离，重新导出的 chunk 清理已有隔离记录
+			var rejecter *migrate.Rejecter
+			if r.Cfg.RejectConfig.Enable && len(waitFullMetas) > 0 {
+				var chunks []string
+				for _, fullMeta := range waitFullMetas {
+					chunks = append(chunks, fullMeta.ChunkDetailS)
+				}
+				err = meta.NewRejectRowDetailModel(r.MetaDB).DeleteRejectRowDetailByChunks(r.Ctx, &meta.RejectRowDetail{
+					DBTypeS:     r.Cfg.DBTypeS,
+					DBTypeT:     r.Cfg.DBTypeT,
+					SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+					TableNameS:  common.StringUPPER(t),
+					TaskMode:    r.Cfg.TaskMode,
+				}, chunks)
+				if err != nil {
+					return err
+				}
+				rejecter, err = migrate.NewRejecter(r.Ctx, r.Cfg, r.MetaDB, common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema), common.StringUPPER(t),
+					waitFullMetas[0].SchemaNameT, waitFullMetas[0].TableNameT)
+				if err != nil {
+					return err
+				}
+				defer rejecter.Close()
+			}
+
+			// chunk 并发调度，开启 adaptive-concurrency 依据写入延迟自动调整并发
+			sched := pool.NewScheduler(common.StringsBuilder(r.Cfg.TaskMode, "/", common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema), ".", common.StringUPPER(t)),
+				r.Cfg.CSVConfig.SQLThreads, r.Cfg.AppConfig)
+			defer sched.Release()
+			g1 := sched.NewGroup()
 
 			for _, fullSyncMeta := range waitFullMetas {
 				m := fullSyncMeta
 				g1.Go(func() error {
-					err = public.IMigrate(NewRows(r.Ctx, m, r.Oracle, r.Cfg, columnNameS, common.MigrateOracleCharsetStringConvertMapping[sourceDBCharset]))
+					err = public.IMigrate(NewRows(r.Ctx, m, r.Oracle, r.Cfg, columnNameS, common.MigrateOracleCharsetStringConvertMapping[sourceDBCharset], rejecter))
 					if err != nil {
+						sched.Feedback(err)
 						// record error, skip error
 						errf := meta.NewCommonModel(r.MetaDB).UpdateFullSyncMetaChunkAndCreateChunkErrorDetail(r.Ctx, &meta.FullSyncMeta{
 							DBTypeS:      m.DBTypeS,
@@ -838,18 +884,38 @@ func (r *CSV) AdjustTableSelectColumn(sourceTable string, oracleCollation bool)
 		return "", err
 	}
 
+	// 字段脱敏规则
+	maskRules, err := meta.NewColumnMaskRuleModel(r.MetaDB).GetColumnMaskRuleMap(r.Ctx, &meta.ColumnMaskRule{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+		TableNameS:  sourceTable,
+	})
+	if err != nil {
+		return "", err
+	}
+
 	var columnNames []string
 
 	for _, rowCol := range columnsINFO {
 		columnName := rowCol["COLUMN_NAME"]
 
 		// 以 utf8mb4 字符集存储 meta
-		convertUtf8Raw, err := common.CharsetConvert([]byte(columnName), common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(r.Cfg.OracleConfig.Charset)], common.CharsetUTF8MB4)
+		convertUtf8Raw, err := common.CharsetConvert([]byte(columnName), common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset], common.CharsetUTF8MB4)
 		if err != nil {
 			return "", fmt.Errorf("column [%s] charset convert failed, %v", columnName, err)
 		}
 		columnName = string(convertUtf8Raw)
 
+		if rule, ok := maskRules[common.StringUPPER(columnName)]; ok {
+			maskExpr, err := common.GenOracleMaskColumnExpr(rule.MaskType, rule.MaskValue, columnName, rowCol["DATA_TYPE"])
+			if err != nil {
+				return "", fmt.Errorf("table [%s] column [%s] mask rule failed: %v", sourceTable, columnName, err)
+			}
+			columnNames = append(columnNames, common.StringsBuilder(maskExpr, ` AS "`, columnName, `"`))
+			continue
+		}
+
 		switch strings.ToUpper(rowCol["DATA_TYPE"]) {
 		// 数字
 		case "NUMBER":
@@ -873,17 +939,19 @@ func (r *CSV) AdjustTableSelectColumn(sourceTable string, oracleCollation bool)
 			if strings.Contains(rowCol["DATA_TYPE"], "INTERVAL") {
 				columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR("`, columnName, `") AS "`, columnName, `"`))
 			} else if strings.Contains(rowCol["DATA_TYPE"], "TIMESTAMP") {
+				// 带时区时间字段按 [app] time-zone 规整
+				timestampColumn := common.GenOracleTimeZoneColumnExpr(common.StringsBuilder(`"`, columnName, `"`), rowCol["DATA_TYPE"], r.Cfg.AppConfig.TimeZone)
 				dataScale, err := strconv.Atoi(rowCol["DATA_SCALE"])
 				if err != nil {
 					return "", fmt.Errorf("aujust oracle timestamp datatype scale [%s] strconv.Atoi failed: %v", rowCol["DATA_SCALE"], err)
 				}
 				if dataScale == 0 {
-					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR("`, columnName, `",'yyyy-mm-dd hh24:mi:ss') AS "`, columnName, `"`))
+					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR(`, timestampColumn, `,'yyyy-mm-dd hh24:mi:ss') AS "`, columnName, `"`))
 				} else if dataScale < 0 && dataScale <= 6 {
-					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR("`, columnName,
-						`",'yyyy-mm-dd hh24:mi:ss.ff`, rowCol["DATA_SCALE"], `') AS "`, columnName, `"`))
+					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR(`, timestampColumn,
+						`,'yyyy-mm-dd hh24:mi:ss.ff`, rowCol["DATA_SCALE"], `') AS "`, columnName, `"`))
 				} else {
-					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR("`, columnName, `",'yyyy-mm-dd hh24:mi:ss.ff6') AS "`, columnName, `"`))
+					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR(`, timestampColumn, `,'yyyy-mm-dd hh24:mi:ss.ff6') AS "`, columnName, `"`))
 				}
 			} else {
 				columnNames = append(columnNames, common.StringsBuilder(`"`, columnName, `"`))
@@ -907,8 +975,8 @@ func (r *CSV) AdjustCSVConfig(sourceDBCharset string) error {
 			zap.String("oracle config charset", r.Cfg.OracleConfig.Charset))
 		return fmt.Errorf("oracle charset [%v] and oracle config charset [%v] aren't equal, please adjust oracle config charset", sourceDBCharset, r.Cfg.OracleConfig.Charset)
 	}
-	if _, ok := common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(r.Cfg.OracleConfig.Charset)]; !ok {
-		return fmt.Errorf("oracle current charset [%v] isn't support, support charset [%v]", r.Cfg.OracleConfig.Charset, common.MigrateOracleCharsetStringConvertMapping)
+	if _, ok := common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset]; !ok {
+		return fmt.Errorf("oracle current charset [%v] isn't support, support charset [%v]", r.Cfg.OracleConfig.ActualCharset, common.MigrateOracleCharsetStringConvertMapping)
 	}
 
 	if r.Cfg.CSVConfig.Charset == "" || strings.EqualFold(r.Cfg.CSVConfig.Charset, common.MYSQLCharsetUTF8) {
@@ -0,0 +1,178 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package migrate
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/meta"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Rejecter 表级坏行隔离，隔离行写入 reject-dir 下 reject_{schema}.{table}.jsonl 文件以及元数据表 [reject_row_detail]
+// 表隔离行数超过 max-reject-rows 返回错误，由调用方按 chunk 失败处理
+type Rejecter struct {
+	Ctx         context.Context
+	Cfg         *config.Config
+	MetaDB      *meta.Meta
+	SchemaNameS string
+	TableNameS  string
+	SchemaNameT string
+	TableNameT  string
+
+	mu     sync.Mutex
+	file   *os.File
+	counts int64
+}
+
+type rejectRecord struct {
+	Chunk string          `json:"chunk"`
+	Stage string          `json:"stage"`
+	Error string          `json:"error"`
+	Row   json.RawMessage `json:"row"`
+}
+
+func NewRejecter(ctx context.Context, cfg *config.Config, metaDB *meta.Meta, schemaNameS, tableNameS, schemaNameT, tableNameT string) (*Rejecter, error) {
+	// 断点续传已隔离行计入上限
+	counts, err := meta.NewRejectRowDetailModel(metaDB).CountsRejectRowDetailByTable(ctx, &meta.RejectRowDetail{
+		DBTypeS:     cfg.DBTypeS,
+		DBTypeT:     cfg.DBTypeT,
+		SchemaNameS: schemaNameS,
+		TableNameS:  tableNameS,
+		TaskMode:    cfg.TaskMode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Rejecter{
+		Ctx:         ctx,
+		Cfg:         cfg,
+		MetaDB:      metaDB,
+		SchemaNameS: schemaNameS,
+		TableNameS:  tableNameS,
+		SchemaNameT: schemaNameT,
+		TableNameT:  tableNameT,
+		counts:      counts,
+	}, nil
+}
+
+// Reject 隔离单行，row 与 columns 一一对应，字段值以十六进制原始字节记录，NULL 记录为 null
+func (r *Rejecter) Reject(chunk, stage string, columns []string, row []interface{}, rejectErr error) error {
+	rowData := make(map[string]interface{}, len(columns))
+	for i, c := range columns {
+		if i >= len(row) {
+			break
+		}
+		column := strings.Trim(c, "`")
+		switch v := row[i].(type) {
+		case nil:
+			rowData[column] = nil
+		case []byte:
+			rowData[column] = hex.EncodeToString(v)
+		case string:
+			rowData[column] = hex.EncodeToString([]byte(v))
+		default:
+			rowData[column] = hex.EncodeToString([]byte(fmt.Sprintf("%v", v)))
+		}
+	}
+	rowJSON, err := json.Marshal(rowData)
+	if err != nil {
+		return fmt.Errorf("schema [%s] table [%s] reject row json marshal failed: %v", r.SchemaNameS, r.TableNameS, err)
+	}
+	record, err := json.Marshal(&rejectRecord{
+		Chunk: chunk,
+		Stage: stage,
+		Error: rejectErr.Error(),
+		Row:   rowJSON,
+	})
+	if err != nil {
+		return fmt.Errorf("schema [%s] table [%s] reject record json marshal failed: %v", r.SchemaNameS, r.TableNameS, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counts++
+	if r.counts > int64(r.Cfg.RejectConfig.MaxRejectRows) {
+		return fmt.Errorf("schema [%s] table [%s] reject rows exceed max-reject-rows [%d], last reject error: %v",
+			r.SchemaNameS, r.TableNameS, r.Cfg.RejectConfig.MaxRejectRows, rejectErr)
+	}
+
+	if r.file == nil {
+		if err = os.MkdirAll(r.Cfg.RejectConfig.RejectDir, os.ModePerm); err != nil {
+			return fmt.Errorf("create reject dir [%s] failed: %v", r.Cfg.RejectConfig.RejectDir, err)
+		}
+		fileName := filepath.Join(r.Cfg.RejectConfig.RejectDir, fmt.Sprintf("reject_%s.%s.jsonl", r.SchemaNameS, r.TableNameS))
+		r.file, err = os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("open reject file [%s] failed: %v", fileName, err)
+		}
+	}
+	if _, err = r.file.Write(append(record, '\n')); err != nil {
+		return fmt.Errorf("write reject file [%s] failed: %v", r.file.Name(), err)
+	}
+
+	if err = meta.NewRejectRowDetailModel(r.MetaDB).CreateRejectRowDetail(r.Ctx, &meta.RejectRowDetail{
+		DBTypeS:      r.Cfg.DBTypeS,
+		DBTypeT:      r.Cfg.DBTypeT,
+		SchemaNameS:  r.SchemaNameS,
+		TableNameS:   r.TableNameS,
+		SchemaNameT:  r.SchemaNameT,
+		TableNameT:   r.TableNameT,
+		TaskMode:     r.Cfg.TaskMode,
+		ChunkDetailS: chunk,
+		RejectStage:  stage,
+		RowData:      string(rowJSON),
+		ErrorDetail:  rejectErr.Error(),
+	}); err != nil {
+		return err
+	}
+
+	zap.L().Warn("reject row",
+		zap.String("schema", r.SchemaNameS),
+		zap.String("table", r.TableNameS),
+		zap.String("chunk", chunk),
+		zap.String("stage", stage),
+		zap.Error(rejectErr))
+	return nil
+}
+
+// ReadRejectFunc 源端读取阶段隔离函数，用于 GetOracleTableRowsData/GetOracleTableRowsDataCSV
+func (r *Rejecter) ReadRejectFunc(chunk string) func(columns []string, row []interface{}, err error) error {
+	if r == nil {
+		return nil
+	}
+	return func(columns []string, row []interface{}, err error) error {
+		return r.Reject(chunk, common.RejectStageRead, columns, row, err)
+	}
+}
+
+func (r *Rejecter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
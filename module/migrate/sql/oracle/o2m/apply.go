@@ -24,10 +24,12 @@ import (
 	"github.com/wentaojin/transferdb/config"
 	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/database/mysql"
+	"github.com/wentaojin/transferdb/database/oracle"
 	"github.com/wentaojin/transferdb/module/migrate/sql/oracle/public"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
-	"sync"
+	"hash/fnv"
+	"strings"
 )
 
 type IncrTask struct {
@@ -36,6 +38,8 @@ type IncrTask struct {
 	DBTypeT        string          `json:"db_type_t"`
 	TaskMode       string          `json:"task_mode"`
 	GlobalSCN      uint64          `json:"global_scn"`
+	StartSCN       uint64          `json:"start_scn"`      // 同键合并变更起始 SCN
+	CheckpointSCN  uint64          `json:"checkpoint_scn"` // 表 checkpoint SCN，小于等于该 SCN 的变更为断点重放
 	SourceTableSCN uint64          `json:"source_table_scn"`
 	SourceSchema   string          `json:"source_schema"`
 	SourceTable    string          `json:"source_table"`
@@ -45,17 +49,18 @@ type IncrTask struct {
 	OracleRedo     string          `json:"oracle_redo"` // Oracle SQL
 	MySQLRedo      []string        `json:"mysql_redo"`  // MySQL 待执行 SQL
 	OperationType  string          `json:"operation_type"`
+	Key            string          `json:"key"`             // 主键/唯一键值，为空表示屏障任务，串行应用
+	MergeCounts    int             `json:"merge_counts"`    // 同键合并变更数
+	InsertConflict string          `json:"insert_conflict"` // INSERT 主键冲突策略
+	MissingRow     string          `json:"missing_row"`     // UPDATE/DELETE 下游行不存在策略
 	MySQL          *mysql.MySQL    `json:"-"`
 	MetaDB         *meta.Meta      `json:"-"`
 }
 
-type IncrResult struct {
-	Task IncrTask
-	Err  error
-}
-
 // 应用当前日志文件中所有记录
-func applyOracleIncrRecord(metaDB *meta.Meta, mysqlDB *mysql.MySQL, cfg *config.Config, logminerMap map[string][]public.Logminer) error {
+// 同表变更按主键/唯一键值哈希至固定 worker，保证同键按 SCN 顺序应用，同键连续变更合并后写入
+// tableSCNMap 为各表 checkpoint SCN，用于识别断点重放变更
+func applyOracleIncrRecord(metaDB *meta.Meta, oracleDB *oracle.Oracle, mysqlDB *mysql.MySQL, cfg *config.Config, logminerMap map[string][]public.Logminer, tableSCNMap map[string]uint64) error {
 	g := &errgroup.Group{}
 	g.SetLimit(cfg.AllConfig.ApplyThreads)
 
@@ -64,43 +69,38 @@ func applyOracleIncrRecord(metaDB *meta.Meta, mysqlDB *mysql.MySQL, cfg *config.
 		sourceTable := tableName
 		g.Go(func() error {
 			if len(rowsResult) > 0 {
-				var (
-					done        = make(chan bool)
-					taskQueue   = make(chan IncrTask, cfg.AllConfig.WorkerQueue)
-					resultQueue = make(chan IncrResult, cfg.AllConfig.WorkerQueue)
-				)
-				// 获取增量执行结果
-				go getIncrResult(done, resultQueue)
-
-				// 转换捕获内容以及数据应用
-				go func(mysql *mysql.MySQL, sourceSchema, sourceTable string, rowsResult []public.Logminer, taskQueue chan IncrTask) {
-					defer func() {
-						if err := recover(); err != nil {
-							zap.L().Fatal("translatorAndApplyOracleIncrementRecord",
-								zap.String("oracle schema", cfg.SchemaConfig.SourceSchema),
-								zap.String("oracle table", sourceTable),
-								zap.Error(fmt.Errorf("%v", err)))
-						}
-					}()
-					if err := translateAndAddOracleIncrRecord(
-						cfg.DBTypeS,
-						cfg.DBTypeT,
-						cfg.TaskMode,
-						sourceSchema,
-						sourceTable,
-						metaDB,
-						mysql,
-						rowsResult, taskQueue); err != nil {
-						return
-					}
-				}(mysqlDB, cfg.SchemaConfig.SourceSchema, sourceTable, rowsResult, taskQueue)
-
-				// 必须在任务分配和获取结果后创建工作池
-				go createWorkerPool(cfg.AllConfig.WorkerThreads, taskQueue, resultQueue)
-				// 等待执行完成
-				<-done
-
-				return nil
+				keyColumns, err := getIncrTableKeyColumns(mysqlDB, common.StringUPPER(rowsResult[0].TargetSchema), common.StringUPPER(rowsResult[0].TargetTable))
+				if err != nil {
+					return err
+				}
+				// 字段脱敏规则
+				mask, err := public.NewColumnMask(mysqlDB.Ctx, metaDB, oracleDB, cfg.DBTypeS, cfg.DBTypeT, cfg.SchemaConfig.SourceSchema, sourceTable)
+				if err != nil {
+					return err
+				}
+				// 转换捕获内容并合并同键变更
+				tasks, err := translateAndMergeOracleIncrRecord(
+					cfg.DBTypeS,
+					cfg.DBTypeT,
+					cfg.TaskMode,
+					cfg.SchemaConfig.SourceSchema,
+					sourceTable,
+					metaDB,
+					mysqlDB,
+					keyColumns,
+					mask,
+					tableSCNMap[strings.ToUpper(sourceTable)],
+					rowsResult)
+				if err != nil {
+					return err
+				}
+				insertConflict, missingRow := getIncrTableConflictPolicy(cfg, sourceTable)
+				for i := range tasks {
+					tasks[i].InsertConflict = insertConflict
+					tasks[i].MissingRow = missingRow
+				}
+				// 数据应用
+				return applyIncrTasks(cfg.AllConfig.WorkerThreads, cfg.AllConfig.WorkerQueue, tasks)
 			}
 			zap.L().Warn("increment table log file logminer null data, transferdb will continue to capture",
 				zap.String("oracle schema", cfg.SchemaConfig.SourceSchema),
@@ -116,25 +116,142 @@ func applyOracleIncrRecord(metaDB *meta.Meta, mysqlDB *mysql.MySQL, cfg *config.
 	return nil
 }
 
-// 任务同步
-func (p *IncrTask) IncrApply() error {
-	// 数据写入并更新元数据表
-	//zap.L().Info("increment applier sql", zap.String("sql", sql))
-	if p.OperationType == common.MigrateOperationUpdate {
-		// update 语句拆分 delete/replace 放一个事务内
-		txn, err := p.MySQL.MySQLDB.BeginTx(p.Ctx, &sql.TxOptions{})
+// 获取目标端表主键字段，不存在主键取首个唯一键，都不存在取 ROWID 代理字段，均不存在返回空，增量串行应用
+func getIncrTableKeyColumns(mysqlDB *mysql.MySQL, targetSchema, targetTable string) ([]string, error) {
+	keys, err := mysqlDB.GetMySQLTablePrimaryKey(targetSchema, targetTable)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		keys, err = mysqlDB.GetMySQLTableUniqueKey(targetSchema, targetTable)
 		if err != nil {
-			return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql redo [%v] transaction start falied: %v", p.SourceTable, p.OracleRedo, p.MySQLRedo, err)
+			return nil, err
 		}
-		for _, s := range p.MySQLRedo {
-			if _, err = txn.ExecContext(p.Ctx, s); err != nil {
-				return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql [%v] transaction doing falied: %v", p.SourceTable, p.OracleRedo, p.MySQLRedo, err)
-			}
+	}
+	if len(keys) == 0 {
+		isExist, err := mysqlDB.IsExistMySQLTableColumn(targetSchema, targetTable, common.MigrateKeylessRowidColumn)
+		if err != nil {
+			return nil, err
 		}
-		if err = txn.Commit(); err != nil {
-			return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql [%v] transaction commit falied: %v", p.SourceTable, p.OracleRedo, p.MySQLRedo, err)
+		if isExist {
+			return []string{common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`")}, nil
 		}
-	} else {
+		return nil, nil
+	}
+	var keyColumns []string
+	for _, col := range strings.Split(keys[0]["COLUMN_LIST"], ",") {
+		keyColumns = append(keyColumns, common.StringsBuilder("`", common.StringUPPER(col), "`"))
+	}
+	return keyColumns, nil
+}
+
+// 获取表冲突策略，表级配置优先，未配置以 [all] 配置为准
+func getIncrTableConflictPolicy(cfg *config.Config, sourceTable string) (string, string) {
+	insertConflict, missingRow := cfg.AllConfig.InsertConflict, cfg.AllConfig.MissingRow
+	for _, c := range cfg.SchemaConfig.ConflictConfig {
+		if common.StringUPPER(c.SourceTable) != common.StringUPPER(sourceTable) {
+			continue
+		}
+		if c.InsertConflict != "" {
+			insertConflict = c.InsertConflict
+		}
+		if c.MissingRow != "" {
+			missingRow = c.MissingRow
+		}
+	}
+	return insertConflict, missingRow
+}
+
+// 按屏障任务（DDL、主键值变更、无键变更）切分任务段
+// 任务段内按键哈希至固定 worker 并行应用，任务段完成后更新 checkpoint，屏障任务单独串行应用
+func applyIncrTasks(workerThreads, workerQueue int, tasks []IncrTask) error {
+	var segment []IncrTask
+	for _, t := range tasks {
+		if t.Key != "" {
+			segment = append(segment, t)
+			continue
+		}
+		if err := applyIncrSegment(workerThreads, workerQueue, segment); err != nil {
+			return err
+		}
+		segment = segment[:0]
+		if err := t.IncrApply(); err != nil {
+			return err
+		}
+	}
+	return applyIncrSegment(workerThreads, workerQueue, segment)
+}
+
+func applyIncrSegment(workerThreads, workerQueue int, segment []IncrTask) error {
+	if len(segment) == 0 {
+		return nil
+	}
+	if workerThreads <= 0 {
+		workerThreads = 1
+	}
+
+	g := &errgroup.Group{}
+	queues := make([]chan IncrTask, workerThreads)
+	for i := range queues {
+		queue := make(chan IncrTask, workerQueue)
+		queues[i] = queue
+		g.Go(func() error {
+			var err error
+			for job := range queue {
+				// 出错后继续消费，避免任务分发阻塞
+				if err != nil {
+					continue
+				}
+				if err = job.ApplyRedo(); err != nil {
+					zap.L().Error("task increment table record",
+						zap.String("payload", job.String()),
+						zap.Error(err))
+				}
+			}
+			return err
+		})
+	}
+
+	checkpoint := segment[0]
+	for _, t := range segment {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(t.Key))
+		queues[h.Sum32()%uint32(workerThreads)] <- t
+		if t.GlobalSCN > checkpoint.GlobalSCN {
+			checkpoint = t
+		}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	// 任务段应用完毕，更新 checkpoint 至任务段最大 SCN
+	return checkpoint.UpdateCheckpoint()
+}
+
+// 任务同步
+func (p *IncrTask) IncrApply() error {
+	if err := p.ApplyRedo(); err != nil {
+		return err
+	}
+	return p.UpdateCheckpoint()
+}
+
+// 数据写入
+// INSERT 主键冲突以及 UPDATE/DELETE 下游行不存在，按冲突策略处理并记录元数据表 [incr_conflict_detail]
+func (p *IncrTask) ApplyRedo() error {
+	//zap.L().Info("increment applier sql", zap.String("sql", sql))
+	switch p.OperationType {
+	case common.MigrateOperationUpdate:
+		return p.applyUpdate()
+	case common.MigrateOperationInsert:
+		return p.applyInsert()
+	case common.MigrateOperationDelete:
+		return p.applyDelete()
+	default:
 		for _, s := range p.MySQLRedo {
 			_, err := p.MySQL.MySQLDB.ExecContext(p.Ctx, s)
 			if err != nil {
@@ -142,8 +259,133 @@ func (p *IncrTask) IncrApply() error {
 			}
 		}
 	}
-	// 数据写入完毕，更新元数据 checkpoint 表
-	// 如果同步中断，数据同步使用会以 global_scn_s 为准，也就是会进行重复消费
+	return nil
+}
+
+// INSERT -> [REPLACE]
+// skip/fail 策略改写 INSERT 以识别主键冲突，overwrite/log-only 策略 REPLACE 影响行数大于 1 即存在冲突
+// 断点重放变更下游可能已写入，直接 REPLACE 覆盖
+func (p *IncrTask) applyInsert() error {
+	if !p.isReplay() && (p.InsertConflict == common.ConflictPolicySkip || p.InsertConflict == common.ConflictPolicyFail) {
+		insertSQL := strings.Replace(p.MySQLRedo[0], `REPLACE INTO `, `INSERT INTO `, 1)
+		if _, err := p.MySQL.MySQLDB.ExecContext(p.Ctx, insertSQL); err != nil {
+			if !mysql.IsMySQLDuplicateKeyError(err) {
+				return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql [%v] exec falied: %v", p.SourceTable, p.OracleRedo, insertSQL, err)
+			}
+			return p.resolveConflict(common.ConflictTypeInsert, p.InsertConflict, insertSQL, err.Error())
+		}
+		return nil
+	}
+
+	res, err := p.MySQL.MySQLDB.ExecContext(p.Ctx, p.MySQLRedo[0])
+	if err != nil {
+		return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql [%v] exec falied: %v", p.SourceTable, p.OracleRedo, p.MySQLRedo, err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected > 1 {
+		return p.resolveConflict(common.ConflictTypeInsert, p.InsertConflict, p.MySQLRedo[0], "")
+	}
+	return nil
+}
+
+// DELETE -> [DELETE]，影响行数为 0 即下游行不存在
+func (p *IncrTask) applyDelete() error {
+	res, err := p.MySQL.MySQLDB.ExecContext(p.Ctx, p.MySQLRedo[0])
+	if err != nil {
+		return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql [%v] exec falied: %v", p.SourceTable, p.OracleRedo, p.MySQLRedo, err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return p.resolveConflict(common.ConflictTypeMissingRow, p.MissingRow, p.MySQLRedo[0], "")
+	}
+	return nil
+}
+
+// UPDATE -> [DELETE, REPLACE]，拆分语句放一个事务内，DELETE 影响行数为 0 即下游行不存在
+// skip/fail 策略回滚不写入，overwrite/log-only 策略以及断点重放变更继续写入变更后数据
+func (p *IncrTask) applyUpdate() error {
+	txn, err := p.MySQL.MySQLDB.BeginTx(p.Ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql redo [%v] transaction start falied: %v", p.SourceTable, p.OracleRedo, p.MySQLRedo, err)
+	}
+	var isMissing bool
+	for i, s := range p.MySQLRedo {
+		res, err := txn.ExecContext(p.Ctx, s)
+		if err != nil {
+			_ = txn.Rollback()
+			return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql [%v] transaction doing falied: %v", p.SourceTable, p.OracleRedo, p.MySQLRedo, err)
+		}
+		if i == 0 {
+			if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+				isMissing = true
+				if !p.isReplay() && (p.MissingRow == common.ConflictPolicySkip || p.MissingRow == common.ConflictPolicyFail) {
+					_ = txn.Rollback()
+					return p.resolveConflict(common.ConflictTypeMissingRow, p.MissingRow, s, "")
+				}
+			}
+		}
+	}
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("single increment table [%s] data oracle redo [%v] insert mysql [%v] transaction commit falied: %v", p.SourceTable, p.OracleRedo, p.MySQLRedo, err)
+	}
+	if isMissing {
+		return p.resolveConflict(common.ConflictTypeMissingRow, p.MissingRow, p.MySQLRedo[0], "")
+	}
+	return nil
+}
+
+// 记录冲突，fail 策略返回错误中断增量同步
+// 断点重放变更（SCN 小于等于表 checkpoint SCN）下游可能已应用，冲突属于重复消费，不记录不中断
+func (p *IncrTask) resolveConflict(conflictType, policy, mysqlRedo, errDetail string) error {
+	if p.isReplay() {
+		zap.L().Info("increment table record replay conflict ignored",
+			zap.String("conflict type", conflictType),
+			zap.Uint64("scn", p.GlobalSCN),
+			zap.Uint64("checkpoint scn", p.CheckpointSCN),
+			zap.String("key", p.Key))
+		return nil
+	}
+	zap.L().Warn("increment table record conflict",
+		zap.String("conflict type", conflictType),
+		zap.String("policy", policy),
+		zap.Uint64("scn", p.GlobalSCN),
+		zap.String("key", p.Key),
+		zap.String("oracle redo", p.OracleRedo),
+		zap.String("mysql redo", mysqlRedo))
+
+	err := meta.NewIncrConflictDetailModel(p.MetaDB).CreateIncrConflictDetail(p.Ctx, &meta.IncrConflictDetail{
+		DBTypeS:      p.DBTypeS,
+		DBTypeT:      p.DBTypeT,
+		SchemaNameS:  p.SourceSchema,
+		TableNameS:   p.SourceTable,
+		SchemaNameT:  p.TargetSchema,
+		TableNameT:   p.TargetTable,
+		TaskMode:     p.TaskMode,
+		ScnS:         p.GlobalSCN,
+		Operation:    p.OperationType,
+		ConflictType: conflictType,
+		Policy:       policy,
+		KeyValues:    p.Key,
+		OracleRedo:   p.OracleRedo,
+		MySQLRedo:    mysqlRedo,
+		ErrorDetail:  errDetail,
+	})
+	if err != nil {
+		return err
+	}
+	if policy == common.ConflictPolicyFail {
+		return fmt.Errorf("single increment table [%s] scn [%d] conflict [%s] policy [%s], oracle redo [%v] mysql redo [%v], please check meta table [incr_conflict_detail]",
+			p.SourceTable, p.GlobalSCN, conflictType, policy, p.OracleRedo, mysqlRedo)
+	}
+	return nil
+}
+
+// 是否断点重放变更，同键合并任务以起始 SCN 判断
+func (p *IncrTask) isReplay() bool {
+	return p.StartSCN <= p.CheckpointSCN
+}
+
+// 数据写入完毕，更新元数据 checkpoint 表
+// 如果同步中断，数据同步使用会以 global_scn_s 为准，也就是会进行重复消费
+func (p *IncrTask) UpdateCheckpoint() error {
 	if p.Operation == common.MigrateOperationDropTable {
 		err := meta.NewCommonModel(p.MetaDB).DeleteIncrSyncMetaAndWaitSyncMeta(p.Ctx, &meta.IncrSyncMeta{
 			DBTypeS:     p.DBTypeS,
@@ -192,42 +434,3 @@ func (p *IncrTask) String() string {
 	}
 	return string(b)
 }
-
-func createWorkerPool(numOfWorkers int, jobQueue chan IncrTask, resultQueue chan IncrResult) {
-	var wg sync.WaitGroup
-	for i := 0; i < numOfWorkers; i++ {
-		wg.Add(1)
-		go worker(&wg, jobQueue, resultQueue)
-	}
-	wg.Wait()
-	close(resultQueue)
-}
-
-func getIncrResult(done chan bool, resultQueue chan IncrResult) {
-	for result := range resultQueue {
-		if result.Err != nil {
-			zap.L().Fatal("task increment table record",
-				zap.String("payload", result.Task.String()),
-				zap.Error(result.Err))
-		}
-	}
-	done <- true
-}
-
-func worker(wg *sync.WaitGroup, jobQueue chan IncrTask, resultQueue chan IncrResult) {
-	defer wg.Done()
-	for job := range jobQueue {
-		if err := job.IncrApply(); err != nil {
-			result := IncrResult{
-				Task: job,
-				Err:  err,
-			}
-			resultQueue <- result
-		}
-		result := IncrResult{
-			Task: job,
-			Err:  nil,
-		}
-		resultQueue <- result
-	}
-}
@@ -0,0 +1,196 @@
+package o2m
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+
+	driverMySQL "github.com/go-sql-driver/mysql"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/database/mysql"
+	gormMySQL "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 模拟下游执行结果，按 SQL 前缀返回影响行数或者错误，记录执行 SQL
+type fakeExecResult struct {
+	affected int64
+	err      error
+}
+
+type fakeDB struct {
+	mu      sync.Mutex
+	results map[string]fakeExecResult
+	execs   []string
+}
+
+func (db *fakeDB) exec(query string) (driver.Result, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.execs = append(db.execs, query)
+	for prefix, r := range db.results {
+		if strings.HasPrefix(query, prefix) {
+			if r.err != nil {
+				return nil, r.err
+			}
+			return fakeResult(r.affected), nil
+		}
+	}
+	return fakeResult(1), nil
+}
+
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return 1, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func (db *fakeDB) count(prefix string) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var c int
+	for _, s := range db.execs {
+		if strings.HasPrefix(s, prefix) {
+			c++
+		}
+	}
+	return c
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{db: c.db, query: query}, nil
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+func (c fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(query)
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error                               { return nil }
+func (s fakeStmt) NumInput() int                              { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) { return s.db.exec(s.query) }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return nil, driver.ErrSkip }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func newFakeIncrTask(t *testing.T, db *fakeDB, operationType string, redo []string) IncrTask {
+	sqlDB := sql.OpenDB(fakeConnector{db: db})
+	gormDB, err := gorm.Open(gormMySQL.New(gormMySQL.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{SkipDefaultTransaction: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("gorm open failed: %v", err)
+	}
+	return IncrTask{
+		Ctx:           context.Background(),
+		SourceTable:   "T1",
+		GlobalSCN:     100,
+		StartSCN:      100,
+		CheckpointSCN: 90,
+		OperationType: operationType,
+		Key:           "`ID` = 1",
+		MySQLRedo:     redo,
+		MySQL:         &mysql.MySQL{Ctx: context.Background(), MySQLDB: sqlDB},
+		MetaDB:        &meta.Meta{GormDB: gormDB},
+	}
+}
+
+func TestIncrTaskConflict(t *testing.T) {
+	var (
+		insert, update, del = common.MigrateOperationInsert, common.MigrateOperationUpdate, common.MigrateOperationDelete
+		replaceSQL          = "REPLACE INTO MARVIN.T1(`ID`) VALUES (1)"
+		deleteSQL           = "DELETE FROM MARVIN.T1 WHERE `ID` = 1"
+		duplicate           = fakeExecResult{err: &driverMySQL.MySQLError{Number: 1062, Message: "Duplicate entry"}}
+		recordPrefix        = "INSERT INTO `incr_conflict_detail"
+	)
+	tests := []struct {
+		name       string
+		operation  string
+		redo       []string
+		policy     string
+		replay     bool
+		results    map[string]fakeExecResult
+		wantErr    bool
+		wantRecord bool
+		wantExec   map[string]int
+	}{
+		// INSERT 主键冲突
+		{name: "insert overwrite", operation: insert, redo: []string{replaceSQL}, policy: common.ConflictPolicyOverwrite,
+			results: map[string]fakeExecResult{"REPLACE": {affected: 2}}, wantRecord: true, wantExec: map[string]int{"REPLACE": 1}},
+		{name: "insert log-only", operation: insert, redo: []string{replaceSQL}, policy: common.ConflictPolicyLogOnly,
+			results: map[string]fakeExecResult{"REPLACE": {affected: 2}}, wantRecord: true, wantExec: map[string]int{"REPLACE": 1}},
+		{name: "insert skip", operation: insert, redo: []string{replaceSQL}, policy: common.ConflictPolicySkip,
+			results: map[string]fakeExecResult{"INSERT INTO MARVIN": duplicate}, wantRecord: true, wantExec: map[string]int{"INSERT INTO MARVIN": 1, "REPLACE": 0}},
+		{name: "insert fail", operation: insert, redo: []string{replaceSQL}, policy: common.ConflictPolicyFail,
+			results: map[string]fakeExecResult{"INSERT INTO MARVIN": duplicate}, wantErr: true, wantRecord: true, wantExec: map[string]int{"INSERT INTO MARVIN": 1}},
+		{name: "insert no conflict", operation: insert, redo: []string{replaceSQL}, policy: common.ConflictPolicyFail,
+			wantExec: map[string]int{"INSERT INTO MARVIN": 1}},
+		{name: "insert replay overwrite", operation: insert, redo: []string{replaceSQL}, policy: common.ConflictPolicyOverwrite, replay: true,
+			results: map[string]fakeExecResult{"REPLACE": {affected: 2}}, wantExec: map[string]int{"REPLACE": 1}},
+		{name: "insert replay fail", operation: insert, redo: []string{replaceSQL}, policy: common.ConflictPolicyFail, replay: true,
+			results: map[string]fakeExecResult{"REPLACE": {affected: 2}, "INSERT INTO MARVIN": duplicate}, wantExec: map[string]int{"REPLACE": 1, "INSERT INTO MARVIN": 0}},
+
+		// DELETE 下游行不存在
+		{name: "delete overwrite", operation: del, redo: []string{deleteSQL}, policy: common.ConflictPolicyOverwrite,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantRecord: true, wantExec: map[string]int{"DELETE": 1}},
+		{name: "delete log-only", operation: del, redo: []string{deleteSQL}, policy: common.ConflictPolicyLogOnly,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantRecord: true, wantExec: map[string]int{"DELETE": 1}},
+		{name: "delete skip", operation: del, redo: []string{deleteSQL}, policy: common.ConflictPolicySkip,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantRecord: true, wantExec: map[string]int{"DELETE": 1}},
+		{name: "delete fail", operation: del, redo: []string{deleteSQL}, policy: common.ConflictPolicyFail,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantErr: true, wantRecord: true, wantExec: map[string]int{"DELETE": 1}},
+		{name: "delete replay fail", operation: del, redo: []string{deleteSQL}, policy: common.ConflictPolicyFail, replay: true,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantExec: map[string]int{"DELETE": 1}},
+
+		// UPDATE 下游行不存在
+		{name: "update overwrite", operation: update, redo: []string{deleteSQL, replaceSQL}, policy: common.ConflictPolicyOverwrite,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantRecord: true, wantExec: map[string]int{"DELETE": 1, "REPLACE": 1}},
+		{name: "update log-only", operation: update, redo: []string{deleteSQL, replaceSQL}, policy: common.ConflictPolicyLogOnly,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantRecord: true, wantExec: map[string]int{"DELETE": 1, "REPLACE": 1}},
+		{name: "update skip", operation: update, redo: []string{deleteSQL, replaceSQL}, policy: common.ConflictPolicySkip,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantRecord: true, wantExec: map[string]int{"DELETE": 1, "REPLACE": 0}},
+		{name: "update fail", operation: update, redo: []string{deleteSQL, replaceSQL}, policy: common.ConflictPolicyFail,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantErr: true, wantRecord: true, wantExec: map[string]int{"DELETE": 1, "REPLACE": 0}},
+		{name: "update replay fail", operation: update, redo: []string{deleteSQL, replaceSQL}, policy: common.ConflictPolicyFail, replay: true,
+			results: map[string]fakeExecResult{"DELETE": {affected: 0}}, wantExec: map[string]int{"DELETE": 1, "REPLACE": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{results: tt.results}
+			task := newFakeIncrTask(t, db, tt.operation, tt.redo)
+			task.InsertConflict, task.MissingRow = tt.policy, tt.policy
+			if tt.replay {
+				task.StartSCN = task.CheckpointSCN
+			}
+			if err := task.ApplyRedo(); (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyRedo() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := db.count(recordPrefix) > 0; got != tt.wantRecord {
+				t.Errorf("conflict recorded = %v, want %v, execs %v", got, tt.wantRecord, db.execs)
+			}
+			for prefix, want := range tt.wantExec {
+				if got := db.count(prefix); got != want {
+					t.Errorf("exec [%s] counts = %d, want %d, execs %v", prefix, got, want, db.execs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package o2m
+
+import (
+	"context"
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/database/oracle"
+	"go.uber.org/zap"
+	"time"
+)
+
+func NewCheckpoint(shutdownCtx context.Context, cfg *config.Config) (*Migrate, error) {
+	ctx := context.WithoutCancel(shutdownCtx)
+	metaDB, err := meta.NewMetaDBEngine(ctx, cfg.MetaConfig, cfg.AppConfig.SlowlogThreshold)
+	if err != nil {
+		return nil, err
+	}
+	// 只有重置需要源端数据库，时间点转换 SCN 以及查询方式增量跟踪字段 checkpoint
+	var oracleDB *oracle.Oracle
+	if cfg.CheckpointConfig.Action == common.CheckpointActionReset {
+		oracleDB, err = oracle.NewOracleDBEngine(ctx, cfg.OracleConfig, cfg.SchemaConfig.SourceSchema)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Migrate{
+		Ctx:         ctx,
+		ShutdownCtx: shutdownCtx,
+		Cfg:         cfg,
+		Oracle:      oracleDB,
+		MetaDB:      metaDB,
+	}, nil
+}
+
+// 增量 checkpoint 查看以及重置，重置前需停止 ALL 模式任务
+func (r *Migrate) Checkpoint() error {
+	incrMetas, err := r.getIncrCheckpointTables()
+	if err != nil {
+		return err
+	}
+
+	if r.Cfg.CheckpointConfig.Action == common.CheckpointActionReset {
+		if err = r.resetIncrCheckpoint(incrMetas); err != nil {
+			return err
+		}
+		if incrMetas, err = r.getIncrCheckpointTables(); err != nil {
+			return err
+		}
+	}
+
+	threadMetas, err := meta.NewIncrThreadMetaModel(r.MetaDB).DetailIncrThreadMetaBySchema(r.Ctx, &meta.IncrThreadMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(renderIncrCheckpoint(incrMetas, threadMetas))
+	return nil
+}
+
+// 获取配置表 checkpoint，未配置表以 [incr_sync_meta] 全部表为准
+func (r *Migrate) getIncrCheckpointTables() ([]meta.IncrSyncMeta, error) {
+	incrMetas, err := meta.NewIncrSyncMetaModel(r.MetaDB).DetailIncrSyncMetaBySchema(r.Ctx, &meta.IncrSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Cfg.CheckpointConfig.Tables) == 0 {
+		return incrMetas, nil
+	}
+
+	tableMetas := make(map[string]meta.IncrSyncMeta)
+	for _, m := range incrMetas {
+		tableMetas[common.StringUPPER(m.TableNameS)] = m
+	}
+	var (
+		filterMetas []meta.IncrSyncMeta
+		notExists   []string
+	)
+	for _, t := range r.Cfg.CheckpointConfig.Tables {
+		if m, ok := tableMetas[t]; ok {
+			filterMetas = append(filterMetas, m)
+		} else {
+			notExists = append(notExists, t)
+		}
+	}
+	if len(notExists) > 0 {
+		return nil, fmt.Errorf("config [checkpoint] tables %v meta table [incr_sync_meta] record isn't exist", notExists)
+	}
+	return filterMetas, nil
+}
+
+// 重置表 checkpoint 至指定 SCN 或者时间点，同时清理 redo 线程进度，避免日志序列号检查误报
+func (r *Migrate) resetIncrCheckpoint(incrMetas []meta.IncrSyncMeta) error {
+	resetSCN := r.Cfg.CheckpointConfig.SCN
+	if r.Cfg.CheckpointConfig.Timestamp != "" {
+		scn, err := r.Oracle.GetOracleTimestampToSCN(r.Cfg.CheckpointConfig.Timestamp)
+		if err != nil {
+			return err
+		}
+		resetSCN = scn
+	}
+
+	for _, m := range incrMetas {
+		tableSCN := resetSCN
+		if r.Cfg.AllConfig.IncrMode == common.IncrModeQuery {
+			scn, err := r.initIncrQueryTableSCN(m.TableNameS, resetSCN)
+			if err != nil {
+				return err
+			}
+			tableSCN = scn
+		}
+		if err := meta.NewIncrSyncMetaModel(r.MetaDB).ResetIncrSyncMetaSCN(r.Ctx, &meta.IncrSyncMeta{
+			DBTypeS:     m.DBTypeS,
+			DBTypeT:     m.DBTypeT,
+			SchemaNameS: m.SchemaNameS,
+			TableNameS:  m.TableNameS,
+			GlobalScnS:  resetSCN,
+			TableScnS:   tableSCN,
+		}); err != nil {
+			return err
+		}
+		zap.L().Warn("increment table checkpoint reset",
+			zap.String("schema", m.SchemaNameS),
+			zap.String("table", m.TableNameS),
+			zap.Uint64("global scn before", m.GlobalScnS),
+			zap.Uint64("table scn before", m.TableScnS),
+			zap.Uint64("global scn after", resetSCN),
+			zap.Uint64("table scn after", tableSCN))
+	}
+
+	return meta.NewIncrThreadMetaModel(r.MetaDB).DeleteIncrThreadMetaBySchema(r.Ctx, &meta.IncrThreadMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+	})
+}
+
+func renderIncrCheckpoint(incrMetas []meta.IncrSyncMeta, threadMetas []meta.IncrThreadMeta) string {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleLight)
+	tw.AppendHeader(table.Row{"SCHEMA_NAME_S", "TABLE_NAME_S", "SCHEMA_NAME_T", "TABLE_NAME_T", "GLOBAL_SCN_S", "TABLE_SCN_S", "UPDATED_AT"})
+	for _, m := range incrMetas {
+		var updatedAt string
+		if m.BaseModel != nil {
+			updatedAt = m.UpdatedAt.Format(time.DateTime)
+		}
+		tw.AppendRow(table.Row{m.SchemaNameS, m.TableNameS, m.SchemaNameT, m.TableNameT, m.GlobalScnS, m.TableScnS, updatedAt})
+	}
+	if len(threadMetas) == 0 {
+		return tw.Render()
+	}
+
+	sw := table.NewWriter()
+	sw.SetStyle(table.StyleLight)
+	sw.AppendHeader(table.Row{"THREAD_S", "SEQUENCE_S", "LOG_FILE_S", "FIRST_SCN_S", "NEXT_SCN_S"})
+	for _, t := range threadMetas {
+		sw.AppendRow(table.Row{t.ThreadS, t.SequenceS, t.LogFileS, t.FirstScnS, t.NextScnS})
+	}
+	return tw.Render() + "\n" + sw.Render()
+}
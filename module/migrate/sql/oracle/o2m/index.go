@@ -0,0 +1,112 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package o2m
+
+import (
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/database/mysql"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"sync/atomic"
+	"time"
+)
+
+// 全量数据导入完成后创建 reverse defer-index 延迟创建的索引/约束
+// KEY 类型并发创建完成后再创建 CONSTRAINT 类型，失败记录 [index_sync_meta] FAILED，重新运行 full 重试
+func (r *Migrate) createDeferIndex() error {
+	startTime := time.Now()
+	indexMetas, err := meta.NewIndexSyncMetaModel(r.MetaDB).DetailUnfinishedIndexSyncMeta(r.Ctx, &meta.IndexSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+	})
+	if err != nil {
+		return err
+	}
+	if len(indexMetas) == 0 {
+		return nil
+	}
+	zap.L().Info("full defer index create start",
+		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+		zap.Int("index totals", len(indexMetas)))
+
+	var failedTotals int64
+	for _, indexType := range []string{common.DeferIndexTypeKey, common.DeferIndexTypeConstraint} {
+		g := &errgroup.Group{}
+		g.SetLimit(r.Cfg.FullConfig.IndexThreads)
+
+		for _, im := range indexMetas {
+			m := im
+			if m.IndexType != indexType {
+				continue
+			}
+			g.Go(func() error {
+				// 收到退出信号，未创建的索引保持原状态
+				if r.ShutdownCtx.Err() != nil {
+					return nil
+				}
+				if err := meta.NewIndexSyncMetaModel(r.MetaDB).UpdateIndexSyncMeta(r.Ctx, &m, map[string]interface{}{
+					"TaskStatus": common.TaskStatusRunning,
+				}); err != nil {
+					return err
+				}
+
+				indexStartTime := time.Now()
+				// 索引/约束已存在（比如上次创建超时但实际成功）视为成功
+				if errw := r.Mysql.WriteMySQLTable(m.IndexDDL); errw != nil && !mysql.IsMySQLDuplicateObjectError(errw) {
+					atomic.AddInt64(&failedTotals, 1)
+					zap.L().Error("full defer index create failed",
+						zap.String("schema", m.SchemaNameT),
+						zap.String("table", m.TableNameT),
+						zap.String("index", m.IndexNameS),
+						zap.String("sql", m.IndexDDL),
+						zap.Error(errw))
+					return meta.NewIndexSyncMetaModel(r.MetaDB).UpdateIndexSyncMeta(r.Ctx, &m, map[string]interface{}{
+						"TaskStatus":  common.TaskStatusFailed,
+						"ErrorDetail": errw.Error(),
+					})
+				}
+
+				zap.L().Info("full defer index create finished",
+					zap.String("schema", m.SchemaNameT),
+					zap.String("table", m.TableNameT),
+					zap.String("index", m.IndexNameS),
+					zap.String("cost", time.Now().Sub(indexStartTime).String()))
+				return meta.NewIndexSyncMetaModel(r.MetaDB).UpdateIndexSyncMeta(r.Ctx, &m, map[string]interface{}{
+					"TaskStatus":  common.TaskStatusSuccess,
+					"ErrorDetail": "",
+				})
+			})
+		}
+		if err = g.Wait(); err != nil {
+			return err
+		}
+	}
+
+	if r.ShutdownCtx.Err() != nil {
+		return common.ErrGracefulShutdown
+	}
+	if failedTotals > 0 {
+		return fmt.Errorf("full schema [%s] defer index create failed totals [%d], detail see meta table [index_sync_meta], please fix and rerunning full mode retry", r.Cfg.SchemaConfig.SourceSchema, failedTotals)
+	}
+	zap.L().Info("full defer index create finished",
+		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+		zap.Int("index totals", len(indexMetas)),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return nil
+}
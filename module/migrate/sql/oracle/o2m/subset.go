@@ -0,0 +1,221 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package o2m
+
+import (
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/oracle"
+	"go.uber.org/zap"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 子集迁移外键关系
+type subsetForeignKey struct {
+	ChildTable    string
+	ChildColumns  []string
+	ParentTable   string
+	ParentColumns []string
+}
+
+// 子集迁移待扩展 ROWID
+type subsetFrontier struct {
+	Table  string
+	Rowids []string
+	Down   bool
+}
+
+// 子集迁移
+// 以 subset-config 根表条件为起点，同一 SCN 沿外键计算关联行闭包：
+// 1、根表以及子表行（向下）继续扩展其子表行以及父表行
+// 2、父表行（向上）只扩展其父表行，避免父表其他子表行全部纳入
+// 返回根表外键连通表 -> 闭包 ROWID（按 ROWID 排序），非连通表不返回，按原有方式迁移
+func (r *Migrate) genSubsetTableRowids(exporters []string, globalSCN uint64) (map[string][]string, error) {
+	startTime := time.Now()
+	schema := common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema)
+
+	var fks []subsetForeignKey
+	for _, t := range exporters {
+		fkRes, err := r.Oracle.GetOracleSchemaTableForeignKey(schema, t)
+		if err != nil {
+			return nil, err
+		}
+		for _, fk := range fkRes {
+			parentTable := common.StringUPPER(fk["RTABLE_NAME"])
+			if !strings.EqualFold(fk["R_OWNER"], schema) || !common.IsContainString(exporters, parentTable) {
+				zap.L().Warn("subset table foreign key parent table isn't migrate table, skip",
+					zap.String("schema", schema),
+					zap.String("table", t),
+					zap.String("constraint", fk["CONSTRAINT_NAME"]),
+					zap.String("parent owner", fk["R_OWNER"]),
+					zap.String("parent table", parentTable))
+				continue
+			}
+			fks = append(fks, subsetForeignKey{
+				ChildTable:    common.StringUPPER(t),
+				ChildColumns:  strings.Split(common.StringUPPER(fk["COLUMN_LIST"]), ","),
+				ParentTable:   parentTable,
+				ParentColumns: strings.Split(common.StringUPPER(fk["RCOLUMN_LIST"]), ","),
+			})
+		}
+	}
+
+	included := make(map[string]map[string]bool)
+	for _, t := range genSubsetConnectedTables(r.Cfg.SchemaConfig.SubsetConfig, fks) {
+		included[t] = make(map[string]bool)
+	}
+
+	var frontiers []subsetFrontier
+	// 新增 ROWID 或者由向上升级为向下，需要继续扩展
+	addRowids := func(table string, rowids []string, down bool) {
+		var news []string
+		for _, rowid := range rowids {
+			expanded, ok := included[table][rowid]
+			if !ok || (down && !expanded) {
+				included[table][rowid] = down
+				news = append(news, rowid)
+			}
+		}
+		if len(news) > 0 {
+			frontiers = append(frontiers, subsetFrontier{Table: table, Rowids: news, Down: down})
+		}
+	}
+
+	for _, sc := range r.Cfg.SchemaConfig.SubsetConfig {
+		if !common.IsContainString(exporters, sc.SourceTable) {
+			return nil, fmt.Errorf("config [schema-config] subset-config source-table [%s] isn't migrate table", sc.SourceTable)
+		}
+		rowids, err := r.querySubsetRowids(common.StringsBuilder(`SELECT ROWIDTOCHAR(ROWID) AS "ROWID_S" FROM `, schema, `.`, sc.SourceTable,
+			` AS OF SCN `, strconv.FormatUint(globalSCN, 10), ` WHERE `, sc.Where))
+		if err != nil {
+			return nil, err
+		}
+		addRowids(sc.SourceTable, rowids, true)
+	}
+
+	for len(frontiers) > 0 {
+		f := frontiers[0]
+		frontiers = frontiers[1:]
+		for _, fk := range fks {
+			var (
+				toTable, toDown      = fk.ParentTable, false
+				toColumns, ofColumns = fk.ParentColumns, fk.ChildColumns
+			)
+			switch {
+			case fk.ChildTable == f.Table:
+			case fk.ParentTable == f.Table && f.Down:
+				toTable, toDown = fk.ChildTable, true
+				toColumns, ofColumns = fk.ChildColumns, fk.ParentColumns
+			default:
+				continue
+			}
+			for i := 0; i < len(f.Rowids); i += common.SubsetChunkRowids {
+				end := i + common.SubsetChunkRowids
+				if end > len(f.Rowids) {
+					end = len(f.Rowids)
+				}
+				rowids := f.Rowids[i:end]
+				rows, err := r.querySubsetRowids(common.StringsBuilder(`SELECT ROWIDTOCHAR(t.ROWID) AS "ROWID_S" FROM `, schema, `.`, toTable,
+					` AS OF SCN `, strconv.FormatUint(globalSCN, 10), ` t WHERE (`, genSubsetColumns("t", toColumns), `) IN (SELECT `,
+					genSubsetColumns("f", ofColumns), ` FROM `, schema, `.`, f.Table, ` AS OF SCN `, strconv.FormatUint(globalSCN, 10),
+					` f WHERE f.ROWID IN (`, common.StringJOIN(rowids, `'`, `'`, `,`), `))`))
+				if err != nil {
+					return nil, err
+				}
+				addRowids(toTable, rows, toDown)
+			}
+		}
+	}
+
+	subsetRowids := make(map[string][]string)
+	for t, rowidMap := range included {
+		rowids := make([]string, 0, len(rowidMap))
+		for rowid := range rowidMap {
+			rowids = append(rowids, rowid)
+		}
+		sort.Slice(rowids, func(i, j int) bool {
+			return common.EncodeOracleRowid(rowids[i]) < common.EncodeOracleRowid(rowids[j])
+		})
+		subsetRowids[t] = rowids
+		zap.L().Info("subset table rowids",
+			zap.String("schema", schema),
+			zap.String("table", t),
+			zap.Int("rows", len(rowids)))
+	}
+	zap.L().Info("subset tables rowids finished",
+		zap.String("schema", schema),
+		zap.Uint64("global scn", globalSCN),
+		zap.Int("tables", len(subsetRowids)),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return subsetRowids, nil
+}
+
+func (r *Migrate) querySubsetRowids(querySQL string) ([]string, error) {
+	_, res, err := oracle.Query(r.Ctx, r.Oracle.OracleDB, querySQL)
+	if err != nil {
+		return nil, err
+	}
+	var rowids []string
+	for _, row := range res {
+		rowids = append(rowids, row["ROWID_S"])
+	}
+	return rowids, nil
+}
+
+// 根表外键无向连通表，连通表只迁移闭包行，保证外键有效
+func genSubsetConnectedTables(subsetCfg []config.SubsetConfig, fks []subsetForeignKey) []string {
+	visited := make(map[string]struct{})
+	var queue, tables []string
+	for _, sc := range subsetCfg {
+		if _, ok := visited[sc.SourceTable]; !ok {
+			visited[sc.SourceTable] = struct{}{}
+			queue = append(queue, sc.SourceTable)
+		}
+	}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		tables = append(tables, t)
+		for _, fk := range fks {
+			var next string
+			switch t {
+			case fk.ChildTable:
+				next = fk.ParentTable
+			case fk.ParentTable:
+				next = fk.ChildTable
+			default:
+				continue
+			}
+			if _, ok := visited[next]; !ok {
+				visited[next] = struct{}{}
+				queue = append(queue, next)
+			}
+		}
+	}
+	return tables
+}
+
+func genSubsetColumns(alias string, columns []string) string {
+	var cols []string
+	for _, c := range columns {
+		cols = append(cols, common.StringsBuilder(alias, `."`, c, `"`))
+	}
+	return strings.Join(cols, ",")
+}
@@ -0,0 +1,165 @@
+package o2m
+
+import (
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/database/mysql"
+	"github.com/wentaojin/transferdb/module/migrate/sql/oracle/public"
+	"reflect"
+	"testing"
+)
+
+func TestMergeIncrTask(t *testing.T) {
+	genTask := func(prefix, op string) IncrTask {
+		task := IncrTask{OperationType: op, StartSCN: 10, MergeCounts: 1}
+		switch op {
+		case common.MigrateOperationInsert:
+			task.MySQLRedo = []string{prefix + "-REPLACE"}
+		case common.MigrateOperationUpdate:
+			task.MySQLRedo = []string{prefix + "-DELETE", prefix + "-REPLACE"}
+		case common.MigrateOperationDelete:
+			task.MySQLRedo = []string{prefix + "-DELETE"}
+		}
+		return task
+	}
+	insert, update, del := common.MigrateOperationInsert, common.MigrateOperationUpdate, common.MigrateOperationDelete
+	tests := []struct {
+		prev, next string
+		checkpoint uint64
+		wantKeep   bool
+		wantOp     string
+		wantRedo   []string
+	}{
+		{prev: insert, next: insert, wantKeep: true, wantOp: insert, wantRedo: []string{"N-REPLACE"}},
+		{prev: insert, next: update, wantKeep: true, wantOp: insert, wantRedo: []string{"N-REPLACE"}},
+		{prev: insert, next: del, wantKeep: false},
+		{prev: insert, next: del, checkpoint: 10, wantKeep: true, wantOp: del, wantRedo: []string{"N-DELETE"}},
+		{prev: update, next: insert, wantKeep: true, wantOp: update, wantRedo: []string{"P-DELETE", "N-REPLACE"}},
+		{prev: update, next: update, wantKeep: true, wantOp: update, wantRedo: []string{"P-DELETE", "N-REPLACE"}},
+		{prev: update, next: del, wantKeep: true, wantOp: del, wantRedo: []string{"P-DELETE"}},
+		{prev: del, next: insert, wantKeep: true, wantOp: update, wantRedo: []string{"P-DELETE", "N-REPLACE"}},
+		{prev: del, next: update, wantKeep: true, wantOp: update, wantRedo: []string{"P-DELETE", "N-REPLACE"}},
+		{prev: del, next: del, wantKeep: true, wantOp: del, wantRedo: []string{"P-DELETE"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s+%s@%d", tt.prev, tt.next, tt.checkpoint), func(t *testing.T) {
+			merged, keep := mergeIncrTask(genTask("P", tt.prev), genTask("N", tt.next), tt.checkpoint)
+			if keep != tt.wantKeep {
+				t.Fatalf("merge keep = %v, want %v", keep, tt.wantKeep)
+			}
+			if !keep {
+				return
+			}
+			if merged.OperationType != tt.wantOp || !reflect.DeepEqual(merged.MySQLRedo, tt.wantRedo) || merged.MergeCounts != 2 {
+				t.Errorf("merge = %s %v counts %d, want %s %v counts 2", merged.OperationType, merged.MySQLRedo, merged.MergeCounts, tt.wantOp, tt.wantRedo)
+			}
+		})
+	}
+}
+
+func TestGenIncrKey(t *testing.T) {
+	data := map[string]interface{}{"`ID`": "1", "`NAME`": "'marvin'"}
+	tests := []struct {
+		name       string
+		keyColumns []string
+		data       map[string]interface{}
+		want       string
+	}{
+		{name: "single key", keyColumns: []string{"`ID`"}, data: data, want: "`ID` = 1"},
+		{name: "composite key", keyColumns: []string{"`ID`", "`NAME`"}, data: data, want: "`ID` = 1 AND `NAME` = 'marvin'"},
+		{name: "missing key column", keyColumns: []string{"`ID`", "`AGE`"}, data: data, want: ""},
+		{name: "no key columns", keyColumns: nil, data: data, want: ""},
+		{name: "nil data", keyColumns: []string{"`ID`"}, data: nil, want: ""},
+		{name: "null key value", keyColumns: []string{"`ID`", "`UK`"}, data: map[string]interface{}{"`ID`": "1", "`UK`": "NULL"}, want: ""},
+		{name: "nil key value", keyColumns: []string{"`UK`"}, data: map[string]interface{}{"`UK`": nil}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := genIncrKey(tt.keyColumns, tt.data); got != tt.want {
+				t.Errorf("genIncrKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateKeylessInsertReplay(t *testing.T) {
+	keyColumns := []string{common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`")}
+	rowID := common.EncodeOracleRowid("AAAR8+AAEAAAACIAAA")
+	redo := "insert into `MARVIN`.`T1`(`ID`,`NAME`) values ('1','a')"
+
+	// 同一 INSERT 重放两次生成相同的按 ROWID 代理字段 REPLACE，配合代理字段唯一索引幂等
+	var replays [][]string
+	for i := 0; i < 2; i++ {
+		stmt, err := parseOracleIncrStmt(redo, "", "MARVIN", "T1")
+		if err != nil {
+			t.Fatalf("parseOracleIncrStmt() error: %v", err)
+		}
+		sqls, operationType, beforeKey, _ := genMySQLIncrSQL(stmt, keyColumns, rowID)
+		if operationType != common.MigrateOperationInsert || beforeKey != common.StringsBuilder(keyColumns[0], " = '", rowID, "'") {
+			t.Errorf("genMySQLIncrSQL() operation = %s, key = %s", operationType, beforeKey)
+		}
+		replays = append(replays, sqls)
+	}
+	want := []string{common.StringsBuilder("REPLACE INTO MARVIN.T1(`ID`,`NAME`,`", common.MigrateKeylessRowidColumn, "`) VALUES (_UTF8MB4'1',_UTF8MB4'a','", rowID, "')")}
+	for _, sqls := range replays {
+		if !reflect.DeepEqual(sqls, want) {
+			t.Errorf("genMySQLIncrSQL() = %v, want %v", sqls, want)
+		}
+	}
+}
+
+func TestTranslateAndMergeOracleIncrRecord(t *testing.T) {
+	keyColumns := []string{"`UK`"}
+	genRecord := func(scn uint64, op, redo string) public.Logminer {
+		return public.Logminer{SCN: scn, SourceSchema: "MARVIN", SourceTable: "T1", TargetSchema: "MARVIN", TargetTable: "T1", SQLRedo: redo, Operation: op}
+	}
+	tests := []struct {
+		name       string
+		checkpoint uint64
+		records    []public.Logminer
+		wantOps    []string
+	}{
+		{
+			// 唯一键 NULL 值不同行不可合并
+			name: "null unique key inserts",
+			records: []public.Logminer{
+				genRecord(10, common.MigrateOperationInsert, `insert into "MARVIN"."T1"("ID","UK") values ('1',NULL)`),
+				genRecord(11, common.MigrateOperationInsert, `insert into "MARVIN"."T1"("ID","UK") values ('2',NULL)`),
+			},
+			wantOps: []string{common.MigrateOperationInsert, common.MigrateOperationInsert},
+		},
+		{
+			name: "insert delete",
+			records: []public.Logminer{
+				genRecord(10, common.MigrateOperationInsert, `insert into "MARVIN"."T1"("ID","UK") values ('1','a')`),
+				genRecord(11, common.MigrateOperationDelete, `delete from "MARVIN"."T1" where "ID" = '1' and "UK" = 'a'`),
+			},
+			wantOps: nil,
+		},
+		{
+			// 断点重放 INSERT 下游可能已写入，保留 DELETE
+			name:       "replay insert delete",
+			checkpoint: 10,
+			records: []public.Logminer{
+				genRecord(10, common.MigrateOperationInsert, `insert into "MARVIN"."T1"("ID","UK") values ('1','a')`),
+				genRecord(11, common.MigrateOperationDelete, `delete from "MARVIN"."T1" where "ID" = '1' and "UK" = 'a'`),
+			},
+			wantOps: []string{common.MigrateOperationDelete},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := translateAndMergeOracleIncrRecord("ORACLE", "MYSQL", common.TaskModeAll, "MARVIN", "T1", nil, &mysql.MySQL{}, keyColumns, nil, tt.checkpoint, tt.records)
+			if err != nil {
+				t.Fatalf("translateAndMergeOracleIncrRecord() error: %v", err)
+			}
+			var ops []string
+			for _, task := range tasks {
+				ops = append(ops, task.OperationType)
+			}
+			if !reflect.DeepEqual(ops, tt.wantOps) {
+				t.Errorf("translateAndMergeOracleIncrRecord() operations = %v, want %v", ops, tt.wantOps)
+			}
+		})
+	}
+}
@@ -24,28 +24,43 @@ import (
 	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/database/mysql"
 	"github.com/wentaojin/transferdb/database/oracle"
+	compareO2T "github.com/wentaojin/transferdb/module/compare/oracle/o2t"
+	"github.com/wentaojin/transferdb/module/migrate"
 	"github.com/wentaojin/transferdb/module/migrate/sql/oracle/public"
+	"github.com/wentaojin/transferdb/module/pool"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Migrate struct {
 	Ctx         context.Context
+	ShutdownCtx context.Context
 	Cfg         *config.Config
 	Oracle      *oracle.Oracle
 	OracleMiner *oracle.Oracle
 	Mysql       *mysql.MySQL
 	MetaDB      *meta.Meta
+	Verify      *compareO2T.Verify // ALL 模式增量在线校验，未开启为 nil
+
+	incrQueryBoundary sync.Map // 查询方式增量各表跟踪字段边界行，key 为源端表名
 }
 
-func NewFuller(ctx context.Context, cfg *config.Config) (*Migrate, error) {
+func NewFuller(shutdownCtx context.Context, cfg *config.Config) (*Migrate, error) {
+	// 数据库操作不随退出信号取消，保证进行中 chunk 写入完成，任务调度由 shutdownCtx 控制退出
+	ctx := context.WithoutCancel(shutdownCtx)
 	oracleDB, err := oracle.NewOracleDBEngine(ctx, cfg.OracleConfig, cfg.SchemaConfig.SourceSchema)
 	if err != nil {
 		return nil, err
 	}
+	// 限流活跃会话监控随任务退出停止，退出时解除暂停保证进行中 chunk 读取完成
+	oracleDB.Throttle, err = oracle.NewThrottle(shutdownCtx, oracleDB, cfg.ThrottleConfig)
+	if err != nil {
+		return nil, err
+	}
 	mysqlDB, err := mysql.NewMySQLDBEngine(ctx, cfg.MySQLConfig)
 	if err != nil {
 		return nil, err
@@ -55,15 +70,17 @@ func NewFuller(ctx context.Context, cfg *config.Config) (*Migrate, error) {
 		return nil, err
 	}
 	return &Migrate{
-		Ctx:    ctx,
-		Cfg:    cfg,
-		Oracle: oracleDB,
-		Mysql:  mysqlDB,
-		MetaDB: metaDB,
+		Ctx:         ctx,
+		ShutdownCtx: shutdownCtx,
+		Cfg:         cfg,
+		Oracle:      oracleDB,
+		Mysql:       mysqlDB,
+		MetaDB:      metaDB,
 	}, nil
 }
 
 func (r *Migrate) Full() error {
+	defer r.Oracle.Throttle.Close()
 	startTime := time.Now()
 	zap.L().Info("source schema full table data sync start",
 		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema))
@@ -95,8 +112,8 @@ func (r *Migrate) Full() error {
 			zap.String("oracle config charset", r.Cfg.OracleConfig.Charset))
 		return fmt.Errorf("oracle charset [%v] and oracle config charset [%v] aren't equal, please adjust oracle config charset", sourceDBCharset, r.Cfg.OracleConfig.Charset)
 	}
-	if _, ok := common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(r.Cfg.OracleConfig.Charset)]; !ok {
-		return fmt.Errorf("oracle current charset [%v] isn't support, support charset [%v]", r.Cfg.OracleConfig.Charset, common.MigrateOracleCharsetStringConvertMapping)
+	if _, ok := common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset]; !ok {
+		return fmt.Errorf("oracle current charset [%v] isn't support, support charset [%v]", r.Cfg.OracleConfig.ActualCharset, common.MigrateOracleCharsetStringConvertMapping)
 	}
 	if !common.IsContainString(common.MigrateDataSupportCharset, common.StringUPPER(r.Cfg.MySQLConfig.Charset)) {
 		return fmt.Errorf("mysql current config charset [%v] isn't support, support charset [%v]", r.Cfg.MySQLConfig.Charset, common.MigrateDataSupportCharset)
@@ -108,6 +125,11 @@ func (r *Migrate) Full() error {
 		return err
 	}
 
+	// 子集迁移仅支持 FULL 模式，ALL 模式增量无法限定子集范围
+	if len(r.Cfg.SchemaConfig.SubsetConfig) > 0 && !strings.EqualFold(r.Cfg.TaskMode, common.TaskModeFull) {
+		return fmt.Errorf("config [schema-config] subset-config only support task mode [%s], current task mode [%s]", common.TaskModeFull, r.Cfg.TaskMode)
+	}
+
 	// 关于全量断点恢复
 	//  - 若想断点恢复，设置 enable-checkpoint true,首次一旦运行则 batch 数不能调整，
 	//  - 若不想断点恢复或者重新调整 batch 数，设置 enable-checkpoint false,清理元数据表 [wait_sync_meta],重新运行全量任务
@@ -133,6 +155,16 @@ func (r *Migrate) Full() error {
 			return err
 		}
 
+		err = meta.NewRejectRowDetailModel(r.MetaDB).DeleteRejectRowDetailBySchemaTaskMode(r.Ctx, &meta.RejectRowDetail{
+			DBTypeS:     r.Cfg.DBTypeS,
+			DBTypeT:     r.Cfg.DBTypeT,
+			SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+			TaskMode:    r.Cfg.TaskMode,
+		})
+		if err != nil {
+			return err
+		}
+
 		for _, tableName := range exporters {
 			err = meta.NewWaitSyncMetaModel(r.MetaDB).DeleteWaitSyncMeta(r.Ctx, &meta.WaitSyncMeta{
 				DBTypeS:     r.Cfg.DBTypeS,
@@ -299,6 +331,18 @@ func (r *Migrate) Full() error {
 	if err != nil {
 		return err
 	}
+	// 优雅退出中断的表 chunk 已切分，状态 WAITING，同样断点续传
+	suspendSyncDetails, err := meta.NewWaitSyncMetaModel(r.MetaDB).QueryWaitSyncMetaByPartTask(r.Ctx, &meta.WaitSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+		TaskMode:    r.Cfg.TaskMode,
+		TaskStatus:  common.TaskStatusWaiting,
+	})
+	if err != nil {
+		return err
+	}
+	partSyncDetails = append(partSyncDetails, suspendSyncDetails...)
 	if len(partSyncDetails) > 0 {
 		for _, t := range partSyncDetails {
 			// 判断 running 状态表 chunk 数是否一致，一致可断点续传
@@ -345,12 +389,20 @@ func (r *Migrate) Full() error {
 		}
 	}
 	if len(waitSyncTables) > 0 {
-		err = r.FullWaitSyncTable(waitSyncTables, tableNameRule, oracleCollation)
+		err = r.FullWaitSyncTable(exporters, waitSyncTables, tableNameRule, oracleCollation)
 		if err != nil {
 			return err
 		}
 	}
 
+	if r.ShutdownCtx.Err() != nil {
+		zap.L().Warn("full table data sync graceful shutdown",
+			zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+			zap.String("resume", "unfinished chunks are marked WAITING, rerun with [enable-checkpoint = true] to resume"),
+			zap.String("cost", time.Now().Sub(startTime).String()))
+		return common.ErrGracefulShutdown
+	}
+
 	// 任务详情
 	succTotals, err := meta.NewWaitSyncMetaModel(r.MetaDB).DetailWaitSyncMeta(r.Ctx, &meta.WaitSyncMeta{
 		DBTypeS:     r.Cfg.DBTypeS,
@@ -373,6 +425,13 @@ func (r *Migrate) Full() error {
 		return err
 	}
 
+	// 全量数据导入完成后创建延迟索引/约束，存在失败表时不创建
+	if len(failedTotals) == 0 {
+		if err = r.createDeferIndex(); err != nil {
+			return err
+		}
+	}
+
 	zap.L().Info("all full table data sync finished",
 		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
 		zap.Int("table totals", len(exporters)),
@@ -397,6 +456,10 @@ func (r *Migrate) FullPartSyncTable(fullPartTables []string, tableNameRule map[s
 	for _, table := range fullPartTables {
 		t := table
 		g.Go(func() error {
+			// 收到退出信号，未开始的表保持原状态
+			if r.ShutdownCtx.Err() != nil {
+				return nil
+			}
 			startTime := time.Now()
 			err := meta.NewWaitSyncMetaModel(r.MetaDB).UpdateWaitSyncMeta(r.Ctx, &meta.WaitSyncMeta{
 				DBTypeS:     r.Cfg.DBTypeS,
@@ -451,7 +514,7 @@ func (r *Migrate) FullPartSyncTable(fullPartTables []string, tableNameRule map[s
 			columnNameS, err := r.Oracle.GetOracleTableRowsColumn(
 				common.StringsBuilder(`SELECT *`, ` FROM `,
 					common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema), `.`, common.StringUPPER(t), ` WHERE ROWNUM = 1`),
-				common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(r.Cfg.OracleConfig.Charset)],
+				common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset],
 				common.StringUPPER(r.Cfg.MySQLConfig.Charset))
 			if err != nil {
 				return nil
@@ -464,6 +527,12 @@ func (r *Migrate) FullPartSyncTable(fullPartTables []string, tableNameRule map[s
 				targetTableName = common.StringUPPER(t)
 			}
 
+			// 无主键/唯一键表 ROWID 代理字段，以 chunk 初始化时查询字段为准
+			isKeyless := len(waitFullMetas) > 0 && strings.Contains(waitFullMetas[0].ColumnDetailS, common.MigrateKeylessRowidColumn)
+			if isKeyless {
+				columnNameS = append(columnNameS, common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`"))
+			}
+
 			sqlStr00 := GenMySQLTablePrepareStmt(common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema), targetTableName, columnNameS, r.Cfg.AppConfig.InsertBatchSize, true)
 			stmt, err := r.Mysql.MySQLDB.PrepareContext(r.Ctx, sqlStr00)
 			if err != nil {
@@ -471,11 +540,49 @@ func (r *Migrate) FullPartSyncTable(fullPartTables []string, tableNameRule map[s
 			}
 			defer stmt.Close()
 
-			g1 := &errgroup.Group{}
-			g1.SetLimit(r.Cfg.FullConfig.SQLThreads)
+			// 坏行隔离，重新导入的 chunk 清理已有隔离记录
+			var rejecter *migrate.Rejecter
+			if r.Cfg.RejectConfig.Enable {
+				var chunks []string
+				for _, fullMeta := range waitFullMetas {
+					chunks = append(chunks, fullMeta.ChunkDetailS)
+				}
+				err = meta.NewRejectRowDetailModel(r.MetaDB).DeleteRejectRowDetailByChunks(r.Ctx, &meta.RejectRowDetail{
+					DBTypeS:     r.Cfg.DBTypeS,
+					DBTypeT:     r.Cfg.DBTypeT,
+					SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+					TableNameS:  common.StringUPPER(t),
+					TaskMode:    r.Cfg.TaskMode,
+				}, chunks)
+				if err != nil {
+					return err
+				}
+				rejecter, err = migrate.NewRejecter(r.Ctx, r.Cfg, r.MetaDB, common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema), common.StringUPPER(t),
+					common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema), targetTableName)
+				if err != nil {
+					return err
+				}
+				defer rejecter.Close()
+			}
+
+			// chunk 并发调度，开启 adaptive-concurrency 依据写入延迟以及下游繁忙错误自动调整并发
+			sched := pool.NewScheduler(common.StringsBuilder(r.Cfg.TaskMode, "/", common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema), ".", common.StringUPPER(t)),
+				r.Cfg.FullConfig.SQLThreads, r.Cfg.AppConfig)
+			defer sched.Release()
+			g1 := sched.NewGroup()
 			for _, fullMeta := range waitFullMetas {
 				m := fullMeta
 				g1.Go(func() error {
+					// 收到退出信号，未开始的 chunk 不再写入
+					if r.ShutdownCtx.Err() != nil {
+						return nil
+					}
+					// 无主键/唯一键表断点续传，RUNNING/FAILED chunk 可能已部分写入，按 chunk ROWID 范围清理后重新导入
+					if isKeyless && (m.TaskStatus == common.TaskStatusRunning || m.TaskStatus == common.TaskStatusFailed) {
+						if errf := r.Mysql.DeleteMySQLTableKeylessChunk(m.SchemaNameT, m.TableNameT, m.ChunkDetailS); errf != nil {
+							return errf
+						}
+					}
 					if errf := meta.NewFullSyncMetaModel(r.MetaDB).UpdateFullSyncMetaChunk(r.Ctx, &meta.FullSyncMeta{
 						DBTypeS:      m.DBTypeS,
 						DBTypeT:      m.DBTypeT,
@@ -490,11 +597,12 @@ func (r *Migrate) FullPartSyncTable(fullPartTables []string, tableNameRule map[s
 					}
 					// 数据写入
 					err = public.IMigrate(NewRows(r.Ctx, m, r.Oracle, r.Mysql, stmt,
-						common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(r.Cfg.OracleConfig.Charset)],
+						common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset],
 						common.StringUPPER(r.Cfg.MySQLConfig.Charset),
-						r.Cfg.FullConfig.ApplyThreads, r.Cfg.AppConfig.InsertBatchSize, r.Cfg.FullConfig.CallTimeout, true, columnNameS))
+						r.Cfg.FullConfig.ApplyThreads, r.Cfg.AppConfig.InsertBatchSize, r.Cfg.FullConfig.CallTimeout, true, columnNameS, r.Cfg.RejectConfig.InvalidCharPolicy, rejecter))
 
 					if err != nil {
+						sched.Feedback(err)
 						// record error, skip error
 						errf := meta.NewCommonModel(r.MetaDB).UpdateFullSyncMetaChunkAndCreateChunkErrorDetail(r.Ctx, &meta.FullSyncMeta{
 							DBTypeS:      m.DBTypeS,
@@ -543,6 +651,11 @@ func (r *Migrate) FullPartSyncTable(fullPartTables []string, tableNameRule map[s
 				return err
 			}
 
+			// 进行中 chunk 写入完成，未完成 chunk 标记 WAITING 等待断点续传
+			if r.ShutdownCtx.Err() != nil {
+				return r.suspendFullSyncTable(t, startTime)
+			}
+
 			// 清理元数据记录
 			// 更新 wait_sync_meta 记录
 			failedChunkTotalErrs, err := meta.NewFullSyncMetaModel(r.MetaDB).CountsErrorFullSyncMeta(r.Ctx, &meta.FullSyncMeta{
@@ -632,8 +745,8 @@ func (r *Migrate) FullPartSyncTable(fullPartTables []string, tableNameRule map[s
 	return nil
 }
 
-func (r *Migrate) FullWaitSyncTable(fullWaitTables []string, tableNameRule map[string]string, oracleCollation bool) error {
-	err := r.InitWaitSyncTableChunk(fullWaitTables, tableNameRule, oracleCollation)
+func (r *Migrate) FullWaitSyncTable(exporters, fullWaitTables []string, tableNameRule map[string]string, oracleCollation bool) error {
+	err := r.InitWaitSyncTableChunk(exporters, fullWaitTables, tableNameRule, oracleCollation)
 	if err != nil {
 		return err
 	}
@@ -645,7 +758,8 @@ func (r *Migrate) FullWaitSyncTable(fullWaitTables []string, tableNameRule map[s
 	return nil
 }
 
-func (r *Migrate) InitWaitSyncTableChunk(fullWaitTables []string, tableNameRule map[string]string, oracleCollation bool) error {
+// exporters 为所有待迁移表，子集迁移以所有待迁移表计算外键闭包，断点续传已完成表同样作为外键连通路径
+func (r *Migrate) InitWaitSyncTableChunk(exporters, fullWaitTables []string, tableNameRule map[string]string, oracleCollation bool) error {
 	startTask := time.Now()
 	zap.L().Info("init source schema table wait_sync_meta and full_sync_meta starting",
 		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
@@ -672,12 +786,25 @@ func (r *Migrate) InitWaitSyncTableChunk(fullWaitTables []string, tableNameRule
 		isConsistentRead = "NO"
 	}
 
+	// 子集迁移，同一 SCN 计算根表外键关联行闭包
+	var subsetRowids map[string][]string
+	if len(r.Cfg.SchemaConfig.SubsetConfig) > 0 {
+		subsetRowids, err = r.genSubsetTableRowids(exporters, globalSCN)
+		if err != nil {
+			return err
+		}
+	}
+
 	g := &errgroup.Group{}
 	g.SetLimit(r.Cfg.FullConfig.TaskThreads)
 
 	for _, table := range fullWaitTables {
 		t := table
 		g.Go(func() error {
+			// 收到退出信号，未切分的表保持 WAITING
+			if r.ShutdownCtx.Err() != nil {
+				return nil
+			}
 			startTime := time.Now()
 			// 库名、表名规则
 			var targetTableName string
@@ -707,6 +834,20 @@ func (r *Migrate) InitWaitSyncTableChunk(fullWaitTables []string, tableNameRule
 				return err
 			}
 
+			// 无主键/唯一键表，下游增加 ROWID 代理字段，源端查询 ROWID 保序编码
+			if r.Cfg.FullConfig.KeylessRowid {
+				isKeyless, err := r.Mysql.IsMySQLTableKeyless(common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema), targetTableName)
+				if err != nil {
+					return err
+				}
+				if isKeyless {
+					if err = r.Mysql.AddMySQLTableKeylessRowidColumn(common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema), targetTableName); err != nil {
+						return err
+					}
+					sourceColumnInfo = common.StringsBuilder(sourceColumnInfo, ",", common.GenOracleRowidOrderColumn())
+				}
+			}
+
 			var (
 				isPartition string
 			)
@@ -721,6 +862,81 @@ func (r *Migrate) InitWaitSyncTableChunk(fullWaitTables []string, tableNameRule
 				return err
 			}
 
+			// 子集表按闭包 ROWID 切分 chunk，强制一致性读，忽略 range 配置
+			if rowids, ok := subsetRowids[common.StringUPPER(t)]; ok {
+				var fullMetas []meta.FullSyncMeta
+				if len(rowids) == 0 {
+					fullMetas = append(fullMetas, meta.FullSyncMeta{
+						DBTypeS:        r.Cfg.DBTypeS,
+						DBTypeT:        r.Cfg.DBTypeT,
+						SchemaNameS:    common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+						TableNameS:     common.StringUPPER(t),
+						SchemaNameT:    common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema),
+						TableNameT:     common.StringUPPER(targetTableName),
+						GlobalScnS:     globalSCN,
+						ConsistentRead: "YES",
+						SQLHint:        sqlHint,
+						ColumnDetailS:  sourceColumnInfo,
+						ChunkDetailS:   `1 = 0`,
+						TaskMode:       r.Cfg.TaskMode,
+						TaskStatus:     common.TaskStatusWaiting,
+					})
+				}
+				for i := 0; i < len(rowids); i += common.SubsetChunkRowids {
+					end := i + common.SubsetChunkRowids
+					if end > len(rowids) {
+						end = len(rowids)
+					}
+					fullMetas = append(fullMetas, meta.FullSyncMeta{
+						DBTypeS:        r.Cfg.DBTypeS,
+						DBTypeT:        r.Cfg.DBTypeT,
+						SchemaNameS:    common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+						TableNameS:     common.StringUPPER(t),
+						SchemaNameT:    common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema),
+						TableNameT:     common.StringUPPER(targetTableName),
+						GlobalScnS:     globalSCN,
+						ConsistentRead: "YES",
+						SQLHint:        sqlHint,
+						ColumnDetailS:  sourceColumnInfo,
+						ChunkDetailS:   common.StringsBuilder(`ROWID BETWEEN '`, rowids[i], `' AND '`, rowids[end-1], `'`),
+						ChunkRowidS:    common.StringJOIN(rowids[i:end], `'`, `'`, `,`),
+						TaskMode:       r.Cfg.TaskMode,
+						TaskStatus:     common.TaskStatusWaiting,
+					})
+				}
+
+				err = meta.NewFullSyncMetaModel(r.MetaDB).BatchCreateFullSyncMeta(r.Ctx, fullMetas, r.Cfg.AppConfig.InsertBatchSize)
+				if err != nil {
+					return err
+				}
+				err = meta.NewWaitSyncMetaModel(r.MetaDB).UpdateWaitSyncMeta(r.Ctx, &meta.WaitSyncMeta{
+					DBTypeS:     r.Cfg.DBTypeS,
+					DBTypeT:     r.Cfg.DBTypeT,
+					SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+					TableNameS:  common.StringUPPER(t),
+					TaskMode:    r.Cfg.TaskMode,
+				}, map[string]interface{}{
+					"TableNumRows":     uint64(len(rowids)),
+					"GlobalScnS":       globalSCN,
+					"ConsistentRead":   "YES",
+					"ChunkTotalNums":   len(fullMetas),
+					"ChunkSuccessNums": 0,
+					"ChunkFailedNums":  0,
+					"IsPartition":      isPartition,
+				})
+				if err != nil {
+					return err
+				}
+
+				zap.L().Info("init source single subset table wait_sync_meta and full_sync_meta finished",
+					zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+					zap.String("table", t),
+					zap.Int("rows", len(rowids)),
+					zap.Int("chunks", len(fullMetas)),
+					zap.String("cost", time.Now().Sub(startTime).String()))
+				return nil
+			}
+
 			taskName := uuid.New().String()
 
 			if err = r.Oracle.StartOracleChunkCreateTask(taskName); err != nil {
@@ -853,6 +1069,53 @@ func (r *Migrate) InitWaitSyncTableChunk(fullWaitTables []string, tableNameRule
 	return nil
 }
 
+func (r *Migrate) suspendFullSyncTable(tableName string, startTime time.Time) error {
+	err := meta.NewFullSyncMetaModel(r.MetaDB).UpdateFullSyncMetaChunkByTaskStatus(r.Ctx, &meta.FullSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+		TableNameS:  common.StringUPPER(tableName),
+		TaskMode:    r.Cfg.TaskMode,
+		TaskStatus:  common.TaskStatusRunning,
+	}, map[string]interface{}{
+		"TaskStatus": common.TaskStatusWaiting,
+	})
+	if err != nil {
+		return err
+	}
+	successChunkFullMeta, err := meta.NewFullSyncMetaModel(r.MetaDB).DetailFullSyncMeta(r.Ctx, &meta.FullSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+		TableNameS:  common.StringUPPER(tableName),
+		TaskMode:    r.Cfg.TaskMode,
+		TaskStatus:  common.TaskStatusSuccess,
+	})
+	if err != nil {
+		return err
+	}
+	err = meta.NewWaitSyncMetaModel(r.MetaDB).UpdateWaitSyncMeta(r.Ctx, &meta.WaitSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+		TableNameS:  common.StringUPPER(tableName),
+		TaskMode:    r.Cfg.TaskMode,
+	}, map[string]interface{}{
+		"TaskStatus":       common.TaskStatusWaiting,
+		"ChunkSuccessNums": int64(len(successChunkFullMeta)),
+	})
+	if err != nil {
+		return err
+	}
+	zap.L().Warn("full single table graceful shutdown",
+		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+		zap.String("table", common.StringUPPER(tableName)),
+		zap.Int("success chunks", len(successChunkFullMeta)),
+		zap.String("status", common.TaskStatusWaiting),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return nil
+}
+
 func (r *Migrate) GetCustomMigrateConfig() map[string]config.MigrateConfig {
 	tableMigrateMap := make(map[string]config.MigrateConfig)
 	for _, t := range r.Cfg.SchemaConfig.MigrateConfig {
@@ -890,19 +1153,39 @@ func (r *Migrate) AdjustTableSelectColumn(sourceTable string, oracleCollation bo
 		return "", err
 	}
 
+	// 字段脱敏规则
+	maskRules, err := meta.NewColumnMaskRuleModel(r.MetaDB).GetColumnMaskRuleMap(r.Ctx, &meta.ColumnMaskRule{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+		TableNameS:  sourceTable,
+	})
+	if err != nil {
+		return "", err
+	}
+
 	var columnNames []string
 
 	for _, rowCol := range columnsINFO {
 		columnName := rowCol["COLUMN_NAME"]
 		// 以 utf8mb4 字符集存储 meta
 
-		convertUtf8Raw, err := common.CharsetConvert([]byte(columnName), common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(r.Cfg.OracleConfig.Charset)], common.CharsetUTF8MB4)
+		convertUtf8Raw, err := common.CharsetConvert([]byte(columnName), common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset], common.CharsetUTF8MB4)
 		if err != nil {
 			return "", fmt.Errorf("column [%s] charset convert failed, %v", columnName, err)
 		}
 
 		columnName = string(convertUtf8Raw)
 
+		if rule, ok := maskRules[common.StringUPPER(columnName)]; ok {
+			maskExpr, err := common.GenOracleMaskColumnExpr(rule.MaskType, rule.MaskValue, columnName, rowCol["DATA_TYPE"])
+			if err != nil {
+				return "", fmt.Errorf("table [%s] column [%s] mask rule failed: %v", sourceTable, columnName, err)
+			}
+			columnNames = append(columnNames, common.StringsBuilder(maskExpr, ` AS "`, columnName, `"`))
+			continue
+		}
+
 		switch strings.ToUpper(rowCol["DATA_TYPE"]) {
 		// 数字
 		case "NUMBER":
@@ -926,17 +1209,19 @@ func (r *Migrate) AdjustTableSelectColumn(sourceTable string, oracleCollation bo
 			if strings.Contains(rowCol["DATA_TYPE"], "INTERVAL") {
 				columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR("`, columnName, `") AS "`, columnName, `"`))
 			} else if strings.Contains(rowCol["DATA_TYPE"], "TIMESTAMP") {
+				// 带时区时间字段按 [app] time-zone 规整
+				timestampColumn := common.GenOracleTimeZoneColumnExpr(common.StringsBuilder(`"`, columnName, `"`), rowCol["DATA_TYPE"], r.Cfg.AppConfig.TimeZone)
 				dataScale, err := strconv.Atoi(rowCol["DATA_SCALE"])
 				if err != nil {
 					return "", fmt.Errorf("aujust oracle timestamp datatype scale [%s] strconv.Atoi failed: %v", rowCol["DATA_SCALE"], err)
 				}
 				if dataScale == 0 {
-					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR("`, columnName, `",'yyyy-mm-dd hh24:mi:ss') AS "`, columnName, `"`))
+					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR(`, timestampColumn, `,'yyyy-mm-dd hh24:mi:ss') AS "`, columnName, `"`))
 				} else if dataScale < 0 && dataScale <= 6 {
-					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR("`, columnName,
-						`",'yyyy-mm-dd hh24:mi:ss.ff`, rowCol["DATA_SCALE"], `') AS "`, columnName, `"`))
+					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR(`, timestampColumn,
+						`,'yyyy-mm-dd hh24:mi:ss.ff`, rowCol["DATA_SCALE"], `') AS "`, columnName, `"`))
 				} else {
-					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR("`, columnName, `",'yyyy-mm-dd hh24:mi:ss.ff6') AS "`, columnName, `"`))
+					columnNames = append(columnNames, common.StringsBuilder(`TO_CHAR(`, timestampColumn, `,'yyyy-mm-dd hh24:mi:ss.ff6') AS "`, columnName, `"`))
 				}
 
 			} else {
@@ -23,6 +23,7 @@ import (
 	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/database/mysql"
 	"github.com/wentaojin/transferdb/database/oracle"
+	compareO2T "github.com/wentaojin/transferdb/module/compare/oracle/o2t"
 	"github.com/wentaojin/transferdb/module/migrate/sql/oracle/public"
 	"go.uber.org/zap"
 	"strconv"
@@ -30,14 +31,20 @@ import (
 	"time"
 )
 
-func NewIncr(ctx context.Context, cfg *config.Config) (*Migrate, error) {
+func NewIncr(shutdownCtx context.Context, cfg *config.Config) (*Migrate, error) {
+	// 数据库操作不随退出信号取消，保证进行中增量 batch 应用完成，任务调度由 shutdownCtx 控制退出
+	ctx := context.WithoutCancel(shutdownCtx)
 	oracleDB, err := oracle.NewOracleDBEngine(ctx, cfg.OracleConfig, cfg.SchemaConfig.SourceSchema)
 	if err != nil {
 		return nil, err
 	}
-	oracleMiner, err := oracle.NewOracleLogminerEngine(ctx, cfg.OracleConfig)
-	if err != nil {
-		return nil, err
+	// 查询方式增量同步无需 logminer
+	var oracleMiner *oracle.Oracle
+	if cfg.AllConfig.IncrMode != common.IncrModeQuery {
+		oracleMiner, err = oracle.NewOracleLogminerEngine(ctx, cfg.OracleConfig)
+		if err != nil {
+			return nil, err
+		}
 	}
 	mysqlDB, err := mysql.NewMySQLDBEngine(ctx, cfg.MySQLConfig)
 	if err != nil {
@@ -48,13 +55,24 @@ func NewIncr(ctx context.Context, cfg *config.Config) (*Migrate, error) {
 		return nil, err
 	}
 
+	// 增量在线校验
+	var verify *compareO2T.Verify
+	if cfg.AllConfig.VerifyInterval > 0 {
+		verify, err = compareO2T.NewVerify(ctx, cfg, oracleDB, mysqlDB, metaDB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Migrate{
 		Ctx:         ctx,
+		ShutdownCtx: shutdownCtx,
 		Cfg:         cfg,
 		Oracle:      oracleDB,
 		OracleMiner: oracleMiner,
 		Mysql:       mysqlDB,
 		MetaDB:      metaDB,
+		Verify:      verify,
 	}, nil
 }
 
@@ -84,8 +102,8 @@ func (r *Migrate) Incr() error {
 			zap.String("oracle config charset", r.Cfg.OracleConfig.Charset))
 		return fmt.Errorf("oracle charset [%v] and oracle config charset [%v] aren't equal, please adjust oracle config charset", sourceDBCharset, r.Cfg.OracleConfig.Charset)
 	}
-	if _, ok := common.MigrateOracleCharsetStringConvertMapping[common.StringUPPER(r.Cfg.OracleConfig.Charset)]; !ok {
-		return fmt.Errorf("oracle current charset [%v] isn't support, support charset [%v]", r.Cfg.OracleConfig.Charset, common.MigrateOracleCharsetStringConvertMapping)
+	if _, ok := common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset]; !ok {
+		return fmt.Errorf("oracle current charset [%v] isn't support, support charset [%v]", r.Cfg.OracleConfig.ActualCharset, common.MigrateOracleCharsetStringConvertMapping)
 	}
 	if !common.IsContainString(common.MigrateDataSupportCharset, common.StringUPPER(r.Cfg.MySQLConfig.Charset)) {
 		return fmt.Errorf("mysql current config charset [%v] isn't support, support charset [%v]", r.Cfg.MySQLConfig.Charset, common.MigrateDataSupportCharset)
@@ -97,6 +115,16 @@ func (r *Migrate) Incr() error {
 		return err
 	}
 
+	// logminer 增量脱敏 EXPR 表达式只可引用当前字段
+	if r.Cfg.AllConfig.IncrMode != common.IncrModeQuery {
+		if err = public.ValidateIncrColumnMask(r.Ctx, r.MetaDB, r.Cfg.DBTypeS, r.Cfg.DBTypeT, r.Cfg.SchemaConfig.SourceSchema); err != nil {
+			return err
+		}
+		if err = r.validateIncrTimeZone(exporters); err != nil {
+			return err
+		}
+	}
+
 	// 判断 [wait_sync_meta] 是否存在错误记录，是否可进行 ALL
 	errTotals, err := meta.NewWaitSyncMetaModel(r.MetaDB).CountsErrWaitSyncMetaBySchema(r.Ctx, &meta.WaitSyncMeta{
 		DBTypeS:     r.Cfg.DBTypeS,
@@ -166,12 +194,7 @@ func (r *Migrate) Incr() error {
 				return fmt.Errorf("table list %s can't incremently sync, because table increment sync meta record is exist and full meta sync isn't finished", panicTables)
 			}
 			// 增量数据同步
-			for range time.Tick(300 * time.Millisecond) {
-				if err := r.syncTableIncrRecord(); err != nil {
-					return err
-				}
-			}
-			return nil
+			return r.loopTableIncr()
 		}
 
 		// 配置文件获取的表列表不等于 increment_sync_meta 表列表数，不能直接增量同步，需要手工调整
@@ -180,6 +203,15 @@ func (r *Migrate) Incr() error {
 
 	// 如果下游数据库增量元数据表 incr_sync_meta 不存在任何记录，说明未进行过数据同步，则进行全量 + 增量数据同步
 	if len(incrExistTableList) == 0 && len(incrIsNotExistTableList) == len(exporters) {
+		// 指定增量起始 SCN 或者时间点，数据已由其他工具加载，跳过全量同步
+		if r.Cfg.AllConfig.StartSCN > 0 || r.Cfg.AllConfig.StartTime != "" {
+			if err = r.initIncrSyncMetaByStartSCN(exporters); err != nil {
+				return err
+			}
+			// 增量数据同步
+			return r.loopTableIncr()
+		}
+
 		// 全量同步
 		err = r.Full()
 		if err != nil {
@@ -215,6 +247,15 @@ func (r *Migrate) Incr() error {
 					targetTableName = common.StringUPPER(table.TableNameS)
 				}
 
+				// 查询方式增量同步，表同步 SCN 记录变更跟踪字段起始 checkpoint
+				tableSCN := table.GlobalScnS
+				if r.Cfg.AllConfig.IncrMode == common.IncrModeQuery {
+					tableSCN, err = r.initIncrQueryTableSCN(table.TableNameS, table.GlobalScnS)
+					if err != nil {
+						return err
+					}
+				}
+
 				incrSyncMetas = append(incrSyncMetas, meta.IncrSyncMeta{
 					DBTypeS:     r.Cfg.DBTypeS,
 					DBTypeT:     r.Cfg.DBTypeT,
@@ -223,7 +264,7 @@ func (r *Migrate) Incr() error {
 					TableNameS:  common.StringUPPER(table.TableNameS),
 					SchemaNameT: common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema),
 					TableNameT:  common.StringUPPER(targetTableName),
-					TableScnS:   table.GlobalScnS,
+					TableScnS:   tableSCN,
 					IsPartition: table.IsPartition,
 				})
 			}
@@ -236,277 +277,322 @@ func (r *Migrate) Incr() error {
 		}
 
 		// 增量数据同步
-		for range time.Tick(300 * time.Millisecond) {
-			if err = r.syncTableIncrRecord(); err != nil {
-				return err
-			}
-		}
-		return nil
+		return r.loopTableIncr()
 	}
 	return fmt.Errorf("increment sync taskflow condition isn't match, can't sync")
 }
 
-func (r *Migrate) syncTableIncrRecord() error {
-	// 获取自定义库表名规则
-	tableNameRule, err := r.GetTableNameRule()
+// 以指定起始 SCN 或者时间点初始化增量元数据表 [incr_sync_meta]
+// 同时写入成功状态 [wait_sync_meta] 记录（chunk 数为 0），断点续传时视为全量已完成
+func (r *Migrate) initIncrSyncMetaByStartSCN(exporters []string) error {
+	startSCN := r.Cfg.AllConfig.StartSCN
+	if r.Cfg.AllConfig.StartTime != "" {
+		scn, err := r.Oracle.GetOracleTimestampToSCN(r.Cfg.AllConfig.StartTime)
+		if err != nil {
+			return err
+		}
+		startSCN = scn
+	}
+
+	// 全量元数据存在记录，说明已进行过全量同步，不允许指定起始 SCN
+	for _, t := range exporters {
+		waitSyncMetas, err := meta.NewWaitSyncMetaModel(r.MetaDB).DetailWaitSyncMeta(r.Ctx, &meta.WaitSyncMeta{
+			DBTypeS:     r.Cfg.DBTypeS,
+			DBTypeT:     r.Cfg.DBTypeT,
+			SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+			TableNameS:  common.StringUPPER(t),
+			TaskMode:    r.Cfg.TaskMode,
+		})
+		if err != nil {
+			return err
+		}
+		if len(waitSyncMetas) > 0 {
+			return fmt.Errorf("table [%s] meta table [wait_sync_meta] record is exist, can't increment sync from start scn [%d], please clear meta table [wait_sync_meta] and [full_sync_meta] table record", t, startSCN)
+		}
+	}
+
+	partitionTables, err := r.Oracle.GetOracleSchemaPartitionTable(r.Cfg.SchemaConfig.SourceSchema)
 	if err != nil {
 		return err
 	}
-
-	// 获取增量所需得日志文件
-	logFiles, err := r.getTableIncrRecordLogfile()
+	// 获取自定义库表名规则
+	tableNameRule, err := r.GetTableNameRule()
 	if err != nil {
 		return err
 	}
-	zap.L().Info("increment table log file get",
-		zap.String("logfile", fmt.Sprintf("%v", logFiles)))
 
-	// 遍历所有日志文件
-	for _, log := range logFiles {
-		// 获取日志文件起始 SCN
-		logFileStartSCN, err := common.StrconvUintBitSize(log["FIRST_CHANGE"], 64)
-		if err != nil {
-			return fmt.Errorf("get oracle log file start scn %s utils.StrconvUintBitSize failed: %v", log["FIRST_CHANGE"], err)
+	var (
+		waitSyncMetas []meta.WaitSyncMeta
+		incrSyncMetas []meta.IncrSyncMeta
+	)
+	for _, t := range exporters {
+		var targetTableName string
+		if val, ok := tableNameRule[common.StringUPPER(t)]; ok {
+			targetTableName = val
+		} else {
+			targetTableName = common.StringUPPER(t)
 		}
-
-		// 获取日志文件结束 SCN
-		logFileEndSCN, err := common.StrconvUintBitSize(log["NEXT_CHANGE"], 64)
-		if err != nil {
-			return fmt.Errorf("get oracle log file end scn %s utils.StrconvUintBitSize failed: %v", log["NEXT_CHANGE"], err)
+		isPartition := "NO"
+		if common.IsContainString(partitionTables, common.StringUPPER(t)) {
+			isPartition = "YES"
 		}
 
-		zap.L().Info("increment table log file logminer",
-			zap.String("logfile", log["LOG_FILE"]),
-			zap.Uint64("logfile start scn", logFileStartSCN),
-			zap.Uint64("logminer start scn", logFileStartSCN),
-			zap.Uint64("logfile end scn", logFileEndSCN))
+		// 查询方式增量同步，表同步 SCN 记录变更跟踪字段起始 checkpoint
+		tableSCN := startSCN
+		if r.Cfg.AllConfig.IncrMode == common.IncrModeQuery {
+			tableSCN, err = r.initIncrQueryTableSCN(t, startSCN)
+			if err != nil {
+				return err
+			}
+		}
 
-		// 获取增量元数据表内所需同步表信息
-		incrSyncMetas, err := meta.NewIncrSyncMetaModel(r.MetaDB).DetailIncrSyncMetaBySchema(r.Ctx, &meta.IncrSyncMeta{
+		waitSyncMetas = append(waitSyncMetas, meta.WaitSyncMeta{
+			DBTypeS:          r.Cfg.DBTypeS,
+			DBTypeT:          r.Cfg.DBTypeT,
+			SchemaNameS:      common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+			TableNameS:       common.StringUPPER(t),
+			TaskMode:         r.Cfg.TaskMode,
+			TaskStatus:       common.TaskStatusSuccess,
+			GlobalScnS:       startSCN,
+			ConsistentRead:   "NO",
+			ChunkTotalNums:   0,
+			ChunkSuccessNums: 0,
+			ChunkFailedNums:  0,
+			IsPartition:      isPartition,
+		})
+		incrSyncMetas = append(incrSyncMetas, meta.IncrSyncMeta{
 			DBTypeS:     r.Cfg.DBTypeS,
 			DBTypeT:     r.Cfg.DBTypeT,
-			SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+			GlobalScnS:  startSCN,
+			SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+			TableNameS:  common.StringUPPER(t),
+			SchemaNameT: common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema),
+			TableNameT:  common.StringUPPER(targetTableName),
+			TableScnS:   tableSCN,
+			IsPartition: isPartition,
 		})
-		if err != nil {
-			return err
-		}
-		if len(incrSyncMetas) == 0 {
-			return fmt.Errorf("mysql increment mete table [incr_sync_meta] can't null")
-		}
+	}
+	// 各表元数据单事务写入，部分失败回滚，重跑不受残留记录影响
+	if err = meta.NewCommonModel(r.MetaDB).BatchCreateWaitSyncMetaAndIncrSyncMeta(r.Ctx, waitSyncMetas, incrSyncMetas, r.Cfg.AppConfig.InsertBatchSize); err != nil {
+		return err
+	}
+	zap.L().Info("increment sync meta init by start scn, skip full sync",
+		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+		zap.Uint64("start scn", startSCN),
+		zap.String("start time", r.Cfg.AllConfig.StartTime),
+		zap.Int("table totals", len(incrSyncMetas)))
+	return nil
+}
 
-		var (
-			transferTableMetaMap map[string]uint64
-			syncSourceTables     []string
-		)
-		transferTableMetaMap = make(map[string]uint64)
-		for _, tbl := range incrSyncMetas {
-			transferTableMetaMap[strings.ToUpper(tbl.TableNameS)] = tbl.TableScnS
-			syncSourceTables = append(syncSourceTables, strings.ToUpper(tbl.TableNameS))
-		}
+// 增量数据同步，按增量同步方式选择 logminer 日志挖掘或者查询轮询
+func (r *Migrate) loopTableIncr() error {
+	if r.Cfg.AllConfig.IncrMode == common.IncrModeQuery {
+		return r.loopTableIncrQuery()
+	}
+	return r.loopTableIncrRecord()
+}
 
-		// 获取 logminer query 起始最小 SCN
-		minSourceTableSCN, err := meta.NewIncrSyncMetaModel(r.MetaDB).GetIncrSyncMetaMinTableScnSBySchema(r.Ctx, &meta.IncrSyncMeta{
-			DBTypeS:     r.Cfg.DBTypeS,
-			DBTypeT:     r.Cfg.DBTypeT,
-			SchemaNameS: r.Cfg.SchemaConfig.SourceSchema})
-		if err != nil {
-			return err
+func (r *Migrate) loopTableIncrRecord() error {
+	ticker := time.NewTicker(300 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ShutdownCtx.Done():
+			// 进行中日志文件应用完成，checkpoint 已持久化至 incr_sync_meta
+			globalSCN, err := meta.NewIncrSyncMetaModel(r.MetaDB).GetIncrSyncMetaMinGlobalScnSBySchema(r.Ctx, &meta.IncrSyncMeta{
+				DBTypeS:     r.Cfg.DBTypeS,
+				DBTypeT:     r.Cfg.DBTypeT,
+				SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+			})
+			if err != nil {
+				return err
+			}
+			zap.L().Warn("increment table data sync graceful shutdown",
+				zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+				zap.Uint64("checkpoint global scn", globalSCN))
+			return common.ErrGracefulShutdown
+		case <-ticker.C:
+			if err := r.syncTableIncrRecord(); err != nil {
+				return err
+			}
 		}
+	}
+}
 
-		// logminer 运行
-		if err = r.OracleMiner.AddOracleLogminerlogFile(log["LOG_FILE"]); err != nil {
-			return err
-		}
+func (r *Migrate) syncTableIncrRecord() error {
+	// 获取自定义库表名规则
+	tableNameRule, err := r.GetTableNameRule()
+	if err != nil {
+		return err
+	}
 
-		if err = r.OracleMiner.StartOracleLogminerStoredProcedure(log["FIRST_CHANGE"]); err != nil {
-			return err
+	// 按日志挖掘窗口依次挖掘，直至窗口包含所有线程当前重做日志
+	for {
+		// 收到退出信号，不再挖掘新的日志窗口
+		if r.ShutdownCtx.Err() != nil {
+			return nil
 		}
-
-		// 捕获数据
-		rowsResult, err := public.GetOracleIncrRecord(r.Ctx, r.OracleMiner,
-			common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
-			common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema),
-			common.StringArrayToCapitalChar(syncSourceTables),
-			tableNameRule,
-			strconv.FormatUint(minSourceTableSCN, 10),
-			r.Cfg.AllConfig.LogminerQueryTimeout)
+		window, err := r.getTableIncrRecordLogfile()
 		if err != nil {
 			return err
 		}
-		zap.L().Info("increment table log extractor", zap.String("logfile", log["LOG_FILE"]),
-			zap.Uint64("logfile start scn", logFileStartSCN),
-			zap.Uint64("source table last scn", minSourceTableSCN),
-			zap.Int("row counts", len(rowsResult)))
+		zap.L().Info("increment table log file window get",
+			zap.Uint64("window start scn", window.StartSCN),
+			zap.Uint64("window end scn", window.EndSCN),
+			zap.Bool("window current redo", window.IsCurrent()),
+			zap.Strings("logfile", window.LogFiles))
 
-		// logminer 关闭
-		if err = r.OracleMiner.EndOracleLogminerStoredProcedure(); err != nil {
+		if err = r.syncTableIncrRecordWindow(window, tableNameRule); err != nil {
 			return err
 		}
-
-		// 获取 Oracle 所有 REDO 列表
-		redoLogList, err := r.OracleMiner.GetOracleALLRedoLogFile()
-		if err != nil {
-			return err
+		if window.IsCurrent() {
+			return nil
 		}
+	}
+}
 
-		//获取当前 CURRENT REDO LOG 信息
-		currentRedoLogFirstChange, currentRedoLogMaxSCN, currentRedoLogFileName, err := r.OracleMiner.GetOracleCurrentRedoMaxSCN()
-		if err != nil {
-			return err
-		}
+func (r *Migrate) syncTableIncrRecordWindow(window public.LogfileWindow, tableNameRule map[string]string) error {
+	// 获取增量元数据表内所需同步表信息
+	incrSyncMetas, err := meta.NewIncrSyncMetaModel(r.MetaDB).DetailIncrSyncMetaBySchema(r.Ctx, &meta.IncrSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+	})
+	if err != nil {
+		return err
+	}
+	if len(incrSyncMetas) == 0 {
+		return fmt.Errorf("mysql increment mete table [incr_sync_meta] can't null")
+	}
 
-		// 按表级别筛选数据
-		var (
-			logminerContentMap map[string][]public.Logminer
-		)
-		if len(rowsResult) > 0 {
-			// 判断当前日志文件是否是重做日志文件
-			if common.IsContainString(redoLogList, log["LOG_FILE"]) {
-				// 判断是否是当前重做日志文件
-				// 如果当前日志文件是当前重做日志文件则 FilterOracleIncrRecord 只运行一次大于或等于对应表数据记录，也就是只重放一次已消费得SCN
-				if logFileStartSCN == currentRedoLogFirstChange && log["LOG_FILE"] == currentRedoLogFileName {
-					logminerContentMap, err = public.FilterOracleIncrRecord(
-						rowsResult,
-						syncSourceTables,
-						transferTableMetaMap,
-						r.Cfg.AllConfig.FilterThreads,
-						common.MigrateCurrentResetFlag,
-					)
-					if err != nil {
-						return err
-					}
-					zap.L().Warn("oracle current redo log reset flag", zap.Int("MigrateCurrentResetFlag", common.MigrateCurrentResetFlag))
-					common.MigrateCurrentResetFlag = 1
-				} else {
-					logminerContentMap, err = public.FilterOracleIncrRecord(
-						rowsResult,
-						syncSourceTables,
-						transferTableMetaMap,
-						r.Cfg.AllConfig.FilterThreads,
-						0,
-					)
-					if err != nil {
-						return err
-					}
-				}
+	var (
+		transferTableMetaMap map[string]uint64
+		syncSourceTables     []string
+	)
+	transferTableMetaMap = make(map[string]uint64)
+	for _, tbl := range incrSyncMetas {
+		transferTableMetaMap[strings.ToUpper(tbl.TableNameS)] = tbl.TableScnS
+		syncSourceTables = append(syncSourceTables, strings.ToUpper(tbl.TableNameS))
+	}
 
-				if len(logminerContentMap) > 0 {
-					// 数据应用
-					if err := applyOracleIncrRecord(r.MetaDB, r.Mysql, r.Cfg, logminerContentMap); err != nil {
-						return err
-					}
-					if logFileStartSCN == currentRedoLogFirstChange && log["LOG_FILE"] == currentRedoLogFileName {
-						// 当前所有日志文件内容应用完毕，判断是否直接更新 GLOBAL_SCN 至当前重做日志文件起始 SCN
-						err = meta.NewCommonModel(r.MetaDB).UpdateIncrSyncMetaSCNByCurrentRedo(r.Ctx,
-							r.Cfg.DBTypeS,
-							r.Cfg.DBTypeT,
-							r.Cfg.SchemaConfig.SourceSchema,
-							currentRedoLogMaxSCN,
-							logFileStartSCN,
-							logFileEndSCN)
-						if err != nil {
-							return err
-						}
-					} else {
-						// 当前所有日志文件内容应用完毕，直接更新 GLOBAL_SCN 至日志文件结束 SCN
-						err = meta.NewCommonModel(r.MetaDB).UpdateIncrSyncMetaSCNByNonCurrentRedo(r.Ctx,
-							r.Cfg.DBTypeS,
-							r.Cfg.DBTypeT,
-							r.Cfg.SchemaConfig.SourceSchema,
-							currentRedoLogMaxSCN,
-							logFileStartSCN,
-							logFileEndSCN,
-							syncSourceTables)
-						if err != nil {
-							return err
-						}
-					}
+	// 获取 logminer query 起始最小 SCN
+	minSourceTableSCN, err := meta.NewIncrSyncMetaModel(r.MetaDB).GetIncrSyncMetaMinTableScnSBySchema(r.Ctx, &meta.IncrSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema})
+	if err != nil {
+		return err
+	}
 
-					continue
-				}
-				zap.L().Warn("increment table log file logminer data that needn't to be consumed by current redo, transferdb will continue to capture")
-				continue
-			}
-			logminerContentMap, err = public.FilterOracleIncrRecord(
-				rowsResult,
-				syncSourceTables,
-				transferTableMetaMap,
-				r.Cfg.AllConfig.FilterThreads,
-				0,
-			)
-			if err != nil {
-				return err
-			}
-			if len(logminerContentMap) > 0 {
-				// 数据应用
-				if err := applyOracleIncrRecord(r.MetaDB, r.Mysql, r.Cfg, logminerContentMap); err != nil {
-					return err
-				}
-				// 当前所有日志文件内容应用完毕，直接更新 GLOBAL_SCN 至日志文件结束 SCN
-				err = meta.NewCommonModel(r.MetaDB).UpdateIncrSyncMetaSCNByArchivedLog(r.Ctx,
-					r.Cfg.DBTypeS,
-					r.Cfg.DBTypeT,
-					r.Cfg.SchemaConfig.SourceSchema,
-					logFileEndSCN,
-					syncSourceTables)
-				if err != nil {
-					return err
-				}
-				continue
-			}
-			zap.L().Warn("increment table log file logminer data that needn't to be consumed by logfile, transferdb will continue to capture")
-			continue
+	// logminer 运行，窗口内所有线程日志文件同一会话挖掘，按 SCN 合并
+	var endSCN string
+	if !window.IsCurrent() {
+		endSCN = strconv.FormatUint(window.EndSCN, 10)
+	}
+	if err = r.OracleMiner.AddOracleLogminerlogFiles(window.LogFiles); err != nil {
+		return err
+	}
+	if err = r.OracleMiner.StartOracleLogminerStoredProcedure(strconv.FormatUint(window.StartSCN, 10), endSCN); err != nil {
+		return err
+	}
+
+	// 捕获数据
+	rowsResult, err := public.GetOracleIncrRecord(r.Ctx, r.OracleMiner,
+		common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+		common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema),
+		common.StringArrayToCapitalChar(syncSourceTables),
+		tableNameRule,
+		strconv.FormatUint(minSourceTableSCN, 10),
+		endSCN,
+		r.Cfg.AllConfig.LogminerQueryTimeout)
+	if err != nil {
+		return err
+	}
+	zap.L().Info("increment table log extractor",
+		zap.Uint64("window start scn", window.StartSCN),
+		zap.Uint64("window end scn", window.EndSCN),
+		zap.Uint64("source table last scn", minSourceTableSCN),
+		zap.Int("row counts", len(rowsResult)))
+
+	// logminer 关闭
+	if err = r.OracleMiner.EndOracleLogminerStoredProcedure(); err != nil {
+		return err
+	}
+
+	// 按表级别筛选数据
+	// 窗口包含当前重做日志，会重复挖掘，FilterOracleIncrRecord 只运行一次大于或等于对应表数据记录，也就是只重放一次已消费得SCN
+	if len(rowsResult) > 0 {
+		resetFlag := 0
+		if window.IsCurrent() {
+			resetFlag = common.MigrateCurrentResetFlag
+		}
+		logminerContentMap, err := public.FilterOracleIncrRecord(
+			rowsResult,
+			syncSourceTables,
+			transferTableMetaMap,
+			r.Cfg.AllConfig.FilterThreads,
+			resetFlag,
+		)
+		if err != nil {
+			return err
+		}
+		if window.IsCurrent() {
+			zap.L().Warn("oracle current redo log reset flag", zap.Int("MigrateCurrentResetFlag", common.MigrateCurrentResetFlag))
+			common.MigrateCurrentResetFlag = 1
 		}
 
-		// 当前日志文件不存在数据记录
-		if common.IsContainString(redoLogList, log["LOG_FILE"]) {
-			if logFileStartSCN == currentRedoLogFirstChange && log["LOG_FILE"] == currentRedoLogFileName {
-				// 当前所有日志文件内容应用完毕，判断是否直接更新 GLOBAL_SCN 至当前重做日志文件起始 SCN
-				err = meta.NewCommonModel(r.MetaDB).UpdateIncrSyncMetaSCNByCurrentRedo(r.Ctx,
-					r.Cfg.DBTypeS,
-					r.Cfg.DBTypeT,
-					r.Cfg.SchemaConfig.SourceSchema,
-					currentRedoLogMaxSCN,
-					logFileStartSCN,
-					logFileEndSCN)
-				if err != nil {
-					return err
-				}
-			} else {
-				// 当前所有日志文件内容应用完毕，判断是否更新 GLOBAL_SCN 至日志文件结束 SCN
-				err = meta.NewCommonModel(r.MetaDB).UpdateIncrSyncMetaSCNByNonCurrentRedo(r.Ctx,
-					r.Cfg.DBTypeS,
-					r.Cfg.DBTypeT,
-					r.Cfg.SchemaConfig.SourceSchema,
-					currentRedoLogMaxSCN,
-					logFileStartSCN,
-					logFileEndSCN,
-					syncSourceTables)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			// 当前所有日志文件内容应用完毕，直接更新 GLOBAL_SCN 至日志文件结束 SCN
-			err = meta.NewCommonModel(r.MetaDB).UpdateIncrSyncMetaSCNByArchivedLog(r.Ctx,
-				r.Cfg.DBTypeS,
-				r.Cfg.DBTypeT,
-				r.Cfg.SchemaConfig.SourceSchema,
-				logFileEndSCN,
-				syncSourceTables)
-			if err != nil {
+		if len(logminerContentMap) > 0 {
+			// 数据应用
+			if err = applyOracleIncrRecord(r.MetaDB, r.Oracle, r.Mysql, r.Cfg, logminerContentMap, transferTableMetaMap); err != nil {
 				return err
 			}
+		} else {
+			zap.L().Warn("increment table log file logminer data that needn't to be consumed, transferdb will continue to capture")
 		}
+	} else {
 		zap.L().Warn("increment table log file logminer null data, transferdb will continue to capture")
-		continue
+	}
+
+	// 窗口内容应用完毕，非当前重做日志窗口直接更新 GLOBAL_SCN 至窗口结束 SCN
+	// 当前重做日志窗口 GLOBAL_SCN 保持不变，表级 SCN 由数据应用更新
+	if !window.IsCurrent() {
+		if err = meta.NewCommonModel(r.MetaDB).UpdateIncrSyncMetaSCNByArchivedLog(r.Ctx,
+			r.Cfg.DBTypeS,
+			r.Cfg.DBTypeT,
+			r.Cfg.SchemaConfig.SourceSchema,
+			window.EndSCN,
+			syncSourceTables); err != nil {
+			return err
+		}
+	}
+
+	// 记录各 redo 线程挖掘进度
+	var threadMetas []meta.IncrThreadMeta
+	for _, t := range window.Threads {
+		threadMetas = append(threadMetas, meta.IncrThreadMeta{
+			DBTypeS:     r.Cfg.DBTypeS,
+			DBTypeT:     r.Cfg.DBTypeT,
+			SchemaNameS: common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema),
+			ThreadS:     t.Thread,
+			SequenceS:   t.Sequence,
+			LogFileS:    t.LogFile,
+			FirstScnS:   t.FirstSCN,
+			NextScnS:    t.NextSCN,
+		})
+	}
+	if err = meta.NewIncrThreadMetaModel(r.MetaDB).UpsertIncrThreadMeta(r.Ctx, threadMetas); err != nil {
+		return err
+	}
+
+	// 非当前重做日志窗口应用完成，所有表已应用至窗口结束 SCN（挖掘条件 SCN < 窗口结束 SCN），增量应用暂停期间在线校验
+	if !window.IsCurrent() && r.Verify != nil {
+		return r.Verify.Verify(window.EndSCN - 1)
 	}
 	return nil
 }
 
-func (r *Migrate) getTableIncrRecordLogfile() ([]map[string]string, error) {
-	var logFiles []map[string]string
-
+// 获取增量所需挖掘的日志窗口，归档日志以及在线重做日志按 redo 线程合并
+func (r *Migrate) getTableIncrRecordLogfile() (public.LogfileWindow, error) {
 	// 获取增量表起始最小 SCN 号
 	globalSCN, err := meta.NewIncrSyncMetaModel(r.MetaDB).GetIncrSyncMetaMinGlobalScnSBySchema(r.Ctx, &meta.IncrSyncMeta{
 		DBTypeS:     r.Cfg.DBTypeS,
@@ -514,35 +600,61 @@ func (r *Migrate) getTableIncrRecordLogfile() ([]map[string]string, error) {
 		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
 	})
 	if err != nil {
-		return logFiles, err
+		return public.LogfileWindow{}, err
 	}
 	strGlobalSCN := strconv.FormatUint(globalSCN, 10)
 
-	// 判断数据是在 archived log Or redo log
-	// 如果 redoSCN 等于 0，说明数据在归档日志
-	redoScn, err := r.OracleMiner.GetOracleRedoLogSCN(strGlobalSCN)
+	threads, err := r.OracleMiner.GetOracleRedoThread()
 	if err != nil {
-		return logFiles, err
+		return public.LogfileWindow{}, err
 	}
 
-	archivedScn, err := r.OracleMiner.GetOracleArchivedLogSCN(strGlobalSCN)
+	// 同一日志归档日志优先，在线重做日志可能被覆盖
+	archivedLogs, err := r.OracleMiner.GetOracleArchivedLogFile(strGlobalSCN)
+	if err != nil {
+		return public.LogfileWindow{}, err
+	}
+	redoLogs, err := r.OracleMiner.GetOracleRedoLogFile(strGlobalSCN)
+	if err != nil {
+		return public.LogfileWindow{}, err
+	}
+	logfiles, err := public.NewThreadLogfiles(append(archivedLogs, redoLogs...))
+	if err != nil {
+		return public.LogfileWindow{}, err
+	}
+
+	threadMetas, err := meta.NewIncrThreadMetaModel(r.MetaDB).DetailIncrThreadMetaBySchema(r.Ctx, &meta.IncrThreadMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+	})
 	if err != nil {
-		return logFiles, err
+		return public.LogfileWindow{}, err
+	}
+	threadSequences := make(map[string]uint64)
+	for _, t := range threadMetas {
+		threadSequences[t.ThreadS] = t.SequenceS
 	}
 
-	// 获取所需挖掘的日志文件
-	if redoScn == 0 {
-		strArchivedSCN := strconv.FormatUint(archivedScn, 10)
-		logFiles, err = r.OracleMiner.GetOracleArchivedLogFile(strArchivedSCN)
+	return public.GenOracleLogfileWindow(globalSCN, logfiles, threads, threadSequences)
+}
+
+// logminer 重做 SQL 带时区时间值无法按 [app] time-zone 规整，配置时区策略时同步表不允许存在带时区时间字段，避免与全量数据时区不一致
+func (r *Migrate) validateIncrTimeZone(exporters []string) error {
+	if r.Cfg.AppConfig.TimeZone == "" {
+		return nil
+	}
+	for _, t := range exporters {
+		columnsINFO, err := r.Oracle.GetOracleSchemaTableColumn(r.Cfg.SchemaConfig.SourceSchema, t, false)
 		if err != nil {
-			return logFiles, err
+			return err
 		}
-	} else {
-		strRedoCN := strconv.FormatUint(redoScn, 10)
-		logFiles, err = r.OracleMiner.GetOracleRedoLogFile(strRedoCN)
-		if err != nil {
-			return logFiles, err
+		for _, c := range columnsINFO {
+			if common.IsOracleTimestampWithTimeZone(c["DATA_TYPE"]) {
+				return fmt.Errorf("oracle schema [%s] table [%s] column [%s] datatype [%s] isn't support config [app] time-zone with incr-mode [%s], please use incr-mode [%s] or remove time-zone",
+					r.Cfg.SchemaConfig.SourceSchema, t, c["COLUMN_NAME"], c["DATA_TYPE"], common.IncrModeLogminer, common.IncrModeQuery)
+			}
 		}
 	}
-	return logFiles, nil
+	return nil
 }
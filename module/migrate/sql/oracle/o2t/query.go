@@ -0,0 +1,719 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package o2t
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/database/mysql"
+	"github.com/wentaojin/transferdb/database/oracle"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 查询方式增量跟踪字段类型
+// table_scn_s 保存跟踪字段 checkpoint：ORA_ROWSCN/NUMBER 保存原值，DATE 保存 YYYYMMDDHH24MISS，TIMESTAMP 保存 YYYYMMDDHH24MISSFF3
+const (
+	incrQueryTrackNumber    = "NUMBER"
+	incrQueryTrackDate      = "DATE"
+	incrQueryTrackTimestamp = "TIMESTAMP"
+
+	incrQueryDateFormat      = "YYYYMMDDHH24MISS"
+	incrQueryTimestampFormat = "YYYYMMDDHH24MISSFF3"
+)
+
+// 获取表变更跟踪字段，表级配置优先，未配置以 [all] 配置为准，均未配置使用 ORA_ROWSCN
+func (r *Migrate) getIncrQueryTrackColumn(sourceTable string) string {
+	trackColumn := r.Cfg.AllConfig.TrackColumn
+	for _, c := range r.Cfg.SchemaConfig.IncrQueryConfig {
+		if common.StringUPPER(c.SourceTable) == common.StringUPPER(sourceTable) && c.TrackColumn != "" {
+			trackColumn = c.TrackColumn
+		}
+	}
+	if trackColumn == "" {
+		return common.IncrQueryTrackRowSCN
+	}
+	return common.StringUPPER(trackColumn)
+}
+
+// 获取表变更跟踪字段类型
+func (r *Migrate) getIncrQueryTrackType(sourceTable, trackColumn string, columnsINFO []map[string]string) (string, error) {
+	if trackColumn == common.IncrQueryTrackRowSCN {
+		return common.IncrQueryTrackRowSCN, nil
+	}
+	for _, rowCol := range columnsINFO {
+		if common.StringUPPER(rowCol["COLUMN_NAME"]) != trackColumn {
+			continue
+		}
+		dataType := common.StringUPPER(rowCol["DATA_TYPE"])
+		switch {
+		case dataType == "NUMBER" || dataType == "INTEGER":
+			return incrQueryTrackNumber, nil
+		case dataType == "DATE":
+			return incrQueryTrackDate, nil
+		case strings.Contains(dataType, "TIMESTAMP"):
+			return incrQueryTrackTimestamp, nil
+		default:
+			return "", fmt.Errorf("oracle table [%s.%s] track column [%s] datatype [%s] isn't support, only support [number date timestamp]",
+				r.Cfg.SchemaConfig.SourceSchema, sourceTable, trackColumn, rowCol["DATA_TYPE"])
+		}
+	}
+	return "", fmt.Errorf("oracle table [%s.%s] track column [%s] isn't exist", r.Cfg.SchemaConfig.SourceSchema, sourceTable, trackColumn)
+}
+
+// 跟踪字段条件，字段原值与 checkpoint 字面量比较，保证可使用跟踪字段索引
+func genIncrQueryTrackCondition(trackColumn, trackType, operator string, checkpoint uint64) string {
+	ckpt := strconv.FormatUint(checkpoint, 10)
+	switch trackType {
+	case common.IncrQueryTrackRowSCN:
+		return common.StringsBuilder(`ORA_ROWSCN `, operator, ` `, ckpt)
+	case incrQueryTrackDate:
+		return common.StringsBuilder(`"`, trackColumn, `" `, operator, ` TO_DATE('`, ckpt, `','`, incrQueryDateFormat, `')`)
+	case incrQueryTrackTimestamp:
+		return common.StringsBuilder(`"`, trackColumn, `" `, operator, ` TO_TIMESTAMP('`, ckpt, `','`, incrQueryTimestampFormat, `')`)
+	default:
+		return common.StringsBuilder(`"`, trackColumn, `" `, operator, ` `, ckpt)
+	}
+}
+
+// 获取 AS OF SCN 快照跟踪字段最大值，不存在满足条件数据返回 false
+func (r *Migrate) getIncrQueryTrackMax(sourceTable, trackColumn, trackType string, globalSCN uint64, whereS string) (uint64, bool, error) {
+	var maxColumn string
+	switch trackType {
+	case incrQueryTrackDate:
+		maxColumn = common.StringsBuilder(`TO_CHAR(MAX("`, trackColumn, `"),'`, incrQueryDateFormat, `')`)
+	case incrQueryTrackTimestamp:
+		maxColumn = common.StringsBuilder(`TO_CHAR(MAX("`, trackColumn, `"),'`, incrQueryTimestampFormat, `')`)
+	default:
+		maxColumn = common.StringsBuilder(`TO_CHAR(MAX("`, trackColumn, `"))`)
+	}
+	querySQL := common.StringsBuilder(`SELECT `, maxColumn, ` AS MAX_VALUE FROM `, common.StringUPPER(r.Cfg.SchemaConfig.SourceSchema), `.`, common.StringUPPER(sourceTable),
+		` AS OF SCN `, strconv.FormatUint(globalSCN, 10))
+	if whereS != "" {
+		querySQL = common.StringsBuilder(querySQL, ` WHERE `, whereS)
+	}
+	_, res, err := oracle.Query(r.Ctx, r.Oracle.OracleDB, querySQL)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(res) == 0 || res[0]["MAX_VALUE"] == "NULLABLE" || res[0]["MAX_VALUE"] == "" {
+		return 0, false, nil
+	}
+	maxValue, err := common.StrconvUintBitSize(res[0]["MAX_VALUE"], 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("oracle table [%s.%s] track column [%s] max value [%s] isn't non-negative integer: %v",
+			r.Cfg.SchemaConfig.SourceSchema, sourceTable, trackColumn, res[0]["MAX_VALUE"], err)
+	}
+	return maxValue, true, nil
+}
+
+// 全量任务结束，获取表跟踪字段起始 checkpoint
+// ORA_ROWSCN 以全量 SCN 为准，跟踪字段以全量 SCN 快照最大值为准
+func (r *Migrate) initIncrQueryTableSCN(sourceTable string, globalSCN uint64) (uint64, error) {
+	trackColumn := r.getIncrQueryTrackColumn(sourceTable)
+	if trackColumn == common.IncrQueryTrackRowSCN {
+		return globalSCN, nil
+	}
+	columnsINFO, err := r.Oracle.GetOracleSchemaTableColumn(r.Cfg.SchemaConfig.SourceSchema, sourceTable, false)
+	if err != nil {
+		return 0, err
+	}
+	trackType, err := r.getIncrQueryTrackType(sourceTable, trackColumn, columnsINFO)
+	if err != nil {
+		return 0, err
+	}
+	maxValue, _, err := r.getIncrQueryTrackMax(sourceTable, trackColumn, trackType, globalSCN, "")
+	if err != nil {
+		return 0, err
+	}
+	return maxValue, nil
+}
+
+func (r *Migrate) loopTableIncrQuery() error {
+	ticker := time.NewTicker(time.Duration(r.Cfg.AllConfig.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	var lastDeleteDetect time.Time
+	for {
+		select {
+		case <-r.ShutdownCtx.Done():
+			// 进行中表轮询完成，checkpoint 已持久化至 incr_sync_meta
+			globalSCN, err := meta.NewIncrSyncMetaModel(r.MetaDB).GetIncrSyncMetaMinGlobalScnSBySchema(r.Ctx, &meta.IncrSyncMeta{
+				DBTypeS:     r.Cfg.DBTypeS,
+				DBTypeT:     r.Cfg.DBTypeT,
+				SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+			})
+			if err != nil {
+				return err
+			}
+			zap.L().Warn("increment table data query sync graceful shutdown",
+				zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+				zap.Uint64("checkpoint global scn", globalSCN))
+			return common.ErrGracefulShutdown
+		case <-ticker.C:
+			deleteDetect := r.Cfg.AllConfig.DeleteDetect &&
+				time.Since(lastDeleteDetect) >= time.Duration(r.Cfg.AllConfig.DeleteDetectInterval)*time.Second
+			if err := r.syncTableIncrQuery(deleteDetect); err != nil {
+				return err
+			}
+			if deleteDetect {
+				lastDeleteDetect = time.Now()
+			}
+		}
+	}
+}
+
+// 以当前 SCN 快照轮询所有表变更数据
+func (r *Migrate) syncTableIncrQuery(deleteDetect bool) error {
+	startTime := time.Now()
+	globalSCN, err := r.Oracle.GetOracleCurrentSnapshotSCN()
+	if err != nil {
+		return err
+	}
+
+	incrMetas, err := meta.NewIncrSyncMetaModel(r.MetaDB).DetailIncrSyncMetaBySchema(r.Ctx, &meta.IncrSyncMeta{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+	})
+	if err != nil {
+		return err
+	}
+
+	g := &errgroup.Group{}
+	g.SetLimit(r.Cfg.AllConfig.ApplyThreads)
+	for _, incrMeta := range incrMetas {
+		m := incrMeta
+		g.Go(func() error {
+			// 收到退出信号，未开始的表不再轮询
+			if r.ShutdownCtx.Err() != nil {
+				return nil
+			}
+			return r.syncTableIncrQueryRecord(m, globalSCN, deleteDetect)
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return err
+	}
+
+	zap.L().Info("increment table data query sync finished",
+		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+		zap.Uint64("global scn", globalSCN),
+		zap.Int("table totals", len(incrMetas)),
+		zap.Bool("delete detect", deleteDetect),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+
+	// 所有表已应用至快照 SCN，增量应用暂停期间在线校验
+	// 开启删除探测时仅探测轮次校验，避免未探测删除误报不一致
+	if r.Verify != nil && r.ShutdownCtx.Err() == nil && (!r.Cfg.AllConfig.DeleteDetect || deleteDetect) {
+		return r.Verify.Verify(globalSCN)
+	}
+	return nil
+}
+
+// 单表变更数据同步，REPLACE 写入 (checkpoint, upper] 区间变更数据，完成后更新 checkpoint
+func (r *Migrate) syncTableIncrQueryRecord(incrMeta meta.IncrSyncMeta, globalSCN uint64, deleteDetect bool) error {
+	startTime := time.Now()
+	sourceTable := common.StringUPPER(incrMeta.TableNameS)
+
+	columnsINFO, err := r.Oracle.GetOracleSchemaTableColumn(r.Cfg.SchemaConfig.SourceSchema, sourceTable, false)
+	if err != nil {
+		return err
+	}
+	trackColumn := r.getIncrQueryTrackColumn(sourceTable)
+	trackType, err := r.getIncrQueryTrackType(sourceTable, trackColumn, columnsINFO)
+	if err != nil {
+		return err
+	}
+
+	// 变更区间上界，ORA_ROWSCN 以当前 SCN 为准，跟踪字段以当前 SCN 快照区间内最大值为准
+	// 跟踪字段精度有限（例如 DATE 秒级），同一跟踪值的事务可能晚于 checkpoint 提交，下界使用 >= 并按边界行去重
+	lowerOperator := ">"
+	if trackType != common.IncrQueryTrackRowSCN {
+		lowerOperator = ">="
+	}
+	lowerS := genIncrQueryTrackCondition(trackColumn, trackType, lowerOperator, incrMeta.TableScnS)
+	upperSCN := globalSCN
+	isChanged := true
+	if trackType != common.IncrQueryTrackRowSCN {
+		upperSCN, isChanged, err = r.getIncrQueryTrackMax(sourceTable, trackColumn, trackType, globalSCN, lowerS)
+		if err != nil {
+			return err
+		}
+		if !isChanged {
+			upperSCN = incrMeta.TableScnS
+		}
+	}
+
+	keyColumns, err := getIncrTableKeyColumns(r.Mysql, common.StringUPPER(incrMeta.SchemaNameT), common.StringUPPER(incrMeta.TableNameT))
+	if err != nil {
+		return err
+	}
+	if len(keyColumns) == 0 {
+		return fmt.Errorf("mysql table [%s.%s] isn't exist primary key, unique key or keyless rowid column, can't increment query sync",
+			incrMeta.SchemaNameT, incrMeta.TableNameT)
+	}
+	isKeyless := keyColumns[0] == common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`")
+
+	var rowCounts int
+	switch {
+	case trackType == common.IncrQueryTrackRowSCN:
+		if upperSCN > incrMeta.TableScnS {
+			rowCounts, err = r.writeTableIncrQueryRows(incrMeta, globalSCN, isKeyless,
+				common.StringsBuilder(lowerS, ` AND `, genIncrQueryTrackCondition(trackColumn, trackType, "<=", upperSCN)), nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+	case isChanged:
+		rowCounts, err = r.writeTableIncrQueryTrackRows(incrMeta, globalSCN, isKeyless, trackColumn, trackType, upperSCN)
+		if err != nil {
+			return err
+		}
+	}
+
+	var deleteCounts int
+	if deleteDetect {
+		deleteCounts, err = r.detectTableIncrQueryDelete(incrMeta, globalSCN, isKeyless, keyColumns, columnsINFO)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = meta.NewIncrSyncMetaModel(r.MetaDB).UpdateIncrSyncMeta(r.Ctx, &meta.IncrSyncMeta{
+		DBTypeS:     incrMeta.DBTypeS,
+		DBTypeT:     incrMeta.DBTypeT,
+		SchemaNameS: incrMeta.SchemaNameS,
+		TableNameS:  incrMeta.TableNameS,
+		GlobalScnS:  globalSCN,
+		TableScnS:   upperSCN,
+	}); err != nil {
+		return err
+	}
+
+	zap.L().Info("increment table data query sync",
+		zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+		zap.String("table", sourceTable),
+		zap.String("track column", trackColumn),
+		zap.Uint64("checkpoint", incrMeta.TableScnS),
+		zap.Uint64("upper", upperSCN),
+		zap.Int("upsert rows", rowCounts),
+		zap.Int("delete rows", deleteCounts),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return nil
+}
+
+// 跟踪字段边界行，记录跟踪值等于 checkpoint 的已写入行摘要，用于下一轮 >= checkpoint 查询去重
+type incrQueryBoundary struct {
+	checkpoint uint64
+	rows       map[string]struct{}
+}
+
+// 跟踪字段变更数据写入，[checkpoint, upper] 区间拆分为 = checkpoint、(checkpoint, upper)、= upper 三段
+// 等于 checkpoint 的行跳过上一轮已写入的相同行，等于 upper 的行记录摘要作为下一轮边界行
+func (r *Migrate) writeTableIncrQueryTrackRows(incrMeta meta.IncrSyncMeta, globalSCN uint64, isKeyless bool, trackColumn, trackType string, upperSCN uint64) (int, error) {
+	sourceTable := common.StringUPPER(incrMeta.TableNameS)
+	seen := make(map[string]struct{})
+	if val, ok := r.incrQueryBoundary.Load(sourceTable); ok {
+		if boundary := val.(incrQueryBoundary); boundary.checkpoint == incrMeta.TableScnS {
+			seen = boundary.rows
+		}
+	}
+	upperRows := make(map[string]struct{})
+
+	if upperSCN == incrMeta.TableScnS {
+		rowCounts, err := r.writeTableIncrQueryRows(incrMeta, globalSCN, isKeyless,
+			genIncrQueryTrackCondition(trackColumn, trackType, "=", incrMeta.TableScnS), seen, upperRows)
+		if err != nil {
+			return rowCounts, err
+		}
+		r.incrQueryBoundary.Store(sourceTable, incrQueryBoundary{checkpoint: upperSCN, rows: upperRows})
+		return rowCounts, nil
+	}
+
+	var rowCounts int
+	for _, w := range []struct {
+		whereS       string
+		seen, record map[string]struct{}
+	}{
+		{whereS: genIncrQueryTrackCondition(trackColumn, trackType, "=", incrMeta.TableScnS), seen: seen},
+		{whereS: common.StringsBuilder(genIncrQueryTrackCondition(trackColumn, trackType, ">", incrMeta.TableScnS), ` AND `,
+			genIncrQueryTrackCondition(trackColumn, trackType, "<", upperSCN))},
+		{whereS: genIncrQueryTrackCondition(trackColumn, trackType, "=", upperSCN), record: upperRows},
+	} {
+		counts, err := r.writeTableIncrQueryRows(incrMeta, globalSCN, isKeyless, w.whereS, w.seen, w.record)
+		rowCounts += counts
+		if err != nil {
+			return rowCounts, err
+		}
+	}
+	r.incrQueryBoundary.Store(sourceTable, incrQueryBoundary{checkpoint: upperSCN, rows: upperRows})
+	return rowCounts, nil
+}
+
+// 读取 AS OF SCN 快照变更数据并 REPLACE 写入，无主键/唯一键表按 ROWID 代理字段先删除后写入
+// seen 非空跳过摘要已存在的行，record 非空记录读取行摘要
+func (r *Migrate) writeTableIncrQueryRows(incrMeta meta.IncrSyncMeta, globalSCN uint64, isKeyless bool, whereS string, seen, record map[string]struct{}) (int, error) {
+	sourceDBCharset := common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset]
+	targetDBCharset := common.StringUPPER(r.Cfg.MySQLConfig.Charset)
+
+	sourceColumnInfo, err := r.AdjustTableSelectColumn(incrMeta.TableNameS, false)
+	if err != nil {
+		return 0, err
+	}
+	if isKeyless {
+		sourceColumnInfo = common.StringsBuilder(sourceColumnInfo, ",", common.GenOracleRowidOrderColumn())
+	}
+	convertRaw, err := common.CharsetConvert([]byte(sourceColumnInfo), common.CharsetUTF8MB4, sourceDBCharset)
+	if err != nil {
+		return 0, fmt.Errorf("schema [%s] table [%s] column [%s] charset convert failed, %v", incrMeta.SchemaNameS, incrMeta.TableNameS, sourceColumnInfo, err)
+	}
+	fromS := common.StringsBuilder(` FROM `, common.StringUPPER(incrMeta.SchemaNameS), `.`, common.StringUPPER(incrMeta.TableNameS))
+
+	columnNameS, err := r.Oracle.GetOracleTableRowsColumn(
+		common.StringsBuilder(`SELECT `, string(convertRaw), fromS, ` WHERE 1 = 0`), sourceDBCharset, targetDBCharset)
+	if err != nil {
+		return 0, err
+	}
+
+	querySQL := common.StringsBuilder(`SELECT `, string(convertRaw), fromS, ` AS OF SCN `, strconv.FormatUint(globalSCN, 10), ` WHERE `, whereS)
+
+	dataChan := make(chan oracle.RowsBatch, common.ChannelBufferSize)
+	g := &errgroup.Group{}
+	g.Go(func() error {
+		defer close(dataChan)
+		return r.Oracle.GetOracleTableRowsData(querySQL, r.Cfg.AppConfig.InsertBatchSize, r.Cfg.FullConfig.CallTimeout, sourceDBCharset, targetDBCharset, r.Cfg.RejectConfig.InvalidCharPolicy, nil, dataChan)
+	})
+
+	var (
+		rowCounts int
+		writeErr  error
+	)
+	for batch := range dataChan {
+		// 写入失败，继续消费避免读取阻塞
+		if writeErr != nil {
+			continue
+		}
+		rows := batch.Rows
+		if seen != nil || record != nil {
+			rows = filterIncrQueryRows(columnNameS, rows, seen, record)
+			if len(rows) == 0 {
+				continue
+			}
+		}
+		if writeErr = r.writeTableIncrQueryBatch(incrMeta, columnNameS, isKeyless, rows); writeErr == nil {
+			rowCounts += len(rows)
+		}
+	}
+	if err = g.Wait(); err != nil {
+		return rowCounts, fmt.Errorf("oracle table [%s.%s] increment query sql [%s] failed: %v", incrMeta.SchemaNameS, incrMeta.TableNameS, querySQL, err)
+	}
+	if writeErr != nil {
+		return rowCounts, fmt.Errorf("mysql table [%s.%s] increment query write failed: %v", incrMeta.SchemaNameT, incrMeta.TableNameT, writeErr)
+	}
+	return rowCounts, nil
+}
+
+// 边界行去重，按字段值摘要过滤 seen 已存在的行，并记录所有行摘要至 record
+func filterIncrQueryRows(columnNameS []string, rows []map[string]interface{}, seen, record map[string]struct{}) []map[string]interface{} {
+	var filtered []map[string]interface{}
+	for _, row := range rows {
+		h := fnv.New128a()
+		for _, col := range columnNameS {
+			_, _ = fmt.Fprintf(h, "%v\x00", row[col])
+		}
+		digest := string(h.Sum(nil))
+		if record != nil {
+			record[digest] = struct{}{}
+		}
+		if _, ok := seen[digest]; ok {
+			continue
+		}
+		filtered = append(filtered, row)
+	}
+	return filtered
+}
+
+func (r *Migrate) writeTableIncrQueryBatch(incrMeta meta.IncrSyncMeta, columnNameS []string, isKeyless bool, rows []map[string]interface{}) error {
+	var args []interface{}
+	for _, row := range rows {
+		for _, col := range columnNameS {
+			args = append(args, row[col])
+		}
+	}
+	targetSchema, targetTable := common.StringUPPER(incrMeta.SchemaNameT), common.StringUPPER(incrMeta.TableNameT)
+	replaceSQL := GenMySQLTablePrepareStmt(targetSchema, targetTable, columnNameS, len(rows), true)
+
+	if !isKeyless {
+		return r.Mysql.WriteMySQLTable(replaceSQL, args...)
+	}
+
+	// 无主键/唯一键表 REPLACE 无法覆盖，同一事务内按 ROWID 代理字段删除后写入
+	var (
+		rowids   []interface{}
+		bindVars []string
+	)
+	rowidColumn := common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`")
+	for _, row := range rows {
+		rowids = append(rowids, row[rowidColumn])
+		bindVars = append(bindVars, "?")
+	}
+	txn, err := r.Mysql.MySQLDB.BeginTx(r.Ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = txn.ExecContext(r.Ctx, common.StringsBuilder(`DELETE FROM `, targetSchema, `.`, targetTable, ` WHERE `, rowidColumn, ` IN (`, strings.Join(bindVars, ","), `)`), rowids...); err != nil {
+		_ = txn.Rollback()
+		return err
+	}
+	if _, err = txn.ExecContext(r.Ctx, replaceSQL, args...); err != nil {
+		_ = txn.Rollback()
+		return err
+	}
+	return txn.Commit()
+}
+
+// 删除检测，目标端键值按键字段顺序分页读取，每页以源端 AS OF SCN 快照回查键值是否存在，删除源端不存在的目标端行
+// 键值以字符串比较，仅支持数字、VARCHAR 类型键以及 ROWID 代理字段，其他类型键跳过检测
+func (r *Migrate) detectTableIncrQueryDelete(incrMeta meta.IncrSyncMeta, globalSCN uint64, isKeyless bool, keyColumns []string, columnsINFO []map[string]string) (int, error) {
+	keys := incrQueryDeleteKeys{isKeyless: isKeyless}
+	// 脱敏键字段以源端脱敏值对比
+	maskRules, err := meta.NewColumnMaskRuleModel(r.MetaDB).GetColumnMaskRuleMap(r.Ctx, &meta.ColumnMaskRule{
+		DBTypeS:     r.Cfg.DBTypeS,
+		DBTypeT:     r.Cfg.DBTypeT,
+		SchemaNameS: r.Cfg.SchemaConfig.SourceSchema,
+		TableNameS:  incrMeta.TableNameS,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, k := range keyColumns {
+		keyName := strings.Trim(k, "`")
+		keys.mysqlColumns = append(keys.mysqlColumns, k)
+		if isKeyless {
+			keys.oraColumns = append(keys.oraColumns, common.GenOracleRowidOrderColumn())
+			keys.oraExprs = append(keys.oraExprs, `ROWID`)
+			keys.numberKeys = append(keys.numberKeys, false)
+			continue
+		}
+		var dataType string
+		for _, rowCol := range columnsINFO {
+			if common.StringUPPER(rowCol["COLUMN_NAME"]) == keyName {
+				dataType = common.StringUPPER(rowCol["DATA_TYPE"])
+			}
+		}
+		switch dataType {
+		case "NUMBER", "INTEGER", "INT", "SMALLINT", "DECIMAL", "NUMERIC":
+			keys.numberKeys = append(keys.numberKeys, true)
+		case "VARCHAR2", "VARCHAR", "NVARCHAR2":
+			keys.numberKeys = append(keys.numberKeys, false)
+		default:
+			zap.L().Warn("increment table data query delete detect skip",
+				zap.String("schema", r.Cfg.SchemaConfig.SourceSchema),
+				zap.String("table", incrMeta.TableNameS),
+				zap.String("key column", keyName),
+				zap.String("datatype", dataType))
+			return 0, nil
+		}
+		keyExpr := common.StringsBuilder(`"`, keyName, `"`)
+		if rule, ok := maskRules[keyName]; ok {
+			keyExpr, err = common.GenOracleMaskColumnExpr(rule.MaskType, rule.MaskValue, keyName, dataType)
+			if err != nil {
+				return 0, err
+			}
+		}
+		keys.oraColumns = append(keys.oraColumns, common.StringsBuilder(keyExpr, ` AS "`, keyName, `"`))
+		keys.oraExprs = append(keys.oraExprs, keyExpr)
+	}
+
+	sourceDBCharset := common.MigrateOracleCharsetStringConvertMapping[r.Cfg.OracleConfig.ActualCharset]
+	targetDBCharset := common.StringUPPER(r.Cfg.MySQLConfig.Charset)
+	sourceSchema, sourceTable := common.StringUPPER(incrMeta.SchemaNameS), common.StringUPPER(incrMeta.TableNameS)
+	targetSchema, targetTable := common.StringUPPER(incrMeta.SchemaNameT), common.StringUPPER(incrMeta.TableNameT)
+
+	// ORACLE IN 列表最多 1000 项
+	pageSize := r.Cfg.AppConfig.InsertBatchSize
+	if pageSize <= 0 || pageSize > 1000 {
+		pageSize = 1000
+	}
+	deletePrefix := common.StringsBuilder(`DELETE FROM `, targetSchema, `.`, targetTable,
+		` WHERE (`, strings.Join(keys.mysqlColumns, ","), `) IN `)
+
+	var (
+		deleteCounts int
+		lastKey      []string
+	)
+	for {
+		myCols, myRes, err := mysql.Query(r.Ctx, r.Mysql.MySQLDB, keys.genTargetPageSQL(targetSchema, targetTable, lastKey, pageSize))
+		if err != nil {
+			return deleteCounts, err
+		}
+		if len(myRes) == 0 {
+			break
+		}
+		var pageKeys [][]string
+		for _, row := range myRes {
+			var values []string
+			for _, c := range myCols {
+				values = append(values, row[c])
+			}
+			pageKeys = append(pageKeys, values)
+		}
+		lastKey = pageKeys[len(pageKeys)-1]
+
+		// 目标端键值转换 UTF8 生成源端回查条件
+		var sourceKeys [][]string
+		for _, values := range pageKeys {
+			var utf8Values []string
+			for _, v := range values {
+				convertUtf8Raw, err := common.CharsetConvert([]byte(v), targetDBCharset, common.CharsetUTF8MB4)
+				if err != nil {
+					return deleteCounts, fmt.Errorf("key value [%s] charset convert failed, %v", v, err)
+				}
+				utf8Values = append(utf8Values, string(convertUtf8Raw))
+			}
+			sourceKeys = append(sourceKeys, utf8Values)
+		}
+		oraCols, oraRes, err := oracle.Query(r.Ctx, r.Oracle.OracleDB, keys.genSourceExistSQL(sourceSchema, sourceTable, globalSCN, sourceKeys))
+		if err != nil {
+			return deleteCounts, err
+		}
+		existKeys := make(map[string]struct{}, len(oraRes))
+		for _, row := range oraRes {
+			var values []string
+			for i, c := range oraCols {
+				convertUtf8Raw, err := common.CharsetConvert([]byte(row[c]), sourceDBCharset, common.CharsetUTF8MB4)
+				if err != nil {
+					return deleteCounts, fmt.Errorf("column [%s] charset convert failed, %v", c, err)
+				}
+				convertTargetRaw, err := common.CharsetConvert(convertUtf8Raw, common.CharsetUTF8MB4, targetDBCharset)
+				if err != nil {
+					return deleteCounts, fmt.Errorf("column [%s] charset convert failed, %v", c, err)
+				}
+				values = append(values, normalizeIncrQueryKey(string(convertTargetRaw), keys.numberKeys[i]))
+			}
+			existKeys[strings.Join(values, "\x00")] = struct{}{}
+		}
+
+		var (
+			args    []interface{}
+			deletes int
+		)
+		for _, values := range pageKeys {
+			var normalized []string
+			for i, v := range values {
+				normalized = append(normalized, normalizeIncrQueryKey(v, keys.numberKeys[i]))
+			}
+			if _, ok := existKeys[strings.Join(normalized, "\x00")]; ok {
+				continue
+			}
+			for _, v := range values {
+				args = append(args, v)
+			}
+			deletes++
+		}
+		if deletes > 0 {
+			if err = r.Mysql.WriteMySQLTable(common.StringsBuilder(deletePrefix, `(`, GenMySQLPrepareBindVarStmt(len(keys.mysqlColumns), deletes), `)`), args...); err != nil {
+				return deleteCounts, fmt.Errorf("mysql table [%s.%s] increment query delete detect failed: %v", incrMeta.SchemaNameT, incrMeta.TableNameT, err)
+			}
+			deleteCounts += deletes
+		}
+		if len(myRes) < pageSize {
+			break
+		}
+	}
+	return deleteCounts, nil
+}
+
+// 删除检测键字段，mysqlColumns 目标端键字段，oraColumns 源端键查询字段，oraExprs 源端键回查表达式
+type incrQueryDeleteKeys struct {
+	isKeyless    bool
+	mysqlColumns []string
+	oraColumns   []string
+	oraExprs     []string
+	numberKeys   []bool
+}
+
+// 目标端键值分页查询，按键字段顺序从上一页最后键值之后读取，唯一键 NULL 值无法定位不做删除
+func (k incrQueryDeleteKeys) genTargetPageSQL(targetSchema, targetTable string, lastKey []string, pageSize int) string {
+	var conds []string
+	for _, c := range k.mysqlColumns {
+		conds = append(conds, common.StringsBuilder(c, ` IS NOT NULL`))
+	}
+	if len(lastKey) > 0 {
+		var values []string
+		for i, v := range lastKey {
+			values = append(values, genIncrQueryKeyLiteral(v, k.numberKeys[i], common.GenMySQLStringLiteral))
+		}
+		conds = append(conds, common.StringsBuilder(`(`, strings.Join(k.mysqlColumns, ","), `) > (`, strings.Join(values, ","), `)`))
+	}
+	return common.StringsBuilder(`SELECT `, strings.Join(k.mysqlColumns, ","), ` FROM `, targetSchema, `.`, targetTable,
+		` WHERE `, strings.Join(conds, ` AND `), ` ORDER BY `, strings.Join(k.mysqlColumns, ","), ` LIMIT `, strconv.Itoa(pageSize))
+}
+
+// 源端 AS OF SCN 快照回查键值，ROWID 代理字段还原 ROWID 回查
+func (k incrQueryDeleteKeys) genSourceExistSQL(sourceSchema, sourceTable string, globalSCN uint64, keys [][]string) string {
+	var tuples []string
+	for _, values := range keys {
+		var literals []string
+		for i, v := range values {
+			if k.isKeyless {
+				literals = append(literals, common.StringsBuilder(`CHARTOROWID(`, common.GenOracleStringLiteral(common.DecodeOracleRowid(v)), `)`))
+				continue
+			}
+			literals = append(literals, genIncrQueryKeyLiteral(v, k.numberKeys[i], common.GenOracleStringLiteral))
+		}
+		if len(literals) == 1 {
+			tuples = append(tuples, literals[0])
+		} else {
+			tuples = append(tuples, common.StringsBuilder(`(`, strings.Join(literals, ","), `)`))
+		}
+	}
+	exprs := strings.Join(k.oraExprs, ",")
+	if len(k.oraExprs) > 1 {
+		exprs = common.StringsBuilder(`(`, exprs, `)`)
+	}
+	return common.StringsBuilder(`SELECT `, strings.Join(k.oraColumns, ","), ` FROM `, sourceSchema, `.`, sourceTable,
+		` AS OF SCN `, strconv.FormatUint(globalSCN, 10), ` WHERE `, exprs, ` IN (`, strings.Join(tuples, ","), `)`)
+}
+
+// 键值字面量，数字键非数字值按字符串处理
+func genIncrQueryKeyLiteral(value string, isNumber bool, stringLiteral func(string) string) string {
+	if isNumber {
+		if d, err := decimal.NewFromString(value); err == nil {
+			return d.String()
+		}
+	}
+	return stringLiteral(value)
+}
+
+// 数字键值统一格式，避免 1.0 与 1 比较不一致
+func normalizeIncrQueryKey(value string, isNumber bool) string {
+	if !isNumber || value == "NULLABLE" {
+		return value
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return value
+	}
+	return d.String()
+}
@@ -0,0 +1,68 @@
+package o2t
+
+import (
+	"testing"
+
+	"github.com/wentaojin/transferdb/common"
+)
+
+func TestFilterIncrQueryRows(t *testing.T) {
+	columns := []string{"`ID`", "`NAME`"}
+	rows := []map[string]interface{}{
+		{"`ID`": "1", "`NAME`": "a"},
+		{"`ID`": "2", "`NAME`": "b"},
+	}
+
+	// 首轮无边界行，全部写入并记录摘要
+	record := make(map[string]struct{})
+	if got := filterIncrQueryRows(columns, rows, nil, record); len(got) != 2 || len(record) != 2 {
+		t.Fatalf("filterIncrQueryRows() = %v, record %d, want 2 rows", got, len(record))
+	}
+
+	// 下一轮 >= checkpoint 重复读取边界行，已写入行跳过，同键变更以及新增行写入
+	next := []map[string]interface{}{
+		{"`ID`": "1", "`NAME`": "a"},
+		{"`ID`": "2", "`NAME`": "c"},
+		{"`ID`": "3", "`NAME`": "d"},
+	}
+	nextRecord := make(map[string]struct{})
+	got := filterIncrQueryRows(columns, next, record, nextRecord)
+	if len(got) != 2 || got[0]["`ID`"] != "2" || got[1]["`ID`"] != "3" || len(nextRecord) != 3 {
+		t.Errorf("filterIncrQueryRows() = %v, record %d, want rows 2,3 record 3", got, len(nextRecord))
+	}
+}
+
+func TestIncrQueryDeleteKeysSQL(t *testing.T) {
+	keys := incrQueryDeleteKeys{
+		mysqlColumns: []string{"`ID`", "`NAME`"},
+		oraColumns:   []string{`"ID" AS "ID"`, `"NAME" AS "NAME"`},
+		oraExprs:     []string{`"ID"`, `"NAME"`},
+		numberKeys:   []bool{true, false},
+	}
+	if got, want := keys.genTargetPageSQL("MARVIN", "T1", nil, 2),
+		"SELECT `ID`,`NAME` FROM MARVIN.T1 WHERE `ID` IS NOT NULL AND `NAME` IS NOT NULL ORDER BY `ID`,`NAME` LIMIT 2"; got != want {
+		t.Errorf("genTargetPageSQL() = %s, want %s", got, want)
+	}
+	if got, want := keys.genTargetPageSQL("MARVIN", "T1", []string{"1.00", "o'k"}, 2),
+		"SELECT `ID`,`NAME` FROM MARVIN.T1 WHERE `ID` IS NOT NULL AND `NAME` IS NOT NULL AND (`ID`,`NAME`) > (1,'o''k') ORDER BY `ID`,`NAME` LIMIT 2"; got != want {
+		t.Errorf("genTargetPageSQL() = %s, want %s", got, want)
+	}
+	if got, want := keys.genSourceExistSQL("MARVIN", "T1", 100, [][]string{{"1", "a"}, {"2", "o'k"}}),
+		`SELECT "ID" AS "ID","NAME" AS "NAME" FROM MARVIN.T1 AS OF SCN 100 WHERE ("ID","NAME") IN ((1,'a'),(2,'o''k'))`; got != want {
+		t.Errorf("genSourceExistSQL() = %s, want %s", got, want)
+	}
+
+	// ROWID 代理字段还原 ROWID 回查
+	rowid := "AAAR8+AAEAAAACIAAA"
+	keyless := incrQueryDeleteKeys{
+		isKeyless:    true,
+		mysqlColumns: []string{common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`")},
+		oraColumns:   []string{common.GenOracleRowidOrderColumn()},
+		oraExprs:     []string{`ROWID`},
+		numberKeys:   []bool{false},
+	}
+	if got, want := keyless.genSourceExistSQL("MARVIN", "T1", 100, [][]string{{common.EncodeOracleRowid(rowid)}}),
+		common.StringsBuilder(`SELECT `, common.GenOracleRowidOrderColumn(), ` FROM MARVIN.T1 AS OF SCN 100 WHERE ROWID IN (CHARTOROWID('`, rowid, `'))`); got != want {
+		t.Errorf("genSourceExistSQL() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package o2t
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wentaojin/transferdb/config"
+)
+
+func TestGenSubsetConnectedTables(t *testing.T) {
+	fks := []subsetForeignKey{
+		{ChildTable: "ORDERS", ChildColumns: []string{"CUST_ID"}, ParentTable: "CUSTOMER", ParentColumns: []string{"ID"}},
+		{ChildTable: "ORDER_ITEM", ChildColumns: []string{"ORDER_ID"}, ParentTable: "ORDERS", ParentColumns: []string{"ID"}},
+		{ChildTable: "ORDER_ITEM", ChildColumns: []string{"PRODUCT_ID"}, ParentTable: "PRODUCT", ParentColumns: []string{"ID"}},
+		{ChildTable: "DEPT_EMP", ChildColumns: []string{"DEPT_ID"}, ParentTable: "DEPT", ParentColumns: []string{"ID"}},
+	}
+	tests := []struct {
+		name   string
+		subset []config.SubsetConfig
+		fks    []subsetForeignKey
+		want   []string
+	}{
+		{name: "root without foreign key", subset: []config.SubsetConfig{{SourceTable: "LOG"}}, fks: fks, want: []string{"LOG"}},
+		{name: "parent and child chain", subset: []config.SubsetConfig{{SourceTable: "CUSTOMER"}}, fks: fks,
+			want: []string{"CUSTOMER", "ORDERS", "ORDER_ITEM", "PRODUCT"}},
+		{name: "child to parent", subset: []config.SubsetConfig{{SourceTable: "PRODUCT"}}, fks: fks,
+			want: []string{"PRODUCT", "ORDER_ITEM", "ORDERS", "CUSTOMER"}},
+		{name: "multiple roots", subset: []config.SubsetConfig{{SourceTable: "DEPT"}, {SourceTable: "ORDERS"}, {SourceTable: "DEPT"}}, fks: fks,
+			want: []string{"DEPT", "ORDERS", "DEPT_EMP", "CUSTOMER", "ORDER_ITEM", "PRODUCT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := genSubsetConnectedTables(tt.subset, tt.fks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("genSubsetConnectedTables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/database/mysql"
 	"github.com/wentaojin/transferdb/database/oracle"
+	"github.com/wentaojin/transferdb/module/migrate"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"strconv"
@@ -31,44 +32,54 @@ import (
 )
 
 type Rows struct {
-	Ctx             context.Context
-	SyncMeta        meta.FullSyncMeta
-	Oracle          *oracle.Oracle
-	MySQL           *mysql.MySQL
-	Stmt            *sql.Stmt
-	SourceDBCharset string
-	TargetDBCharset string
-	ApplyThreads    int
-	CallTimeout     int
-	BatchSize       int
-	SafeMode        bool
-	ColumnNameS     []string
-	ReadChannel     chan []map[string]interface{}
-	WriteChannel    chan []interface{}
+	Ctx               context.Context
+	SyncMeta          meta.FullSyncMeta
+	Oracle            *oracle.Oracle
+	MySQL             *mysql.MySQL
+	Stmt              *sql.Stmt
+	SourceDBCharset   string
+	TargetDBCharset   string
+	ApplyThreads      int
+	CallTimeout       int
+	BatchSize         int
+	SafeMode          bool
+	ColumnNameS       []string
+	Rejecter          *migrate.Rejecter
+	InvalidCharPolicy string
+	ReadChannel       chan oracle.RowsBatch
+	WriteChannel      chan writeBatch
+}
+
+// 写入批次，Vals 按字段顺序平铺，RawRows 为各行源端原始字节，写入阶段隔离行记录原始字节
+type writeBatch struct {
+	Vals    []interface{}
+	RawRows [][]interface{}
 }
 
 func NewRows(ctx context.Context, syncMeta meta.FullSyncMeta,
-	oracle *oracle.Oracle, mysql *mysql.MySQL, stmt *sql.Stmt, sourceDBCharset string, targetDBCharset string, applyThreads, batchSize, callTimeout int, safeMode bool,
-	columnNameS []string) *Rows {
+	oracleDB *oracle.Oracle, mysql *mysql.MySQL, stmt *sql.Stmt, sourceDBCharset string, targetDBCharset string, applyThreads, batchSize, callTimeout int, safeMode bool,
+	columnNameS []string, invalidCharPolicy string, rejecter *migrate.Rejecter) *Rows {
 
-	readChannel := make(chan []map[string]interface{}, common.ChannelBufferSize)
-	writeChannel := make(chan []interface{}, common.ChannelBufferSize)
+	readChannel := make(chan oracle.RowsBatch, common.ChannelBufferSize)
+	writeChannel := make(chan writeBatch, common.ChannelBufferSize)
 
 	return &Rows{
-		Ctx:             ctx,
-		SyncMeta:        syncMeta,
-		Oracle:          oracle,
-		MySQL:           mysql,
-		Stmt:            stmt,
-		SourceDBCharset: sourceDBCharset,
-		TargetDBCharset: targetDBCharset,
-		ApplyThreads:    applyThreads,
-		SafeMode:        safeMode,
-		BatchSize:       batchSize,
-		CallTimeout:     callTimeout,
-		ColumnNameS:     columnNameS,
-		ReadChannel:     readChannel,
-		WriteChannel:    writeChannel,
+		Ctx:               ctx,
+		SyncMeta:          syncMeta,
+		Oracle:            oracleDB,
+		MySQL:             mysql,
+		Stmt:              stmt,
+		SourceDBCharset:   sourceDBCharset,
+		TargetDBCharset:   targetDBCharset,
+		ApplyThreads:      applyThreads,
+		SafeMode:          safeMode,
+		BatchSize:         batchSize,
+		CallTimeout:       callTimeout,
+		ColumnNameS:       columnNameS,
+		Rejecter:          rejecter,
+		InvalidCharPolicy: invalidCharPolicy,
+		ReadChannel:       readChannel,
+		WriteChannel:      writeChannel,
 	}
 }
 
@@ -87,19 +98,25 @@ func (t *Rows) ReadData() error {
 	}
 	columnDetailS = string(convertRaw)
 
+	// 子集迁移 chunk 以 ROWID 列表精确过滤
+	chunkDetailS := t.SyncMeta.ChunkDetailS
+	if t.SyncMeta.ChunkRowidS != "" {
+		chunkDetailS = common.StringsBuilder(chunkDetailS, ` AND ROWID IN (`, t.SyncMeta.ChunkRowidS, `)`)
+	}
+
 	switch {
 	case strings.EqualFold(t.SyncMeta.ConsistentRead, "YES") && strings.EqualFold(t.SyncMeta.SQLHint, ""):
-		originQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.ColumnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` AS OF SCN `, strconv.FormatUint(t.SyncMeta.GlobalScnS, 10), ` WHERE `, t.SyncMeta.ChunkDetailS)
-		execQuerySQL = common.StringsBuilder(`SELECT `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` AS OF SCN `, strconv.FormatUint(t.SyncMeta.GlobalScnS, 10), ` WHERE `, t.SyncMeta.ChunkDetailS)
+		originQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.ColumnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` AS OF SCN `, strconv.FormatUint(t.SyncMeta.GlobalScnS, 10), ` WHERE `, chunkDetailS)
+		execQuerySQL = common.StringsBuilder(`SELECT `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` AS OF SCN `, strconv.FormatUint(t.SyncMeta.GlobalScnS, 10), ` WHERE `, chunkDetailS)
 	case strings.EqualFold(t.SyncMeta.ConsistentRead, "YES") && !strings.EqualFold(t.SyncMeta.SQLHint, ""):
-		originQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.SQLHint, ` `, t.SyncMeta.ColumnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` AS OF SCN `, strconv.FormatUint(t.SyncMeta.GlobalScnS, 10), ` WHERE `, t.SyncMeta.ChunkDetailS)
-		execQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.SQLHint, ` `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` AS OF SCN `, strconv.FormatUint(t.SyncMeta.GlobalScnS, 10), ` WHERE `, t.SyncMeta.ChunkDetailS)
+		originQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.SQLHint, ` `, t.SyncMeta.ColumnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` AS OF SCN `, strconv.FormatUint(t.SyncMeta.GlobalScnS, 10), ` WHERE `, chunkDetailS)
+		execQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.SQLHint, ` `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` AS OF SCN `, strconv.FormatUint(t.SyncMeta.GlobalScnS, 10), ` WHERE `, chunkDetailS)
 	case strings.EqualFold(t.SyncMeta.ConsistentRead, "NO") && !strings.EqualFold(t.SyncMeta.SQLHint, ""):
-		originQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.SQLHint, ` `, t.SyncMeta.ColumnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, t.SyncMeta.ChunkDetailS)
-		execQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.SQLHint, ` `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, t.SyncMeta.ChunkDetailS)
+		originQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.SQLHint, ` `, t.SyncMeta.ColumnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, chunkDetailS)
+		execQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.SQLHint, ` `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, chunkDetailS)
 	default:
-		originQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.ColumnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, t.SyncMeta.ChunkDetailS)
-		execQuerySQL = common.StringsBuilder(`SELECT `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, t.SyncMeta.ChunkDetailS)
+		originQuerySQL = common.StringsBuilder(`SELECT `, t.SyncMeta.ColumnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, chunkDetailS)
+		execQuerySQL = common.StringsBuilder(`SELECT `, columnDetailS, ` FROM `, t.SyncMeta.SchemaNameS, `.`, t.SyncMeta.TableNameS, ` WHERE `, chunkDetailS)
 	}
 
 	zap.L().Info("source schema table chunk rows extractor starting",
@@ -110,7 +127,7 @@ func (t *Rows) ReadData() error {
 		zap.String("exec sql", execQuerySQL),
 		zap.String("startTime", startTime.String()))
 
-	err = t.Oracle.GetOracleTableRowsData(execQuerySQL, t.BatchSize, t.CallTimeout, t.SourceDBCharset, t.TargetDBCharset, t.ReadChannel)
+	err = t.Oracle.GetOracleTableRowsData(execQuerySQL, t.BatchSize, t.CallTimeout, t.SourceDBCharset, t.TargetDBCharset, t.InvalidCharPolicy, t.Rejecter.ReadRejectFunc(t.SyncMeta.ChunkDetailS), t.ReadChannel)
 	if err != nil {
 		// 通道关闭
 		close(t.ReadChannel)
@@ -136,7 +153,7 @@ func (t *Rows) ProcessData() error {
 	for dataC := range t.ReadChannel {
 		var batchRows []any
 
-		for _, dMap := range dataC {
+		for _, dMap := range dataC.Rows {
 			// get value order by column
 			var (
 				rowsTMP []any
@@ -157,7 +174,7 @@ func (t *Rows) ProcessData() error {
 		}
 
 		// 数据输入
-		t.WriteChannel <- batchRows
+		t.WriteChannel <- writeBatch{Vals: batchRows, RawRows: dataC.RawRows}
 	}
 
 	// 通道关闭
@@ -180,20 +197,20 @@ func (t *Rows) ApplyData() error {
 		zap.String("startTime", startTime.String()))
 
 	for dataC := range t.WriteChannel {
-		vals := dataC
+		vals, rawRows := dataC.Vals, dataC.RawRows
 		g.Go(func() error {
 			// prepare exec
 			if len(vals) == preArgNums {
 				_, err := t.Stmt.ExecContext(t.Ctx, vals...)
 				if err != nil {
-					return fmt.Errorf("target sql execute failed: %v", err)
+					return t.applyReject(vals, rawRows, err)
 				}
 			} else {
 				bathSize := len(vals) / len(t.ColumnNameS)
 				sqlStr01 := GenMySQLTablePrepareStmt(t.SyncMeta.SchemaNameT, t.SyncMeta.TableNameT, t.ColumnNameS, bathSize, t.SafeMode)
 				err := t.MySQL.WriteMySQLTable(sqlStr01, vals...)
 				if err != nil {
-					return fmt.Errorf("target sql execute failed: %v", err)
+					return t.applyReject(vals, rawRows, err)
 				}
 			}
 			return nil
@@ -213,3 +230,37 @@ func (t *Rows) ApplyData() error {
 
 	return nil
 }
+
+// 批次写入失败，开启坏行隔离且属于数据类错误时二分定位失败行并隔离，其余行继续写入
+// 隔离行记录源端原始字节，与读取阶段隔离一致，rawRows 与行数不一致时记录写入值
+func (t *Rows) applyReject(vals []interface{}, rawRows [][]interface{}, err error) error {
+	if t.Rejecter == nil || !mysql.IsMySQLDataError(err) {
+		return fmt.Errorf("target sql execute failed: %v", err)
+	}
+	columnCounts := len(t.ColumnNameS)
+	rowCounts := len(vals) / columnCounts
+	if len(rawRows) != rowCounts {
+		rawRows = nil
+	}
+	if rowCounts == 1 {
+		row := vals
+		if len(rawRows) == 1 {
+			row = rawRows[0]
+		}
+		return t.Rejecter.Reject(t.SyncMeta.ChunkDetailS, common.RejectStageWrite, t.ColumnNameS, row, err)
+	}
+	mid := rowCounts / 2
+	parts := []writeBatch{{Vals: vals[:mid*columnCounts]}, {Vals: vals[mid*columnCounts:]}}
+	if rawRows != nil {
+		parts[0].RawRows, parts[1].RawRows = rawRows[:mid], rawRows[mid:]
+	}
+	for _, part := range parts {
+		sqlStr := GenMySQLTablePrepareStmt(t.SyncMeta.SchemaNameT, t.SyncMeta.TableNameT, t.ColumnNameS, len(part.Vals)/columnCounts, t.SafeMode)
+		if errw := t.MySQL.WriteMySQLTable(sqlStr, part.Vals...); errw != nil {
+			if errr := t.applyReject(part.Vals, part.RawRows, errw); errr != nil {
+				return errr
+			}
+		}
+	}
+	return nil
+}
@@ -146,7 +146,9 @@ func GenMySQLPrepareBindVarStmt(columns, bindVarBatch int) string {
 
 // Oracle SQL 转换
 // ORACLE 数据库同步需要开附加日志且表需要捕获字段列日志，Logminer 内容 UPDATE/DELETE/INSERT 语句会带所有字段信息
-func translateAndAddOracleIncrRecord(dbTypeS, dbTypeT, taskMode, sourceSchema, sourceTable string, metaDB *meta.Meta, mysql *mysql.MySQL, logminers []public.Logminer, taskQueue chan IncrTask) error {
+// 同键连续变更合并为单个任务，DDL、主键值变更以及无法获取键值的变更作为屏障任务（Key 为空），屏障前后不合并
+// checkpointSCN 为表 checkpoint SCN，不大于该 SCN 的变更为断点重放变更，下游可能已写入
+func translateAndMergeOracleIncrRecord(dbTypeS, dbTypeT, taskMode, sourceSchema, sourceTable string, metaDB *meta.Meta, mysql *mysql.MySQL, keyColumns []string, mask *public.ColumnMask, checkpointSCN uint64, logminers []public.Logminer) ([]IncrTask, error) {
 
 	startTime := time.Now()
 	zap.L().Info("oracle table increment log apply start",
@@ -154,10 +156,21 @@ func translateAndAddOracleIncrRecord(dbTypeS, dbTypeT, taskMode, sourceSchema, s
 		zap.String("oracle table", sourceTable),
 		zap.Time("start time", startTime))
 
+	var (
+		tasks    []IncrTask
+		merges   int
+		keyIndex = make(map[string]int)
+		dropped  = make(map[int]struct{})
+	)
+	isKeylessRowid := len(keyColumns) == 1 && keyColumns[0] == common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`")
+
+	// 解析捕获内容，脱敏字段批量计算后再生成目标端 SQL
+	records := make([]public.Logminer, 0, len(logminers))
+	stmts := make([]*public.Stmt, 0, len(logminers))
 	for _, rows := range logminers {
 		// 如果 sqlRedo 存在记录则继续处理，不存在记录则报错
 		if rows.SQLRedo == "" {
-			return fmt.Errorf("does not meet expectations [oracle sql redo is be null], please check")
+			return tasks, fmt.Errorf("does not meet expectations [oracle sql redo is be null], please check")
 		}
 
 		if rows.Operation == common.MigrateOperationDDL {
@@ -182,12 +195,25 @@ func translateAndAddOracleIncrRecord(dbTypeS, dbTypeT, taskMode, sourceSchema, s
 		// 比如：UPDATE MARVIN.MARVIN1 SET ID = 2 , NAME = 'marvin' WHERE ID = 2 AND NAME = 'pty'
 		// 比如: drop table marvin.marvin7
 		// 比如: truncate table marvin.marvin7
-		mysqlRedo, operationType, err := translateOracleToMySQLSQL(rows.SQLRedo, rows.SQLUndo, common.StringUPPER(rows.TargetSchema), common.StringUPPER(rows.TargetTable))
+		stmt, err := parseOracleIncrStmt(rows.SQLRedo, rows.SQLUndo, common.StringUPPER(rows.TargetSchema), common.StringUPPER(rows.TargetTable))
 		if err != nil {
-			return err
+			return tasks, err
 		}
+		records = append(records, rows)
+		stmts = append(stmts, stmt)
+	}
+	if err := maskIncrStmts(stmts, mask); err != nil {
+		return tasks, err
+	}
+
+	for i, rows := range records {
+		// 无主键/唯一键表 ROWID 代理字段，依据 ROW_ID 定位
+		var rowID string
+		if isKeylessRowid {
+			rowID = common.EncodeOracleRowid(rows.RowID)
+		}
+		mysqlRedo, operationType, beforeKey, afterKey := genMySQLIncrSQL(stmts[i], keyColumns, rowID)
 
-		// 注册任务到 Job 队列
 		lp := IncrTask{
 			Ctx:            mysql.Ctx,
 			DBTypeS:        dbTypeS,
@@ -196,7 +222,9 @@ func translateAndAddOracleIncrRecord(dbTypeS, dbTypeT, taskMode, sourceSchema, s
 			MetaDB:         metaDB,
 			MySQL:          mysql,
 			GlobalSCN:      rows.SCN, // 更新元数据 GLOBAL_SCN 至当前消费的 SCN 号
+			StartSCN:       rows.SCN,
 			SourceTableSCN: rows.SCN,
+			CheckpointSCN:  checkpointSCN,
 			SourceSchema:   rows.SourceSchema,
 			SourceTable:    rows.SourceTable,
 			TargetSchema:   rows.TargetSchema,
@@ -204,39 +232,112 @@ func translateAndAddOracleIncrRecord(dbTypeS, dbTypeT, taskMode, sourceSchema, s
 			OracleRedo:     rows.SQLRedo,
 			MySQLRedo:      mysqlRedo,
 			Operation:      rows.Operation,
-			OperationType:  operationType}
+			OperationType:  operationType,
+			MergeCounts:    1}
+
+		// 主键值变更或无键值，作为屏障任务串行应用
+		if beforeKey == "" || beforeKey != afterKey {
+			tasks = append(tasks, lp)
+			keyIndex = make(map[string]int)
+			continue
+		}
+		lp.Key = afterKey
+
+		if idx, ok := keyIndex[lp.Key]; ok {
+			merged, keep := mergeIncrTask(tasks[idx], lp, checkpointSCN)
+			tasks[idx] = merged
+			merges++
+			// INSERT + DELETE 抵消，后续同键变更重新生成任务
+			if !keep {
+				dropped[idx] = struct{}{}
+				delete(keyIndex, lp.Key)
+			}
+			continue
+		}
+		keyIndex[lp.Key] = len(tasks)
+		tasks = append(tasks, lp)
 
 		// 避免太多日志输出
 		// zlog.zap.L().Info("translator oracle payload", zap.String("payload", lp.Marshal()))
-		taskQueue <- lp
+	}
+
+	if len(dropped) > 0 {
+		var applyTasks []IncrTask
+		for i, t := range tasks {
+			if _, ok := dropped[i]; !ok {
+				applyTasks = append(applyTasks, t)
+			}
+		}
+		tasks = applyTasks
 	}
 
 	endTime := time.Now()
 	zap.L().Info("oracle table increment log apply finished",
 		zap.String("oracle schema", sourceSchema),
 		zap.String("oracle table", sourceTable),
+		zap.Int("logminer records", len(logminers)),
+		zap.Int("apply tasks", len(tasks)),
+		zap.Int("merge records", merges),
 		zap.String("status", "success"),
 		zap.Time("start time", startTime),
 		zap.Time("end time", endTime),
 		zap.String("cost time", time.Since(startTime).String()))
 
-	// 任务结束，关闭通道
-	close(taskQueue)
+	return tasks, nil
+}
 
-	return nil
+// 同键连续变更合并，SQL 形态：UPDATE -> [DELETE, REPLACE]、INSERT -> [REPLACE]、DELETE -> [DELETE]
+// INSERT + INSERT/UPDATE -> INSERT，INSERT + DELETE 抵消（返回 false 丢弃任务，下游行未写入无需删除）
+// INSERT 为断点重放变更（SCN 不大于 checkpointSCN）时下游可能已写入，INSERT + DELETE -> DELETE
+// UPDATE/DELETE + INSERT/UPDATE -> UPDATE，UPDATE/DELETE + DELETE -> DELETE
+func mergeIncrTask(prev, next IncrTask, checkpointSCN uint64) (IncrTask, bool) {
+	merged := next
+	merged.StartSCN = prev.StartSCN
+	merged.MergeCounts = prev.MergeCounts + next.MergeCounts
+
+	switch {
+	case prev.OperationType == common.MigrateOperationInsert && next.OperationType == common.MigrateOperationDelete:
+		if prev.StartSCN > checkpointSCN {
+			return merged, false
+		}
+		merged.OperationType = common.MigrateOperationDelete
+		merged.MySQLRedo = []string{next.MySQLRedo[0]}
+	case prev.OperationType == common.MigrateOperationInsert:
+		merged.OperationType = common.MigrateOperationInsert
+		merged.MySQLRedo = []string{next.MySQLRedo[len(next.MySQLRedo)-1]}
+	case next.OperationType == common.MigrateOperationDelete:
+		merged.OperationType = common.MigrateOperationDelete
+		merged.MySQLRedo = []string{prev.MySQLRedo[0]}
+	default:
+		merged.OperationType = common.MigrateOperationUpdate
+		merged.MySQLRedo = []string{prev.MySQLRedo[0], next.MySQLRedo[len(next.MySQLRedo)-1]}
+	}
+	return merged, true
 }
 
-// Oracle SQL 转换
-// 1、INSERT INTO / REPLACE INTO
-// 2、UPDATE / DELETE、REPLACE INTO
-func translateOracleToMySQLSQL(oracleSQLRedo, oracleSQLUndo, targetSchema, targetTable string) ([]string, string, error) {
-	var (
-		sqls          []string
-		operationType string
-	)
+// 按主键/唯一键字段生成键值，例如：`ID` = 1 AND `NAME` = 'marvin'，任一键字段缺失或为 NULL 返回空
+// 唯一键允许多行 NULL，NULL 键值不同行不可合并
+func genIncrKey(keyColumns []string, data map[string]interface{}) string {
+	if len(keyColumns) == 0 || data == nil {
+		return ""
+	}
+	var values []string
+	for _, col := range keyColumns {
+		v, ok := data[col]
+		if !ok || v == nil || strings.EqualFold(fmt.Sprintf("%v", v), "NULL") {
+			return ""
+		}
+		values = append(values, fmt.Sprintf("%s = %v", col, v))
+	}
+	return strings.Join(values, " AND ")
+}
+
+// 解析 Oracle SQL，库名、表名转换为目标端
+// UPDATE 变更后值取 undo 语句变更前值，变更字段以 redo 语句变更前字段为准
+func parseOracleIncrStmt(oracleSQLRedo, oracleSQLUndo, targetSchema, targetTable string) (*public.Stmt, error) {
 	astNode, err := public.ParseSQL(oracleSQLRedo)
 	if err != nil {
-		return []string{}, operationType, fmt.Errorf("parse error: %v\n", err.Error())
+		return nil, fmt.Errorf("parse error: %v\n", err.Error())
 	}
 
 	stmt := public.ExtractStmt(astNode)
@@ -245,12 +346,10 @@ func translateOracleToMySQLSQL(oracleSQLRedo, oracleSQLUndo, targetSchema, targe
 	stmt.Schema = targetSchema
 	stmt.Table = targetTable
 
-	switch {
-	case stmt.Operation == common.MigrateOperationUpdate:
-		operationType = common.MigrateOperationUpdate
+	if stmt.Operation == common.MigrateOperationUpdate {
 		astUndoNode, err := public.ParseSQL(oracleSQLUndo)
 		if err != nil {
-			return []string{}, operationType, fmt.Errorf("parse error: %v\n", err.Error())
+			return nil, fmt.Errorf("parse error: %v\n", err.Error())
 		}
 		undoStmt := public.ExtractStmt(astUndoNode)
 
@@ -258,14 +357,28 @@ func translateOracleToMySQLSQL(oracleSQLRedo, oracleSQLUndo, targetSchema, targe
 		for column, _ := range stmt.Before {
 			stmt.Columns = append(stmt.Columns, strings.ToUpper(column))
 		}
+	}
+	return stmt, nil
+}
 
-		var deleteSQL string
+// Oracle SQL 转换
+// 1、INSERT INTO / REPLACE INTO
+// 2、UPDATE / DELETE、REPLACE INTO
+// 3、同时返回变更前后主键/唯一键值，DDL 以及键值缺失返回空
+// 4、无主键/唯一键表 UPDATE/DELETE 只影响一行，存在 ROWID 代理字段（rowID 非空）依据代理字段定位并写入代理字段
+// 5、字段脱敏由 maskIncrStmts 预先计算，WHERE 条件以脱敏后变更前值生成
+func genMySQLIncrSQL(stmt *public.Stmt, keyColumns []string, rowID string) ([]string, string, string, string) {
+	var (
+		sqls                []string
+		operationType       string
+		beforeKey, afterKey string
+	)
 
-		if stmt.WhereExpr == "" {
-			deleteSQL = common.StringsBuilder(`DELETE FROM `, stmt.Schema, ".", stmt.Table)
-		} else {
-			deleteSQL = common.StringsBuilder(`DELETE FROM `, stmt.Schema, ".", stmt.Table, ` `, stmt.WhereExpr)
-		}
+	switch {
+	case stmt.Operation == common.MigrateOperationUpdate:
+		operationType = common.MigrateOperationUpdate
+
+		deleteSQL := genIncrDeleteSQL(stmt, keyColumns, rowID)
 
 		var (
 			values []string
@@ -285,9 +398,16 @@ func translateOracleToMySQLSQL(oracleSQLRedo, oracleSQLUndo, targetSchema, targe
 		sqls = append(sqls, deleteSQL)
 		sqls = append(sqls, insertSQL)
 
+		beforeKey = genIncrKey(keyColumns, stmt.Before)
+		afterKey = genIncrKey(keyColumns, stmt.Data)
+
 	case stmt.Operation == common.MigrateOperationInsert:
 		operationType = common.MigrateOperationInsert
 
+		if rowID != "" {
+			genKeylessRowidData(stmt, rowID)
+		}
+
 		var values []string
 
 		for _, col := range stmt.Columns {
@@ -304,19 +424,19 @@ func translateOracleToMySQLSQL(oracleSQLRedo, oracleSQLUndo, targetSchema, targe
 
 		sqls = append(sqls, replaceSQL)
 
+		beforeKey = genIncrKey(keyColumns, stmt.Data)
+		afterKey = beforeKey
+
 	case stmt.Operation == common.MigrateOperationDelete:
 		operationType = common.MigrateOperationDelete
 
-		var deleteSQL string
-
-		if stmt.WhereExpr == "" {
-			deleteSQL = common.StringsBuilder(`DELETE FROM `, stmt.Schema, ".", stmt.Table)
-		} else {
-			deleteSQL = common.StringsBuilder(`DELETE FROM `, stmt.Schema, ".", stmt.Table, ` `, stmt.WhereExpr)
-		}
+		deleteSQL := genIncrDeleteSQL(stmt, keyColumns, rowID)
 
 		sqls = append(sqls, deleteSQL)
 
+		beforeKey = genIncrKey(keyColumns, stmt.Before)
+		afterKey = beforeKey
+
 	case stmt.Operation == common.MigrateOperationTruncate:
 		operationType = common.MigrateOperationTruncateTable
 
@@ -330,5 +450,63 @@ func translateOracleToMySQLSQL(oracleSQLRedo, oracleSQLUndo, targetSchema, targe
 
 		sqls = append(sqls, dropSQL)
 	}
-	return sqls, operationType, nil
+	return sqls, operationType, beforeKey, afterKey
+}
+
+// DELETE 语句，存在主键/唯一键按原条件删除，无主键/唯一键表只删除一行，存在 ROWID 代理字段按代理字段删除
+func genIncrDeleteSQL(stmt *public.Stmt, keyColumns []string, rowID string) string {
+	var deleteSQL string
+	switch {
+	case rowID != "":
+		deleteSQL = common.StringsBuilder(`DELETE FROM `, stmt.Schema, ".", stmt.Table, " WHERE `", common.MigrateKeylessRowidColumn, "` = '", rowID, "' LIMIT 1")
+		stmt.Before[common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`")] = common.StringsBuilder("'", rowID, "'")
+		if stmt.Operation == common.MigrateOperationUpdate {
+			genKeylessRowidData(stmt, rowID)
+		}
+	case stmt.WhereExpr == "":
+		deleteSQL = common.StringsBuilder(`DELETE FROM `, stmt.Schema, ".", stmt.Table)
+	default:
+		deleteSQL = common.StringsBuilder(`DELETE FROM `, stmt.Schema, ".", stmt.Table, ` `, stmt.WhereExpr)
+	}
+	if rowID == "" && len(keyColumns) == 0 {
+		deleteSQL = common.StringsBuilder(deleteSQL, ` LIMIT 1`)
+	}
+	return deleteSQL
+}
+
+// 写入 ROWID 代理字段
+func genKeylessRowidData(stmt *public.Stmt, rowID string) {
+	column := common.StringsBuilder("`", common.MigrateKeylessRowidColumn, "`")
+	if _, ok := stmt.Data[column]; !ok {
+		stmt.Columns = append(stmt.Columns, column)
+	}
+	stmt.Data[column] = common.StringsBuilder("'", rowID, "'")
+}
+
+// 变更字段值批量脱敏，存在脱敏的变更前值重新生成 WHERE 条件
+func maskIncrStmts(stmts []*public.Stmt, mask *public.ColumnMask) error {
+	if mask == nil {
+		return nil
+	}
+	var (
+		datas   []map[string]interface{}
+		befores []*public.Stmt
+	)
+	for _, stmt := range stmts {
+		switch stmt.Operation {
+		case common.MigrateOperationInsert, common.MigrateOperationUpdate, common.MigrateOperationDelete:
+			datas = append(datas, stmt.Data, stmt.Before)
+			befores = append(befores, stmt)
+		}
+	}
+	masked, err := mask.MaskDatas(datas)
+	if err != nil {
+		return err
+	}
+	for i, stmt := range befores {
+		if masked[2*i+1] {
+			stmt.WhereExpr = public.GenMaskWhereExpr(stmt.Before)
+		}
+	}
+	return nil
 }
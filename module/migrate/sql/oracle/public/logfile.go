@@ -0,0 +1,155 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package public
+
+import (
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"math"
+	"sort"
+)
+
+// redo 线程日志文件
+type ThreadLogfile struct {
+	Thread    string `json:"thread"`
+	Sequence  uint64 `json:"sequence"`
+	FirstSCN  uint64 `json:"first_scn"`
+	NextSCN   uint64 `json:"next_scn"` // 当前重做日志为 math.MaxUint64
+	LogFile   string `json:"log_file"`
+	IsCurrent bool   `json:"is_current"`
+}
+
+// 日志挖掘窗口，合并所有 redo 线程 SCN 区间重叠的日志文件，同一 logminer 会话挖掘
+type LogfileWindow struct {
+	StartSCN uint64          `json:"start_scn"`
+	EndSCN   uint64          `json:"end_scn"` // 窗口结束 SCN（不包含），窗口内各线程均为当前重做日志时为 0，挖掘至最新
+	LogFiles []string        `json:"log_files"`
+	Threads  []ThreadLogfile `json:"threads"` // 各线程窗口内最后一个日志文件
+}
+
+func (w LogfileWindow) IsCurrent() bool {
+	return w.EndSCN == 0
+}
+
+// 解析日志文件列表，同一线程同一序列号只保留首个（归档日志优先于在线重做日志传入）
+func NewThreadLogfiles(logs []map[string]string) ([]ThreadLogfile, error) {
+	var (
+		logfiles []ThreadLogfile
+		exists   = make(map[string]struct{})
+	)
+	for _, l := range logs {
+		key := common.StringsBuilder(l["THREAD"], "/", l["SEQUENCE"])
+		if _, ok := exists[key]; ok {
+			continue
+		}
+		exists[key] = struct{}{}
+
+		sequence, err := common.StrconvUintBitSize(l["SEQUENCE"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("get oracle log file [%s] sequence %s utils.StrconvUintBitSize failed: %v", l["LOG_FILE"], l["SEQUENCE"], err)
+		}
+		firstSCN, err := common.StrconvUintBitSize(l["FIRST_CHANGE"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("get oracle log file [%s] start scn %s utils.StrconvUintBitSize failed: %v", l["LOG_FILE"], l["FIRST_CHANGE"], err)
+		}
+		lf := ThreadLogfile{
+			Thread:    l["THREAD"],
+			Sequence:  sequence,
+			FirstSCN:  firstSCN,
+			NextSCN:   math.MaxUint64,
+			LogFile:   l["LOG_FILE"],
+			IsCurrent: l["STATUS"] == "CURRENT",
+		}
+		if !lf.IsCurrent {
+			lf.NextSCN, err = common.StrconvUintBitSize(l["NEXT_CHANGE"], 64)
+			if err != nil {
+				return nil, fmt.Errorf("get oracle log file [%s] end scn %s utils.StrconvUintBitSize failed: %v", l["LOG_FILE"], l["NEXT_CHANGE"], err)
+			}
+		}
+		logfiles = append(logfiles, lf)
+	}
+	return logfiles, nil
+}
+
+// 生成日志挖掘窗口
+// 窗口起始为 startSCN，结束为各线程首个非当前日志文件结束 SCN 最小值，窗口内添加所有线程与区间重叠的日志文件
+// threadSequences 为各线程上次挖掘的日志序列号，用于检查日志文件缺失
+func GenOracleLogfileWindow(startSCN uint64, logfiles []ThreadLogfile, threads []string, threadSequences map[string]uint64) (LogfileWindow, error) {
+	threadLogs := make(map[string][]ThreadLogfile)
+	for _, lf := range logfiles {
+		threadLogs[lf.Thread] = append(threadLogs[lf.Thread], lf)
+	}
+
+	window := LogfileWindow{StartSCN: startSCN}
+	endSCN := uint64(math.MaxUint64)
+	for _, t := range threads {
+		logs, ok := threadLogs[t]
+		if !ok {
+			return window, fmt.Errorf("oracle redo thread [%s] log file after scn [%d] isn't exist, please check archived log", t, startSCN)
+		}
+		sort.Slice(logs, func(i, j int) bool {
+			return logs[i].Sequence < logs[j].Sequence
+		})
+		for i := 1; i < len(logs); i++ {
+			if logs[i].Sequence != logs[i-1].Sequence+1 {
+				return window, fmt.Errorf("oracle redo thread [%s] log file sequence [%d] isn't exist, please check archived log", t, logs[i-1].Sequence+1)
+			}
+		}
+		if seq, ok := threadSequences[t]; ok && logs[0].Sequence > seq+1 {
+			return window, fmt.Errorf("oracle redo thread [%s] log file sequence [%d] isn't exist, last logminer sequence [%d], please check archived log", t, seq+1, seq)
+		}
+		threadLogs[t] = logs
+		if !logs[0].IsCurrent && logs[0].NextSCN < endSCN {
+			endSCN = logs[0].NextSCN
+		}
+	}
+
+	var included []ThreadLogfile
+	for _, t := range threads {
+		var (
+			last   ThreadLogfile
+			isLast bool
+		)
+		for _, lf := range threadLogs[t] {
+			if lf.FirstSCN >= endSCN {
+				break
+			}
+			included = append(included, lf)
+			last, isLast = lf, true
+		}
+		// 线程窗口内无日志文件（例如 RAC 节点晚于窗口结束 SCN 启动），不记录线程进度
+		if isLast {
+			window.Threads = append(window.Threads, last)
+		}
+	}
+	sort.Slice(included, func(i, j int) bool {
+		if included[i].FirstSCN == included[j].FirstSCN {
+			return included[i].Thread < included[j].Thread
+		}
+		return included[i].FirstSCN < included[j].FirstSCN
+	})
+	for _, lf := range included {
+		window.LogFiles = append(window.LogFiles, lf.LogFile)
+	}
+	// logminer 起始 SCN 不得小于已添加日志文件最小起始 SCN
+	if len(included) > 0 && included[0].FirstSCN > window.StartSCN {
+		window.StartSCN = included[0].FirstSCN
+	}
+	if endSCN != math.MaxUint64 {
+		window.EndSCN = endSCN
+	}
+	return window, nil
+}
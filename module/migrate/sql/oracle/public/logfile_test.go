@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package public
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenOracleLogfileWindow(t *testing.T) {
+	logs, err := NewThreadLogfiles([]map[string]string{
+		{"THREAD": "1", "SEQUENCE": "10", "FIRST_CHANGE": "100", "NEXT_CHANGE": "200", "STATUS": "ARCHIVED", "LOG_FILE": "arch_1_10"},
+		{"THREAD": "2", "SEQUENCE": "5", "FIRST_CHANGE": "90", "NEXT_CHANGE": "150", "STATUS": "ARCHIVED", "LOG_FILE": "arch_2_5"},
+		{"THREAD": "1", "SEQUENCE": "10", "FIRST_CHANGE": "100", "NEXT_CHANGE": "200", "STATUS": "ACTIVE", "LOG_FILE": "redo_1_10"},
+		{"THREAD": "2", "SEQUENCE": "6", "FIRST_CHANGE": "150", "NEXT_CHANGE": "NULLABLE", "STATUS": "CURRENT", "LOG_FILE": "redo_2_6"},
+		{"THREAD": "1", "SEQUENCE": "11", "FIRST_CHANGE": "200", "NEXT_CHANGE": "281474976710655", "STATUS": "CURRENT", "LOG_FILE": "redo_1_11"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	threads := []string{"1", "2"}
+
+	cases := []struct {
+		startSCN uint64
+		endSCN   uint64
+		logFiles []string
+	}{
+		{120, 150, []string{"arch_2_5", "arch_1_10"}},
+		{150, 200, []string{"arch_1_10", "redo_2_6"}},
+		{200, 0, []string{"redo_2_6", "redo_1_11"}},
+	}
+	for _, c := range cases {
+		var filters []ThreadLogfile
+		for _, l := range logs {
+			if l.NextSCN > c.startSCN {
+				filters = append(filters, l)
+			}
+		}
+		window, err := GenOracleLogfileWindow(c.startSCN, filters, threads, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if window.EndSCN != c.endSCN || !reflect.DeepEqual(window.LogFiles, c.logFiles) {
+			t.Fatalf("start scn [%d] window end scn [%d] log files %v, want [%d] %v", c.startSCN, window.EndSCN, window.LogFiles, c.endSCN, c.logFiles)
+		}
+	}
+
+	if _, err = GenOracleLogfileWindow(120, logs, threads, map[string]uint64{"2": 3}); err == nil {
+		t.Fatal("expect thread 2 missing sequence error")
+	}
+	if _, err = GenOracleLogfileWindow(120, logs[1:], threads, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GenOracleLogfileWindow(120, []ThreadLogfile{logs[0], logs[3], logs[1]}, []string{"1", "2", "3"}, nil); err == nil {
+		t.Fatal("expect thread 3 missing log error")
+	}
+
+	// 线程 3 首个日志文件晚于窗口结束 SCN，窗口不包含该线程进度
+	lateLogs, err := NewThreadLogfiles([]map[string]string{
+		{"THREAD": "3", "SEQUENCE": "1", "FIRST_CHANGE": "300", "NEXT_CHANGE": "NULLABLE", "STATUS": "CURRENT", "LOG_FILE": "redo_3_1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range logs {
+		if l.Thread == "1" {
+			lateLogs = append(lateLogs, l)
+		}
+	}
+	window, err := GenOracleLogfileWindow(120, lateLogs, []string{"1", "3"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if window.EndSCN != 200 || !reflect.DeepEqual(window.LogFiles, []string{"arch_1_10"}) ||
+		len(window.Threads) != 1 || window.Threads[0].Thread != "1" || window.Threads[0].Sequence != 10 {
+		t.Fatalf("window end scn [%d] log files %v threads %v, want [200] [arch_1_10] thread 1 sequence 10", window.EndSCN, window.LogFiles, window.Threads)
+	}
+}
@@ -40,10 +40,12 @@ type Logminer struct {
 	SQLRedo      string
 	SQLUndo      string
 	Operation    string
+	RowID        string
 }
 
 // 捕获增量数据
-func GetOracleIncrRecord(ctx context.Context, oracle *oracle.Oracle, sourceSchema, targetSchema string, sourceTable string, tableNameRule map[string]string, lastCheckpoint string, queryTimeout int) ([]Logminer, error) {
+// endCheckpoint 为空表示不限制结束 SCN
+func GetOracleIncrRecord(ctx context.Context, oracle *oracle.Oracle, sourceSchema, targetSchema string, sourceTable string, tableNameRule map[string]string, lastCheckpoint, endCheckpoint string, queryTimeout int) ([]Logminer, error) {
 	var lcs []Logminer
 
 	c, cancel := context.WithTimeout(ctx, time.Duration(queryTimeout)*time.Second)
@@ -54,13 +56,18 @@ func GetOracleIncrRecord(ctx context.Context, oracle *oracle.Oracle, sourceSchem
        TABLE_NAME AS SOURCE_TABLE,
        SQL_REDO,
        SQL_UNDO,
-       OPERATION
+       OPERATION,
+       ROW_ID
   FROM V$LOGMNR_CONTENTS
  WHERE 1 = 1
    AND UPPER(SEG_OWNER) = '`, common.StringUPPER(sourceSchema), `'
    AND UPPER(TABLE_NAME) IN (`, sourceTable, `)
    AND OPERATION IN ('INSERT', 'DELETE', 'UPDATE', 'DDL')
-   AND SCN >= `, lastCheckpoint, ` ORDER BY SCN`)
+   AND SCN >= `, lastCheckpoint)
+	if endCheckpoint != "" {
+		querySQL = common.StringsBuilder(querySQL, ` AND SCN < `, endCheckpoint)
+	}
+	querySQL = common.StringsBuilder(querySQL, ` ORDER BY SCN`)
 
 	startTime := time.Now()
 
@@ -72,7 +79,7 @@ func GetOracleIncrRecord(ctx context.Context, oracle *oracle.Oracle, sourceSchem
 
 	for rows.Next() {
 		var lc Logminer
-		if err = rows.Scan(&lc.SCN, &lc.SourceSchema, &lc.SourceTable, &lc.SQLRedo, &lc.SQLUndo, &lc.Operation); err != nil {
+		if err = rows.Scan(&lc.SCN, &lc.SourceSchema, &lc.SourceTable, &lc.SQLRedo, &lc.SQLUndo, &lc.Operation, &lc.RowID); err != nil {
 			return lcs, err
 		}
 
@@ -152,7 +159,7 @@ func FilterOracleIncrRecord(
 				return nil
 
 			} else if currentResetFlag == 1 {
-				if rows.SCN > sourceTableSCNMAP[strings.ToUpper(rows.SQLRedo)] {
+				if rows.SCN > sourceTableSCNMAP[strings.ToUpper(rows.SourceTable)] {
 					if rows.Operation == common.MigrateOperationDDL {
 						splitDDL := strings.Split(rows.SQLRedo, ` `)
 						ddl := common.StringsBuilder(splitDDL[0], ` `, splitDDL[1])
@@ -0,0 +1,210 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package public
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/database/oracle"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 增量字段脱敏
+// 脱敏表达式统一于 ORACLE 端 DUAL 计算，保证与全量脱敏结果一致，EXPR 表达式只可引用当前字段
+// 全量以及查询方式增量脱敏表达式下推源端查询，无需 DUAL 计算
+type ColumnMask struct {
+	Oracle *oracle.Oracle
+	Exprs  map[string]string // `COLUMN` -> 脱敏表达式
+	Types  map[string]string // `COLUMN` -> 字段数据类型
+}
+
+// 单次 DUAL 计算脱敏行数
+const maskBatchRows = 100
+
+// 批量计算行序号字段
+const maskBatchIndexColumn = "_TRANSFERDB_MASK_IDX"
+
+// 表不存在脱敏规则返回 nil
+func NewColumnMask(ctx context.Context, metaDB *meta.Meta, oracleDB *oracle.Oracle, dbTypeS, dbTypeT, sourceSchema, sourceTable string) (*ColumnMask, error) {
+	maskRules, err := meta.NewColumnMaskRuleModel(metaDB).GetColumnMaskRuleMap(ctx, &meta.ColumnMaskRule{
+		DBTypeS:     dbTypeS,
+		DBTypeT:     dbTypeT,
+		SchemaNameS: sourceSchema,
+		TableNameS:  sourceTable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(maskRules) == 0 {
+		return nil, nil
+	}
+
+	columnsINFO, err := oracleDB.GetOracleSchemaTableColumn(sourceSchema, sourceTable, false)
+	if err != nil {
+		return nil, err
+	}
+	m := &ColumnMask{
+		Oracle: oracleDB,
+		Exprs:  make(map[string]string),
+		Types:  make(map[string]string),
+	}
+	for _, rowCol := range columnsINFO {
+		columnName := common.StringUPPER(rowCol["COLUMN_NAME"])
+		rule, ok := maskRules[columnName]
+		if !ok {
+			continue
+		}
+		maskExpr, err := common.GenOracleMaskColumnExpr(rule.MaskType, rule.MaskValue, columnName, rowCol["DATA_TYPE"])
+		if err != nil {
+			return nil, fmt.Errorf("table [%s] column [%s] mask rule failed: %v", sourceTable, columnName, err)
+		}
+		column := common.StringsBuilder("`", columnName, "`")
+		m.Exprs[column] = maskExpr
+		m.Types[column] = common.StringUPPER(rowCol["DATA_TYPE"])
+	}
+	return m, nil
+}
+
+// logminer 增量脱敏只以变更字段值计算，EXPR 表达式引用其他字段无法计算，增量启动前校验
+func ValidateIncrColumnMask(ctx context.Context, metaDB *meta.Meta, dbTypeS, dbTypeT, sourceSchema string) error {
+	maskRules, err := meta.NewColumnMaskRuleModel(metaDB).DetailColumnMaskRuleByDBType(ctx, &meta.ColumnMaskRule{
+		DBTypeS: dbTypeS,
+		DBTypeT: dbTypeT,
+	})
+	if err != nil {
+		return err
+	}
+	for _, rule := range maskRules {
+		if common.StringUPPER(rule.SchemaNameS) != common.StringUPPER(sourceSchema) || common.StringUPPER(rule.MaskType) != common.MaskTypeExpr {
+			continue
+		}
+		for _, column := range common.GenOracleMaskExprColumns(rule.MaskValue) {
+			if common.StringUPPER(column) != common.StringUPPER(rule.ColumnNameS) {
+				return fmt.Errorf("table [%s] column [%s] mask expr [%s] reference column [%s], logminer increment only support expr reference current column, please use incr-mode query or adjust mask rule",
+					rule.TableNameS, rule.ColumnNameS, rule.MaskValue, column)
+			}
+		}
+	}
+	return nil
+}
+
+// 批量脱敏变更字段值，data 为 `COLUMN` -> SQL 字面量，每 maskBatchRows 行 UNION ALL 一次查询，返回各行是否存在脱敏字段
+func (m *ColumnMask) MaskDatas(datas []map[string]interface{}) ([]bool, error) {
+	masked := make([]bool, len(datas))
+	if m == nil {
+		return masked, nil
+	}
+	var (
+		idxs      []int
+		columns   []string
+		columnSet = make(map[string]struct{})
+	)
+	for i, data := range datas {
+		for column := range data {
+			if _, ok := m.Exprs[column]; !ok {
+				continue
+			}
+			masked[i] = true
+			if _, ok := columnSet[column]; !ok {
+				columnSet[column] = struct{}{}
+				columns = append(columns, column)
+			}
+		}
+		if masked[i] {
+			idxs = append(idxs, i)
+		}
+	}
+	if len(idxs) == 0 {
+		return masked, nil
+	}
+	sort.Strings(columns)
+
+	for start := 0; start < len(idxs); start += maskBatchRows {
+		end := start + maskBatchRows
+		if end > len(idxs) {
+			end = len(idxs)
+		}
+		batch := idxs[start:end]
+		_, res, err := oracle.Query(m.Oracle.Ctx, m.Oracle.OracleDB, m.genMaskBatchSQL(columns, datas, batch))
+		if err != nil {
+			return masked, err
+		}
+		if len(res) != len(batch) {
+			return masked, fmt.Errorf("column mask query result rows [%d] isn't equal [%d]", len(res), len(batch))
+		}
+		for _, row := range res {
+			idx, err := strconv.Atoi(row[maskBatchIndexColumn])
+			if err != nil || idx < 0 || idx >= len(datas) {
+				return masked, fmt.Errorf("column mask query result index [%s] is invalid", row[maskBatchIndexColumn])
+			}
+			for _, column := range columns {
+				if _, ok := datas[idx][column]; ok {
+					datas[idx][column] = common.GenMySQLStringLiteral(row[strings.Trim(column, "`")])
+				}
+			}
+		}
+	}
+	return masked, nil
+}
+
+// 批量脱敏查询，各行字段值字面量 UNION ALL 后统一计算脱敏表达式，行内缺失字段以 NULL 占位
+// 字段值字面量按源端字段类型转换后计算，例如 DATE 字段 HASH 与全量均以 DATE 类型 TO_CHAR
+func (m *ColumnMask) genMaskBatchSQL(columns []string, datas []map[string]interface{}, idxs []int) string {
+	maskExprs := []string{common.StringsBuilder(`"`, maskBatchIndexColumn, `"`)}
+	for _, column := range columns {
+		maskExprs = append(maskExprs, common.StringsBuilder(m.Exprs[column], ` AS "`, strings.Trim(column, "`"), `"`))
+	}
+
+	var rows []string
+	for _, idx := range idxs {
+		literals := []string{common.StringsBuilder(strconv.Itoa(idx), ` AS "`, maskBatchIndexColumn, `"`)}
+		for _, column := range columns {
+			literal := "NULL"
+			if val, ok := datas[idx][column]; ok {
+				literal = fmt.Sprintf("%v", val)
+			}
+			// 字面量转换为源端字段类型，与全量脱敏计算一致
+			literal = common.GenOracleMaskLiteral(literal, m.Types[column])
+			literals = append(literals, common.StringsBuilder(literal, ` AS "`, strings.Trim(column, "`"), `"`))
+		}
+		rows = append(rows, common.StringsBuilder(`SELECT `, strings.Join(literals, ","), ` FROM DUAL`))
+	}
+	return common.StringsBuilder(`SELECT `, strings.Join(maskExprs, ","), ` FROM (`, strings.Join(rows, ` UNION ALL `), `)`)
+}
+
+// 脱敏字段变更前值无法匹配下游，以脱敏后变更前值重新生成 WHERE 条件
+func GenMaskWhereExpr(before map[string]interface{}) string {
+	var columns []string
+	for column := range before {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var conds []string
+	for _, column := range columns {
+		value := fmt.Sprintf("%v", before[column])
+		if strings.EqualFold(value, "NULL") {
+			conds = append(conds, common.StringsBuilder(column, " IS NULL"))
+		} else {
+			conds = append(conds, common.StringsBuilder(column, " = ", value))
+		}
+	}
+	return common.StringsBuilder("WHERE ", strings.Join(conds, " AND "))
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package public
+
+import (
+	"github.com/wentaojin/transferdb/common"
+	"testing"
+)
+
+func TestGenMaskBatchSQL(t *testing.T) {
+	m := &ColumnMask{
+		Exprs: map[string]string{"`NAME`": `SUBSTR("NAME",1,1)`, "`NOTE`": `'***'`},
+		Types: map[string]string{"`NAME`": "VARCHAR2", "`NOTE`": "CLOB"},
+	}
+	datas := []map[string]interface{}{
+		{"`ID`": "'1'", "`NAME`": "'marvin'"},
+		{"`ID`": "'2'"},
+		{"`ID`": "'3'", "`NAME`": "NULL", "`NOTE`": "'n'"},
+	}
+
+	// 每行一个 DUAL 分支，行内缺失字段以 NULL 占位，CLOB 以 TO_CLOB 转换
+	want := `SELECT "_TRANSFERDB_MASK_IDX",SUBSTR("NAME",1,1) AS "NAME",'***' AS "NOTE" FROM (` +
+		`SELECT 0 AS "_TRANSFERDB_MASK_IDX",'marvin' AS "NAME",TO_CLOB(NULL) AS "NOTE" FROM DUAL UNION ALL ` +
+		`SELECT 2 AS "_TRANSFERDB_MASK_IDX",NULL AS "NAME",TO_CLOB('n') AS "NOTE" FROM DUAL)`
+	if got := m.genMaskBatchSQL([]string{"`NAME`", "`NOTE`"}, datas, []int{0, 2}); got != want {
+		t.Errorf("genMaskBatchSQL() = %s, want %s", got, want)
+	}
+
+	// 不存在脱敏字段无需查询
+	masked, err := m.MaskDatas([]map[string]interface{}{datas[1], nil})
+	if err != nil || len(masked) != 2 || masked[0] || masked[1] {
+		t.Errorf("MaskDatas() = %v, %v, want no masked", masked, err)
+	}
+}
+
+func TestGenMaskBatchSQLDate(t *testing.T) {
+	// 全量脱敏表达式直接作用于 DATE 类型字段
+	fullExpr, err := common.GenOracleMaskColumnExpr(common.MaskTypeHash, "salt", "CREATED", "DATE")
+	if err != nil {
+		t.Fatalf("GenOracleMaskColumnExpr() error: %v", err)
+	}
+	m := &ColumnMask{
+		Exprs: map[string]string{"`CREATED`": fullExpr, "`AMOUNT`": `'***'`},
+		Types: map[string]string{"`CREATED`": "DATE", "`AMOUNT`": "NUMBER"},
+	}
+	datas := []map[string]interface{}{
+		{"`CREATED`": "_UTF8MB4'2020-01-01 08:00:00'", "`AMOUNT`": "_UTF8MB4'1.50'"},
+	}
+
+	// 增量字面量转换为 DATE/NUMBER 后计算同一脱敏表达式，与全量结果一致
+	want := `SELECT "_TRANSFERDB_MASK_IDX",'***' AS "AMOUNT",` + fullExpr + ` AS "CREATED" FROM (` +
+		`SELECT 0 AS "_TRANSFERDB_MASK_IDX",TO_NUMBER('1.50') AS "AMOUNT",TO_DATE('2020-01-01 08:00:00','YYYY-MM-DD HH24:MI:SS') AS "CREATED" FROM DUAL)`
+	if got := m.genMaskBatchSQL([]string{"`AMOUNT`", "`CREATED`"}, datas, []int{0}); got != want {
+		t.Errorf("genMaskBatchSQL() = %s, want %s", got, want)
+	}
+}
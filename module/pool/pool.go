@@ -33,6 +33,10 @@ type IPool interface {
 	RunningWorkerCount() int
 	// GetPoolWorkerCount returns the number of workers.
 	GetPoolWorkerCount() int
+	// SetConcurrency sets the number of workers allowed to run tasks at the same time, range [1, maxWorkers].
+	SetConcurrency(n int)
+	// GetConcurrency returns the number of workers allowed to run tasks at the same time.
+	GetConcurrency() int
 }
 
 type Task struct {
@@ -49,6 +53,10 @@ type Result struct {
 
 type pool struct {
 	maxWorkers int
+	// concurrency represents the number of workers allowed to run tasks at the same time. Default is maxWorkers.
+	concurrency int
+	// inflight represents tasks added but not yet completed, used by Wait()
+	inflight sync.WaitGroup
 	// workerStack represent worker stack, used to judge current task all worker whether done
 	workerStack []int
 	// workers represents worker do nums
@@ -82,7 +90,8 @@ func NewPool(maxWorkers int, opts ...Option) IPool {
 	cancelCtx, cancel := context.WithCancel(context.Background())
 	p := &pool{
 		maxWorkers:     maxWorkers,
-		retryCount:     0,
+		concurrency:    maxWorkers,
+		retryCount:     1,
 		taskQueue:      nil,
 		taskQueueSize:  common.ChannelBufferSize,
 		resultCallback: nil,
@@ -120,19 +129,12 @@ func NewPool(maxWorkers int, opts ...Option) IPool {
 }
 
 func (p *pool) AddTask(t Task) {
+	p.inflight.Add(1)
 	p.taskQueue <- t
 }
 
 func (p *pool) Wait() {
-	for {
-		p.lock.Lock()
-		workerStackLen := len(p.workerStack)
-		p.lock.Unlock()
-		if len(p.taskQueue) == 0 && workerStackLen == len(p.workers) {
-			break
-		}
-		time.Sleep(50 * time.Millisecond)
-	}
+	p.inflight.Wait()
 }
 
 // RunningWorkerCount returns the number of workers that are currently working.
@@ -149,6 +151,27 @@ func (p *pool) GetPoolWorkerCount() int {
 	return len(p.workers)
 }
 
+// SetConcurrency sets the number of workers allowed to run tasks at the same time.
+func (p *pool) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	if n > p.maxWorkers {
+		n = p.maxWorkers
+	}
+	p.lock.Lock()
+	p.concurrency = n
+	p.lock.Unlock()
+	p.cond.Broadcast()
+}
+
+// GetConcurrency returns the number of workers allowed to run tasks at the same time.
+func (p *pool) GetConcurrency() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.concurrency
+}
+
 func (p *pool) Release() {
 	close(p.taskQueue)
 	p.cancel()
@@ -168,7 +191,7 @@ func (p *pool) Release() {
 func (p *pool) dispatch() {
 	for t := range p.taskQueue {
 		p.cond.L.Lock()
-		for len(p.workerStack) == 0 {
+		for len(p.workerStack) == 0 || len(p.workers)-len(p.workerStack) >= p.concurrency {
 			p.cond.Wait()
 		}
 		p.cond.L.Unlock()
@@ -189,5 +212,5 @@ func (p *pool) pushWorker(workerIndex int) {
 	p.lock.Lock()
 	p.workerStack = append(p.workerStack, workerIndex)
 	p.lock.Unlock()
-	p.cond.Signal()
+	p.cond.Broadcast()
 }
@@ -0,0 +1,338 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pool
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/wentaojin/transferdb/config"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 下游 MySQL 锁等待/死锁以及 TiDB server busy 类错误码，出现需降低并发
+var backoffErrorCodes = map[uint16]struct{}{
+	1205: {}, // MySQL lock wait timeout exceeded
+	1213: {}, // MySQL deadlock found when trying to get lock
+	9001: {}, // TiDB PD server timeout
+	9002: {}, // TiDB TiKV server timeout
+	9003: {}, // TiDB TiKV server is busy
+	9004: {}, // TiDB resolve lock timeout
+	9005: {}, // TiDB region is unavailable
+	9007: {}, // TiDB write conflict
+}
+
+// 错误多以 fmt.Errorf("%v") 形式透传，无法 errors.As 时按错误信息匹配
+var backoffErrorMessages = []string{
+	"error 1205", "error 1213", "error 9001", "error 9002", "error 9003", "error 9004", "error 9005", "error 9007",
+	"lock wait timeout exceeded", "deadlock found", "server is busy", "region is unavailable",
+}
+
+// IsBackoffError 判断是否下游繁忙类错误
+func IsBackoffError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		if _, ok := backoffErrorCodes[me.Number]; ok {
+			return true
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range backoffErrorMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// 当前运行 scheduler 并发信息，通过 pprof 端口 /debug/vars 查看
+var (
+	schedulerMu       sync.Mutex
+	schedulerRegistry = make(map[string]*Scheduler)
+)
+
+func init() {
+	expvar.Publish("transferdb_concurrency", expvar.Func(func() interface{} {
+		schedulerMu.Lock()
+		defer schedulerMu.Unlock()
+		stats := make(map[string]SchedulerStat)
+		for name, s := range schedulerRegistry {
+			stats[name] = s.Stat()
+		}
+		return stats
+	}))
+}
+
+type SchedulerStat struct {
+	Concurrency    int     `json:"concurrency"`
+	MinWorkers     int     `json:"min_workers"`
+	MaxWorkers     int     `json:"max_workers"`
+	RunningWorkers int     `json:"running_workers"`
+	AvgLatencyMS   int64   `json:"avg_latency_ms"`
+	Throughput     float64 `json:"throughput"`
+	Backoffs       int64   `json:"backoffs"`
+}
+
+// Scheduler full/csv/compare 共用任务调度，基于 IPool 运行任务
+// 开启自适应并发，按写入延迟、吞吐以及下游繁忙错误在 [min, max] 区间内调整并发
+type Scheduler struct {
+	name           string
+	pool           IPool
+	adaptive       bool
+	minWorkers     int
+	maxWorkers     int
+	targetLatency  time.Duration
+	adjustInterval time.Duration
+	ctx            context.Context
+	cancel         context.CancelFunc
+
+	mu sync.Mutex
+	// 当前调整周期统计
+	completed    int64
+	latencySum   time.Duration
+	backoffCount int64
+	// 上一调整周期
+	lastThroughput float64
+	lastLatency    time.Duration
+	lastIncreased  bool
+	totalBackoffs  int64
+}
+
+type Group struct {
+	s       *Scheduler
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+type groupJob struct {
+	group *Group
+	fn    func() error
+}
+
+func NewScheduler(name string, maxWorkers int, cfg config.AppConfig) *Scheduler {
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+	minWorkers := cfg.MinConcurrency
+	if minWorkers < 1 {
+		minWorkers = 1
+	}
+	if minWorkers > maxWorkers {
+		minWorkers = maxWorkers
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		name:           name,
+		adaptive:       cfg.AdaptiveConcurrency,
+		minWorkers:     minWorkers,
+		maxWorkers:     maxWorkers,
+		targetLatency:  time.Duration(cfg.TargetWriteLatency) * time.Millisecond,
+		adjustInterval: time.Duration(cfg.AdjustInterval) * time.Second,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	s.pool = NewPool(maxWorkers,
+		WithExecuteTask(func(t Task) error {
+			job := t.Job.(*groupJob)
+			startTime := time.Now()
+			err := job.fn()
+			s.Observe(time.Now().Sub(startTime), err)
+			return err
+		}),
+		WithResultCallback(func(r Result) {
+			job := r.Task.Job.(*groupJob)
+			if r.Err != nil {
+				job.group.errOnce.Do(func() {
+					job.group.err = r.Err
+				})
+			}
+			job.group.wg.Done()
+		}))
+
+	schedulerMu.Lock()
+	schedulerRegistry[name] = s
+	schedulerMu.Unlock()
+
+	if s.adaptive && s.adjustInterval > 0 {
+		go s.adjust()
+	}
+	return s
+}
+
+// NewGroup 同 errgroup.Group 用法，Wait 返回首个错误
+func (s *Scheduler) NewGroup() *Group {
+	return &Group{s: s}
+}
+
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	g.s.pool.AddTask(Task{
+		Attr:  g.s.name,
+		Stage: "schedule",
+		Job:   &groupJob{group: g, fn: fn},
+	})
+}
+
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
+
+// Observe 记录任务耗时以及错误，任务内部吞掉的错误（例如 chunk 错误记录元数据后 skip）需主动反馈
+func (s *Scheduler) Observe(latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed++
+	s.latencySum += latency
+	if IsBackoffError(err) {
+		s.backoffCount++
+		s.totalBackoffs++
+	}
+}
+
+// Feedback 反馈任务内部错误，不计入任务耗时
+func (s *Scheduler) Feedback(err error) {
+	if !IsBackoffError(err) {
+		return
+	}
+	s.mu.Lock()
+	s.backoffCount++
+	s.totalBackoffs++
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) Concurrency() int {
+	return s.pool.GetConcurrency()
+}
+
+func (s *Scheduler) Stat() SchedulerStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SchedulerStat{
+		Concurrency:    s.pool.GetConcurrency(),
+		MinWorkers:     s.minWorkers,
+		MaxWorkers:     s.maxWorkers,
+		RunningWorkers: s.pool.RunningWorkerCount(),
+		AvgLatencyMS:   s.lastLatency.Milliseconds(),
+		Throughput:     s.lastThroughput,
+		Backoffs:       s.totalBackoffs,
+	}
+}
+
+func (s *Scheduler) Release() {
+	s.cancel()
+	s.pool.Release()
+	schedulerMu.Lock()
+	if v, ok := schedulerRegistry[s.name]; ok && v == s {
+		delete(schedulerRegistry, s.name)
+	}
+	schedulerMu.Unlock()
+}
+
+func (s *Scheduler) adjust() {
+	ticker := time.NewTicker(s.adjustInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			completed, latencySum, backoffs := s.completed, s.latencySum, s.backoffCount
+			s.completed, s.latencySum, s.backoffCount = 0, 0, 0
+			s.mu.Unlock()
+
+			current := s.pool.GetConcurrency()
+			next, reason := s.nextConcurrency(current, completed, latencySum, backoffs)
+			if next != current {
+				s.pool.SetConcurrency(next)
+				zap.L().Info("adaptive concurrency adjust",
+					zap.String("scheduler", s.name),
+					zap.Int("from", current),
+					zap.Int("to", next),
+					zap.String("reason", reason))
+			}
+		}
+	}
+}
+
+// 吞吐信号只有调整周期内完成的任务数，不统计行数；任务数少或者单任务耗时超过调整周期时周期内无任务完成，不做调整
+// 并发调整：
+//   - 下游锁等待/繁忙错误，并发减半
+//   - 平均写入延迟超过目标值，并发减少 1/4
+//   - 平均写入延迟低于目标值 80%，并发增加；若上次增加后吞吐未提升（源端读取成为瓶颈）则保持
+func (s *Scheduler) nextConcurrency(current int, completed int64, latencySum time.Duration, backoffs int64) (int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if backoffs > 0 {
+		s.lastIncreased = false
+		return s.clamp(current / 2), fmt.Sprintf("target busy or lock wait errors [%d]", backoffs)
+	}
+	if completed == 0 {
+		return current, "no task completed"
+	}
+
+	avgLatency := latencySum / time.Duration(completed)
+	throughput := float64(completed) / s.adjustInterval.Seconds()
+	lastThroughput := s.lastThroughput
+	s.lastLatency = avgLatency
+	s.lastThroughput = throughput
+
+	switch {
+	case s.targetLatency > 0 && avgLatency > s.targetLatency:
+		s.lastIncreased = false
+		step := current / 4
+		if step < 1 {
+			step = 1
+		}
+		return s.clamp(current - step), fmt.Sprintf("avg latency [%v] exceeds target [%v]", avgLatency, s.targetLatency)
+	case s.targetLatency <= 0 || avgLatency < s.targetLatency*8/10:
+		if s.lastIncreased && throughput <= lastThroughput*1.05 {
+			s.lastIncreased = false
+			return current, fmt.Sprintf("throughput [%.2f] not improved, source throughput bound", throughput)
+		}
+		step := current / 10
+		if step < 1 {
+			step = 1
+		}
+		next := s.clamp(current + step)
+		s.lastIncreased = next > current
+		return next, fmt.Sprintf("avg latency [%v] below target [%v]", avgLatency, s.targetLatency)
+	default:
+		s.lastIncreased = false
+		return current, "avg latency near target"
+	}
+}
+
+func (s *Scheduler) clamp(n int) int {
+	if n < s.minWorkers {
+		return s.minWorkers
+	}
+	if n > s.maxWorkers {
+		return s.maxWorkers
+	}
+	return n
+}
@@ -41,6 +41,7 @@ func (w *worker) start(p *pool, workerID int) {
 				w.handleResult(t, workerID, p, err)
 			}
 			p.pushWorker(workerID)
+			p.inflight.Done()
 		}
 	}()
 }
@@ -0,0 +1,69 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package precheck
+
+import (
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"regexp"
+	"strings"
+)
+
+var mysqlGrantRegex = regexp.MustCompile("(?i)^GRANT (.+?) ON (\\S+) TO ")
+
+// 解析 SHOW GRANTS，只统计全局 *.* 以及 schema.* 级别权限，ALL PRIVILEGES 视为拥有全部权限
+func ParseMySQLGrantPrivileges(grants []string, schemaName string) map[string]struct{} {
+	privs := make(map[string]struct{})
+	for _, g := range grants {
+		matches := mysqlGrantRegex.FindStringSubmatch(strings.TrimSpace(g))
+		if matches == nil {
+			continue
+		}
+		scope := strings.ReplaceAll(matches[2], "`", "")
+		if scope != "*.*" && !strings.EqualFold(scope, common.StringsBuilder(schemaName, ".*")) {
+			continue
+		}
+		for _, p := range strings.Split(matches[1], ",") {
+			privs[common.StringUPPER(strings.TrimSpace(p))] = struct{}{}
+		}
+	}
+	return privs
+}
+
+func MissingMySQLPrivileges(privs map[string]struct{}, required []string) []string {
+	if _, ok := privs["ALL PRIVILEGES"]; ok {
+		return nil
+	}
+	if _, ok := privs["ALL"]; ok {
+		return nil
+	}
+	var missing []string
+	for _, r := range required {
+		if _, ok := privs[r]; !ok {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// CURRENT_USER() user@host 转换为 'user'@'host'
+func GenMySQLUserIdentity(user string) string {
+	idx := strings.LastIndex(user, "@")
+	if idx < 0 {
+		return fmt.Sprintf("'%s'", user)
+	}
+	return fmt.Sprintf("'%s'@'%s'", user[:idx], user[idx+1:])
+}
@@ -0,0 +1,30 @@
+package precheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMissingMySQLPrivileges(t *testing.T) {
+	required := []string{"SELECT", "INSERT", "CREATE"}
+	tests := []struct {
+		name   string
+		grants []string
+		want   string
+	}{
+		{name: "all privileges", grants: []string{"GRANT ALL PRIVILEGES ON *.* TO `root`@`%` WITH GRANT OPTION"}, want: ""},
+		{name: "schema scope", grants: []string{"GRANT SELECT, INSERT, CREATE ON `steven`.* TO `u`@`%`"}, want: ""},
+		{name: "global and schema", grants: []string{"GRANT SELECT ON *.* TO `u`@`%`", "GRANT INSERT ON `steven`.* TO `u`@`%`"}, want: "CREATE"},
+		{name: "other schema", grants: []string{"GRANT ALL PRIVILEGES ON `other`.* TO `u`@`%`"}, want: "SELECT,INSERT,CREATE"},
+		{name: "table scope", grants: []string{"GRANT SELECT ON `steven`.`t1` TO `u`@`%`"}, want: "SELECT,INSERT,CREATE"},
+		{name: "usage", grants: []string{"GRANT USAGE ON *.* TO `u`@`%`"}, want: "SELECT,INSERT,CREATE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(MissingMySQLPrivileges(ParseMySQLGrantPrivileges(tt.grants, "steven"), required), ",")
+			if got != tt.want {
+				t.Errorf("missing mysql privileges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
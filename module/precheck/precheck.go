@@ -0,0 +1,501 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/meta"
+	"github.com/wentaojin/transferdb/database/mysql"
+	"github.com/wentaojin/transferdb/database/oracle"
+	"github.com/wentaojin/transferdb/module/reverse/oracle/public"
+	"go.uber.org/zap"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 检查项分类
+const (
+	checkMetaDB          = "META_DB"
+	checkOracleConnect   = "ORACLE_CONNECT"
+	checkOraclePrivilege = "ORACLE_PRIVILEGE"
+	checkOracleLogMode   = "ORACLE_LOG_MODE"
+	checkSupplementalLog = "SUPPLEMENTAL_LOG"
+	checkCharset         = "CHARSET"
+	checkTableKey        = "TABLE_PK_UK"
+	checkTargetConnect   = "TARGET_CONNECT"
+	checkTargetPrivilege = "TARGET_PRIVILEGE"
+	checkTargetVariable  = "TARGET_VARIABLE"
+)
+
+type Precheck struct {
+	Ctx    context.Context
+	Cfg    *config.Config
+	Oracle *oracle.Oracle
+	Mysql  *mysql.MySQL
+	Items  []Item
+	FixSQL []string
+}
+
+// 检查结果
+type Item struct {
+	Check  string
+	Object string
+	Result string
+	Detail string
+}
+
+func IPrecheck(ctx context.Context, cfg *config.Config) error {
+	p := &Precheck{
+		Ctx: ctx,
+		Cfg: cfg,
+	}
+	return p.Precheck()
+}
+
+// 按 target-mode 运行前预检查，输出 pass/warn/fail 报告以及可选修复 SQL，存在 FAIL 返回错误
+func (p *Precheck) Precheck() error {
+	startTime := time.Now()
+	zap.L().Info("precheck start",
+		zap.String("target mode", p.Cfg.PrecheckConfig.TargetMode),
+		zap.String("schema", p.Cfg.SchemaConfig.SourceSchema))
+
+	p.checkMetaDB()
+
+	oracleDB, err := oracle.NewOracleDBEngine(p.Ctx, p.Cfg.OracleConfig, p.Cfg.SchemaConfig.SourceSchema)
+	if err != nil {
+		p.add(checkOracleConnect, p.Cfg.OracleConfig.ServiceName, common.PrecheckResultFail, err.Error())
+	} else {
+		p.Oracle = oracleDB
+		p.add(checkOracleConnect, p.Cfg.OracleConfig.ServiceName, common.PrecheckResultPass, "")
+		p.checkOraclePrivilege()
+		if p.isLogminerMode() {
+			p.checkOracleLogMode()
+		}
+		p.checkCharset()
+		if !strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeReverse) {
+			p.checkTableKey()
+		}
+	}
+
+	// CSV 模式不写入目标端
+	if !strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeCSV) {
+		mysqlDB, err := mysql.NewMySQLDBEngine(p.Ctx, p.Cfg.MySQLConfig)
+		if err != nil {
+			p.add(checkTargetConnect, fmt.Sprintf("%s:%d", p.Cfg.MySQLConfig.Host, p.Cfg.MySQLConfig.Port), common.PrecheckResultFail, err.Error())
+		} else {
+			p.Mysql = mysqlDB
+			p.add(checkTargetConnect, fmt.Sprintf("%s:%d", p.Cfg.MySQLConfig.Host, p.Cfg.MySQLConfig.Port), common.PrecheckResultPass, "")
+			p.checkTargetPrivilege()
+			p.checkTargetVariable()
+		}
+	}
+
+	return p.report(startTime)
+}
+
+func (p *Precheck) isLogminerMode() bool {
+	return strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeAll) &&
+		strings.EqualFold(p.Cfg.AllConfig.IncrMode, common.IncrModeLogminer)
+}
+
+func (p *Precheck) add(check, object, result, detail string) {
+	p.Items = append(p.Items, Item{
+		Check:  check,
+		Object: object,
+		Result: result,
+		Detail: detail,
+	})
+}
+
+// 修复 SQL 去重
+func (p *Precheck) addFix(sql string) {
+	if !common.IsContainString(p.FixSQL, sql) {
+		p.FixSQL = append(p.FixSQL, sql)
+	}
+}
+
+func (p *Precheck) checkMetaDB() {
+	object := fmt.Sprintf("%s:%d/%s", p.Cfg.MetaConfig.Host, p.Cfg.MetaConfig.Port, p.Cfg.MetaConfig.MetaSchema)
+	metaDB, err := meta.NewMetaDBEngine(p.Ctx, p.Cfg.MetaConfig, p.Cfg.AppConfig.SlowlogThreshold)
+	if err != nil {
+		p.add(checkMetaDB, object, common.PrecheckResultFail, err.Error())
+		return
+	}
+	// 预检查仅验证连通性以及元数据库创建，验证完成释放连接
+	if sqlDB, err := metaDB.GormDB.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+	p.add(checkMetaDB, object, common.PrecheckResultPass, fmt.Sprintf("meta schema [%s] connected", p.Cfg.MetaConfig.MetaSchema))
+}
+
+// 权限以 docs/transferdb_privs.md 为准
+func (p *Precheck) checkOraclePrivilege() {
+	user, err := p.Oracle.GetOracleCurrentUser()
+	if err != nil {
+		p.add(checkOraclePrivilege, "USER", common.PrecheckResultFail, err.Error())
+		return
+	}
+	roles, err := p.Oracle.GetOracleSessionRoles()
+	if err != nil {
+		p.add(checkOraclePrivilege, "SESSION_ROLES", common.PrecheckResultFail, err.Error())
+		return
+	}
+	privs, err := p.Oracle.GetOracleSessionPrivs()
+	if err != nil {
+		p.add(checkOraclePrivilege, "SESSION_PRIVS", common.PrecheckResultFail, err.Error())
+		return
+	}
+
+	if !common.IsContainString(roles, "DBA") {
+		p.add(checkOraclePrivilege, "DBA", common.PrecheckResultWarn, fmt.Sprintf("user [%s] isn't granted role DBA, privileges are checked one by one", user))
+	}
+	if !common.IsContainString(roles, "EXECUTE_CATALOG_ROLE") {
+		p.add(checkOraclePrivilege, "EXECUTE_CATALOG_ROLE", common.PrecheckResultWarn, fmt.Sprintf("user [%s] isn't granted role EXECUTE_CATALOG_ROLE", user))
+		p.addFix(fmt.Sprintf("GRANT EXECUTE_CATALOG_ROLE TO %s;", user))
+	}
+
+	// 数据字典
+	for _, obj := range []string{"DBA_TABLES", "DBA_TAB_COLUMNS", "DBA_CONSTRAINTS", "DBA_INDEXES", "NLS_DATABASE_PARAMETERS"} {
+		if err = p.Oracle.IsOracleObjectAccessible(obj); err != nil {
+			p.add(checkOraclePrivilege, obj, common.PrecheckResultFail, err.Error())
+			p.addFix(fmt.Sprintf("GRANT SELECT ANY DICTIONARY TO %s;", user))
+			continue
+		}
+		p.add(checkOraclePrivilege, obj, common.PrecheckResultPass, "")
+	}
+	views := []string{"DATABASE"}
+	if p.isLogminerMode() {
+		views = append(views, "ARCHIVED_LOG", "LOG", "LOGFILE", "THREAD")
+	}
+	for _, v := range views {
+		if err = p.Oracle.IsOracleObjectAccessible(common.StringsBuilder("V$", v)); err != nil {
+			p.add(checkOraclePrivilege, common.StringsBuilder("V$", v), common.PrecheckResultFail, err.Error())
+			p.addFix(fmt.Sprintf("GRANT SELECT ON V_$%s TO %s;", v, user))
+			continue
+		}
+		p.add(checkOraclePrivilege, common.StringsBuilder("V$", v), common.PrecheckResultPass, "")
+	}
+
+	switch {
+	case p.isLogminerMode():
+		if !common.IsContainString(roles, "SELECT_CATALOG_ROLE") {
+			p.add(checkOraclePrivilege, "SELECT_CATALOG_ROLE", common.PrecheckResultWarn, fmt.Sprintf("user [%s] isn't granted role SELECT_CATALOG_ROLE", user))
+			p.addFix(fmt.Sprintf("GRANT SELECT_CATALOG_ROLE TO %s;", user))
+		}
+		// 固定顺序检查，保证报告检查项顺序稳定
+		for _, o := range []struct{ obj, fix string }{
+			{obj: "DBMS_LOGMNR", fix: fmt.Sprintf("GRANT EXECUTE ON DBMS_LOGMNR TO %s;", user)},
+			{obj: "V_$LOGMNR_CONTENTS", fix: fmt.Sprintf("GRANT SELECT ON V_$LOGMNR_CONTENTS TO %s;", user)},
+		} {
+			obj, fix := o.obj, o.fix
+			ok, err := p.Oracle.IsOracleObjectVisible("SYS", obj)
+			if err != nil {
+				p.add(checkOraclePrivilege, obj, common.PrecheckResultFail, err.Error())
+				continue
+			}
+			if !ok {
+				p.add(checkOraclePrivilege, obj, common.PrecheckResultFail, fmt.Sprintf("user [%s] hasn't privilege on SYS.%s", user, obj))
+				p.addFix(fix)
+				continue
+			}
+			p.add(checkOraclePrivilege, obj, common.PrecheckResultPass, "")
+		}
+		// ORACLE 12c 及以上版本需要 LOGMINING 系统权限
+		version, err := p.Oracle.GetOracleDBVersion()
+		if err != nil {
+			p.add(checkOraclePrivilege, "LOGMINING", common.PrecheckResultFail, err.Error())
+			return
+		}
+		if common.VersionOrdinal(version) >= common.VersionOrdinal("12") {
+			if !common.IsContainString(privs, "LOGMINING") {
+				p.add(checkOraclePrivilege, "LOGMINING", common.PrecheckResultFail, fmt.Sprintf("oracle version [%s] user [%s] isn't granted system privilege LOGMINING", version, user))
+				p.addFix(fmt.Sprintf("GRANT LOGMINING TO %s;", user))
+			} else {
+				p.add(checkOraclePrivilege, "LOGMINING", common.PrecheckResultPass, "")
+			}
+		}
+	case strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeAll) && strings.EqualFold(p.Cfg.AllConfig.IncrMode, common.IncrModeQuery):
+		// query 增量 AS OF SCN 闪回查询
+		if !common.IsContainString(privs, "FLASHBACK ANY TABLE") {
+			p.add(checkOraclePrivilege, "FLASHBACK ANY TABLE", common.PrecheckResultWarn, fmt.Sprintf("user [%s] isn't granted system privilege FLASHBACK ANY TABLE, incr-mode query requires flashback privilege on sync tables", user))
+			p.addFix(fmt.Sprintf("GRANT FLASHBACK ANY TABLE TO %s;", user))
+		}
+	}
+
+	// 数据校验 LOB 字段两端哈希对比，源端 DBMS_CRYPTO 计算
+	if strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeCompare) ||
+		(strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeAll) && p.Cfg.AllConfig.VerifyInterval > 0) {
+		ok, err := p.Oracle.IsOracleObjectVisible("SYS", "DBMS_CRYPTO")
+		switch {
+		case err != nil:
+			p.add(checkOraclePrivilege, "DBMS_CRYPTO", common.PrecheckResultFail, err.Error())
+		case !ok:
+			p.add(checkOraclePrivilege, "DBMS_CRYPTO", common.PrecheckResultWarn, fmt.Sprintf("user [%s] hasn't privilege on SYS.DBMS_CRYPTO, tables with lob columns can't be compared", user))
+			p.addFix(fmt.Sprintf("GRANT EXECUTE ON SYS.DBMS_CRYPTO TO %s;", user))
+		default:
+			p.add(checkOraclePrivilege, "DBMS_CRYPTO", common.PrecheckResultPass, "")
+		}
+	}
+}
+
+// 归档模式以及附加日志，仅 ALL 模式 logminer 增量
+func (p *Precheck) checkOracleLogMode() {
+	logMode, err := p.Oracle.GetOracleDatabaseLogMode()
+	if err != nil {
+		p.add(checkOracleLogMode, "V$DATABASE", common.PrecheckResultFail, err.Error())
+		return
+	}
+	if !strings.EqualFold(logMode["LOG_MODE"], "ARCHIVELOG") {
+		p.add(checkOracleLogMode, "LOG_MODE", common.PrecheckResultFail, fmt.Sprintf("database log mode [%s], logminer requires ARCHIVELOG", logMode["LOG_MODE"]))
+		p.addFix("-- ARCHIVELOG 需重启数据库：SHUTDOWN IMMEDIATE; STARTUP MOUNT; ALTER DATABASE ARCHIVELOG; ALTER DATABASE OPEN;")
+	} else {
+		p.add(checkOracleLogMode, "LOG_MODE", common.PrecheckResultPass, "")
+	}
+	if strings.EqualFold(logMode["SUPPLEMENTAL_LOG_DATA_MIN"], "NO") {
+		p.add(checkSupplementalLog, "DATABASE MINIMAL", common.PrecheckResultFail, "database minimal supplemental logging isn't enabled")
+		p.addFix("ALTER DATABASE ADD SUPPLEMENTAL LOG DATA;")
+	} else {
+		p.add(checkSupplementalLog, "DATABASE MINIMAL", common.PrecheckResultPass, "")
+	}
+
+	// 库级别 (ALL) COLUMNS 附加日志开启则无需表级别
+	if strings.EqualFold(logMode["SUPPLEMENTAL_LOG_DATA_ALL"], "YES") {
+		p.add(checkSupplementalLog, "DATABASE ALL COLUMNS", common.PrecheckResultPass, "")
+		return
+	}
+	exporters, err := p.filterTables()
+	if err != nil {
+		p.add(checkSupplementalLog, p.Cfg.SchemaConfig.SourceSchema, common.PrecheckResultFail, err.Error())
+		return
+	}
+	logTables, err := p.Oracle.GetOracleSchemaAllColumnLogGroupTable(p.Cfg.SchemaConfig.SourceSchema)
+	if err != nil {
+		p.add(checkSupplementalLog, p.Cfg.SchemaConfig.SourceSchema, common.PrecheckResultFail, err.Error())
+		return
+	}
+	var missing int
+	for _, t := range exporters {
+		if common.IsContainString(logTables, t) {
+			continue
+		}
+		missing++
+		p.add(checkSupplementalLog, common.StringsBuilder(p.Cfg.SchemaConfig.SourceSchema, ".", t), common.PrecheckResultFail, "table (ALL) COLUMNS supplemental logging isn't enabled")
+		p.addFix(fmt.Sprintf("ALTER TABLE %s.%s ADD SUPPLEMENTAL LOG DATA (ALL) COLUMNS;", p.Cfg.SchemaConfig.SourceSchema, t))
+	}
+	if missing == 0 {
+		p.add(checkSupplementalLog, common.StringsBuilder(p.Cfg.SchemaConfig.SourceSchema, " TABLES"), common.PrecheckResultPass, "")
+	}
+}
+
+// exclude-table 过滤结果基于集合无固定顺序，排序保证报告检查项顺序稳定
+func (p *Precheck) filterTables() ([]string, error) {
+	exporters, err := public.FilterCFGTable(p.Cfg, p.Oracle)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(exporters)
+	return exporters, nil
+}
+
+func (p *Precheck) checkCharset() {
+	lang, err := p.Oracle.GetOracleDBCharacterSet()
+	if err != nil {
+		p.add(checkCharset, "ORACLE", common.PrecheckResultFail, err.Error())
+		return
+	}
+	dbCharset := common.StringUPPER(lang[strings.LastIndex(lang, ".")+1:])
+	_, supported := common.MigrateOracleCharsetStringConvertMapping[dbCharset]
+	switch {
+	case !supported && strings.EqualFold(p.Cfg.OracleConfig.ActualCharset, p.Cfg.OracleConfig.Charset):
+		p.add(checkCharset, "ORACLE", common.PrecheckResultFail, fmt.Sprintf("oracle database charset [%s] isn't support", dbCharset))
+	case !strings.EqualFold(dbCharset, p.Cfg.OracleConfig.Charset):
+		p.add(checkCharset, "ORACLE", common.PrecheckResultWarn, fmt.Sprintf("oracle database charset [%s] isn't equal to config [oracle] charset [%s]", dbCharset, p.Cfg.OracleConfig.Charset))
+	case !strings.EqualFold(p.Cfg.OracleConfig.ActualCharset, p.Cfg.OracleConfig.Charset):
+		p.add(checkCharset, "ORACLE", common.PrecheckResultWarn, fmt.Sprintf("oracle database charset [%s] decode by actual-charset [%s]", dbCharset, p.Cfg.OracleConfig.ActualCharset))
+	default:
+		p.add(checkCharset, "ORACLE", common.PrecheckResultPass, dbCharset)
+	}
+
+	object, targetCharset := "TARGET", p.Cfg.MySQLConfig.Charset
+	if strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeCSV) {
+		object, targetCharset = "CSV", p.Cfg.CSVConfig.Charset
+	}
+	if !common.IsContainString(common.MigrateDataSupportCharset, common.StringUPPER(targetCharset)) {
+		p.add(checkCharset, object, common.PrecheckResultFail, fmt.Sprintf("charset [%s] isn't support, support charset [%v]", targetCharset, common.MigrateDataSupportCharset))
+		return
+	}
+	p.add(checkCharset, object, common.PrecheckResultPass, common.StringUPPER(targetCharset))
+}
+
+// 数据校验要求主键/唯一键，其他模式无主键/唯一键表断点续传以及增量同步可能产生重复数据
+func (p *Precheck) checkTableKey() {
+	exporters, err := p.filterTables()
+	if err != nil {
+		p.add(checkTableKey, p.Cfg.SchemaConfig.SourceSchema, common.PrecheckResultFail, err.Error())
+		return
+	}
+	keylessTables, err := p.Oracle.GetOracleSchemaTableWithoutPUKey(p.Cfg.SchemaConfig.SourceSchema)
+	if err != nil {
+		p.add(checkTableKey, p.Cfg.SchemaConfig.SourceSchema, common.PrecheckResultFail, err.Error())
+		return
+	}
+	result := common.PrecheckResultWarn
+	if strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeCompare) {
+		result = common.PrecheckResultFail
+	}
+	var keyless int
+	for _, t := range exporters {
+		if !common.IsContainString(keylessTables, t) {
+			continue
+		}
+		keyless++
+		p.add(checkTableKey, common.StringsBuilder(p.Cfg.SchemaConfig.SourceSchema, ".", t), result, "table hasn't primary key, unique key or unique index")
+	}
+	if keyless == 0 {
+		p.add(checkTableKey, common.StringsBuilder(p.Cfg.SchemaConfig.SourceSchema, " TABLES"), common.PrecheckResultPass, fmt.Sprintf("tables [%d]", len(exporters)))
+	}
+}
+
+func (p *Precheck) checkTargetPrivilege() {
+	var required []string
+	switch common.StringUPPER(p.Cfg.PrecheckConfig.TargetMode) {
+	case common.TaskModeReverse:
+		required = []string{"SELECT", "CREATE", "ALTER", "INDEX", "DROP", "REFERENCES"}
+	case common.TaskModeCompare:
+		required = []string{"SELECT"}
+	default:
+		required = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "ALTER", "INDEX", "DROP"}
+	}
+
+	user, err := p.Mysql.GetMySQLCurrentUser()
+	if err != nil {
+		p.add(checkTargetPrivilege, "CURRENT_USER", common.PrecheckResultFail, err.Error())
+		return
+	}
+	grants, err := p.Mysql.GetMySQLCurrentUserGrants()
+	if err != nil {
+		p.add(checkTargetPrivilege, user, common.PrecheckResultFail, err.Error())
+		return
+	}
+	missing := MissingMySQLPrivileges(ParseMySQLGrantPrivileges(grants, p.Cfg.SchemaConfig.TargetSchema), required)
+	if len(missing) > 0 {
+		p.add(checkTargetPrivilege, user, common.PrecheckResultFail, fmt.Sprintf("schema [%s] missing privileges [%s]", p.Cfg.SchemaConfig.TargetSchema, strings.Join(missing, ",")))
+		p.addFix(fmt.Sprintf("GRANT %s ON `%s`.* TO %s;", strings.Join(missing, ", "), p.Cfg.SchemaConfig.TargetSchema, GenMySQLUserIdentity(user)))
+		return
+	}
+	p.add(checkTargetPrivilege, user, common.PrecheckResultPass, strings.Join(required, ","))
+}
+
+// sql_mode 以及 lower_case_table_names
+func (p *Precheck) checkTargetVariable() {
+	sqlMode, lowerCaseTableNames, err := p.Mysql.GetMySQLSessionSQLModeAndLowerCaseTableNames()
+	if err != nil {
+		p.add(checkTargetVariable, "sql_mode", common.PrecheckResultFail, err.Error())
+		return
+	}
+	modes := strings.Split(common.StringUPPER(sqlMode), ",")
+	var fixModes []string
+	for _, m := range modes {
+		if m != "" && m != "NO_BACKSLASH_ESCAPES" {
+			fixModes = append(fixModes, m)
+		}
+	}
+	switch {
+	case common.IsContainString(modes, "NO_BACKSLASH_ESCAPES"):
+		// 增量以及修复 SQL 字符串以反斜杠转义
+		result := common.PrecheckResultWarn
+		if strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeAll) || strings.EqualFold(p.Cfg.PrecheckConfig.TargetMode, common.TaskModeCompare) {
+			result = common.PrecheckResultFail
+		}
+		p.add(checkTargetVariable, "sql_mode", result, fmt.Sprintf("sql_mode [%s] contains NO_BACKSLASH_ESCAPES, backslash escaped string literal will be written incorrectly", sqlMode))
+		if !common.IsContainString(fixModes, "STRICT_TRANS_TABLES") {
+			fixModes = append(fixModes, "STRICT_TRANS_TABLES")
+		}
+		p.addFix(fmt.Sprintf("SET GLOBAL sql_mode = '%s';", strings.Join(fixModes, ",")))
+	case !common.IsContainString(modes, "STRICT_TRANS_TABLES") && !common.IsContainString(modes, "STRICT_ALL_TABLES"):
+		p.add(checkTargetVariable, "sql_mode", common.PrecheckResultWarn, fmt.Sprintf("sql_mode [%s] isn't strict mode, data truncation or invalid value will be written with warning only", sqlMode))
+		p.addFix(fmt.Sprintf("SET GLOBAL sql_mode = '%s';", strings.Join(append(fixModes, "STRICT_TRANS_TABLES"), ",")))
+	default:
+		p.add(checkTargetVariable, "sql_mode", common.PrecheckResultPass, sqlMode)
+	}
+
+	// TiDB 只支持 lower_case_table_names = 2，忽略检查
+	if strings.EqualFold(p.Cfg.DBTypeT, common.DatabaseTypeTiDB) {
+		return
+	}
+	if lowerCaseTableNames != "0" {
+		p.add(checkTargetVariable, "lower_case_table_names", common.PrecheckResultWarn, fmt.Sprintf("lower_case_table_names [%s], oracle upper case table name will be stored lower case, check/compare table name match may be affected", lowerCaseTableNames))
+		p.addFix("-- lower_case_table_names 只能初始化时设置，需重建实例后配置 lower_case_table_names = 0")
+		return
+	}
+	p.add(checkTargetVariable, "lower_case_table_names", common.PrecheckResultPass, lowerCaseTableNames)
+}
+
+func (p *Precheck) report(startTime time.Time) error {
+	var pass, warn, fail int
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleLight)
+	tw.AppendHeader(table.Row{"#", "CHECK", "OBJECT", "RESULT", "DETAIL"})
+	for i, item := range p.Items {
+		switch item.Result {
+		case common.PrecheckResultPass:
+			pass++
+		case common.PrecheckResultWarn:
+			warn++
+		case common.PrecheckResultFail:
+			fail++
+		}
+		tw.AppendRow(table.Row{i + 1, item.Check, item.Object, item.Result, item.Detail})
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("transferdb precheck report\ntarget mode: %s\ntask flow: %s -> %s\nschema: %s\ncheck time: %s\n\n",
+		p.Cfg.PrecheckConfig.TargetMode, p.Cfg.DBTypeS, p.Cfg.DBTypeT, p.Cfg.SchemaConfig.SourceSchema, startTime.Format("2006-01-02 15:04:05")))
+	sb.WriteString(tw.Render())
+	sb.WriteString(fmt.Sprintf("\n\nsummary: pass [%d] warn [%d] fail [%d]\n", pass, warn, fail))
+	if err := os.WriteFile(p.Cfg.PrecheckConfig.ReportFile, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("write precheck report file [%s] failed: %v", p.Cfg.PrecheckConfig.ReportFile, err)
+	}
+
+	// 修复 SQL 需人工审核后执行
+	if p.Cfg.PrecheckConfig.FixSQLFile != "" && len(p.FixSQL) > 0 {
+		fixSQL := common.StringsBuilder("-- transferdb precheck fix sql, review before execute\n", strings.Join(p.FixSQL, "\n"), "\n")
+		if err := os.WriteFile(p.Cfg.PrecheckConfig.FixSQLFile, []byte(fixSQL), 0644); err != nil {
+			return fmt.Errorf("write precheck fix sql file [%s] failed: %v", p.Cfg.PrecheckConfig.FixSQLFile, err)
+		}
+	}
+
+	zap.L().Info("precheck finished",
+		zap.String("target mode", p.Cfg.PrecheckConfig.TargetMode),
+		zap.Int("pass", pass),
+		zap.Int("warn", warn),
+		zap.Int("fail", fail),
+		zap.String("report file", p.Cfg.PrecheckConfig.ReportFile),
+		zap.String("fix sql file", p.Cfg.PrecheckConfig.FixSQLFile),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+
+	if fail > 0 {
+		return fmt.Errorf("precheck target mode [%s] failed, pass [%d] warn [%d] fail [%d], detail see report file [%s]",
+			p.Cfg.PrecheckConfig.TargetMode, pass, warn, fail, p.Cfg.PrecheckConfig.ReportFile)
+	}
+	return nil
+}
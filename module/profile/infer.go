@@ -0,0 +1,169 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package profile
+
+import (
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"strconv"
+	"strings"
+)
+
+// MySQL/TiDB DECIMAL 最大精度以及最大 scale，TEXT/MEDIUMTEXT 最大字节数（按 4 字节/字符估算）
+const (
+	decimalMaxPrecision = 65
+	decimalMaxScale     = 30
+	textMaxBytes        = 65535
+	mediumTextMaxBytes  = 16777215
+	charMaxBytes        = 4
+)
+
+// 单表一次扫描，字段统计别名按字段顺序编号
+// NUMBER：非空行数、整数部分最大位数、小数部分最大位数、科学计数法行数
+// VARCHAR2/CLOB：非空行数、最大字符长度
+func GenProfileSQL(schemaName, tableName string, columns []Column, samplePercent float64) string {
+	var stats []string
+	stats = append(stats, `COUNT(1) AS "ROWS_S"`)
+	for i, c := range columns {
+		col := fmt.Sprintf(`"%s"`, c.ColumnName)
+		stats = append(stats, fmt.Sprintf(`COUNT(%s) AS "CNT_%d"`, col, i))
+		switch c.DataType {
+		case common.BuildInOracleDatatypeNumber:
+			stats = append(stats,
+				fmt.Sprintf(`MAX(LENGTH(TO_CHAR(TRUNC(ABS(%s))))) AS "INT_%d"`, col, i),
+				fmt.Sprintf(`MAX(CASE WHEN %[1]s <> TRUNC(%[1]s) THEN LENGTH(TO_CHAR(ABS(%[1]s) - TRUNC(ABS(%[1]s)))) - 1 ELSE 0 END) AS "SCALE_%[2]d"`, col, i),
+				fmt.Sprintf(`SUM(CASE WHEN INSTR(TO_CHAR(%s), 'E') > 0 THEN 1 ELSE 0 END) AS "SCI_%d"`, col, i))
+		case common.BuildInOracleDatatypeClob, common.BuildInOracleDatatypeNclob:
+			stats = append(stats, fmt.Sprintf(`MAX(DBMS_LOB.GETLENGTH(%s)) AS "LEN_%d"`, col, i))
+		default:
+			stats = append(stats, fmt.Sprintf(`MAX(LENGTH(%s)) AS "LEN_%d"`, col, i))
+		}
+	}
+	var sample string
+	if samplePercent > 0 && samplePercent < 100 {
+		sample = fmt.Sprintf(` SAMPLE (%v)`, samplePercent)
+	}
+	return fmt.Sprintf(`SELECT %s FROM "%s"."%s"%s`, strings.Join(stats, ",\n"), schemaName, tableName, sample)
+}
+
+// 规则源端类型与 reverse 字段原始类型匹配格式保持一致
+// NUMBER -> NUMBER(38,127)、NUMBER(*,x) -> NUMBER(38,x)、VARCHAR2(n)、CLOB
+func GenColumnTypeS(c Column) string {
+	switch c.DataType {
+	case common.BuildInOracleDatatypeNumber:
+		if c.DataScale < 0 {
+			return common.BuildInOracleDatatypeNumber
+		}
+		return fmt.Sprintf("%s(*,%d)", common.BuildInOracleDatatypeNumber, c.DataScale)
+	case common.BuildInOracleDatatypeClob, common.BuildInOracleDatatypeNclob:
+		return c.DataType
+	default:
+		return fmt.Sprintf("%s(%d)", c.DataType, c.DeclaredLength)
+	}
+}
+
+// 按剖析统计推断目标类型，无数据、无法推断或者无需收紧返回空
+func InferColumnType(c Column, stats map[string]string, idx int, cfg config.ProfileConfig) (string, string) {
+	notNulls, err := strconv.Atoi(stats[fmt.Sprintf("CNT_%d", idx)])
+	if err != nil || notNulls == 0 {
+		return "", ""
+	}
+	switch c.DataType {
+	case common.BuildInOracleDatatypeNumber:
+		if stats[fmt.Sprintf("SCI_%d", idx)] != "0" {
+			return "", ""
+		}
+		intDigits, err := strconv.Atoi(stats[fmt.Sprintf("INT_%d", idx)])
+		if err != nil {
+			return "", ""
+		}
+		scale, err := strconv.Atoi(stats[fmt.Sprintf("SCALE_%d", idx)])
+		if err != nil {
+			return "", ""
+		}
+		// 声明 scale 的字段以声明 scale 为准
+		if c.DataScale >= 0 {
+			scale = c.DataScale
+		}
+		return InferNumberType(intDigits, scale, cfg.Headroom),
+			fmt.Sprintf("profile rows [%s] not null [%d] max integer digits [%d] max scale [%d] sample percent [%v]",
+				stats["ROWS_S"], notNulls, intDigits, scale, cfg.SamplePercent)
+	default:
+		maxLength, err := strconv.Atoi(stats[fmt.Sprintf("LEN_%d", idx)])
+		if err != nil {
+			return "", ""
+		}
+		isLob := c.DataType == common.BuildInOracleDatatypeClob || c.DataType == common.BuildInOracleDatatypeNclob
+		return InferCharType(maxLength, c.DeclaredLength, cfg.VarcharMaxLength, cfg.Headroom, isLob),
+			fmt.Sprintf("profile rows [%s] not null [%d] max char length [%d] sample percent [%v]",
+				stats["ROWS_S"], notNulls, maxLength, cfg.SamplePercent)
+	}
+}
+
+// 整数按位数（含余量）对应 TINYINT/SMALLINT/INT/BIGINT/DECIMAL(p)，与内置 NUMBER(p,0) 转换规则一致；小数 DECIMAL(p,s)
+func InferNumberType(intDigits, scale, headroom int) string {
+	precision := withHeadroom(intDigits, headroom)
+	if scale == 0 {
+		switch {
+		case precision < 3:
+			return "TINYINT"
+		case precision < 5:
+			return "SMALLINT"
+		case precision < 9:
+			return "INT"
+		case precision < 19:
+			return "BIGINT"
+		case precision <= decimalMaxPrecision:
+			return fmt.Sprintf("DECIMAL(%d)", precision)
+		default:
+			return ""
+		}
+	}
+	if scale > decimalMaxScale || precision+scale > decimalMaxPrecision {
+		return ""
+	}
+	return fmt.Sprintf("DECIMAL(%d,%d)", precision+scale, scale)
+}
+
+// 字符长度（含余量）不超过 varcharMaxLength 对应 VARCHAR(n)，VARCHAR2 长度未收紧返回空
+// CLOB 超过 varcharMaxLength 按最大字节数对应 TEXT/MEDIUMTEXT
+func InferCharType(maxLength, declaredLength, varcharMaxLength, headroom int, isLob bool) string {
+	length := withHeadroom(maxLength, headroom)
+	if length < 1 {
+		length = 1
+	}
+	if !isLob {
+		if length >= declaredLength || length > varcharMaxLength {
+			return ""
+		}
+		return fmt.Sprintf("VARCHAR(%d)", length)
+	}
+	switch {
+	case length <= varcharMaxLength:
+		return fmt.Sprintf("VARCHAR(%d)", length)
+	case length*charMaxBytes <= textMaxBytes:
+		return "TEXT"
+	case length*charMaxBytes <= mediumTextMaxBytes:
+		return "MEDIUMTEXT"
+	default:
+		return ""
+	}
+}
+
+func withHeadroom(n, headroom int) int {
+	return n + (n*headroom+99)/100
+}
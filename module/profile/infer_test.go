@@ -0,0 +1,33 @@
+package profile
+
+import "testing"
+
+func TestInferColumnDatatype(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "tinyint", got: InferNumberType(1, 0, 20), want: "TINYINT"},
+		{name: "int", got: InferNumberType(6, 0, 20), want: "INT"},
+		{name: "bigint headroom", got: InferNumberType(8, 0, 20), want: "BIGINT"},
+		{name: "decimal integer", got: InferNumberType(20, 0, 20), want: "DECIMAL(24)"},
+		{name: "decimal", got: InferNumberType(5, 2, 20), want: "DECIMAL(8,2)"},
+		{name: "decimal scale overflow", got: InferNumberType(1, 38, 20), want: ""},
+		{name: "varchar", got: InferCharType(100, 4000, 4000, 20, false), want: "VARCHAR(120)"},
+		{name: "varchar not tighter", got: InferCharType(300, 300, 4000, 20, false), want: ""},
+		{name: "clob varchar", got: InferCharType(1000, 0, 4000, 20, true), want: "VARCHAR(1200)"},
+		{name: "clob text", got: InferCharType(10000, 0, 4000, 20, true), want: "TEXT"},
+		{name: "clob mediumtext", got: InferCharType(100000, 0, 4000, 20, true), want: "MEDIUMTEXT"},
+		{name: "column type number", got: GenColumnTypeS(Column{DataType: "NUMBER", DataScale: -1}), want: "NUMBER"},
+		{name: "column type number scale", got: GenColumnTypeS(Column{DataType: "NUMBER", DataScale: 0}), want: "NUMBER(*,0)"},
+		{name: "column type varchar2", got: GenColumnTypeS(Column{DataType: "VARCHAR2", DeclaredLength: 4000}), want: "VARCHAR2(4000)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("infer column datatype = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
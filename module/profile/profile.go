@@ -0,0 +1,194 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package profile
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/oracle"
+	"github.com/wentaojin/transferdb/module/reverse/oracle/public"
+	"github.com/wentaojin/transferdb/module/rule"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Profile struct {
+	Ctx    context.Context
+	Cfg    *config.Config
+	Oracle *oracle.Oracle
+}
+
+// 剖析字段
+type Column struct {
+	TableName      string
+	ColumnName     string
+	DataType       string
+	DataScale      int // -1 代表未指定 scale
+	DeclaredLength int
+}
+
+func IProfile(ctx context.Context, cfg *config.Config) error {
+	oracleDB, err := oracle.NewOracleDBEngine(ctx, cfg.OracleConfig, cfg.SchemaConfig.SourceSchema)
+	if err != nil {
+		return err
+	}
+	p := &Profile{
+		Ctx:    ctx,
+		Cfg:    cfg,
+		Oracle: oracleDB,
+	}
+	return p.Profile()
+}
+
+// 剖析源端实际数据，推断更紧凑的目标字段类型，输出字段级数据类型规则文件
+// 规则文件人工审核后通过 -mode import 导入元数据库 [column_datatype_rule]，reverse 阶段生效
+func (p *Profile) Profile() error {
+	startTime := time.Now()
+	schema := common.StringUPPER(p.Cfg.SchemaConfig.SourceSchema)
+	zap.L().Info("profile table column datatype start",
+		zap.String("schema", schema),
+		zap.Float64("sample percent", p.Cfg.ProfileConfig.SamplePercent))
+
+	exporters, err := public.FilterCFGTable(p.Cfg, p.Oracle)
+	if err != nil {
+		return err
+	}
+	columnRes, err := p.Oracle.GetOracleSchemaProfileColumn(schema, p.Cfg.ProfileConfig.VarcharMinLength)
+	if err != nil {
+		return err
+	}
+
+	tableColumns := make(map[string][]Column)
+	for _, c := range columnRes {
+		if !common.IsContainString(exporters, c["TABLE_NAME"]) {
+			continue
+		}
+		dataScale, err := strconv.Atoi(c["DATA_SCALE"])
+		if err != nil {
+			return fmt.Errorf("oracle schema [%s] table [%s] column [%s] data_scale string to int failed: %v", schema, c["TABLE_NAME"], c["COLUMN_NAME"], err)
+		}
+		declaredLength := c["DATA_LENGTH"]
+		if strings.EqualFold(c["CHAR_USED"], "C") {
+			declaredLength = c["CHAR_LENGTH"]
+		}
+		length, err := strconv.Atoi(declaredLength)
+		if err != nil {
+			return fmt.Errorf("oracle schema [%s] table [%s] column [%s] data_length string to int failed: %v", schema, c["TABLE_NAME"], c["COLUMN_NAME"], err)
+		}
+		tableColumns[c["TABLE_NAME"]] = append(tableColumns[c["TABLE_NAME"]], Column{
+			TableName:      c["TABLE_NAME"],
+			ColumnName:     c["COLUMN_NAME"],
+			DataType:       common.StringUPPER(c["DATA_TYPE"]),
+			DataScale:      dataScale,
+			DeclaredLength: length,
+		})
+	}
+
+	var (
+		mu    sync.Mutex
+		rules []rule.ColumnDatatypeRule
+	)
+	g := &errgroup.Group{}
+	g.SetLimit(p.Cfg.ProfileConfig.ProfileThreads)
+
+	for table, columns := range tableColumns {
+		t := table
+		cols := columns
+		g.Go(func() error {
+			tableStartTime := time.Now()
+			stats, err := p.Oracle.GetOracleTableProfileStats(GenProfileSQL(schema, t, cols, p.Cfg.ProfileConfig.SamplePercent))
+			if err != nil {
+				return err
+			}
+			var tableRules []rule.ColumnDatatypeRule
+			for i, c := range cols {
+				columnTypeT, comment := InferColumnType(c, stats, i, p.Cfg.ProfileConfig)
+				if columnTypeT == "" {
+					continue
+				}
+				tableRules = append(tableRules, rule.ColumnDatatypeRule{
+					SchemaNameS: schema,
+					TableNameS:  t,
+					ColumnNameS: c.ColumnName,
+					ColumnTypeS: GenColumnTypeS(c),
+					ColumnTypeT: columnTypeT,
+					Comment:     comment,
+				})
+			}
+			mu.Lock()
+			rules = append(rules, tableRules...)
+			mu.Unlock()
+
+			zap.L().Info("profile single table column datatype finished",
+				zap.String("schema", schema),
+				zap.String("table", t),
+				zap.String("rows", stats["ROWS_S"]),
+				zap.Int("profile columns", len(cols)),
+				zap.Int("proposal columns", len(tableRules)),
+				zap.String("cost", time.Now().Sub(tableStartTime).String()))
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return err
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].TableNameS != rules[j].TableNameS {
+			return rules[i].TableNameS < rules[j].TableNameS
+		}
+		return rules[i].ColumnNameS < rules[j].ColumnNameS
+	})
+
+	f := &rule.File{
+		Version:             common.RuleFileVersion,
+		DBTypeS:             common.StringUPPER(p.Cfg.DBTypeS),
+		DBTypeT:             common.StringUPPER(p.Cfg.DBTypeT),
+		ExportTime:          startTime.Format("2006-01-02 15:04:05"),
+		ColumnDatatypeRules: rules,
+	}
+	// 规则文件格式以文件后缀为准
+	format := common.RuleFormatTOML
+	switch strings.ToLower(filepath.Ext(p.Cfg.ProfileConfig.ProfileFile)) {
+	case ".yaml", ".yml":
+		format = common.RuleFormatYAML
+	}
+	data, err := f.Encode(format)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(p.Cfg.ProfileConfig.ProfileFile, data, 0644); err != nil {
+		return fmt.Errorf("write profile rule file [%s] failed: %v", p.Cfg.ProfileConfig.ProfileFile, err)
+	}
+
+	zap.L().Info("profile table column datatype finished",
+		zap.String("schema", schema),
+		zap.Int("profile tables", len(tableColumns)),
+		zap.Int("proposal column datatype rules", len(rules)),
+		zap.String("profile file", p.Cfg.ProfileConfig.ProfileFile),
+		zap.String("tips", "review profile file, then set [rule] rule-file and run -mode import (dry-run first)"),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return nil
+}
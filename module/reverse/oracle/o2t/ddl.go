@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/database/meta"
 	"github.com/wentaojin/transferdb/module/reverse"
 	"go.uber.org/zap"
 	"strings"
@@ -235,6 +237,56 @@ func (d *DDL) GenDDLStructure() ([]string, []string) {
 	return reverseDDLS, compDDLS
 }
 
+// 延迟创建索引，建表只保留主键（无主键保留唯一键，保证断点续传 REPLACE 幂等）
+// 其余索引以及外键拆分记录元数据表 [index_sync_meta]，全量数据导入完成后创建
+func (d *DDL) GenDeferIndex(dbTypeS, dbTypeT string) []meta.IndexSyncMeta {
+	var (
+		primaryKeys, uniqueKeys, deferKeys []string
+		indexMetas                         []meta.IndexSyncMeta
+	)
+	for _, k := range d.TableKeys {
+		switch {
+		case strings.HasPrefix(k, "PRIMARY KEY"):
+			primaryKeys = append(primaryKeys, k)
+		case strings.HasPrefix(k, "UNIQUE"):
+			uniqueKeys = append(uniqueKeys, k)
+		default:
+			deferKeys = append(deferKeys, k)
+		}
+	}
+	if len(primaryKeys) > 0 {
+		d.TableKeys = primaryKeys
+		deferKeys = append(uniqueKeys, deferKeys...)
+	} else {
+		d.TableKeys = uniqueKeys
+	}
+
+	for _, k := range deferKeys {
+		indexMetas = append(indexMetas, d.genIndexSyncMeta(dbTypeS, dbTypeT, common.DeferIndexTypeKey, k))
+	}
+	for _, fk := range d.TableForeignKeys {
+		indexMetas = append(indexMetas, d.genIndexSyncMeta(dbTypeS, dbTypeT, common.DeferIndexTypeConstraint, fk))
+	}
+	d.TableForeignKeys = nil
+	return indexMetas
+}
+
+// 索引/约束定义格式 KEY `name` (...) 或者 CONSTRAINT `name` ...
+func (d *DDL) genIndexSyncMeta(dbTypeS, dbTypeT, indexType, key string) meta.IndexSyncMeta {
+	return meta.IndexSyncMeta{
+		DBTypeS:     dbTypeS,
+		DBTypeT:     dbTypeT,
+		SchemaNameS: common.StringUPPER(d.SourceSchemaName),
+		TableNameS:  common.StringUPPER(d.SourceTableName),
+		IndexType:   indexType,
+		IndexNameS:  strings.Split(key, "`")[1],
+		SchemaNameT: d.TargetSchemaName,
+		TableNameT:  d.TargetTableName,
+		IndexDDL:    fmt.Sprintf("ALTER TABLE `%s`.`%s` ADD %s", d.TargetSchemaName, d.TargetTableName, key),
+		TaskStatus:  common.TaskStatusWaiting,
+	}
+}
+
 func (d *DDL) String() string {
 	jsonBytes, _ := json.Marshal(d)
 	return string(jsonBytes)
@@ -104,6 +104,13 @@ func (r *Reverse) Reverse() error {
 	}
 
 	oracleDBCharset := strings.Split(charset, ".")[1]
+	// 数据库声明字符集与实际存储编码不一致，以 actual-charset 转换表结构字符集、默认值以及注释
+	if !strings.EqualFold(r.Cfg.OracleConfig.ActualCharset, r.Cfg.OracleConfig.Charset) {
+		zap.L().Warn("oracle db charset declared and actual aren't equal, reverse using actual charset",
+			zap.String("declared charset", oracleDBCharset),
+			zap.String("actual charset", r.Cfg.OracleConfig.ActualCharset))
+		oracleDBCharset = r.Cfg.OracleConfig.ActualCharset
+	}
 	nlsComp, err := r.Oracle.GetOracleDBCharacterNLSCompCollation()
 	if err != nil {
 		return err
@@ -171,7 +178,8 @@ func (r *Reverse) Reverse() error {
 		TargetSchemaName: common.StringUPPER(r.Cfg.SchemaConfig.TargetSchema),
 		SourceTables:     exporterTables,
 		OracleCollation:  oracleCollation,
-		SourceDBCharset:  common.StringUPPER(r.Cfg.OracleConfig.Charset),
+		TimeZone:         r.Cfg.AppConfig.TimeZone,
+		SourceDBCharset:  r.Cfg.OracleConfig.ActualCharset,
 		TargetDBCharset:  common.StringUPPER(r.Cfg.MySQLConfig.Charset),
 		Threads:          r.Cfg.ReverseConfig.ReverseThreads,
 		Oracle:           r.Oracle,
@@ -276,6 +284,12 @@ func (r *Reverse) Reverse() error {
 				return nil
 			}
 
+			// 延迟创建索引/约束，建表语句只保留主键（无主键保留唯一键）
+			var deferIndexes []meta.IndexSyncMeta
+			if r.Cfg.ReverseConfig.DeferIndex {
+				deferIndexes = ddl.GenDeferIndex(r.Cfg.DBTypeS, r.Cfg.DBTypeT)
+			}
+
 			errSql, errw := IWriter(f, ddl)
 			if errw != nil {
 				if errm := meta.NewErrorLogDetailModel(r.MetaDB).CreateErrorLog(r.Ctx, &meta.ErrorLogDetail{
@@ -303,6 +317,16 @@ func (r *Reverse) Reverse() error {
 				return nil
 			}
 
+			if r.Cfg.ReverseConfig.DeferIndex {
+				if err = meta.NewIndexSyncMetaModel(r.MetaDB).ReplaceIndexSyncMetaByTable(r.Ctx, &meta.IndexSyncMeta{
+					DBTypeS:     r.Cfg.DBTypeS,
+					DBTypeT:     r.Cfg.DBTypeT,
+					SchemaNameS: t.SourceSchemaName,
+					TableNameS:  t.SourceTableName,
+				}, deferIndexes); err != nil {
+					return err
+				}
+			}
 			return nil
 		})
 	}
@@ -38,6 +38,7 @@ type Change struct {
 	SourceDBCharset  string          `json:"source_db_charset"`
 	TargetDBCharset  string          `json:"target_db_charset"`
 	OracleCollation  bool            `json:"oracle_collation"`
+	TimeZone         string          `json:"time_zone"`
 	Oracle           *oracle.Oracle  `json:"-"`
 	MetaDB           *meta.Meta      `json:"-"`
 }
@@ -186,7 +187,7 @@ func (r *Change) ChangeTableColumnDatatype() (map[string]map[string]string, erro
 						DataDefault:   rowCol["DATA_DEFAULT"],
 						Comment:       rowCol["COMMENTS"],
 					},
-				}, buildinDatatypeNames)
+				}, buildinDatatypeNames, r.TimeZone)
 				if err != nil {
 					return err
 				}
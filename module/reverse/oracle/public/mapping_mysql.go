@@ -44,7 +44,8 @@ type ColumnInfo struct {
 	Comment           string
 }
 
-func OracleTableColumnMapMySQLRule(sourceSchema, sourceTable string, column Column, buildinDatatypes []meta.BuildinDatatypeRule) (string, string, error) {
+// timeZone 非空时 TIMESTAMP WITH LOCAL TIME ZONE 内置 DATETIME 映射为 TIMESTAMP（会话时区语义一致），TIMESTAMP WITH TIME ZONE 数据规整至 timeZone 写入 DATETIME
+func OracleTableColumnMapMySQLRule(sourceSchema, sourceTable string, column Column, buildinDatatypes []meta.BuildinDatatypeRule, timeZone string) (string, string, error) {
 	var (
 		// oracle 表原始字段类型
 		originColumnType string
@@ -462,6 +463,12 @@ func OracleTableColumnMapMySQLRule(sourceSchema, sourceTable string, column Colu
 			}
 		} else if strings.Contains(column.DataType, "TIMESTAMP") {
 			originColumnType = column.DataType
+			if timeZone != "" && common.IsOracleTimestampWithLocalTimeZone(originColumnType) {
+				// 自定义内置规则保持不变
+				if val, ok := buildinDatatypeMap[common.StringUPPER(originColumnType)]; ok && strings.EqualFold(val, common.BuildInMySQLDatatypeDatetime) {
+					buildinDatatypeMap[common.StringUPPER(originColumnType)] = common.BuildInMySQLDatatypeTimestamp
+				}
+			}
 			if dataScale <= 6 {
 				if val, ok := buildinDatatypeMap[common.StringUPPER(originColumnType)]; ok {
 					buildInColumnType = fmt.Sprintf("%s(%d)", common.StringUPPER(val), dataScale)
@@ -0,0 +1,189 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/meta"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+func IExport(ctx context.Context, cfg *config.Config) error {
+	startTime := time.Now()
+	zap.L().Info("export rule tables start",
+		zap.String("db type s", cfg.DBTypeS),
+		zap.String("db type t", cfg.DBTypeT),
+		zap.String("rule file", cfg.RuleConfig.RuleFile),
+		zap.String("rule format", cfg.RuleConfig.RuleFormat))
+
+	metaDB, err := meta.NewMetaDBEngine(ctx, cfg.MetaConfig, cfg.AppConfig.SlowlogThreshold)
+	if err != nil {
+		return err
+	}
+
+	f, err := LoadMetaRule(ctx, metaDB, cfg.DBTypeS, cfg.DBTypeT)
+	if err != nil {
+		return err
+	}
+	f.Version = common.RuleFileVersion
+	f.ExportTime = startTime.Format("2006-01-02 15:04:05")
+
+	data, err := f.Encode(cfg.RuleConfig.RuleFormat)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(cfg.RuleConfig.RuleFile, data, 0644); err != nil {
+		return fmt.Errorf("write rule file [%s] failed: %v", cfg.RuleConfig.RuleFile, err)
+	}
+
+	zap.L().Info("export rule tables finished",
+		zap.String("rule file", cfg.RuleConfig.RuleFile),
+		zap.Int("schema datatype rules", len(f.SchemaDatatypeRules)),
+		zap.Int("table datatype rules", len(f.TableDatatypeRules)),
+		zap.Int("column datatype rules", len(f.ColumnDatatypeRules)),
+		zap.Int("column defaultval rules", len(f.BuildinColumnDefaultval)),
+		zap.Int("global defaultval rules", len(f.BuildinGlobalDefaultval)),
+		zap.Int("table name rules", len(f.TableNameRules)),
+		zap.Int("column mask rules", len(f.ColumnMaskRules)),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return nil
+}
+
+// 读取元数据库当前规则
+func LoadMetaRule(ctx context.Context, metaDB *meta.Meta, dbTypeS, dbTypeT string) (*File, error) {
+	f := &File{
+		DBTypeS: common.StringUPPER(dbTypeS),
+		DBTypeT: common.StringUPPER(dbTypeT),
+	}
+
+	schemaRules, err := meta.NewSchemaDatatypeRuleModel(metaDB).DetailSchemaRuleByDBType(ctx, &meta.SchemaDatatypeRule{
+		DBTypeS: dbTypeS, DBTypeT: dbTypeT})
+	if err != nil {
+		return f, err
+	}
+	for _, r := range schemaRules {
+		f.SchemaDatatypeRules = append(f.SchemaDatatypeRules, SchemaDatatypeRule{
+			SchemaNameS: r.SchemaNameS,
+			ColumnTypeS: r.ColumnTypeS,
+			ColumnTypeT: r.ColumnTypeT,
+			Comment:     baseComment(r.BaseModel),
+		})
+	}
+
+	tableRules, err := meta.NewTableDatatypeRuleModel(metaDB).DetailTableRuleByDBType(ctx, &meta.TableDatatypeRule{
+		DBTypeS: dbTypeS, DBTypeT: dbTypeT})
+	if err != nil {
+		return f, err
+	}
+	for _, r := range tableRules {
+		f.TableDatatypeRules = append(f.TableDatatypeRules, TableDatatypeRule{
+			SchemaNameS: r.SchemaNameS,
+			TableNameS:  r.TableNameS,
+			ColumnTypeS: r.ColumnTypeS,
+			ColumnTypeT: r.ColumnTypeT,
+			Comment:     baseComment(r.BaseModel),
+		})
+	}
+
+	columnRules, err := meta.NewColumnDatatypeRuleModel(metaDB).DetailColumnRuleByDBType(ctx, &meta.ColumnDatatypeRule{
+		DBTypeS: dbTypeS, DBTypeT: dbTypeT})
+	if err != nil {
+		return f, err
+	}
+	for _, r := range columnRules {
+		f.ColumnDatatypeRules = append(f.ColumnDatatypeRules, ColumnDatatypeRule{
+			SchemaNameS: r.SchemaNameS,
+			TableNameS:  r.TableNameS,
+			ColumnNameS: r.ColumnNameS,
+			ColumnTypeS: r.ColumnTypeS,
+			ColumnTypeT: r.ColumnTypeT,
+			Comment:     baseComment(r.BaseModel),
+		})
+	}
+
+	columnDefaultvals, err := meta.NewBuildinColumnDefaultvalModel(metaDB).DetailColumnDefaultValByDBType(ctx, &meta.BuildinColumnDefaultval{
+		DBTypeS: dbTypeS, DBTypeT: dbTypeT})
+	if err != nil {
+		return f, err
+	}
+	for _, r := range columnDefaultvals {
+		f.BuildinColumnDefaultval = append(f.BuildinColumnDefaultval, BuildinColumnDefaultval{
+			SchemaNameS:   r.SchemaNameS,
+			TableNameS:    r.TableNameS,
+			ColumnNameS:   r.ColumnNameS,
+			DefaultValueS: r.DefaultValueS,
+			DefaultValueT: r.DefaultValueT,
+			Comment:       baseComment(r.BaseModel),
+		})
+	}
+
+	globalDefaultvals, err := meta.NewBuildinGlobalDefaultvalModel(metaDB).DetailGlobalDefaultValByDBType(ctx, &meta.BuildinGlobalDefaultval{
+		DBTypeS: dbTypeS, DBTypeT: dbTypeT})
+	if err != nil {
+		return f, err
+	}
+	for _, r := range globalDefaultvals {
+		f.BuildinGlobalDefaultval = append(f.BuildinGlobalDefaultval, BuildinGlobalDefaultval{
+			DefaultValueS: r.DefaultValueS,
+			DefaultValueT: r.DefaultValueT,
+			Comment:       baseComment(r.BaseModel),
+		})
+	}
+
+	tableNameRules, err := meta.NewTableNameRuleModel(metaDB).DetailTableNameRuleByDBType(ctx, &meta.TableNameRule{
+		DBTypeS: dbTypeS, DBTypeT: dbTypeT})
+	if err != nil {
+		return f, err
+	}
+	for _, r := range tableNameRules {
+		f.TableNameRules = append(f.TableNameRules, TableNameRule{
+			SchemaNameS: r.SchemaNameS,
+			TableNameS:  r.TableNameS,
+			SchemaNameT: r.SchemaNameT,
+			TableNameT:  r.TableNameT,
+			Comment:     baseComment(r.BaseModel),
+		})
+	}
+
+	columnMaskRules, err := meta.NewColumnMaskRuleModel(metaDB).DetailColumnMaskRuleByDBType(ctx, &meta.ColumnMaskRule{
+		DBTypeS: dbTypeS, DBTypeT: dbTypeT})
+	if err != nil {
+		return f, err
+	}
+	for _, r := range columnMaskRules {
+		f.ColumnMaskRules = append(f.ColumnMaskRules, ColumnMaskRule{
+			SchemaNameS: r.SchemaNameS,
+			TableNameS:  r.TableNameS,
+			ColumnNameS: r.ColumnNameS,
+			MaskType:    r.MaskType,
+			MaskValue:   r.MaskValue,
+			Comment:     baseComment(r.BaseModel),
+		})
+	}
+	return f, nil
+}
+
+func baseComment(b *meta.BaseModel) string {
+	if b == nil {
+		return ""
+	}
+	return b.Comment
+}
@@ -0,0 +1,267 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rule
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/wentaojin/transferdb/common"
+	"gopkg.in/yaml.v2"
+	"os"
+	"strings"
+)
+
+// 规则文件，按上下游数据库类型导出
+type File struct {
+	Version                 int                       `toml:"version" yaml:"version"`
+	DBTypeS                 string                    `toml:"db-type-s" yaml:"db-type-s"`
+	DBTypeT                 string                    `toml:"db-type-t" yaml:"db-type-t"`
+	ExportTime              string                    `toml:"export-time" yaml:"export-time"`
+	SchemaDatatypeRules     []SchemaDatatypeRule      `toml:"schema-datatype-rule" yaml:"schema-datatype-rule"`
+	TableDatatypeRules      []TableDatatypeRule       `toml:"table-datatype-rule" yaml:"table-datatype-rule"`
+	ColumnDatatypeRules     []ColumnDatatypeRule      `toml:"column-datatype-rule" yaml:"column-datatype-rule"`
+	BuildinColumnDefaultval []BuildinColumnDefaultval `toml:"buildin-column-defaultval" yaml:"buildin-column-defaultval"`
+	BuildinGlobalDefaultval []BuildinGlobalDefaultval `toml:"buildin-global-defaultval" yaml:"buildin-global-defaultval"`
+	TableNameRules          []TableNameRule           `toml:"table-name-rule" yaml:"table-name-rule"`
+	ColumnMaskRules         []ColumnMaskRule          `toml:"column-mask-rule" yaml:"column-mask-rule"`
+}
+
+type SchemaDatatypeRule struct {
+	SchemaNameS string `toml:"schema-name-s" yaml:"schema-name-s"`
+	ColumnTypeS string `toml:"column-type-s" yaml:"column-type-s"`
+	ColumnTypeT string `toml:"column-type-t" yaml:"column-type-t"`
+	Comment     string `toml:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+type TableDatatypeRule struct {
+	SchemaNameS string `toml:"schema-name-s" yaml:"schema-name-s"`
+	TableNameS  string `toml:"table-name-s" yaml:"table-name-s"`
+	ColumnTypeS string `toml:"column-type-s" yaml:"column-type-s"`
+	ColumnTypeT string `toml:"column-type-t" yaml:"column-type-t"`
+	Comment     string `toml:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+type ColumnDatatypeRule struct {
+	SchemaNameS string `toml:"schema-name-s" yaml:"schema-name-s"`
+	TableNameS  string `toml:"table-name-s" yaml:"table-name-s"`
+	ColumnNameS string `toml:"column-name-s" yaml:"column-name-s"`
+	ColumnTypeS string `toml:"column-type-s" yaml:"column-type-s"`
+	ColumnTypeT string `toml:"column-type-t" yaml:"column-type-t"`
+	Comment     string `toml:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+type BuildinColumnDefaultval struct {
+	SchemaNameS   string `toml:"schema-name-s" yaml:"schema-name-s"`
+	TableNameS    string `toml:"table-name-s" yaml:"table-name-s"`
+	ColumnNameS   string `toml:"column-name-s" yaml:"column-name-s"`
+	DefaultValueS string `toml:"default-value-s" yaml:"default-value-s"`
+	DefaultValueT string `toml:"default-value-t" yaml:"default-value-t"`
+	Comment       string `toml:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+type BuildinGlobalDefaultval struct {
+	DefaultValueS string `toml:"default-value-s" yaml:"default-value-s"`
+	DefaultValueT string `toml:"default-value-t" yaml:"default-value-t"`
+	Comment       string `toml:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+type TableNameRule struct {
+	SchemaNameS string `toml:"schema-name-s" yaml:"schema-name-s"`
+	TableNameS  string `toml:"table-name-s" yaml:"table-name-s"`
+	SchemaNameT string `toml:"schema-name-t" yaml:"schema-name-t"`
+	TableNameT  string `toml:"table-name-t" yaml:"table-name-t"`
+	Comment     string `toml:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+type ColumnMaskRule struct {
+	SchemaNameS string `toml:"schema-name-s" yaml:"schema-name-s"`
+	TableNameS  string `toml:"table-name-s" yaml:"table-name-s"`
+	ColumnNameS string `toml:"column-name-s" yaml:"column-name-s"`
+	MaskType    string `toml:"mask-type" yaml:"mask-type"`
+	MaskValue   string `toml:"mask-value,omitempty" yaml:"mask-value,omitempty"`
+	Comment     string `toml:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+func (f *File) Encode(format string) ([]byte, error) {
+	switch common.StringUPPER(format) {
+	case common.RuleFormatTOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(f); err != nil {
+			return nil, fmt.Errorf("encode rule file by toml failed: %v", err)
+		}
+		return buf.Bytes(), nil
+	case common.RuleFormatYAML:
+		data, err := yaml.Marshal(f)
+		if err != nil {
+			return nil, fmt.Errorf("encode rule file by yaml failed: %v", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("rule file format [%s] isn't support", format)
+	}
+}
+
+func DecodeFile(file, format string) (*File, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read rule file [%s] failed: %v", file, err)
+	}
+	f := &File{}
+	switch common.StringUPPER(format) {
+	case common.RuleFormatTOML:
+		md, err := toml.Decode(string(data), f)
+		if err != nil {
+			return nil, fmt.Errorf("decode rule file [%s] by toml failed: %v", file, err)
+		}
+		// 与 yaml 一致严格解析，存在未知字段报错
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("decode rule file [%s] by toml failed: unknown fields %v", file, undecoded)
+		}
+	case common.RuleFormatYAML:
+		if err = yaml.UnmarshalStrict(data, f); err != nil {
+			return nil, fmt.Errorf("decode rule file [%s] by yaml failed: %v", file, err)
+		}
+	default:
+		return nil, fmt.Errorf("rule file format [%s] isn't support", format)
+	}
+	return f, nil
+}
+
+// 规则文件校验：版本、上下游类型、必填字段以及规则唯一键重复
+func (f *File) Validate(dbTypeS, dbTypeT string) error {
+	if f.Version <= 0 || f.Version > common.RuleFileVersion {
+		return fmt.Errorf("rule file version [%d] isn't support, current support max version [%d]", f.Version, common.RuleFileVersion)
+	}
+	if !strings.EqualFold(f.DBTypeS, dbTypeS) || !strings.EqualFold(f.DBTypeT, dbTypeT) {
+		return fmt.Errorf("rule file db type [%s -> %s] isn't match task db type [%s -> %s]", f.DBTypeS, f.DBTypeT, dbTypeS, dbTypeT)
+	}
+
+	var errs []string
+	keys := make(map[string]struct{})
+	check := func(section string, idx int, key string, fields map[string]string) {
+		for name, val := range fields {
+			if strings.TrimSpace(val) == "" {
+				errs = append(errs, fmt.Sprintf("[%s] item [%d] field [%s] can not be null", section, idx, name))
+			}
+		}
+		k := common.StringsBuilder(section, "/", key)
+		if _, ok := keys[k]; ok {
+			errs = append(errs, fmt.Sprintf("[%s] item [%d] rule [%s] is duplicate", section, idx, key))
+		}
+		keys[k] = struct{}{}
+	}
+
+	for i, r := range f.SchemaDatatypeRules {
+		check("schema-datatype-rule", i, r.Key(), map[string]string{
+			"schema-name-s": r.SchemaNameS, "column-type-s": r.ColumnTypeS, "column-type-t": r.ColumnTypeT})
+	}
+	for i, r := range f.TableDatatypeRules {
+		check("table-datatype-rule", i, r.Key(), map[string]string{
+			"schema-name-s": r.SchemaNameS, "table-name-s": r.TableNameS, "column-type-s": r.ColumnTypeS, "column-type-t": r.ColumnTypeT})
+	}
+	for i, r := range f.ColumnDatatypeRules {
+		check("column-datatype-rule", i, r.Key(), map[string]string{
+			"schema-name-s": r.SchemaNameS, "table-name-s": r.TableNameS, "column-name-s": r.ColumnNameS, "column-type-s": r.ColumnTypeS, "column-type-t": r.ColumnTypeT})
+	}
+	for i, r := range f.BuildinColumnDefaultval {
+		check("buildin-column-defaultval", i, r.Key(), map[string]string{
+			"schema-name-s": r.SchemaNameS, "table-name-s": r.TableNameS, "column-name-s": r.ColumnNameS, "default-value-t": r.DefaultValueT})
+	}
+	for i, r := range f.BuildinGlobalDefaultval {
+		check("buildin-global-defaultval", i, r.Key(), map[string]string{
+			"default-value-s": r.DefaultValueS, "default-value-t": r.DefaultValueT})
+	}
+	for i, r := range f.TableNameRules {
+		check("table-name-rule", i, r.Key(), map[string]string{
+			"schema-name-s": r.SchemaNameS, "table-name-s": r.TableNameS, "schema-name-t": r.SchemaNameT, "table-name-t": r.TableNameT})
+	}
+	for i, r := range f.ColumnMaskRules {
+		check("column-mask-rule", i, r.Key(), map[string]string{
+			"schema-name-s": r.SchemaNameS, "table-name-s": r.TableNameS, "column-name-s": r.ColumnNameS, "mask-type": r.MaskType})
+		// 脱敏参数校验
+		if _, err := common.GenOracleMaskColumnExpr(r.MaskType, r.MaskValue, r.ColumnNameS, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("[column-mask-rule] item [%d] %v", i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("rule file validate failed:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// 源端库表字段名统一大写，与元数据库写入保持一致
+func (f *File) Normalize() {
+	f.DBTypeS = common.StringUPPER(f.DBTypeS)
+	f.DBTypeT = common.StringUPPER(f.DBTypeT)
+	for i := range f.SchemaDatatypeRules {
+		f.SchemaDatatypeRules[i].SchemaNameS = common.StringUPPER(f.SchemaDatatypeRules[i].SchemaNameS)
+	}
+	for i := range f.TableDatatypeRules {
+		f.TableDatatypeRules[i].SchemaNameS = common.StringUPPER(f.TableDatatypeRules[i].SchemaNameS)
+		f.TableDatatypeRules[i].TableNameS = common.StringUPPER(f.TableDatatypeRules[i].TableNameS)
+	}
+	for i := range f.ColumnDatatypeRules {
+		f.ColumnDatatypeRules[i].SchemaNameS = common.StringUPPER(f.ColumnDatatypeRules[i].SchemaNameS)
+		f.ColumnDatatypeRules[i].TableNameS = common.StringUPPER(f.ColumnDatatypeRules[i].TableNameS)
+		f.ColumnDatatypeRules[i].ColumnNameS = common.StringUPPER(f.ColumnDatatypeRules[i].ColumnNameS)
+	}
+	for i := range f.BuildinColumnDefaultval {
+		f.BuildinColumnDefaultval[i].SchemaNameS = common.StringUPPER(f.BuildinColumnDefaultval[i].SchemaNameS)
+		f.BuildinColumnDefaultval[i].TableNameS = common.StringUPPER(f.BuildinColumnDefaultval[i].TableNameS)
+		f.BuildinColumnDefaultval[i].ColumnNameS = common.StringUPPER(f.BuildinColumnDefaultval[i].ColumnNameS)
+	}
+	for i := range f.TableNameRules {
+		f.TableNameRules[i].SchemaNameS = common.StringUPPER(f.TableNameRules[i].SchemaNameS)
+		f.TableNameRules[i].TableNameS = common.StringUPPER(f.TableNameRules[i].TableNameS)
+	}
+	for i := range f.ColumnMaskRules {
+		f.ColumnMaskRules[i].SchemaNameS = common.StringUPPER(f.ColumnMaskRules[i].SchemaNameS)
+		f.ColumnMaskRules[i].TableNameS = common.StringUPPER(f.ColumnMaskRules[i].TableNameS)
+		f.ColumnMaskRules[i].ColumnNameS = common.StringUPPER(f.ColumnMaskRules[i].ColumnNameS)
+		f.ColumnMaskRules[i].MaskType = common.StringUPPER(f.ColumnMaskRules[i].MaskType)
+	}
+}
+
+// 规则唯一键与元数据表唯一索引保持一致
+func (r SchemaDatatypeRule) Key() string {
+	return common.StringsBuilder(common.StringUPPER(r.SchemaNameS), ".", common.StringUPPER(r.ColumnTypeS))
+}
+
+func (r TableDatatypeRule) Key() string {
+	return common.StringsBuilder(common.StringUPPER(r.SchemaNameS), ".", common.StringUPPER(r.TableNameS), ".", common.StringUPPER(r.ColumnTypeS))
+}
+
+func (r ColumnDatatypeRule) Key() string {
+	return common.StringsBuilder(common.StringUPPER(r.SchemaNameS), ".", common.StringUPPER(r.TableNameS), ".", common.StringUPPER(r.ColumnNameS))
+}
+
+func (r BuildinColumnDefaultval) Key() string {
+	return common.StringsBuilder(common.StringUPPER(r.SchemaNameS), ".", common.StringUPPER(r.TableNameS), ".", common.StringUPPER(r.ColumnNameS))
+}
+
+func (r BuildinGlobalDefaultval) Key() string {
+	return common.StringUPPER(r.DefaultValueS)
+}
+
+func (r TableNameRule) Key() string {
+	return common.StringsBuilder(common.StringUPPER(r.SchemaNameS), ".", common.StringUPPER(r.TableNameS))
+}
+
+func (r ColumnMaskRule) Key() string {
+	return common.StringsBuilder(common.StringUPPER(r.SchemaNameS), ".", common.StringUPPER(r.TableNameS), ".", common.StringUPPER(r.ColumnNameS))
+}
@@ -0,0 +1,96 @@
+package rule
+
+import (
+	"github.com/wentaojin/transferdb/common"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    File
+		wantErr string
+	}{
+		{
+			name: "valid",
+			file: File{Version: common.RuleFileVersion, DBTypeS: "oracle", DBTypeT: "tidb",
+				TableNameRules:  []TableNameRule{{SchemaNameS: "marvin", TableNameS: "t1", SchemaNameT: "steven", TableNameT: "t1"}},
+				ColumnMaskRules: []ColumnMaskRule{{SchemaNameS: "marvin", TableNameS: "t1", ColumnNameS: "c1", MaskType: common.MaskTypeNullify}}},
+		},
+		{
+			name:    "unsupported version",
+			file:    File{Version: common.RuleFileVersion + 1, DBTypeS: "ORACLE", DBTypeT: "TIDB"},
+			wantErr: "version",
+		},
+		{
+			name:    "db type mismatch",
+			file:    File{Version: common.RuleFileVersion, DBTypeS: "ORACLE", DBTypeT: "MYSQL"},
+			wantErr: "isn't match",
+		},
+		{
+			name: "empty field",
+			file: File{Version: common.RuleFileVersion, DBTypeS: "ORACLE", DBTypeT: "TIDB",
+				SchemaDatatypeRules: []SchemaDatatypeRule{{SchemaNameS: "MARVIN", ColumnTypeS: "NUMBER"}}},
+			wantErr: "field [column-type-t] can not be null",
+		},
+		{
+			name: "duplicate rule ignore case",
+			file: File{Version: common.RuleFileVersion, DBTypeS: "ORACLE", DBTypeT: "TIDB",
+				TableNameRules: []TableNameRule{
+					{SchemaNameS: "MARVIN", TableNameS: "T1", SchemaNameT: "STEVEN", TableNameT: "T1"},
+					{SchemaNameS: "marvin", TableNameS: "t1", SchemaNameT: "STEVEN", TableNameT: "T2"}}},
+			wantErr: "is duplicate",
+		},
+		{
+			name: "invalid mask type",
+			file: File{Version: common.RuleFileVersion, DBTypeS: "ORACLE", DBTypeT: "TIDB",
+				ColumnMaskRules: []ColumnMaskRule{{SchemaNameS: "MARVIN", TableNameS: "T1", ColumnNameS: "C1", MaskType: "UNKNOWN"}}},
+			wantErr: "[column-mask-rule] item [0]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.file.Validate("ORACLE", "TIDB")
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("validate error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("validate error = %v, want contains %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeFileStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		wantErr string
+	}{
+		{name: "toml", format: common.RuleFormatTOML, content: "version = 1\ndb-type-s = \"ORACLE\"\n"},
+		{name: "toml unknown field", format: common.RuleFormatTOML, content: "version = 1\ndb-type-s = \"ORACLE\"\n\n[[table-name-rule]]\nschema-name-s = \"MARVIN\"\ntable-name = \"T1\"\n", wantErr: "unknown fields"},
+		{name: "yaml unknown field", format: common.RuleFormatYAML, content: "version: 1\ndb-type: ORACLE\n", wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "rule")
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := DecodeFile(file, tt.format)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("DecodeFile() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DecodeFile() error = %v, want contains %q", err, tt.wantErr)
+			}
+		})
+	}
+}
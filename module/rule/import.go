@@ -0,0 +1,259 @@
+/*
+Copyright © 2020 Marvin
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rule
+
+import (
+	"context"
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/wentaojin/transferdb/common"
+	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/database/meta"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+type Diff struct {
+	Section string
+	Key     string
+	Status  string
+	Before  string
+	After   string
+}
+
+type keyer interface {
+	comparable
+	Key() string
+}
+
+// 对比规则文件与元数据库现有规则，返回需 upsert 的规则以及差异明细
+func diffRule[T keyer](section string, fileRules, metaRules []T) ([]T, []Diff) {
+	var (
+		upserts []T
+		diffs   []Diff
+	)
+	metaMap := make(map[string]T)
+	for _, r := range metaRules {
+		metaMap[r.Key()] = r
+	}
+	fileKeys := make(map[string]struct{})
+	for _, r := range fileRules {
+		k := r.Key()
+		fileKeys[k] = struct{}{}
+		old, ok := metaMap[k]
+		switch {
+		case !ok:
+			upserts = append(upserts, r)
+			diffs = append(diffs, Diff{Section: section, Key: k, Status: common.RuleDiffAdded, After: fmt.Sprintf("%+v", r)})
+		case old != r:
+			upserts = append(upserts, r)
+			diffs = append(diffs, Diff{Section: section, Key: k, Status: common.RuleDiffChanged, Before: fmt.Sprintf("%+v", old), After: fmt.Sprintf("%+v", r)})
+		default:
+			diffs = append(diffs, Diff{Section: section, Key: k, Status: common.RuleDiffSame})
+		}
+	}
+	// 元数据库存在而文件不存在的规则，upsert 不做删除，仅提示
+	for _, r := range metaRules {
+		if _, ok := fileKeys[r.Key()]; !ok {
+			diffs = append(diffs, Diff{Section: section, Key: r.Key(), Status: common.RuleDiffMetaOnly, Before: fmt.Sprintf("%+v", r)})
+		}
+	}
+	return upserts, diffs
+}
+
+func IImport(ctx context.Context, cfg *config.Config) error {
+	startTime := time.Now()
+	zap.L().Info("import rule tables start",
+		zap.String("db type s", cfg.DBTypeS),
+		zap.String("db type t", cfg.DBTypeT),
+		zap.String("rule file", cfg.RuleConfig.RuleFile),
+		zap.String("rule format", cfg.RuleConfig.RuleFormat),
+		zap.Bool("dry run", cfg.RuleConfig.DryRun))
+
+	f, err := DecodeFile(cfg.RuleConfig.RuleFile, cfg.RuleConfig.RuleFormat)
+	if err != nil {
+		return err
+	}
+	if err = f.Validate(cfg.DBTypeS, cfg.DBTypeT); err != nil {
+		return err
+	}
+	f.Normalize()
+
+	metaDB, err := meta.NewMetaDBEngine(ctx, cfg.MetaConfig, cfg.AppConfig.SlowlogThreshold)
+	if err != nil {
+		return err
+	}
+	current, err := LoadMetaRule(ctx, metaDB, cfg.DBTypeS, cfg.DBTypeT)
+	if err != nil {
+		return err
+	}
+
+	var diffs []Diff
+	schemaRules, d := diffRule("schema-datatype-rule", f.SchemaDatatypeRules, current.SchemaDatatypeRules)
+	diffs = append(diffs, d...)
+	tableRules, d := diffRule("table-datatype-rule", f.TableDatatypeRules, current.TableDatatypeRules)
+	diffs = append(diffs, d...)
+	columnRules, d := diffRule("column-datatype-rule", f.ColumnDatatypeRules, current.ColumnDatatypeRules)
+	diffs = append(diffs, d...)
+	columnDefaultvals, d := diffRule("buildin-column-defaultval", f.BuildinColumnDefaultval, current.BuildinColumnDefaultval)
+	diffs = append(diffs, d...)
+	globalDefaultvals, d := diffRule("buildin-global-defaultval", f.BuildinGlobalDefaultval, current.BuildinGlobalDefaultval)
+	diffs = append(diffs, d...)
+	tableNameRules, d := diffRule("table-name-rule", f.TableNameRules, current.TableNameRules)
+	diffs = append(diffs, d...)
+	columnMaskRules, d := diffRule("column-mask-rule", f.ColumnMaskRules, current.ColumnMaskRules)
+	diffs = append(diffs, d...)
+
+	fmt.Println(renderDiff(diffs))
+
+	upsertCounts := len(schemaRules) + len(tableRules) + len(columnRules) + len(columnDefaultvals) + len(globalDefaultvals) + len(tableNameRules) + len(columnMaskRules)
+	if cfg.RuleConfig.DryRun || upsertCounts == 0 {
+		zap.L().Info("import rule tables finished, skip upsert",
+			zap.Bool("dry run", cfg.RuleConfig.DryRun),
+			zap.Int("upsert counts", upsertCounts),
+			zap.String("cost", time.Now().Sub(startTime).String()))
+		return nil
+	}
+
+	batchSize := cfg.AppConfig.InsertBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	dbTypeS, dbTypeT := common.StringUPPER(cfg.DBTypeS), common.StringUPPER(cfg.DBTypeT)
+
+	var (
+		schemaRulesS       []meta.SchemaDatatypeRule
+		tableRulesS        []meta.TableDatatypeRule
+		columnRulesS       []meta.ColumnDatatypeRule
+		columnDefaultvalsS []meta.BuildinColumnDefaultval
+		globalDefaultvalsS []meta.BuildinGlobalDefaultval
+		tableNameRulesS    []meta.TableNameRule
+		columnMaskRulesS   []meta.ColumnMaskRule
+	)
+	for _, r := range schemaRules {
+		schemaRulesS = append(schemaRulesS, meta.SchemaDatatypeRule{
+			DBTypeS:     dbTypeS,
+			DBTypeT:     dbTypeT,
+			SchemaNameS: common.StringUPPER(r.SchemaNameS),
+			ColumnTypeS: r.ColumnTypeS,
+			ColumnTypeT: r.ColumnTypeT,
+			BaseModel:   &meta.BaseModel{Comment: r.Comment},
+		})
+	}
+	for _, r := range tableRules {
+		tableRulesS = append(tableRulesS, meta.TableDatatypeRule{
+			DBTypeS:     dbTypeS,
+			DBTypeT:     dbTypeT,
+			SchemaNameS: common.StringUPPER(r.SchemaNameS),
+			TableNameS:  common.StringUPPER(r.TableNameS),
+			ColumnTypeS: r.ColumnTypeS,
+			ColumnTypeT: r.ColumnTypeT,
+			BaseModel:   &meta.BaseModel{Comment: r.Comment},
+		})
+	}
+	for _, r := range columnRules {
+		columnRulesS = append(columnRulesS, meta.ColumnDatatypeRule{
+			DBTypeS:     dbTypeS,
+			DBTypeT:     dbTypeT,
+			SchemaNameS: common.StringUPPER(r.SchemaNameS),
+			TableNameS:  common.StringUPPER(r.TableNameS),
+			ColumnNameS: common.StringUPPER(r.ColumnNameS),
+			ColumnTypeS: r.ColumnTypeS,
+			ColumnTypeT: r.ColumnTypeT,
+			BaseModel:   &meta.BaseModel{Comment: r.Comment},
+		})
+	}
+	for _, r := range columnDefaultvals {
+		columnDefaultvalsS = append(columnDefaultvalsS, meta.BuildinColumnDefaultval{
+			DBTypeS:       dbTypeS,
+			DBTypeT:       dbTypeT,
+			SchemaNameS:   common.StringUPPER(r.SchemaNameS),
+			TableNameS:    common.StringUPPER(r.TableNameS),
+			ColumnNameS:   common.StringUPPER(r.ColumnNameS),
+			DefaultValueS: r.DefaultValueS,
+			DefaultValueT: r.DefaultValueT,
+			BaseModel:     &meta.BaseModel{Comment: r.Comment},
+		})
+	}
+	for _, r := range globalDefaultvals {
+		globalDefaultvalsS = append(globalDefaultvalsS, meta.BuildinGlobalDefaultval{
+			DBTypeS:       dbTypeS,
+			DBTypeT:       dbTypeT,
+			DefaultValueS: r.DefaultValueS,
+			DefaultValueT: r.DefaultValueT,
+			BaseModel:     &meta.BaseModel{Comment: r.Comment},
+		})
+	}
+	for _, r := range tableNameRules {
+		tableNameRulesS = append(tableNameRulesS, meta.TableNameRule{
+			DBTypeS:     dbTypeS,
+			DBTypeT:     dbTypeT,
+			SchemaNameS: common.StringUPPER(r.SchemaNameS),
+			TableNameS:  common.StringUPPER(r.TableNameS),
+			SchemaNameT: r.SchemaNameT,
+			TableNameT:  r.TableNameT,
+			BaseModel:   &meta.BaseModel{Comment: r.Comment},
+		})
+	}
+
+	for _, r := range columnMaskRules {
+		columnMaskRulesS = append(columnMaskRulesS, meta.ColumnMaskRule{
+			DBTypeS:     dbTypeS,
+			DBTypeT:     dbTypeT,
+			SchemaNameS: common.StringUPPER(r.SchemaNameS),
+			TableNameS:  common.StringUPPER(r.TableNameS),
+			ColumnNameS: common.StringUPPER(r.ColumnNameS),
+			MaskType:    common.StringUPPER(r.MaskType),
+			MaskValue:   r.MaskValue,
+			BaseModel:   &meta.BaseModel{Comment: r.Comment},
+		})
+	}
+
+	if err = meta.NewCommonModel(metaDB).UpsertRuleTables(ctx, schemaRulesS, tableRulesS, columnRulesS,
+		columnDefaultvalsS, globalDefaultvalsS, tableNameRulesS, columnMaskRulesS, batchSize); err != nil {
+		return err
+	}
+
+	zap.L().Info("import rule tables finished",
+		zap.String("rule file", cfg.RuleConfig.RuleFile),
+		zap.Int("upsert counts", upsertCounts),
+		zap.String("cost", time.Now().Sub(startTime).String()))
+	return nil
+}
+
+func renderDiff(diffs []Diff) string {
+	var added, changed, same, metaOnly int
+	sw := table.NewWriter()
+	sw.SetStyle(table.StyleLight)
+	sw.AppendHeader(table.Row{"RULE", "KEY", "STATUS", "META", "FILE"})
+	for _, d := range diffs {
+		switch d.Status {
+		case common.RuleDiffAdded:
+			added++
+		case common.RuleDiffChanged:
+			changed++
+		case common.RuleDiffSame:
+			same++
+			continue
+		case common.RuleDiffMetaOnly:
+			metaOnly++
+		}
+		sw.AppendRow(table.Row{d.Section, d.Key, d.Status, d.Before, d.After})
+	}
+	return strings.Join([]string{sw.Render(),
+		fmt.Sprintf("rule diff summary: added [%d] changed [%d] unchanged [%d] meta-only [%d]", added, changed, same, metaOnly)}, "\n")
+}
@@ -0,0 +1,38 @@
+package rule
+
+import (
+	"github.com/wentaojin/transferdb/common"
+	"testing"
+)
+
+func TestDiffRule(t *testing.T) {
+	metaRules := []TableNameRule{
+		{SchemaNameS: "MARVIN", TableNameS: "T1", SchemaNameT: "STEVEN", TableNameT: "T1"},
+		{SchemaNameS: "MARVIN", TableNameS: "T2", SchemaNameT: "STEVEN", TableNameT: "T2"},
+		{SchemaNameS: "MARVIN", TableNameS: "T3", SchemaNameT: "STEVEN", TableNameT: "T3"},
+	}
+	fileRules := []TableNameRule{
+		{SchemaNameS: "MARVIN", TableNameS: "T1", SchemaNameT: "STEVEN", TableNameT: "T1"},
+		{SchemaNameS: "MARVIN", TableNameS: "T2", SchemaNameT: "STEVEN", TableNameT: "T2_NEW"},
+		{SchemaNameS: "MARVIN", TableNameS: "T4", SchemaNameT: "STEVEN", TableNameT: "T4"},
+	}
+	upserts, diffs := diffRule("table-name-rule", fileRules, metaRules)
+
+	if len(upserts) != 2 || upserts[0].TableNameT != "T2_NEW" || upserts[1].TableNameS != "T4" {
+		t.Fatalf("diff rule upserts = %+v, want T2 changed and T4 added", upserts)
+	}
+	want := map[string]string{
+		"MARVIN.T1": common.RuleDiffSame,
+		"MARVIN.T2": common.RuleDiffChanged,
+		"MARVIN.T3": common.RuleDiffMetaOnly,
+		"MARVIN.T4": common.RuleDiffAdded,
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("diff rule diffs = %+v, want %d items", diffs, len(want))
+	}
+	for _, d := range diffs {
+		if d.Section != "table-name-rule" || want[d.Key] != d.Status {
+			t.Errorf("diff rule key [%s] section [%s] status = %s, want %s", d.Key, d.Section, d.Status, want[d.Key])
+		}
+	}
+}
@@ -20,7 +20,10 @@ import (
 	"fmt"
 	"github.com/wentaojin/transferdb/common"
 	"github.com/wentaojin/transferdb/config"
+	"github.com/wentaojin/transferdb/module/precheck"
 	"github.com/wentaojin/transferdb/module/prepare"
+	"github.com/wentaojin/transferdb/module/profile"
+	"github.com/wentaojin/transferdb/module/rule"
 	"strings"
 )
 
@@ -75,6 +78,36 @@ func Run(ctx context.Context, cfg *config.Config) error {
 		if err != nil {
 			return err
 		}
+	case common.TaskModeExport:
+		// 自定义转换规则导出
+		err := rule.IExport(ctx, cfg)
+		if err != nil {
+			return err
+		}
+	case common.TaskModeImport:
+		// 自定义转换规则导入
+		err := rule.IImport(ctx, cfg)
+		if err != nil {
+			return err
+		}
+	case common.TaskModeCheckpoint:
+		// 增量 checkpoint 查看以及重置
+		err := ICheckpoint(ctx, cfg)
+		if err != nil {
+			return err
+		}
+	case common.TaskModeProfile:
+		// 数据类型剖析 - 输出字段级数据类型规则文件
+		err := profile.IProfile(ctx, cfg)
+		if err != nil {
+			return err
+		}
+	case common.TaskModePrecheck:
+		// 运行前预检查 - 输出检查报告以及修复 SQL
+		err := precheck.IPrecheck(ctx, cfg)
+		if err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("flag [mode] can not null or value configure error")
 	}
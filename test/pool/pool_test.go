@@ -17,8 +17,10 @@ package main
 
 import (
 	"fmt"
+	"github.com/wentaojin/transferdb/config"
 	"github.com/wentaojin/transferdb/module/pool"
 	"golang.org/x/sync/errgroup"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -71,3 +73,35 @@ func BenchmarkErrGroup(b *testing.B) {
 	}
 	b.StopTimer()
 }
+
+func TestSchedulerGroup(t *testing.T) {
+	s := pool.NewScheduler("test", 4, config.AppConfig{AdaptiveConcurrency: true, MinConcurrency: 1, TargetWriteLatency: 1, AdjustInterval: 1})
+	defer s.Release()
+
+	var counts int64
+	g := s.NewGroup()
+	for i := 0; i < 100; i++ {
+		num := i
+		g.Go(func() error {
+			atomic.AddInt64(&counts, 1)
+			if num == 50 {
+				return fmt.Errorf("Error 1205: Lock wait timeout exceeded; try restarting transaction")
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err == nil {
+		t.Fatal("scheduler group should return task error")
+	}
+	if counts != 100 {
+		t.Fatalf("scheduler group task counts [%d] isn't equal 100", counts)
+	}
+	if !pool.IsBackoffError(fmt.Errorf("exec failed: Error 9003: TiKV server is busy")) {
+		t.Fatal("tidb server busy should backoff")
+	}
+	s.Feedback(fmt.Errorf("Error 1213: Deadlock found when trying to get lock"))
+	time.Sleep(1500 * time.Millisecond)
+	if s.Concurrency() != 2 {
+		t.Fatalf("scheduler concurrency [%d] should backoff to 2", s.Concurrency())
+	}
+}